@@ -43,6 +43,7 @@ import {ApiExplorerComponent, ApiExplorerEndpointsResolver} from './apiexplorer/
 import {PurchasesComponent, PurchasesResolver} from './account/purchases/purchases.component';
 import {ChatListComponent, ChatSearchResolver} from "./channels/chatMessages.component";
 import {SubscriptionsComponent, SubscriptionsResolver} from './account/subscriptions/subscriptions.component';
+import {NotificationSchedulesComponent, NotificationSchedulesResolver} from './notifications/notificationSchedules.component';
 
 const routes: Routes = [
   {
@@ -91,7 +92,8 @@ const routes: Routes = [
         ]
       },
       {path: 'apiexplorer', component: ApiExplorerComponent, resolve: [ApiExplorerEndpointsResolver]},
-      {path: 'chat', component: ChatListComponent, resolve: [ChatSearchResolver]}
+      {path: 'chat', component: ChatListComponent, resolve: [ChatSearchResolver]},
+      {path: 'notifications', component: NotificationSchedulesComponent, resolve: [NotificationSchedulesResolver]}
     ]},
   {path: 'login', component: LoginComponent, canActivate: [LoginGuard]},
 
@@ -0,0 +1,261 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+import {Component, Injectable, OnInit} from '@angular/core';
+import {HttpClient, HttpParams} from '@angular/common/http';
+import {ActivatedRoute, ActivatedRouteSnapshot, Resolve, Router, RouterStateSnapshot} from '@angular/router';
+import {UntypedFormBuilder, UntypedFormGroup, Validators} from '@angular/forms';
+import {Observable, of} from 'rxjs';
+import {catchError} from 'rxjs/operators';
+import {ConfigParams, UserRole} from '../console.service';
+import {AuthenticationService} from '../authentication.service';
+
+/** A notification sent later, or repeatedly on a cron schedule, to a segment of users. */
+export interface NotificationSchedule {
+  id?: string
+  subject?: string
+  content?: object
+  code?: number
+  sender_id?: string
+  persistent?: boolean
+  push?: boolean
+  // One of 'users', 'all', 'group' or 'active'.
+  segment?: string
+  user_ids?: Array<string>
+  group_id?: string
+  active_days?: number
+  // UNIX time of the next send.
+  send_time?: number
+  cron?: string
+  // One of 'scheduled', 'sending', 'complete' or 'cancelled'.
+  status?: string
+  run_count?: number
+  sent_count?: number
+  last_run_time?: number
+  create_time?: number
+  update_time?: number
+}
+
+export interface NotificationScheduleList {
+  schedules?: Array<NotificationSchedule>
+  next_cursor?: string
+}
+
+/** Calls the console notification schedule endpoints, which are not part of the generated console API. */
+@Injectable({providedIn: 'root'})
+export class NotificationScheduleService {
+  constructor(private readonly httpClient: HttpClient, private readonly config: ConfigParams) {}
+
+  listSchedules(status?: string, cursor?: string): Observable<NotificationScheduleList> {
+    let params = new HttpParams();
+    if (status) {
+      params = params.set('status', status);
+    }
+    if (cursor) {
+      params = params.set('cursor', cursor);
+    }
+    return this.httpClient.get<NotificationScheduleList>(this.config.host + '/v2/console/notification/schedule', {params});
+  }
+
+  createSchedule(body: NotificationSchedule): Observable<NotificationSchedule> {
+    return this.httpClient.post<NotificationSchedule>(this.config.host + '/v2/console/notification/schedule', body);
+  }
+
+  cancelSchedule(id: string): Observable<NotificationSchedule> {
+    return this.httpClient.post<NotificationSchedule>(this.config.host + `/v2/console/notification/schedule/${encodeURIComponent(id)}/cancel`, {});
+  }
+}
+
+@Component({
+  templateUrl: './notificationSchedules.component.html',
+  styleUrls: ['./notificationSchedules.component.scss']
+})
+export class NotificationSchedulesComponent implements OnInit {
+  public error = '';
+  public createError = '';
+  public createSuccess = false;
+  public creating = false;
+  public schedules: Array<NotificationSchedule> = [];
+  public nextCursor = '';
+  public activeStatus = '';
+  public readonly statuses = ['scheduled', 'sending', 'complete', 'cancelled'];
+  public readonly segments = ['users', 'all', 'group', 'active'];
+  public createForm: UntypedFormGroup;
+
+  constructor(
+    private readonly route: ActivatedRoute,
+    private readonly router: Router,
+    private readonly scheduleService: NotificationScheduleService,
+    private readonly authService: AuthenticationService,
+    private readonly formBuilder: UntypedFormBuilder,
+  ) {
+    this.createForm = this.formBuilder.group({
+      subject: ['', Validators.required],
+      content: '{}',
+      code: [1, Validators.compose([Validators.required, Validators.min(1)])],
+      sender_id: '',
+      persistent: true,
+      push: false,
+      segment: ['all', Validators.required],
+      user_ids: '',
+      group_id: '',
+      active_days: 7,
+      send_time: '',
+      cron: '',
+    });
+  }
+
+  ngOnInit(): void {
+    const qp = this.route.snapshot.queryParamMap;
+    this.activeStatus = qp.get('status') || '';
+    this.nextCursor = qp.get('cursor') || '';
+
+    this.route.data.subscribe(
+      d => {
+        if (d) {
+          if (d[0]) {
+            this.error = '';
+            this.schedules.length = 0;
+            this.schedules.push(...(d[0].schedules || []));
+            this.nextCursor = d[0].next_cursor;
+          }
+          if (d.error) {
+            this.error = d.error;
+          }
+        }
+      },
+      err => {
+        this.error = err;
+      });
+  }
+
+  search(state: number): void {
+    const cursor = state === 1 ? this.nextCursor : '';
+    this.scheduleService.listSchedules(this.activeStatus, cursor).subscribe(d => {
+      this.error = '';
+      this.schedules.length = 0;
+      this.schedules.push(...(d.schedules || []));
+      this.nextCursor = d.next_cursor;
+      this.router.navigate([], {
+        relativeTo: this.route,
+        queryParams: {status: this.activeStatus, cursor},
+      });
+    }, err => {
+      this.error = err;
+    });
+  }
+
+  create(): void {
+    this.createError = '';
+    this.createSuccess = false;
+
+    let content: object;
+    try {
+      content = JSON.parse(this.f.content.value || '{}');
+    } catch (e) {
+      this.createError = 'Content must be valid JSON.';
+      return;
+    }
+
+    const body: NotificationSchedule = {
+      subject: this.f.subject.value,
+      content,
+      code: Number(this.f.code.value),
+      sender_id: this.f.sender_id.value,
+      persistent: this.f.persistent.value,
+      push: this.f.push.value,
+      segment: this.f.segment.value,
+      cron: this.f.cron.value,
+    };
+    switch (body.segment) {
+      case 'users':
+        body.user_ids = this.f.user_ids.value.split(/[\s,]+/).filter(id => id !== '');
+        break;
+      case 'group':
+        body.group_id = this.f.group_id.value;
+        break;
+      case 'active':
+        body.active_days = Number(this.f.active_days.value);
+        break;
+    }
+    if (this.f.send_time.value) {
+      body.send_time = Math.floor(new Date(this.f.send_time.value).getTime() / 1000);
+    }
+
+    this.creating = true;
+    this.scheduleService.createSchedule(body).subscribe(schedule => {
+      this.creating = false;
+      this.createSuccess = true;
+      this.schedules.unshift(schedule);
+    }, err => {
+      this.creating = false;
+      this.createError = err;
+    });
+  }
+
+  cancel(event, i: number, schedule: NotificationSchedule): void {
+    event.target.disabled = true;
+    event.preventDefault();
+    this.error = '';
+    this.scheduleService.cancelSchedule(schedule.id).subscribe(updated => {
+      this.schedules[i] = updated;
+    }, err => {
+      event.target.disabled = false;
+      this.error = err;
+    });
+  }
+
+  cancelAllowed(schedule: NotificationSchedule): boolean {
+    return this.updateAllowed() && (schedule.status === 'scheduled' || schedule.status === 'sending');
+  }
+
+  updateAllowed(): boolean {
+    // Maintainers, admin and developers are allowed.
+    return this.authService.sessionRole <= UserRole.USER_ROLE_MAINTAINER;
+  }
+
+  segmentTarget(schedule: NotificationSchedule): string {
+    switch (schedule.segment) {
+      case 'users':
+        return `${schedule.user_ids?.length || 0} users`;
+      case 'group':
+        return `group ${schedule.group_id}`;
+      case 'active':
+        return `online in last ${schedule.active_days} days`;
+      default:
+        return 'all users';
+    }
+  }
+
+  formatTime(seconds: number): string {
+    return seconds ? new Date(seconds * 1000).toISOString() : '';
+  }
+
+  get f(): any {
+    return this.createForm.controls;
+  }
+}
+
+@Injectable({providedIn: 'root'})
+export class NotificationSchedulesResolver implements Resolve<NotificationScheduleList> {
+  constructor(private readonly scheduleService: NotificationScheduleService) {}
+
+  resolve(route: ActivatedRouteSnapshot, state: RouterStateSnapshot): Observable<NotificationScheduleList> {
+    return this.scheduleService.listSchedules(route.queryParamMap.get('status'), route.queryParamMap.get('cursor'))
+      .pipe(catchError(error => {
+        route.data = {...route.data, error};
+        return of(null);
+      }));
+  }
+}
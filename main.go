@@ -96,6 +96,53 @@ func main() {
 				os.Exit(1)
 			}
 			return
+		case "storage-export":
+			// Log to stderr so exported data can be written to stdout.
+			exportLogger := server.NewJSONLogger(os.Stderr, zapcore.InfoLevel, server.JSONFormat)
+			config := server.NewConfig(exportLogger)
+			var dbAddress, format, output, collection, keyPrefix, userID, updateTimeStart, updateTimeEnd string
+			flags := flag.NewFlagSet("storage-export", flag.ExitOnError)
+			flags.StringVar(&dbAddress, "database.address", config.GetDatabase().Addresses[0], "Address of CockroachDB server (username:password@address:port/dbname).")
+			flags.StringVar(&format, "format", server.StorageExportFormatJSONL, "Export format, either 'jsonl' or 'csv'.")
+			flags.StringVar(&output, "output", "", "File to write the export to. Default is stdout.")
+			flags.StringVar(&collection, "collection", "", "Only export objects in this collection.")
+			flags.StringVar(&keyPrefix, "key_prefix", "", "Only export objects whose key starts with this prefix.")
+			flags.StringVar(&userID, "user_id", "", "Only export objects owned by this user ID.")
+			flags.StringVar(&updateTimeStart, "update_time_start", "", "Only export objects updated at or after this RFC3339 time.")
+			flags.StringVar(&updateTimeEnd, "update_time_end", "", "Only export objects updated before this RFC3339 time.")
+			if err := flags.Parse(os.Args[2:]); err != nil {
+				exportLogger.Fatal("Could not parse storage-export flags.")
+			}
+			if format != server.StorageExportFormatJSONL && format != server.StorageExportFormatCSV {
+				exportLogger.Fatal("Storage export format must be 'jsonl' or 'csv'.", zap.String("format", format))
+			}
+			filter, err := server.ParseStorageExportFilter(collection, keyPrefix, userID, updateTimeStart, updateTimeEnd)
+			if err != nil {
+				exportLogger.Fatal("Invalid storage export filter.", zap.Error(err))
+			}
+			config.GetDatabase().Addresses = []string{dbAddress}
+
+			w := os.Stdout
+			if output != "" {
+				if w, err = os.Create(output); err != nil {
+					exportLogger.Fatal("Could not create storage export file.", zap.String("output", output), zap.Error(err))
+				}
+			}
+			db, _ := server.DbConnect(context.Background(), exportLogger, config)
+			count, err := server.ExportStorage(context.Background(), db, w, format, filter)
+			db.Close()
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				exportLogger.Fatal("Storage export failed.", zap.Int("count", count), zap.Error(err))
+			}
+			exportLogger.Info("Storage export complete.", zap.Int("count", count))
+			return
+		case "matchmaker-sim":
+			// Log to stderr so the report can be written to stdout.
+			server.MatchmakerSimParse(os.Args[2:], server.NewJSONLogger(os.Stderr, zapcore.InfoLevel, server.JSONFormat))
+			return
 		case "healthcheck":
 			resp, err := http.Get("http://localhost:7350")
 			if err != nil || resp.StatusCode != http.StatusOK {
@@ -146,7 +193,8 @@ func main() {
 	loginAttemptCache := server.NewLocalLoginAttemptCache()
 	statusRegistry := server.NewLocalStatusRegistry(logger, config, sessionRegistry, jsonpbMarshaler)
 	tracker := server.StartLocalTracker(logger, config, sessionRegistry, statusRegistry, metrics, jsonpbMarshaler)
-	router := server.NewLocalMessageRouter(sessionRegistry, tracker, jsonpbMarshaler)
+	notificationPusher := server.NewLocalNotificationPusher(logger, db, config.GetPush())
+	router := server.NewLocalMessageRouter(sessionRegistry, tracker, notificationPusher, jsonpbMarshaler)
 	leaderboardCache := server.NewLocalLeaderboardCache(ctx, logger, startupLogger, db)
 	leaderboardRankCache := server.NewLocalLeaderboardRankCache(ctx, startupLogger, db, config.GetLeaderboard(), leaderboardCache)
 	leaderboardScheduler := server.NewLocalLeaderboardScheduler(logger, db, config, leaderboardCache, leaderboardRankCache)
@@ -166,6 +214,8 @@ func main() {
 		startupLogger.Fatal("Failed initializing runtime modules", zap.Error(err))
 	}
 	matchmaker := server.NewLocalMatchmaker(logger, startupLogger, config, router, metrics, runtime)
+	runtime.SetMatchmaker(matchmaker)
+	runtime.SetNotificationPusher(notificationPusher)
 	partyRegistry := server.NewLocalPartyRegistry(logger, matchmaker, tracker, streamManager, router, config.GetName())
 	tracker.SetPartyJoinListener(partyRegistry.Join)
 	tracker.SetPartyLeaveListener(partyRegistry.Leave)
@@ -177,13 +227,24 @@ func main() {
 		}
 	}()
 
+	storageExpiryReaper := server.NewLocalStorageExpiryReaper(logger, db, metrics, storageIndex, config.GetStorage())
+	storageExpiryReaper.Start()
+
+	notificationScheduler := server.NewLocalNotificationScheduler(logger, db, tracker, router, config.GetNotification())
+	notificationScheduler.Start()
+
+	groupJoinRequestReaper := server.NewLocalGroupJoinRequestReaper(logger, db)
+	groupJoinRequestReaper.Start()
+
+	userOnlineTimeWriter := server.NewLocalUserOnlineTimeWriter(logger, db)
+
 	leaderboardScheduler.Start(runtime)
 	googleRefundScheduler.Start(runtime)
 
-	pipeline := server.NewPipeline(logger, config, db, jsonpbMarshaler, jsonpbUnmarshaler, sessionRegistry, statusRegistry, matchRegistry, partyRegistry, matchmaker, tracker, router, runtime)
+	pipeline := server.NewPipeline(logger, config, db, jsonpbMarshaler, jsonpbUnmarshaler, sessionRegistry, statusRegistry, matchRegistry, partyRegistry, matchmaker, tracker, router, runtime, userOnlineTimeWriter)
 	statusHandler := server.NewLocalStatusHandler(logger, sessionRegistry, matchRegistry, tracker, metrics, config.GetName())
 
-	evrPipeline := server.NewEvrPipeline(logger, startupLogger, db, jsonpbMarshaler, jsonpbUnmarshaler, config, version, socialClient, storageIndex, leaderboardScheduler, leaderboardCache, leaderboardRankCache, sessionRegistry, sessionCache, statusRegistry, matchRegistry, matchmaker, tracker, router, streamManager, metrics, pipeline, runtime)
+	evrPipeline := server.NewEvrPipeline(logger, startupLogger, db, jsonpbMarshaler, jsonpbUnmarshaler, config, version, socialClient, storageIndex, leaderboardScheduler, leaderboardCache, leaderboardRankCache, sessionRegistry, sessionCache, statusRegistry, matchRegistry, matchmaker, tracker, router, notificationPusher, streamManager, metrics, pipeline, runtime)
 	apiServer := server.StartApiServer(logger, startupLogger, db, jsonpbMarshaler, jsonpbUnmarshaler, config, version, socialClient, storageIndex, leaderboardCache, leaderboardRankCache, sessionRegistry, sessionCache, statusRegistry, matchRegistry, matchmaker, tracker, router, streamManager, metrics, pipeline, runtime, evrPipeline)
 	consoleServer := server.StartConsoleServer(logger, startupLogger, db, config, tracker, router, streamManager, metrics, sessionRegistry, sessionCache, consoleSessionCache, loginAttemptCache, statusRegistry, statusHandler, runtimeInfo, matchRegistry, configWarnings, semver, leaderboardCache, leaderboardRankCache, leaderboardScheduler, storageIndex, apiServer, runtime, cookie)
 
@@ -223,19 +284,42 @@ func main() {
 		startupLogger.Info("Shutdown started")
 	}
 
+	// Drain the broadcasters, letting active matches end naturally.
+	var skipGraceful bool
+	if graceSeconds != 0 {
+		select {
+		case <-evrPipeline.Drain():
+			startupLogger.Info("All broadcasters drained")
+		case <-timerCh:
+			startupLogger.Info("Shutdown grace period expired while draining broadcasters")
+			// Stop the matches immediately.
+			expired := make(chan time.Time)
+			close(expired)
+			timerCh = expired
+		case <-c:
+			// A second interrupt has been received, skip the rest of the grace period too.
+			startupLogger.Info("Skipping graceful shutdown")
+			skipGraceful = true
+		}
+	}
+
 	// Stop any running authoritative matches and do not accept any new ones.
-	select {
-	case <-matchRegistry.Stop(graceSeconds):
-		// Graceful shutdown has completed.
-		startupLogger.Info("All authoritative matches stopped")
-	case <-timerCh:
-		// Timer has expired, terminate matches immediately.
-		startupLogger.Info("Shutdown grace period expired")
-		<-matchRegistry.Stop(0)
-	case <-c:
-		// A second interrupt has been received.
-		startupLogger.Info("Skipping graceful shutdown")
+	if skipGraceful {
 		<-matchRegistry.Stop(0)
+	} else {
+		select {
+		case <-matchRegistry.Stop(graceSeconds):
+			// Graceful shutdown has completed.
+			startupLogger.Info("All authoritative matches stopped")
+		case <-timerCh:
+			// Timer has expired, terminate matches immediately.
+			startupLogger.Info("Shutdown grace period expired")
+			<-matchRegistry.Stop(0)
+		case <-c:
+			// A second interrupt has been received.
+			startupLogger.Info("Skipping graceful shutdown")
+			<-matchRegistry.Stop(0)
+		}
 	}
 	if timer != nil {
 		timer.Stop()
@@ -251,10 +335,16 @@ func main() {
 	matchmaker.Stop()
 	leaderboardScheduler.Stop()
 	googleRefundScheduler.Stop()
+	storageExpiryReaper.Stop()
+	notificationScheduler.Stop()
+	groupJoinRequestReaper.Stop()
+	notificationPusher.Stop()
 	tracker.Stop()
 	statusRegistry.Stop()
 	sessionCache.Stop()
 	sessionRegistry.Stop()
+	userOnlineTimeWriter.Stop()
+	storageIndex.Stop()
 	metrics.Stop(logger)
 	loginAttemptCache.Stop()
 
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS leaderboard_rank_history (
+    PRIMARY KEY (owner_id, leaderboard_id, expiry_time),
+    FOREIGN KEY (leaderboard_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+
+    leaderboard_id VARCHAR(128) NOT NULL,
+    owner_id       UUID         NOT NULL,
+    expiry_time    TIMESTAMPTZ  NOT NULL,
+    username       VARCHAR(128),
+    score          BIGINT       NOT NULL DEFAULT 0,
+    subscore       BIGINT       NOT NULL DEFAULT 0,
+    num_score      INT          NOT NULL DEFAULT 1,
+    rank           BIGINT       NOT NULL,
+    total          BIGINT       NOT NULL,
+    create_time    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS leaderboard_rank_history;
@@ -0,0 +1,29 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS leaderboard_derived_source (
+    PRIMARY KEY (leaderboard_id, source_id),
+    FOREIGN KEY (leaderboard_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+    FOREIGN KEY (source_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+
+    leaderboard_id VARCHAR(128)     NOT NULL,
+    source_id      VARCHAR(128)     NOT NULL,
+    weight         DOUBLE PRECISION NOT NULL DEFAULT 1
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS leaderboard_derived_source;
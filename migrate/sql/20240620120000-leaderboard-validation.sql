@@ -0,0 +1,49 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS leaderboard_validation (
+    PRIMARY KEY (leaderboard_id),
+    FOREIGN KEY (leaderboard_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+
+    leaderboard_id          VARCHAR(128) NOT NULL,
+    max_score_delta         BIGINT       NOT NULL DEFAULT 0 CHECK (max_score_delta >= 0),
+    rate_limit_count        INT          NOT NULL DEFAULT 0 CHECK (rate_limit_count >= 0),
+    rate_limit_interval_sec INT          NOT NULL DEFAULT 0 CHECK (rate_limit_interval_sec >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS leaderboard_record_quarantine (
+    PRIMARY KEY (leaderboard_id, owner_id),
+    FOREIGN KEY (leaderboard_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+
+    leaderboard_id VARCHAR(128) NOT NULL,
+    owner_id       UUID         NOT NULL,
+    username       VARCHAR(128),
+    score          BIGINT       NOT NULL DEFAULT 0,
+    subscore       BIGINT       NOT NULL DEFAULT 0,
+    metadata       JSONB        NOT NULL DEFAULT '{}',
+    operator       SMALLINT     NOT NULL DEFAULT 0,
+    reason         VARCHAR(512) NOT NULL DEFAULT '',
+    expiry_time    TIMESTAMPTZ  NOT NULL DEFAULT '1970-01-01 00:00:00 UTC',
+    create_time    TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS leaderboard_record_quarantine_create_time_idx
+    ON leaderboard_record_quarantine (leaderboard_id, create_time, owner_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS leaderboard_record_quarantine;
+DROP TABLE IF EXISTS leaderboard_validation;
@@ -0,0 +1,25 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+-- Only the string values of the content are searched, not its keys or JSON syntax.
+CREATE INDEX IF NOT EXISTS message_content_search_idx ON message USING GIN (jsonb_to_tsvector('simple', content, '["string"]'));
+
+CREATE INDEX IF NOT EXISTS message_sender_id_create_time_idx ON message (sender_id, create_time);
+
+-- +migrate Down
+DROP INDEX IF EXISTS message_sender_id_create_time_idx;
+DROP INDEX IF EXISTS message_content_search_idx;
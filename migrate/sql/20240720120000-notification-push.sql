@@ -0,0 +1,42 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS user_push_token (
+    PRIMARY KEY (provider, token),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+
+    provider    VARCHAR(64)   NOT NULL,
+    token       VARCHAR(4096) NOT NULL,
+    user_id     UUID          NOT NULL,
+    create_time TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    update_time TIMESTAMPTZ   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_push_token_user_id_idx ON user_push_token (user_id);
+
+CREATE TABLE IF NOT EXISTS user_notification_opt_out (
+    PRIMARY KEY (user_id, code),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+
+    user_id     UUID        NOT NULL,
+    code        INTEGER     NOT NULL,
+    create_time TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS user_notification_opt_out;
+DROP TABLE IF EXISTS user_push_token;
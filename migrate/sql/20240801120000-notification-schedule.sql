@@ -0,0 +1,53 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS notification_schedule (
+    PRIMARY KEY (id),
+
+    id            UUID         NOT NULL,
+    subject       VARCHAR(255) NOT NULL,
+    content       JSONB        NOT NULL DEFAULT '{}',
+    code          SMALLINT     NOT NULL,
+    sender_id     UUID         NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    persistent    BOOLEAN      NOT NULL DEFAULT TRUE,
+    push          BOOLEAN      NOT NULL DEFAULT FALSE,
+    segment       VARCHAR(16)  NOT NULL, -- users, all, group, active
+    user_ids      UUID[]       NOT NULL DEFAULT '{}',
+    group_id      UUID,
+    active_days   INTEGER      NOT NULL DEFAULT 0,
+    cron          VARCHAR(255) NOT NULL DEFAULT '',
+    status        VARCHAR(16)  NOT NULL, -- scheduled, sending, complete, cancelled
+    next_run_time TIMESTAMPTZ,
+    run_cursor    UUID,        -- The last user sent to in the current run.
+    run_count     INTEGER      NOT NULL DEFAULT 0,
+    sent_count    BIGINT       NOT NULL DEFAULT 0,
+    last_run_time TIMESTAMPTZ,
+    create_time   TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    update_time   TIMESTAMPTZ  NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notification_schedule_status_next_run_time_idx ON notification_schedule (status, next_run_time);
+CREATE INDEX IF NOT EXISTS notification_schedule_create_time_id_idx ON notification_schedule (create_time, id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS online_time TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_online_time_id_idx ON users (online_time, id) WHERE online_time IS NOT NULL;
+
+-- +migrate Down
+DROP INDEX IF EXISTS users_online_time_id_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS online_time;
+DROP TABLE IF EXISTS notification_schedule;
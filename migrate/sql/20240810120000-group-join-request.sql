@@ -0,0 +1,40 @@
+/*
+ * Copyright 2024 The Nakama Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+-- +migrate Up
+ALTER TABLE groups
+    ADD COLUMN IF NOT EXISTS join_questions          JSONB   NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS join_request_expiry_sec INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS group_join_request (
+    PRIMARY KEY (group_id, user_id),
+    FOREIGN KEY (group_id) REFERENCES groups (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+
+    group_id    UUID        NOT NULL,
+    user_id     UUID        NOT NULL,
+    answers     JSONB       NOT NULL DEFAULT '{}',
+    create_time TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expire_time TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS group_join_request_expire_time_idx ON group_join_request (expire_time) WHERE expire_time IS NOT NULL;
+
+-- +migrate Down
+DROP TABLE IF EXISTS group_join_request;
+ALTER TABLE groups
+    DROP COLUMN IF EXISTS join_request_expiry_sec,
+    DROP COLUMN IF EXISTS join_questions;
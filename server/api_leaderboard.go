@@ -21,6 +21,7 @@ import (
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -200,6 +201,13 @@ func (s *ApiServer) WriteLeaderboardRecord(ctx context.Context, in *api.WriteLea
 		return nil, status.Error(codes.NotFound, "Leaderboard not found.")
 	} else if err == ErrLeaderboardAuthoritative {
 		return nil, status.Error(codes.PermissionDenied, "Leaderboard only allows authoritative score submissions.")
+	} else if err == ErrLeaderboardRecordRateLimited {
+		return nil, status.Error(codes.ResourceExhausted, "Leaderboard score submissions too frequent.")
+	} else if err == ErrLeaderboardRecordQuarantined {
+		return nil, status.Error(codes.FailedPrecondition, "Leaderboard score submission held for review.")
+	} else if runtimeErr, ok := err.(*runtime.Error); ok && runtimeErr.Code > 0 && runtimeErr.Code < 17 {
+		// Rejected by the runtime validation function.
+		return nil, status.Error(codes.Code(runtimeErr.Code), runtimeErr.Message)
 	} else if err != nil {
 		return nil, status.Error(codes.Internal, "Error writing score to leaderboard.")
 	}
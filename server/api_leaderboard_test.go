@@ -113,7 +113,7 @@ nk.leaderboard_create(%q, %v, %q, %q, reset, metadata)
 		tracker := &LocalTracker{}
 		sessionCache := NewLocalSessionCache(1_000, 3_600)
 
-		pipeline := NewPipeline(logger, cfg, db, protojsonMarshaler, protojsonUnmarshaler, nil, nil, nil, nil, nil, tracker, router, runtime)
+		pipeline := NewPipeline(logger, cfg, db, protojsonMarshaler, protojsonUnmarshaler, nil, nil, nil, nil, nil, tracker, router, runtime, nil)
 
 		apiServer := StartApiServer(logger, logger, db, protojsonMarshaler,
 			protojsonUnmarshaler, cfg, "3.0.0", nil, nil, rtData.leaderboardCache,
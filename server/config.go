@@ -52,6 +52,9 @@ type Config interface {
 	GetGoogleAuth() *GoogleAuthConfig
 	GetSatori() *SatoriConfig
 	GetStorage() *StorageConfig
+	GetChat() *ChatConfig
+	GetPush() *PushConfig
+	GetNotification() *NotificationConfig
 
 	Clone() (Config, error)
 }
@@ -290,6 +293,64 @@ func CheckConfig(logger *zap.Logger, config Config) map[string]string {
 	if config.GetMatchmaker().RevThreshold < 0 {
 		logger.Fatal("Matchmaker reverse matching threshold must be >= 0", zap.Int("matchmaker.rev_threshold", config.GetMatchmaker().RevThreshold))
 	}
+	if config.GetMatchmaker().StatsWindow < 1 {
+		logger.Fatal("Matchmaker stats window must be >= 1", zap.Int("matchmaker.stats_window", config.GetMatchmaker().StatsWindow))
+	}
+	if config.GetMatchmaker().StatsQueues < 1 {
+		logger.Fatal("Matchmaker stats queues must be >= 1", zap.Int("matchmaker.stats_queues", config.GetMatchmaker().StatsQueues))
+	}
+
+	// Storage index directories are relative to the data directory.
+	if config.GetStorage().IndexDir != "" && !filepath.IsAbs(config.GetStorage().IndexDir) {
+		config.GetStorage().IndexDir = filepath.Join(config.GetDataDir(), config.GetStorage().IndexDir)
+	}
+	if config.GetStorage().ChangeFeedFile != "" && !filepath.IsAbs(config.GetStorage().ChangeFeedFile) {
+		config.GetStorage().ChangeFeedFile = filepath.Join(config.GetDataDir(), config.GetStorage().ChangeFeedFile)
+	}
+	if config.GetStorage().ChangeFeedQueueSize < 1 {
+		logger.Fatal("Storage change feed queue size must be >= 1", zap.Int("storage.change_feed_queue_size", config.GetStorage().ChangeFeedQueueSize))
+	}
+	if config.GetStorage().ExpiryReapIntervalSec < 0 {
+		logger.Fatal("Storage expiry reap interval seconds must be >= 0", zap.Int("storage.expiry_reap_interval_sec", config.GetStorage().ExpiryReapIntervalSec))
+	}
+	if config.GetStorage().ExpiryReapBatchSize < 1 {
+		logger.Fatal("Storage expiry reap batch size must be >= 1", zap.Int("storage.expiry_reap_batch_size", config.GetStorage().ExpiryReapBatchSize))
+	}
+	if config.GetStorage().ChangeFeedWebhookRetries < 0 {
+		logger.Fatal("Storage change feed webhook retries must be >= 0", zap.Int("storage.change_feed_webhook_retries", config.GetStorage().ChangeFeedWebhookRetries))
+	}
+	if config.GetChat().SearchMaxLimit < 1 {
+		logger.Fatal("Chat search max limit must be >= 1", zap.Int("chat.search_max_limit", config.GetChat().SearchMaxLimit))
+	}
+	contentFilter, err := NewChannelMessageFilter(config.GetChat().FilterWords, config.GetChat().FilterAction)
+	if err != nil {
+		logger.Fatal("Invalid chat content filter", zap.Strings("chat.filter_words", config.GetChat().FilterWords), zap.String("chat.filter_action", config.GetChat().FilterAction), zap.Error(err))
+	}
+	config.GetChat().contentFilter = contentFilter
+	if config.GetPush().QueueSize < 1 {
+		logger.Fatal("Push queue size must be >= 1", zap.Int("push.queue_size", config.GetPush().QueueSize))
+	}
+	if config.GetPush().Workers < 1 {
+		logger.Fatal("Push workers must be >= 1", zap.Int("push.workers", config.GetPush().Workers))
+	}
+	if config.GetPush().MaxAttempts < 1 {
+		logger.Fatal("Push max attempts must be >= 1", zap.Int("push.max_attempts", config.GetPush().MaxAttempts))
+	}
+	if config.GetPush().RetryBackoffMs < 0 {
+		logger.Fatal("Push retry backoff milliseconds must be >= 0", zap.Int("push.retry_backoff_ms", config.GetPush().RetryBackoffMs))
+	}
+	if config.GetPush().ApnsURL != "" && config.GetPush().ApnsTopic == "" {
+		logger.Fatal("Push APNs topic must be set when the APNs URL is set", zap.String("push.apns_url", config.GetPush().ApnsURL))
+	}
+	if config.GetNotification().ScheduleIntervalSec < 0 {
+		logger.Fatal("Notification schedule interval seconds must be >= 0", zap.Int("notification.schedule_interval_sec", config.GetNotification().ScheduleIntervalSec))
+	}
+	if config.GetNotification().ScheduleBatchSize < 1 {
+		logger.Fatal("Notification schedule batch size must be >= 1", zap.Int("notification.schedule_batch_size", config.GetNotification().ScheduleBatchSize))
+	}
+	if config.GetNotification().ScheduleMaxUserIds < 1 {
+		logger.Fatal("Notification schedule max user IDs must be >= 1", zap.Int("notification.schedule_max_user_ids", config.GetNotification().ScheduleMaxUserIds))
+	}
 
 	// If the runtime path is not overridden, set it to `datadir/modules`.
 	if config.GetRuntime().Path == "" {
@@ -435,26 +496,29 @@ func convertRuntimeEnv(logger *zap.Logger, existingEnv map[string]string, mergeE
 }
 
 type config struct {
-	Name             string             `yaml:"name" json:"name" usage:"Nakama server’s node name - must be unique."`
-	Config           []string           `yaml:"config" json:"config" usage:"The absolute file path to configuration YAML file."`
-	ShutdownGraceSec int                `yaml:"shutdown_grace_sec" json:"shutdown_grace_sec" usage:"Maximum number of seconds to wait for the server to complete work before shutting down. Default is 0 seconds. If 0 the server will shut down immediately when it receives a termination signal."`
-	Datadir          string             `yaml:"data_dir" json:"data_dir" usage:"An absolute path to a writeable folder where Nakama will store its data."`
-	Logger           *LoggerConfig      `yaml:"logger" json:"logger" usage:"Logger levels and output."`
-	Metrics          *MetricsConfig     `yaml:"metrics" json:"metrics" usage:"Metrics settings."`
-	Session          *SessionConfig     `yaml:"session" json:"session" usage:"Session authentication settings."`
-	Socket           *SocketConfig      `yaml:"socket" json:"socket" usage:"Socket configuration."`
-	Database         *DatabaseConfig    `yaml:"database" json:"database" usage:"Database connection settings."`
-	Social           *SocialConfig      `yaml:"social" json:"social" usage:"Properties for social provider integrations."`
-	Runtime          *RuntimeConfig     `yaml:"runtime" json:"runtime" usage:"Script Runtime properties."`
-	Match            *MatchConfig       `yaml:"match" json:"match" usage:"Authoritative realtime match properties."`
-	Tracker          *TrackerConfig     `yaml:"tracker" json:"tracker" usage:"Presence tracker properties."`
-	Console          *ConsoleConfig     `yaml:"console" json:"console" usage:"Console settings."`
-	Leaderboard      *LeaderboardConfig `yaml:"leaderboard" json:"leaderboard" usage:"Leaderboard settings."`
-	Matchmaker       *MatchmakerConfig  `yaml:"matchmaker" json:"matchmaker" usage:"Matchmaker settings."`
-	IAP              *IAPConfig         `yaml:"iap" json:"iap" usage:"In-App Purchase settings."`
-	GoogleAuth       *GoogleAuthConfig  `yaml:"google_auth" json:"google_auth" usage:"Google's auth settings."`
-	Satori           *SatoriConfig      `yaml:"satori" json:"satori" usage:"Satori integration settings."`
-	Storage          *StorageConfig     `yaml:"storage" json:"storage" usage:"Storage settings."`
+	Name             string              `yaml:"name" json:"name" usage:"Nakama server’s node name - must be unique."`
+	Config           []string            `yaml:"config" json:"config" usage:"The absolute file path to configuration YAML file."`
+	ShutdownGraceSec int                 `yaml:"shutdown_grace_sec" json:"shutdown_grace_sec" usage:"Maximum number of seconds to wait for the server to complete work before shutting down. Default is 0 seconds. If 0 the server will shut down immediately when it receives a termination signal."`
+	Datadir          string              `yaml:"data_dir" json:"data_dir" usage:"An absolute path to a writeable folder where Nakama will store its data."`
+	Logger           *LoggerConfig       `yaml:"logger" json:"logger" usage:"Logger levels and output."`
+	Metrics          *MetricsConfig      `yaml:"metrics" json:"metrics" usage:"Metrics settings."`
+	Session          *SessionConfig      `yaml:"session" json:"session" usage:"Session authentication settings."`
+	Socket           *SocketConfig       `yaml:"socket" json:"socket" usage:"Socket configuration."`
+	Database         *DatabaseConfig     `yaml:"database" json:"database" usage:"Database connection settings."`
+	Social           *SocialConfig       `yaml:"social" json:"social" usage:"Properties for social provider integrations."`
+	Runtime          *RuntimeConfig      `yaml:"runtime" json:"runtime" usage:"Script Runtime properties."`
+	Match            *MatchConfig        `yaml:"match" json:"match" usage:"Authoritative realtime match properties."`
+	Tracker          *TrackerConfig      `yaml:"tracker" json:"tracker" usage:"Presence tracker properties."`
+	Console          *ConsoleConfig      `yaml:"console" json:"console" usage:"Console settings."`
+	Leaderboard      *LeaderboardConfig  `yaml:"leaderboard" json:"leaderboard" usage:"Leaderboard settings."`
+	Matchmaker       *MatchmakerConfig   `yaml:"matchmaker" json:"matchmaker" usage:"Matchmaker settings."`
+	IAP              *IAPConfig          `yaml:"iap" json:"iap" usage:"In-App Purchase settings."`
+	GoogleAuth       *GoogleAuthConfig   `yaml:"google_auth" json:"google_auth" usage:"Google's auth settings."`
+	Satori           *SatoriConfig       `yaml:"satori" json:"satori" usage:"Satori integration settings."`
+	Storage          *StorageConfig      `yaml:"storage" json:"storage" usage:"Storage settings."`
+	Chat             *ChatConfig         `yaml:"chat" json:"chat" usage:"Chat channel settings."`
+	Push             *PushConfig         `yaml:"push" json:"push" usage:"Push notification settings."`
+	Notification     *NotificationConfig `yaml:"notification" json:"notification" usage:"Scheduled notification settings."`
 }
 
 // NewConfig constructs a Config struct which represents server settings, and populates it with default values.
@@ -483,6 +547,9 @@ func NewConfig(logger *zap.Logger) *config {
 		GoogleAuth:       NewGoogleAuthConfig(),
 		Satori:           NewSatoriConfig(),
 		Storage:          NewStorageConfig(),
+		Chat:             NewChatConfig(),
+		Push:             NewPushConfig(),
+		Notification:     NewNotificationConfig(),
 	}
 }
 
@@ -503,6 +570,9 @@ func (c *config) Clone() (Config, error) {
 	configSatori := *(c.Satori)
 	configStorage := *(c.Storage)
 	configGoogleAuth := *(c.GoogleAuth)
+	configChat := *(c.Chat)
+	configPush := *(c.Push)
+	configNotification := *(c.Notification)
 	nc := &config{
 		Name:             c.Name,
 		Datadir:          c.Datadir,
@@ -523,6 +593,9 @@ func (c *config) Clone() (Config, error) {
 		Satori:           &configSatori,
 		GoogleAuth:       &configGoogleAuth,
 		Storage:          &configStorage,
+		Chat:             &configChat,
+		Push:             &configPush,
+		Notification:     &configNotification,
 	}
 	nc.Socket.CertPEMBlock = make([]byte, len(c.Socket.CertPEMBlock))
 	copy(nc.Socket.CertPEMBlock, c.Socket.CertPEMBlock)
@@ -545,6 +618,10 @@ func (c *config) Clone() (Config, error) {
 	}
 	nc.Leaderboard.BlacklistRankCache = make([]string, len(c.Leaderboard.BlacklistRankCache))
 	copy(nc.Leaderboard.BlacklistRankCache, c.Leaderboard.BlacklistRankCache)
+	nc.Leaderboard.BlacklistRankHistory = make([]string, len(c.Leaderboard.BlacklistRankHistory))
+	copy(nc.Leaderboard.BlacklistRankHistory, c.Leaderboard.BlacklistRankHistory)
+	nc.Chat.FilterWords = make([]string, len(c.Chat.FilterWords))
+	copy(nc.Chat.FilterWords, c.Chat.FilterWords)
 
 	return nc, nil
 }
@@ -625,6 +702,18 @@ func (c *config) GetStorage() *StorageConfig {
 	return c.Storage
 }
 
+func (c *config) GetChat() *ChatConfig {
+	return c.Chat
+}
+
+func (c *config) GetPush() *PushConfig {
+	return c.Push
+}
+
+func (c *config) GetNotification() *NotificationConfig {
+	return c.Notification
+}
+
 // LoggerConfig is configuration relevant to logging levels and output.
 type LoggerConfig struct {
 	Level    string `yaml:"level" json:"level" usage:"Log level to set. Valid values are 'debug', 'info', 'warn', 'error'. Default 'info'."`
@@ -964,12 +1053,14 @@ type LeaderboardConfig struct {
 	BlacklistRankCache   []string `yaml:"blacklist_rank_cache" json:"blacklist_rank_cache" usage:"Disable rank cache for leaderboards with matching identifiers. To disable rank cache entirely, use '*', otherwise leave blank to enable rank cache."`
 	CallbackQueueSize    int      `yaml:"callback_queue_size" json:"callback_queue_size" usage:"Size of the leaderboard and tournament callback queue that sequences expiry/reset/end invocations. Default 65536."`
 	CallbackQueueWorkers int      `yaml:"callback_queue_workers" json:"callback_queue_workers" usage:"Number of workers to use for concurrent processing of leaderboard and tournament callbacks. Default 8."`
+	BlacklistRankHistory []string `yaml:"blacklist_rank_history" json:"blacklist_rank_history" usage:"Disable rank history snapshots, taken when a leaderboard or tournament period expires, for leaderboards with matching identifiers. To disable rank history entirely, use '*'."`
 	RankCacheWorkers     int      `yaml:"rank_cache_workers" json:"rank_cache_workers" usage:"The number of parallel workers to use while populating leaderboard rank cache from the database. Higher number of workers usually makes the process faster but at the cost of increased database load. Default 1."`
 }
 
 func NewLeaderboardConfig() *LeaderboardConfig {
 	return &LeaderboardConfig{
 		BlacklistRankCache:   []string{},
+		BlacklistRankHistory: []string{},
 		CallbackQueueSize:    65536,
 		CallbackQueueWorkers: 8,
 		RankCacheWorkers:     1,
@@ -977,11 +1068,14 @@ func NewLeaderboardConfig() *LeaderboardConfig {
 }
 
 type MatchmakerConfig struct {
-	MaxTickets   int  `yaml:"max_tickets" json:"max_tickets" usage:"Maximum number of concurrent matchmaking tickets allowed per session or party. Default 3."`
-	IntervalSec  int  `yaml:"interval_sec" json:"interval_sec" usage:"How quickly the matchmaker attempts to form matches, in seconds. Default 15."`
-	MaxIntervals int  `yaml:"max_intervals" json:"max_intervals" usage:"How many intervals the matchmaker attempts to find matches at the max player count, before allowing min count. Default 2."`
-	RevPrecision bool `yaml:"rev_precision" json:"rev_precision" usage:"Reverse matching precision. Default false."`
-	RevThreshold int  `yaml:"rev_threshold" json:"rev_threshold" usage:"Reverse matching threshold. Default 1."`
+	MaxTickets    int    `yaml:"max_tickets" json:"max_tickets" usage:"Maximum number of concurrent matchmaking tickets allowed per session or party. Default 3."`
+	IntervalSec   int    `yaml:"interval_sec" json:"interval_sec" usage:"How quickly the matchmaker attempts to form matches, in seconds. Default 15."`
+	MaxIntervals  int    `yaml:"max_intervals" json:"max_intervals" usage:"How many intervals the matchmaker attempts to find matches at the max player count, before allowing min count. Default 2."`
+	RevPrecision  bool   `yaml:"rev_precision" json:"rev_precision" usage:"Reverse matching precision. Default false."`
+	RevThreshold  int    `yaml:"rev_threshold" json:"rev_threshold" usage:"Reverse matching threshold. Default 1."`
+	QueueProperty string `yaml:"queue_property" json:"queue_property" usage:"String property grouping tickets into queues for status and wait time statistics. Default 'mode'."`
+	StatsWindow   int    `yaml:"stats_window" json:"stats_window" usage:"Number of recently matched tickets per queue used to estimate wait times. Default 100."`
+	StatsQueues   int    `yaml:"stats_queues" json:"stats_queues" usage:"Maximum number of queues to keep wait time statistics for, the least recently matched queue is dropped first. Default 100."`
 }
 
 func NewMatchmakerConfig() *MatchmakerConfig {
@@ -991,6 +1085,10 @@ func NewMatchmakerConfig() *MatchmakerConfig {
 		MaxIntervals: 2,
 		RevPrecision: false,
 		RevThreshold: 1,
+
+		QueueProperty: "mode",
+		StatsWindow:   100,
+		StatsQueues:   100,
 	}
 }
 
@@ -1085,9 +1183,84 @@ func NewGoogleAuthConfig() *GoogleAuthConfig {
 }
 
 type StorageConfig struct {
-	DisableIndexOnly bool `yaml:"disable_index_only" json:"disable_index_only" usage:"Override and disable 'index_only' storage indices config and fallback to reading from the database."`
+	DisableIndexOnly bool   `yaml:"disable_index_only" json:"disable_index_only" usage:"Override and disable 'index_only' storage indices config and fallback to reading from the database."`
+	IndexDir         string `yaml:"index_dir" json:"index_dir" usage:"Persist storage indices to this directory, so startup only replays storage changes since the last checkpoint. Relative paths are under the data directory. Default is empty, indices are kept in memory only."`
+
+	ChangeFeedQueueSize      int      `yaml:"change_feed_queue_size" json:"change_feed_queue_size" usage:"Maximum number of storage change events waiting to be delivered, further events are dropped. Default 10000."`
+	ChangeFeedCollections    []string `yaml:"change_feed_collections" json:"change_feed_collections" usage:"Only send storage changes in these collections to the change feed sinks. Default is empty, all collections are sent."`
+	ChangeFeedWebhookURL     string   `yaml:"change_feed_webhook_url" json:"change_feed_webhook_url" usage:"POST batches of storage change events as JSON to this URL. Default is empty, disabled."`
+	ChangeFeedWebhookRetries int      `yaml:"change_feed_webhook_retries" json:"change_feed_webhook_retries" usage:"Number of times a failed change feed webhook request is retried before the batch is dropped. Default 5."`
+	ChangeFeedFile           string   `yaml:"change_feed_file" json:"change_feed_file" usage:"Append storage change events as JSON lines to this file. Relative paths are under the data directory. Default is empty, disabled."`
+
+	ExpiryReapIntervalSec int `yaml:"expiry_reap_interval_sec" json:"expiry_reap_interval_sec" usage:"How often, in seconds, storage objects past their expiry time are deleted. Set to 0 to disable. Default 60."`
+	ExpiryReapBatchSize   int `yaml:"expiry_reap_batch_size" json:"expiry_reap_batch_size" usage:"Maximum number of expired storage objects deleted per database query. Default 1000."`
 }
 
 func NewStorageConfig() *StorageConfig {
-	return &StorageConfig{}
+	return &StorageConfig{
+		ChangeFeedQueueSize:      10_000,
+		ChangeFeedWebhookRetries: 5,
+		ExpiryReapIntervalSec:    60,
+		ExpiryReapBatchSize:      1000,
+	}
+}
+
+type ChatConfig struct {
+	FilterWords    []string `yaml:"filter_words" json:"filter_words" usage:"Words and phrases filtered from chat channel messages, matched as whole words regardless of case. Default is empty, no filtering."`
+	FilterAction   string   `yaml:"filter_action" json:"filter_action" usage:"What happens to chat channel messages containing filtered words. Valid values are 'redact' to replace them with asterisks, or 'reject' to refuse the message. Default 'redact'."`
+	SearchMaxLimit int      `yaml:"search_max_limit" json:"search_max_limit" usage:"Maximum number of chat channel messages returned by a single search. Default 100."`
+
+	contentFilter *ChannelMessageFilter
+}
+
+func NewChatConfig() *ChatConfig {
+	return &ChatConfig{
+		FilterWords:    []string{},
+		FilterAction:   ChannelMessageFilterRedact,
+		SearchMaxLimit: 100,
+	}
+}
+
+// ContentFilter returns the filter applied to sent and updated chat channel messages, or nil if there is none.
+func (c *ChatConfig) ContentFilter() *ChannelMessageFilter {
+	return c.contentFilter
+}
+
+type PushConfig struct {
+	QueueSize      int `yaml:"queue_size" json:"queue_size" usage:"Maximum number of push notifications waiting to be sent or retried, further pushes are dropped. Default 10000."`
+	Workers        int `yaml:"workers" json:"workers" usage:"Number of push notifications sent concurrently. Default 4."`
+	MaxAttempts    int `yaml:"max_attempts" json:"max_attempts" usage:"Number of times a push notification is attempted before it is dropped. Default 5."`
+	RetryBackoffMs int `yaml:"retry_backoff_ms" json:"retry_backoff_ms" usage:"Delay in milliseconds before a failed push notification is retried, doubled on each further attempt. Default 1000."`
+
+	FcmURL       string `yaml:"fcm_url" json:"fcm_url" usage:"Send push notifications to device tokens registered for the 'fcm' provider by POSTing FCM HTTP v1 messages to this URL, for example 'https://fcm.googleapis.com/v1/projects/<project>/messages:send'. Default is empty, disabled."`
+	FcmKey       string `yaml:"fcm_key" json:"fcm_key" usage:"Bearer token sent with FCM requests."`
+	ApnsURL      string `yaml:"apns_url" json:"apns_url" usage:"Send push notifications to device tokens registered for the 'apns' provider through this APNs server, for example 'https://api.push.apple.com'. Default is empty, disabled."`
+	ApnsKey      string `yaml:"apns_key" json:"apns_key" usage:"Bearer provider token sent with APNs requests."`
+	ApnsTopic    string `yaml:"apns_topic" json:"apns_topic" usage:"APNs topic, usually the app's bundle ID. Required when the APNs URL is set."`
+	WebhookURL   string `yaml:"webhook_url" json:"webhook_url" usage:"POST push notifications for offline users as JSON to this URL. Default is empty, disabled."`
+	WebhookKey   string `yaml:"webhook_key" json:"webhook_key" usage:"Sign webhook requests with an HMAC-SHA256 of the body using this key, sent in the 'X-Nakama-Signature' header. Default is empty, requests are not signed."`
+	LocalEnabled bool   `yaml:"local_enabled" json:"local_enabled" usage:"Log push notifications for offline users instead of sending them anywhere, for local testing. Default false."`
+}
+
+func NewPushConfig() *PushConfig {
+	return &PushConfig{
+		QueueSize:      10_000,
+		Workers:        4,
+		MaxAttempts:    5,
+		RetryBackoffMs: 1000,
+	}
+}
+
+type NotificationConfig struct {
+	ScheduleIntervalSec int `yaml:"schedule_interval_sec" json:"schedule_interval_sec" usage:"How often, in seconds, scheduled notifications that are due are sent. Set to 0 to disable sending scheduled notifications on this node. Default 10."`
+	ScheduleBatchSize   int `yaml:"schedule_batch_size" json:"schedule_batch_size" usage:"Number of users a scheduled notification is sent to per batch. Default 1000."`
+	ScheduleMaxUserIds  int `yaml:"schedule_max_user_ids" json:"schedule_max_user_ids" usage:"Maximum number of user IDs a scheduled notification may be explicitly sent to. Default 10000."`
+}
+
+func NewNotificationConfig() *NotificationConfig {
+	return &NotificationConfig{
+		ScheduleIntervalSec: 10,
+		ScheduleBatchSize:   1000,
+		ScheduleMaxUserIds:  10_000,
+	}
 }
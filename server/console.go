@@ -262,6 +262,18 @@ func StartConsoleServer(logger *zap.Logger, startupLogger *zap.Logger, db *sql.D
 
 	grpcGatewayRouter := mux.NewRouter()
 	grpcGatewayRouter.HandleFunc("/v2/console/storage/import", s.importStorage)
+	grpcGatewayRouter.HandleFunc("/v2/console/storage/export", s.exportStorage).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/storage/index/{name}/rebuild", s.rebuildStorageIndex).Methods(http.MethodPost)
+	grpcGatewayRouter.HandleFunc("/v2/console/leaderboard/{id}/records/{subset:friends|group}/{subject_id}", s.listLeaderboardRecordsSubset).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/leaderboard/{id}/quarantine", s.listLeaderboardQuarantine).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/leaderboard/{id}/quarantine/{owner_id}/approve", s.approveLeaderboardQuarantine).Methods(http.MethodPost)
+	grpcGatewayRouter.HandleFunc("/v2/console/leaderboard/{id}/quarantine/{owner_id}", s.deleteLeaderboardQuarantine).Methods(http.MethodDelete)
+	grpcGatewayRouter.HandleFunc("/v2/console/channel/search", s.searchChannelMessages).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/channel/purge", s.purgeChannelMessages).Methods(http.MethodPost)
+	grpcGatewayRouter.HandleFunc("/v2/console/notification/schedule", s.listNotificationSchedules).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/notification/schedule", s.createNotificationSchedule).Methods(http.MethodPost)
+	grpcGatewayRouter.HandleFunc("/v2/console/notification/schedule/{id}", s.getNotificationSchedule).Methods(http.MethodGet)
+	grpcGatewayRouter.HandleFunc("/v2/console/notification/schedule/{id}/cancel", s.cancelNotificationSchedule).Methods(http.MethodPost)
 
 	// Register public subscription callback endpoints
 	if config.GetIAP().Apple.NotificationsEndpointId != "" {
@@ -559,6 +571,38 @@ func checkAuth(ctx context.Context, logger *zap.Logger, config Config, auth stri
 	return ctx, false
 }
 
+// checkHTTPAuth authenticates a request to a console handler registered directly on the HTTP router, outside the
+// gRPC gateway and its interceptor, and checks the console user's role is at least as privileged as maxRole. If not,
+// it writes the error response and returns false.
+func (s *ConsoleServer) checkHTTPAuth(w http.ResponseWriter, r *http.Request, maxRole console.UserRole) bool {
+	// Check authentication.
+	auth := r.Header.Get("authorization")
+	if len(auth) == 0 {
+		s.writeHTTPAuthError(w, 401, "Console authentication required.")
+		return false
+	}
+	ctx, ok := checkAuth(r.Context(), s.logger, s.config, auth, s.consoleSessionCache, s.loginAttemptCache)
+	if !ok {
+		s.writeHTTPAuthError(w, 401, "Console authentication invalid.")
+		return false
+	}
+
+	// Check user role
+	role := ctx.Value(ctxConsoleRoleKey{}).(console.UserRole)
+	if role > maxRole {
+		s.writeHTTPAuthError(w, 403, "Forbidden")
+		return false
+	}
+	return true
+}
+
+func (s *ConsoleServer) writeHTTPAuthError(w http.ResponseWriter, code int, message string) {
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(message)); err != nil {
+		s.logger.Error("Error writing console authentication response", zap.Error(err))
+	}
+}
+
 func adminBasicAuth(config *ConsoleConfig) func(h http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,142 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type consoleChannelMessagePurgeRequest struct {
+	UserId    string `json:"user_id"`
+	ChannelId string `json:"channel_id"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+}
+
+type consoleChannelMessagePurgeResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// searchChannelMessages lists the chat messages in a channel containing all the words in a search text, newest first.
+func (s *ConsoleServer) searchChannelMessages(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_READONLY) {
+		return
+	}
+
+	query := r.URL.Query()
+	channelID := query.Get("channel_id")
+	channelIdToStreamResult, err := ChannelIdToStream(channelID)
+	if err != nil {
+		s.writeChannelModerationError(w, 400, errors.New("invalid channel ID"))
+		return
+	}
+
+	maxLimit := s.config.GetChat().SearchMaxLimit
+	limit := maxLimit
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > maxLimit {
+			s.writeChannelModerationError(w, 400, fmt.Errorf("limit must be 1-%d", maxLimit))
+			return
+		}
+	}
+
+	list, err := ChannelMessagesSearch(r.Context(), s.logger, s.db, uuid.Nil, channelIdToStreamResult.Stream, channelID, query.Get("text"), limit, query.Get("cursor"))
+	if err != nil {
+		s.writeChannelModerationError(w, channelModerationErrorCode(err), err)
+		return
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(list)
+	if err != nil {
+		s.writeChannelModerationError(w, 500, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		s.logger.Error("Error writing channel moderation response", zap.Error(err))
+	}
+}
+
+// purgeChannelMessages deletes the messages a user sent within a time range, in one channel or in all channels.
+func (s *ConsoleServer) purgeChannelMessages(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_MAINTAINER) {
+		return
+	}
+
+	request := &consoleChannelMessagePurgeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		s.writeChannelModerationError(w, 400, errors.New("invalid request body"))
+		return
+	}
+
+	userID, err := uuid.FromString(request.UserId)
+	if err != nil {
+		s.writeChannelModerationError(w, 400, errors.New("invalid user ID"))
+		return
+	}
+
+	var stream *PresenceStream
+	if request.ChannelId != "" {
+		channelIdToStreamResult, err := ChannelIdToStream(request.ChannelId)
+		if err != nil {
+			s.writeChannelModerationError(w, 400, errors.New("invalid channel ID"))
+			return
+		}
+		stream = &channelIdToStreamResult.Stream
+	}
+
+	var end time.Time
+	if request.EndTime > 0 {
+		end = time.Unix(request.EndTime, 0)
+	}
+
+	deleted, err := ChannelMessagesPurge(r.Context(), s.logger, s.db, s.router, userID, stream, time.Unix(request.StartTime, 0), end)
+	if err != nil {
+		s.writeChannelModerationError(w, channelModerationErrorCode(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&consoleChannelMessagePurgeResponse{Deleted: deleted}); err != nil {
+		s.logger.Error("Error writing channel moderation response", zap.Error(err))
+	}
+}
+
+func (s *ConsoleServer) writeChannelModerationError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(fmt.Sprintf("Error moderating channel messages - %s.", err))); err != nil {
+		s.logger.Error("Error writing channel moderation response", zap.Error(err))
+	}
+}
+
+func channelModerationErrorCode(err error) int {
+	switch {
+	case errors.Is(err, ErrChannelMessageSearchEmpty), errors.Is(err, ErrChannelMessagePurgeRange), errors.Is(err, runtime.ErrChannelCursorInvalid):
+		return 400
+	default:
+		return 500
+	}
+}
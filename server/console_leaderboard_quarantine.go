@@ -0,0 +1,133 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/gorilla/mux"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type consoleLeaderboardQuarantineList struct {
+	Records []*LeaderboardQuarantinedRecord `json:"records"`
+	Cursor  string                          `json:"cursor,omitempty"`
+}
+
+// listLeaderboardQuarantine lists the submissions held back from a leaderboard for review, oldest first.
+func (s *ConsoleServer) listLeaderboardQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_READONLY) {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			s.writeLeaderboardQuarantineError(w, 400, errors.New("limit must be 1-100"))
+			return
+		}
+	}
+
+	records, cursor, err := LeaderboardQuarantineList(r.Context(), s.logger, s.db, s.leaderboardCache, mux.Vars(r)["id"], limit, query.Get("cursor"))
+	if err != nil {
+		s.writeLeaderboardQuarantineError(w, leaderboardQuarantineErrorCode(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(&consoleLeaderboardQuarantineList{Records: records, Cursor: cursor}); err != nil {
+		s.logger.Error("Error writing leaderboard quarantine response", zap.Error(err))
+	}
+}
+
+// approveLeaderboardQuarantine writes a quarantined submission to its leaderboard, and responds with the resulting record.
+func (s *ConsoleServer) approveLeaderboardQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_MAINTAINER) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if _, err := uuid.FromString(vars["owner_id"]); err != nil {
+		s.writeLeaderboardQuarantineError(w, 400, errors.New("invalid owner ID"))
+		return
+	}
+
+	record, err := LeaderboardQuarantineApprove(r.Context(), s.logger, s.db, s.leaderboardCache, s.leaderboardRankCache, vars["id"], vars["owner_id"])
+	if err != nil {
+		s.writeLeaderboardQuarantineError(w, leaderboardQuarantineErrorCode(err), err)
+		return
+	}
+	s.logger.Info("Approved quarantined leaderboard record.", zap.String("leaderboard_id", vars["id"]), zap.String("owner_id", vars["owner_id"]))
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(record)
+	if err != nil {
+		s.writeLeaderboardQuarantineError(w, 500, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		s.logger.Error("Error writing leaderboard quarantine response", zap.Error(err))
+	}
+}
+
+// deleteLeaderboardQuarantine discards a quarantined submission.
+func (s *ConsoleServer) deleteLeaderboardQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_MAINTAINER) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if _, err := uuid.FromString(vars["owner_id"]); err != nil {
+		s.writeLeaderboardQuarantineError(w, 400, errors.New("invalid owner ID"))
+		return
+	}
+
+	if err := LeaderboardQuarantineDelete(r.Context(), s.logger, s.db, vars["id"], vars["owner_id"]); err != nil {
+		s.writeLeaderboardQuarantineError(w, leaderboardQuarantineErrorCode(err), err)
+		return
+	}
+	s.logger.Info("Deleted quarantined leaderboard record.", zap.String("leaderboard_id", vars["id"]), zap.String("owner_id", vars["owner_id"]))
+
+	w.WriteHeader(204)
+}
+
+func (s *ConsoleServer) writeLeaderboardQuarantineError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(fmt.Sprintf("Error managing quarantined leaderboard records - %s.", err))); err != nil {
+		s.logger.Error("Error writing leaderboard quarantine response", zap.Error(err))
+	}
+}
+
+func leaderboardQuarantineErrorCode(err error) int {
+	switch {
+	case errors.Is(err, ErrLeaderboardNotFound), errors.Is(err, ErrLeaderboardQuarantineNotFound):
+		return 404
+	case errors.Is(err, ErrLeaderboardInvalidCursor):
+		return 400
+	case errors.Is(err, ErrLeaderboardQuarantineExpired):
+		return 409
+	default:
+		return 500
+	}
+}
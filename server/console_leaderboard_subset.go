@@ -0,0 +1,95 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/gorilla/mux"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// listLeaderboardRecordsSubset lists the records of a user and their friends, or of a group's members, ranked
+// relative to each other.
+func (s *ConsoleServer) listLeaderboardRecordsSubset(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_DEVELOPER) {
+		return
+	}
+
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	writeError := func(code int, err error) {
+		w.WriteHeader(code)
+		if _, err := w.Write([]byte(fmt.Sprintf("Error listing leaderboard records - %s.", err))); err != nil {
+			s.logger.Error("Error writing leaderboard records subset response", zap.Error(err))
+		}
+	}
+
+	subjectID, err := uuid.FromString(vars["subject_id"])
+	if err != nil {
+		writeError(400, errors.New("invalid user or group ID"))
+		return
+	}
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			writeError(400, errors.New("limit must be 1-100"))
+			return
+		}
+	}
+	var expiry int64
+	if e := query.Get("expiry"); e != "" {
+		if expiry, err = strconv.ParseInt(e, 10, 64); err != nil || expiry < 0 {
+			writeError(400, errors.New("expiry must be a time since epoch in seconds"))
+			return
+		}
+	}
+
+	var list *api.LeaderboardRecordList
+	if vars["subset"] == "group" {
+		list, err = LeaderboardRecordsListGroup(r.Context(), s.logger, s.db, s.leaderboardCache, vars["id"], subjectID, limit, query.Get("cursor"), expiry)
+	} else {
+		list, err = LeaderboardRecordsListFriends(r.Context(), s.logger, s.db, s.leaderboardCache, vars["id"], subjectID, limit, query.Get("cursor"), expiry)
+	}
+	switch {
+	case errors.Is(err, ErrLeaderboardNotFound):
+		writeError(404, err)
+		return
+	case errors.Is(err, ErrLeaderboardInvalidCursor):
+		writeError(400, err)
+		return
+	case err != nil:
+		writeError(500, err)
+		return
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(list)
+	if err != nil {
+		writeError(500, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		s.logger.Error("Error writing leaderboard records subset response", zap.Error(err))
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+)
+
+type consoleNotificationScheduleList struct {
+	Schedules  []*NotificationSchedule `json:"schedules"`
+	NextCursor string                  `json:"next_cursor"`
+}
+
+// listNotificationSchedules lists scheduled notifications newest first, optionally filtered by status.
+func (s *ConsoleServer) listNotificationSchedules(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_READONLY) {
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			s.writeNotificationScheduleError(w, 400, errors.New("limit must be 1-100"))
+			return
+		}
+	}
+
+	schedules, cursor, err := NotificationScheduleList(r.Context(), s.logger, s.db, query.Get("status"), limit, query.Get("cursor"))
+	if err != nil {
+		s.writeNotificationScheduleError(w, notificationScheduleErrorCode(err), err)
+		return
+	}
+
+	s.writeNotificationScheduleResponse(w, &consoleNotificationScheduleList{Schedules: schedules, NextCursor: cursor})
+}
+
+// createNotificationSchedule schedules a notification to a segment of users.
+func (s *ConsoleServer) createNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_MAINTAINER) {
+		return
+	}
+
+	request := &NotificationSchedule{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		s.writeNotificationScheduleError(w, 400, errors.New("invalid request body"))
+		return
+	}
+
+	schedule, err := NotificationScheduleCreate(r.Context(), s.logger, s.db, request, s.config.GetNotification().ScheduleMaxUserIds)
+	if err != nil {
+		s.writeNotificationScheduleError(w, notificationScheduleErrorCode(err), err)
+		return
+	}
+
+	s.writeNotificationScheduleResponse(w, schedule)
+}
+
+// getNotificationSchedule returns a scheduled notification and its progress.
+func (s *ConsoleServer) getNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_READONLY) {
+		return
+	}
+
+	schedule, err := NotificationScheduleGet(r.Context(), s.logger, s.db, mux.Vars(r)["id"])
+	if err != nil {
+		s.writeNotificationScheduleError(w, notificationScheduleErrorCode(err), err)
+		return
+	}
+
+	s.writeNotificationScheduleResponse(w, schedule)
+}
+
+// cancelNotificationSchedule stops a scheduled notification from being sent again.
+func (s *ConsoleServer) cancelNotificationSchedule(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_MAINTAINER) {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if err := NotificationScheduleCancel(r.Context(), s.logger, s.db, id); err != nil {
+		s.writeNotificationScheduleError(w, notificationScheduleErrorCode(err), err)
+		return
+	}
+
+	schedule, err := NotificationScheduleGet(r.Context(), s.logger, s.db, id)
+	if err != nil {
+		s.writeNotificationScheduleError(w, notificationScheduleErrorCode(err), err)
+		return
+	}
+
+	s.writeNotificationScheduleResponse(w, schedule)
+}
+
+func (s *ConsoleServer) writeNotificationScheduleResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Error writing notification schedule response", zap.Error(err))
+	}
+}
+
+func (s *ConsoleServer) writeNotificationScheduleError(w http.ResponseWriter, code int, err error) {
+	w.WriteHeader(code)
+	if _, err := w.Write([]byte(fmt.Sprintf("Error with notification schedule - %s.", err))); err != nil {
+		s.logger.Error("Error writing notification schedule response", zap.Error(err))
+	}
+}
+
+func notificationScheduleErrorCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNotificationScheduleInvalid):
+		return 400
+	case errors.Is(err, ErrNotificationScheduleNotFound):
+		return 404
+	case errors.Is(err, ErrNotificationScheduleNotActive):
+		return 409
+	default:
+		return 500
+	}
+}
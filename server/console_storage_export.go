@@ -0,0 +1,204 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+)
+
+const (
+	StorageExportFormatJSONL = "jsonl"
+	StorageExportFormatCSV   = "csv"
+)
+
+var storageExportLikeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// StorageExportFilter selects the storage objects to export. Zero values match all objects.
+type StorageExportFilter struct {
+	Collection      string
+	KeyPrefix       string
+	UserID          string
+	UpdateTimeStart time.Time // Inclusive.
+	UpdateTimeEnd   time.Time // Exclusive.
+}
+
+func (s *ConsoleServer) exportStorage(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_DEVELOPER) {
+		return
+	}
+
+	query := r.URL.Query()
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = StorageExportFormatJSONL
+	}
+	filter, err := ParseStorageExportFilter(query.Get("collection"), query.Get("key_prefix"), query.Get("user_id"), query.Get("update_time_start"), query.Get("update_time_end"))
+	if err == nil && format != StorageExportFormatJSONL && format != StorageExportFormatCSV {
+		err = fmt.Errorf("format must be '%s' or '%s'", StorageExportFormatJSONL, StorageExportFormatCSV)
+	}
+	if err != nil {
+		w.WriteHeader(400)
+		if _, err := w.Write([]byte(fmt.Sprintf("Error exporting storage - %s.", err))); err != nil {
+			s.logger.Error("Error writing storage export response", zap.Error(err))
+		}
+		return
+	}
+
+	if format == StorageExportFormatCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/jsonl")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=storage-%d.%s", time.Now().Unix(), format))
+
+	// Headers are sent with the first row, so failures past this point can only be logged.
+	count, err := ExportStorage(r.Context(), s.db, w, format, filter)
+	if err != nil {
+		s.logger.Error("Error exporting storage", zap.Int("count", count), zap.Error(err))
+		return
+	}
+	s.logger.Info("Exported storage records.", zap.String("format", format), zap.Int("count", count))
+}
+
+// ParseStorageExportFilter validates the raw filter values, with times given as RFC3339 strings.
+func ParseStorageExportFilter(collection, keyPrefix, userID, updateTimeStart, updateTimeEnd string) (*StorageExportFilter, error) {
+	filter := &StorageExportFilter{
+		Collection: collection,
+		KeyPrefix:  keyPrefix,
+	}
+	if userID != "" {
+		if _, err := uuid.FromString(userID); err != nil {
+			return nil, errors.New("invalid user ID")
+		}
+		filter.UserID = userID
+	}
+	if updateTimeStart != "" {
+		t, err := time.Parse(time.RFC3339, updateTimeStart)
+		if err != nil {
+			return nil, errors.New("update time start must be an RFC3339 timestamp")
+		}
+		filter.UpdateTimeStart = t
+	}
+	if updateTimeEnd != "" {
+		t, err := time.Parse(time.RFC3339, updateTimeEnd)
+		if err != nil {
+			return nil, errors.New("update time end must be an RFC3339 timestamp")
+		}
+		filter.UpdateTimeEnd = t
+	}
+	return filter, nil
+}
+
+// ExportStorage streams all storage objects matching the filter to w, as JSON Lines or CSV readable by
+// importStorageJSON and importStorageCSV respectively, and returns the number of objects written.
+func ExportStorage(ctx context.Context, db *sql.DB, w io.Writer, format string, filter *StorageExportFilter) (int, error) {
+	// Objects that have expired but are yet to be reaped are not exported.
+	query := "SELECT collection, key, user_id, value, read, write, expire_time FROM storage WHERE " + storageNotExpiredCondition
+	params := make([]interface{}, 0, 5)
+	if filter.Collection != "" {
+		params = append(params, filter.Collection)
+		query += " AND collection = $" + strconv.Itoa(len(params))
+	}
+	if filter.KeyPrefix != "" {
+		params = append(params, storageExportLikeEscaper.Replace(filter.KeyPrefix)+"%")
+		query += " AND key LIKE $" + strconv.Itoa(len(params))
+	}
+	if filter.UserID != "" {
+		params = append(params, filter.UserID)
+		query += " AND user_id = $" + strconv.Itoa(len(params))
+	}
+	if !filter.UpdateTimeStart.IsZero() {
+		params = append(params, filter.UpdateTimeStart)
+		query += " AND update_time >= $" + strconv.Itoa(len(params))
+	}
+	if !filter.UpdateTimeEnd.IsZero() {
+		params = append(params, filter.UpdateTimeEnd)
+		query += " AND update_time < $" + strconv.Itoa(len(params))
+	}
+	query += " ORDER BY collection, key, user_id"
+
+	var encode func(o *importStorageObject) error
+	var flush func() error
+	switch format {
+	case StorageExportFormatJSONL:
+		enc := json.NewEncoder(w)
+		encode = func(o *importStorageObject) error {
+			return enc.Encode(o)
+		}
+		flush = func() error { return nil }
+	case StorageExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"collection", "key", "user_id", "value", "permission_read", "permission_write", "expire_time"}); err != nil {
+			return 0, err
+		}
+		encode = func(o *importStorageObject) error {
+			var expireTime string
+			if o.ExpireTime != nil {
+				expireTime = o.ExpireTime.Format(time.RFC3339)
+			}
+			return cw.Write([]string{o.Collection, o.Key, o.UserID, string(o.Value.(json.RawMessage)), strconv.Itoa(o.PermissionRead), strconv.Itoa(o.PermissionWrite), expireTime})
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return 0, fmt.Errorf("unknown storage export format: %q", format)
+	}
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var value string
+		var expireTime sql.NullTime
+		o := &importStorageObject{}
+		if err := rows.Scan(&o.Collection, &o.Key, &o.UserID, &value, &o.PermissionRead, &o.PermissionWrite, &expireTime); err != nil {
+			return count, err
+		}
+		o.Value = json.RawMessage(value)
+		if expireTime.Valid {
+			t := expireTime.Time.UTC()
+			o.ExpireTime = &t
+		}
+		if err := encode(o); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestStorageExportRoundTrip(t *testing.T) {
+	db := NewDB(t)
+
+	collection := GenerateString()
+	expireTime := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	ops := StorageOpWrites{
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection:      collection,
+				Key:             "a_1",
+				Value:           `{"foo":"bar"}`,
+				PermissionRead:  &wrapperspb.Int32Value{Value: 2},
+				PermissionWrite: &wrapperspb.Int32Value{Value: 1},
+			},
+		},
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection:      collection,
+				Key:             "ab",
+				Value:           `{"foo":"baz"}`,
+				PermissionRead:  &wrapperspb.Int32Value{Value: 1},
+				PermissionWrite: &wrapperspb.Int32Value{Value: 0},
+			},
+			ExpireTime: expireTime,
+		},
+	}
+	_, _, err := StorageWriteObjects(context.Background(), logger, db, metrics, storageIdx, true, ops)
+	assert.Nil(t, err, "err was not nil")
+
+	for _, format := range []string{StorageExportFormatJSONL, StorageExportFormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			// The underscore must match literally, not as a LIKE wildcard.
+			buf := &bytes.Buffer{}
+			count, err := ExportStorage(context.Background(), db, buf, format, &StorageExportFilter{Collection: collection, KeyPrefix: "a_"})
+			assert.Nil(t, err, "err was not nil")
+			assert.Equal(t, 1, count)
+
+			buf = &bytes.Buffer{}
+			count, err = ExportStorage(context.Background(), db, buf, format, &StorageExportFilter{Collection: collection})
+			assert.Nil(t, err, "err was not nil")
+			assert.Equal(t, 2, count)
+
+			_, err = StorageDeleteObjects(context.Background(), logger, db, storageIdx, true, StorageOpDeletes{
+				{OwnerID: uuid.Nil.String(), ObjectID: &api.DeleteStorageObjectId{Collection: collection, Key: "a_1"}},
+				{OwnerID: uuid.Nil.String(), ObjectID: &api.DeleteStorageObjectId{Collection: collection, Key: "ab"}},
+			})
+			assert.Nil(t, err, "err was not nil")
+
+			if format == StorageExportFormatCSV {
+				err = importStorageCSV(context.Background(), logger, db, metrics, storageIdx, buf.Bytes())
+			} else {
+				err = importStorageJSON(context.Background(), logger, db, metrics, storageIdx, buf.Bytes())
+			}
+			assert.Nil(t, err, "err was not nil")
+
+			readData, err := StorageReadObjects(context.Background(), logger, db, uuid.Nil, []*api.ReadStorageObjectId{
+				{Collection: collection, Key: "a_1"},
+				{Collection: collection, Key: "ab"},
+			})
+			assert.Nil(t, err, "err was not nil")
+			assert.Len(t, readData.Objects, 2)
+			for _, o := range readData.Objects {
+				if o.Key == "a_1" {
+					assert.JSONEq(t, `{"foo":"bar"}`, o.Value)
+					assert.EqualValues(t, 2, o.PermissionRead)
+					assert.EqualValues(t, 1, o.PermissionWrite)
+				}
+			}
+
+			var restoredExpireTime time.Time
+			err = db.QueryRowContext(context.Background(), "SELECT expire_time FROM storage WHERE collection = $1 AND key = $2", collection, "ab").Scan(&restoredExpireTime)
+			assert.Nil(t, err, "err was not nil")
+			assert.True(t, expireTime.Equal(restoredExpireTime), "expire time was not restored")
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
@@ -41,35 +42,11 @@ type importStorageObject struct {
 	Value           interface{} `json:"value" csv:"value"`
 	PermissionRead  int         `json:"permission_read" csv:"permission_read"`
 	PermissionWrite int         `json:"permission_write" csv:"permission_write"`
+	ExpireTime      *time.Time  `json:"expire_time,omitempty" csv:"expire_time"` // Optional, the object never expires if absent.
 }
 
 func (s *ConsoleServer) importStorage(w http.ResponseWriter, r *http.Request) {
-	// Check authentication.
-
-	auth := r.Header.Get("authorization")
-	if len(auth) == 0 {
-		w.WriteHeader(401)
-		if _, err := w.Write([]byte("Console authentication required.")); err != nil {
-			s.logger.Error("Error writing storage import response", zap.Error(err))
-		}
-		return
-	}
-	ctx, ok := checkAuth(r.Context(), s.logger, s.config, auth, s.consoleSessionCache, s.loginAttemptCache)
-	if !ok {
-		w.WriteHeader(401)
-		if _, err := w.Write([]byte("Console authentication invalid.")); err != nil {
-			s.logger.Error("Error writing storage import response", zap.Error(err))
-		}
-		return
-	}
-
-	// Check user role
-	role := ctx.Value(ctxConsoleRoleKey{}).(console.UserRole)
-	if role > console.UserRole_USER_ROLE_DEVELOPER {
-		w.WriteHeader(403)
-		if _, err := w.Write([]byte("Forbidden")); err != nil {
-			s.logger.Error("Error writing storage import response", zap.Error(err))
-		}
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_DEVELOPER) {
 		return
 	}
 
@@ -127,8 +104,8 @@ func (s *ConsoleServer) importStorage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Examine file name to determine if it's a JSON or CSV import.
-	if strings.HasSuffix(strings.ToLower(filename), ".json") {
-		// File has .json suffix, try to import as JSON.
+	if lower := strings.ToLower(filename); strings.HasSuffix(lower, ".json") || strings.HasSuffix(lower, ".jsonl") {
+		// File has .json or .jsonl suffix, try to import as JSON.
 		err = importStorageJSON(r.Context(), s.logger, s.db, s.metrics, s.storageIndex, fileBytes)
 	} else {
 		// Assume all other files are CSV.
@@ -149,7 +126,18 @@ func importStorageJSON(ctx context.Context, logger *zap.Logger, db *sql.DB, metr
 	importedData := make([]*importStorageObject, 0)
 	ops := StorageOpWrites{}
 
-	if err := json.Unmarshal(fileBytes, &importedData); err != nil {
+	if trimmed := bytes.TrimSpace(fileBytes); len(trimmed) > 0 && trimmed[0] == byteBracket {
+		// JSON Lines, one object per line, as produced by storage export.
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		for decoder.More() {
+			d := &importStorageObject{}
+			if err := decoder.Decode(d); err != nil {
+				logger.Warn("Could not parse JSON Lines file.", zap.Error(err))
+				return errors.New("imported file contains bad data")
+			}
+			importedData = append(importedData, d)
+		}
+	} else if err := json.Unmarshal(fileBytes, &importedData); err != nil {
 		logger.Warn("Could not parse JSON file.", zap.Error(err))
 		return errors.New("imported file contains bad data")
 	}
@@ -183,7 +171,7 @@ func importStorageJSON(ctx context.Context, logger *zap.Logger, db *sql.DB, metr
 			return errors.New("failed to marshal storage object value to json. Value field must contain valid json")
 		}
 
-		ops = append(ops, &StorageOpWrite{
+		op := &StorageOpWrite{
 			OwnerID: d.UserID,
 			Object: &api.WriteStorageObject{
 				Collection:      d.Collection,
@@ -192,7 +180,11 @@ func importStorageJSON(ctx context.Context, logger *zap.Logger, db *sql.DB, metr
 				PermissionRead:  &wrapperspb.Int32Value{Value: int32(d.PermissionRead)},
 				PermissionWrite: &wrapperspb.Int32Value{Value: int32(d.PermissionWrite)},
 			},
-		})
+		}
+		if d.ExpireTime != nil {
+			op.ExpireTime = *d.ExpireTime
+		}
+		ops = append(ops, op)
 	}
 
 	if len(ops) == 0 {
@@ -282,6 +274,14 @@ func importStorageCSV(ctx context.Context, logger *zap.Logger, db *sql.DB, metri
 				return fmt.Errorf("value must be a JSON object on row #%d", len(ops)+1)
 			}
 
+			// The expire time column is optional, and an empty value never expires.
+			var expireTime time.Time
+			if i, ok := columnIndexes["expire_time"]; ok && record[i] != "" {
+				if expireTime, err = time.Parse(time.RFC3339, record[i]); err != nil {
+					return fmt.Errorf("invalid expire time supplied on row #%d. It must be an RFC3339 timestamp", len(ops)+1)
+				}
+			}
+
 			ops = append(ops, &StorageOpWrite{
 				OwnerID: user,
 				Object: &api.WriteStorageObject{
@@ -291,6 +291,7 @@ func importStorageCSV(ctx context.Context, logger *zap.Logger, db *sql.DB, metri
 					PermissionRead:  &wrapperspb.Int32Value{Value: int32(pr)},
 					PermissionWrite: &wrapperspb.Int32Value{Value: int32(pw)},
 				},
+				ExpireTime: expireTime,
 			})
 		}
 	}
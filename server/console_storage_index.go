@@ -0,0 +1,43 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/heroiclabs/nakama/v3/console"
+	"go.uber.org/zap"
+)
+
+// rebuildStorageIndex re-indexes a storage index from scratch, removing any stale entries.
+func (s *ConsoleServer) rebuildStorageIndex(w http.ResponseWriter, r *http.Request) {
+	if !s.checkHTTPAuth(w, r, console.UserRole_USER_ROLE_DEVELOPER) {
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if err := s.storageIndex.Rebuild(r.Context(), name); err != nil {
+		s.logger.Error("Error rebuilding storage index", zap.String("index_name", name), zap.Error(err))
+		w.WriteHeader(400)
+		if _, err := w.Write([]byte(fmt.Sprintf("Error rebuilding storage index - %s.", err))); err != nil {
+			s.logger.Error("Error writing storage index rebuild response", zap.Error(err))
+		}
+		return
+	}
+
+	w.WriteHeader(204)
+}
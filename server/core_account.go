@@ -458,7 +458,7 @@ func ExportAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, userID u
 	return export, nil
 }
 
-func DeleteAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, sessionRegistry SessionRegistry, sessionCache SessionCache, tracker Tracker, userID uuid.UUID, recorded bool) error {
+func DeleteAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config Config, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, sessionRegistry SessionRegistry, sessionCache SessionCache, tracker Tracker, storageIndex StorageIndex, userID uuid.UUID, recorded bool) error {
 	if userID == uuid.Nil {
 		return errors.New("cannot delete the system user")
 	}
@@ -466,7 +466,17 @@ func DeleteAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config C
 	ts := time.Now().UTC().Unix()
 
 	var deleted bool
+	var storageDeletes StorageOpDeletes
 	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+		// Delete the user's storage objects explicitly, rather than by cascade, so they're removed from the storage
+		// indices and published to the storage change feed.
+		var err error
+		storageDeletes, err = storageDeleteUserObjects(ctx, tx, userID)
+		if err != nil {
+			logger.Debug("Could not delete storage objects.", zap.Error(err), zap.String("user_id", userID.String()))
+			return err
+		}
+
 		count, err := DeleteUser(ctx, tx, userID)
 		if err != nil {
 			logger.Debug("Could not delete user", zap.Error(err), zap.String("user_id", userID.String()))
@@ -505,6 +515,8 @@ func DeleteAccount(ctx context.Context, logger *zap.Logger, db *sql.DB, config C
 	}
 
 	if deleted {
+		storageIndex.Delete(ctx, storageDeletes)
+
 		// Logout and disconnect.
 		if err := SessionLogout(config, sessionCache, userID, "", ""); err != nil {
 			return err
@@ -39,8 +39,10 @@ import (
 var (
 	errChannelMessageIdInvalid = errors.New("Invalid message identifier")
 
-	errChannelMessageNotFound = errors.New("channel message not found")
-	errChannelMessagePersist  = errors.New("error persisting channel message")
+	errChannelMessageNotFound  = errors.New("channel message not found")
+	errChannelMessageNotJoined = errors.New("must join channel before sending messages")
+	errChannelMessagePersist   = errors.New("error persisting channel message")
+	errChannelMessageRejected  = errors.New("channel message rejected by content filter")
 )
 
 // Wrapper type to avoid allocating a stream struct when the input is invalid.
@@ -90,27 +92,8 @@ func ChannelMessagesList(ctx context.Context, logger *zap.Logger, db *sql.DB, ca
 	}
 
 	// Check channel permissions for non-authoritative calls.
-	if caller != uuid.Nil {
-		switch stream.Mode {
-		case StreamModeGroup:
-			// If it's a group, check membership.
-			allowed, err := groupCheckUserPermission(ctx, logger, db, stream.Subject, caller, 2)
-			if err != nil {
-				return nil, err
-			}
-			if !allowed {
-				return nil, runtime.ErrChannelGroupNotFound
-			}
-		case StreamModeDM:
-			// If it's a DM chat, check that the user is one of the chat participants.
-			if stream.Subject != caller && stream.Subcontext != caller {
-				return nil, runtime.ErrChannelIDInvalid
-			}
-		case StreamModeChannel:
-			fallthrough
-		default:
-			// No
-		}
+	if err := channelCheckReadPermission(ctx, logger, db, caller, stream); err != nil {
+		return nil, err
 	}
 
 	query := `SELECT id, code, sender_id, username, content, create_time, update_time FROM message
@@ -290,7 +273,41 @@ WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3:
 	}, nil
 }
 
-func ChannelMessageSend(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, channelStream PresenceStream, channelId, content, senderId, senderUsername string, persist bool) (*rtapi.ChannelMessageAck, error) {
+// channelCheckReadPermission checks the caller may read the channel's messages. A nil caller is always allowed.
+func channelCheckReadPermission(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream) error {
+	if caller == uuid.Nil {
+		return nil
+	}
+
+	switch stream.Mode {
+	case StreamModeGroup:
+		// If it's a group, check membership.
+		allowed, err := groupCheckUserPermission(ctx, logger, db, stream.Subject, caller, 2)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return runtime.ErrChannelGroupNotFound
+		}
+	case StreamModeDM:
+		// If it's a DM chat, check that the user is one of the chat participants.
+		if stream.Subject != caller && stream.Subcontext != caller {
+			return runtime.ErrChannelIDInvalid
+		}
+	case StreamModeChannel:
+		fallthrough
+	default:
+		// No
+	}
+	return nil
+}
+
+func ChannelMessageSend(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, filter *ChannelMessageFilter, channelStream PresenceStream, channelId, content, senderId, senderUsername string, persist bool) (*rtapi.ChannelMessageAck, error) {
+	content, err := filter.Apply(content)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := time.Now().Unix()
 	message := &api.ChannelMessage{
 		ChannelId:  channelId,
@@ -339,7 +356,12 @@ VALUES ($1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10)`
 	return ack, nil
 }
 
-func ChannelMessageUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, channelStream PresenceStream, channelId, messageId, content, senderId, senderUsername string, persist bool) (*rtapi.ChannelMessageAck, error) {
+func ChannelMessageUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, filter *ChannelMessageFilter, channelStream PresenceStream, channelId, messageId, content, senderId, senderUsername string, persist bool) (*rtapi.ChannelMessageAck, error) {
+	content, err := filter.Apply(content)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := time.Now().Unix()
 	message := &api.ChannelMessage{
 		ChannelId:  channelId,
@@ -374,10 +396,13 @@ func ChannelMessageUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, r
 	}
 
 	if persist {
-		// First find and update the referenced message.
+		// First find and update the referenced message. Thread replies keep their parent message ID in the content.
 		var dbCreateTime pgtype.Timestamptz
-		query := "UPDATE message SET update_time = $5, username = $4, content = $3 WHERE id = $1 AND sender_id = $2 RETURNING create_time"
-		err := db.QueryRowContext(ctx, query, messageId, message.SenderId, message.Content, message.Username, time.Unix(message.UpdateTime.Seconds, 0).UTC()).Scan(&dbCreateTime)
+		var dbParentID sql.NullString
+		query := `UPDATE message SET update_time = $5, username = $4,
+content = CASE WHEN parent_id IS NULL THEN $3::JSONB ELSE jsonb_build_object('parent_id', parent_id, 'content', $3::JSONB) END
+WHERE id = $1 AND sender_id = $2 RETURNING create_time, parent_id`
+		err := db.QueryRowContext(ctx, query, messageId, message.SenderId, message.Content, message.Username, time.Unix(message.UpdateTime.Seconds, 0).UTC()).Scan(&dbCreateTime, &dbParentID)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, errChannelMessageNotFound
@@ -387,6 +412,11 @@ func ChannelMessageUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, r
 		}
 		// Replace the message create time with the real one from DB.
 		message.CreateTime = &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()}
+		if dbParentID.Valid {
+			if message.Content, err = channelMessageReplyWrap(dbParentID.String, message.Content); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	router.SendToStream(logger, channelStream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
@@ -0,0 +1,163 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	ChannelMessageFilterRedact = "redact"
+	ChannelMessageFilterReject = "reject"
+)
+
+// ChannelMessageFilter redacts or rejects chat messages containing any of a set of words or phrases.
+type ChannelMessageFilter struct {
+	pattern *regexp.Regexp
+	reject  bool
+}
+
+// NewChannelMessageFilter compiles a filter for the given words and action. It returns nil if there are no words.
+func NewChannelMessageFilter(words []string, action string) (*ChannelMessageFilter, error) {
+	var reject bool
+	switch action {
+	case ChannelMessageFilterRedact:
+	case ChannelMessageFilterReject:
+		reject = true
+	default:
+		return nil, fmt.Errorf("unknown filter action %q", action)
+	}
+
+	alternatives := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		// Phrases match regardless of the whitespace between their words.
+		alternatives = append(alternatives, strings.Join(strings.Fields(regexp.QuoteMeta(word)), `[\s\p{Z}]+`))
+	}
+	if len(alternatives) == 0 {
+		return nil, nil
+	}
+
+	// Word boundaries are checked on each match instead of with \b, which only knows ASCII words.
+	pattern, err := regexp.Compile(`(?i)(?:` + strings.Join(alternatives, "|") + `)`)
+	if err != nil {
+		return nil, err
+	}
+	return &ChannelMessageFilter{pattern: pattern, reject: reject}, nil
+}
+
+// Apply checks the decoded string values in a JSON object message content, so escaped characters can't hide a word.
+// It returns the content with filtered words replaced by asterisks, or errChannelMessageRejected if the filter
+// rejects messages containing them.
+func (f *ChannelMessageFilter) Apply(content string) (string, error) {
+	if f == nil {
+		return content, nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return "", err
+	}
+	value, matched := f.filterValue(value)
+	if !matched {
+		return content, nil
+	}
+	if f.reject {
+		return "", errChannelMessageRejected
+	}
+
+	filtered, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(filtered), nil
+}
+
+func (f *ChannelMessageFilter) filterValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case string:
+		return f.replaceWords(v)
+	case map[string]interface{}:
+		var matched bool
+		for key, child := range v {
+			var childMatched bool
+			if v[key], childMatched = f.filterValue(child); childMatched {
+				matched = true
+			}
+		}
+		return v, matched
+	case []interface{}:
+		var matched bool
+		for i, child := range v {
+			var childMatched bool
+			if v[i], childMatched = f.filterValue(child); childMatched {
+				matched = true
+			}
+		}
+		return v, matched
+	default:
+		return value, false
+	}
+}
+
+// replaceWords replaces the matches in a string that start and end on word boundaries with asterisks, and reports
+// whether there were any.
+func (f *ChannelMessageFilter) replaceWords(s string) (string, bool) {
+	var b strings.Builder
+	var last, offset int
+	var matched bool
+	for offset < len(s) {
+		loc := f.pattern.FindStringIndex(s[offset:])
+		if loc == nil {
+			break
+		}
+		start, end := offset+loc[0], offset+loc[1]
+		if start == end || !channelMessageWordBoundary(s, start) || !channelMessageWordBoundary(s, end) {
+			// Part of a longer word, a match may still start later within it.
+			_, size := utf8.DecodeRuneInString(s[start:])
+			offset = start + size
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(s[start:end])))
+		last, offset = end, end
+		matched = true
+	}
+	if !matched {
+		return s, false
+	}
+	b.WriteString(s[last:])
+	return b.String(), true
+}
+
+// channelMessageWordBoundary reports whether position i in s is between a word character and a non-word character,
+// in any script.
+func channelMessageWordBoundary(s string, i int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	after, _ := utf8.DecodeRuneInString(s[i:])
+	return channelMessageWordRune(before) != channelMessageWordRune(after)
+}
+
+func channelMessageWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r)
+}
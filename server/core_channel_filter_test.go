@@ -0,0 +1,77 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestChannelMessageFilter(t *testing.T) {
+	words := []string{"darn", "heck no", " "}
+
+	tests := []struct {
+		name    string
+		action  string
+		content string
+		want    string
+		wantErr error
+	}{
+		{name: "clean", action: ChannelMessageFilterRedact, content: `{"msg":"hello there"}`, want: `{"msg":"hello there"}`},
+		{name: "redact word", action: ChannelMessageFilterRedact, content: `{"msg":"well DARN it"}`, want: `{"msg":"well **** it"}`},
+		{name: "redact partial word", action: ChannelMessageFilterRedact, content: `{"msg":"darning socks"}`, want: `{"msg":"darning socks"}`},
+		{name: "redact phrase", action: ChannelMessageFilterRedact, content: `{"msg":"heck   no"}`, want: `{"msg":"*********"}`},
+		{name: "redact nested", action: ChannelMessageFilterRedact, content: `{"a":{"b":["darn",1]}}`, want: `{"a":{"b":["****",1]}}`},
+		{name: "keys untouched", action: ChannelMessageFilterRedact, content: `{"darn":"ok"}`, want: `{"darn":"ok"}`},
+		{name: "redact escaped word", action: ChannelMessageFilterRedact, content: `{"msg":"d\u0061rn"}`, want: `{"msg":"****"}`},
+		{name: "redact unicode boundaries", action: ChannelMessageFilterRedact, content: `{"msg":"¡darn! darné éheck no"}`, want: `{"msg":"¡****! darné éheck no"}`},
+		{name: "reject escaped word", action: ChannelMessageFilterReject, content: `{"msg":"\u0064arn"}`, wantErr: errChannelMessageRejected},
+		{name: "reject", action: ChannelMessageFilterReject, content: `{"msg":"darn"}`, wantErr: errChannelMessageRejected},
+		{name: "reject clean", action: ChannelMessageFilterReject, content: `{"msg":"fine"}`, want: `{"msg":"fine"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewChannelMessageFilter(words, tt.action)
+			if err != nil {
+				t.Fatalf("error creating filter: %v", err)
+			}
+			got, err := filter.Apply(tt.content)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChannelMessageFilterEmpty(t *testing.T) {
+	filter, err := NewChannelMessageFilter([]string{"", "  "}, ChannelMessageFilterRedact)
+	if err != nil {
+		t.Fatalf("error creating filter: %v", err)
+	}
+	if filter != nil {
+		t.Fatalf("expected no filter")
+	}
+	if got, err := filter.Apply(`{"msg":"darn"}`); err != nil || got != `{"msg":"darn"}` {
+		t.Fatalf("expected content unchanged, got %s, %v", got, err)
+	}
+
+	if _, err := NewChannelMessageFilter([]string{"darn"}, "block"); err == nil {
+		t.Fatalf("expected error for unknown action")
+	}
+}
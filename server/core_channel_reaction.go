@@ -0,0 +1,204 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const channelMessageReactionMaxLength = 64
+
+var ErrChannelMessageReactionInvalid = errors.New("channel message reaction must be 1-64 bytes with no whitespace")
+
+// ChannelMessageReaction is the aggregated count of one reaction on a channel message.
+type ChannelMessageReaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// Whether the user the reactions were listed for is one of those who reacted.
+	Reacted bool `json:"reacted"`
+}
+
+// channelMessageReactionContent is the content of the reaction messages sent to a channel when a reaction is
+// added or removed. The message ID is the ID of the message reacted to.
+type channelMessageReactionContent struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	Added bool   `json:"added"`
+}
+
+func channelMessageReactionValid(emoji string) bool {
+	if emoji == "" || len(emoji) > channelMessageReactionMaxLength || !utf8.ValidString(emoji) {
+		return false
+	}
+	return strings.IndexFunc(emoji, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) == -1
+}
+
+// ChannelMessageReactionAdd adds a user's reaction to a persisted channel message, and sends the updated reaction
+// count to the channel. Adding a reaction the user already added has no effect. The user must be allowed to send
+// messages to the channel.
+func ChannelMessageReactionAdd(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, channelStream PresenceStream, channelId, messageId, emoji string, userID uuid.UUID, username string) (*ChannelMessageReaction, error) {
+	// The final select sees the reactions from before the insert, so the inserted reaction is counted separately.
+	query := `WITH target AS (
+	SELECT id FROM message WHERE id = $1 AND stream_mode = $4 AND stream_subject = $5::UUID AND stream_descriptor = $6::UUID AND stream_label = $7
+), changed AS (
+	INSERT INTO message_reaction (message_id, emoji, user_id) SELECT id, $3, $2 FROM target
+	ON CONFLICT (message_id, emoji, user_id) DO NOTHING
+	RETURNING 1
+)
+SELECT (SELECT count(*) FROM target), (SELECT count(*) FROM changed), (SELECT count(*) FROM message_reaction WHERE message_id = $1 AND emoji = $3)`
+
+	return channelMessageReactionChange(ctx, logger, db, tracker, router, channelStream, channelId, messageId, emoji, userID, username, query, true)
+}
+
+// ChannelMessageReactionRemove removes a user's reaction from a persisted channel message, and sends the updated
+// reaction count to the channel. Removing a reaction the user has not added has no effect. The user must be allowed
+// to send messages to the channel.
+func ChannelMessageReactionRemove(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, channelStream PresenceStream, channelId, messageId, emoji string, userID uuid.UUID, username string) (*ChannelMessageReaction, error) {
+	// The final select sees the reactions from before the delete, so the deleted reaction is counted separately.
+	query := `WITH target AS (
+	SELECT id FROM message WHERE id = $1 AND stream_mode = $4 AND stream_subject = $5::UUID AND stream_descriptor = $6::UUID AND stream_label = $7
+), changed AS (
+	DELETE FROM message_reaction WHERE message_id IN (SELECT id FROM target) AND emoji = $3 AND user_id = $2
+	RETURNING 1
+)
+SELECT (SELECT count(*) FROM target), (SELECT count(*) FROM changed), (SELECT count(*) FROM message_reaction WHERE message_id = $1 AND emoji = $3)`
+
+	return channelMessageReactionChange(ctx, logger, db, tracker, router, channelStream, channelId, messageId, emoji, userID, username, query, false)
+}
+
+func channelMessageReactionChange(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, channelStream PresenceStream, channelId, messageId, emoji string, userID uuid.UUID, username, query string, add bool) (*ChannelMessageReaction, error) {
+	if _, err := uuid.FromString(messageId); err != nil {
+		return nil, errChannelMessageIdInvalid
+	}
+	if !channelMessageReactionValid(emoji) {
+		return nil, ErrChannelMessageReactionInvalid
+	}
+
+	if err := channelCheckSendPermission(ctx, logger, db, tracker, userID, channelStream); err != nil {
+		return nil, err
+	}
+
+	var dbFound, dbChanged, dbCount int
+	if err := db.QueryRowContext(ctx, query, messageId, userID, emoji, channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label).Scan(&dbFound, &dbChanged, &dbCount); err != nil {
+		logger.Error("Error persisting channel message reaction", zap.Error(err))
+		return nil, errChannelMessagePersist
+	}
+	if dbFound == 0 {
+		return nil, errChannelMessageNotFound
+	}
+
+	reaction := &ChannelMessageReaction{Emoji: emoji, Count: dbCount, Reacted: add}
+	if add {
+		reaction.Count += dbChanged
+	} else {
+		reaction.Count -= dbChanged
+	}
+	if dbChanged == 0 {
+		// Nothing changed, so there's nothing to tell the channel.
+		return reaction, nil
+	}
+
+	content, err := json.Marshal(&channelMessageReactionContent{Emoji: emoji, Count: reaction.Count, Added: add})
+	if err != nil {
+		return nil, err
+	}
+
+	ts := time.Now().Unix()
+	message := &api.ChannelMessage{
+		ChannelId:  channelId,
+		MessageId:  messageId,
+		Code:       &wrapperspb.Int32Value{Value: ChannelMessageTypeChatReaction},
+		SenderId:   userID.String(),
+		Username:   username,
+		Content:    string(content),
+		CreateTime: &timestamppb.Timestamp{Seconds: ts},
+		UpdateTime: &timestamppb.Timestamp{Seconds: ts},
+		Persistent: &wrapperspb.BoolValue{Value: false},
+	}
+	switch channelStream.Mode {
+	case StreamModeChannel:
+		message.RoomName = channelStream.Label
+	case StreamModeGroup:
+		message.GroupId = channelStream.Subject.String()
+	case StreamModeDM:
+		message.UserIdOne = channelStream.Subject.String()
+		message.UserIdTwo = channelStream.Subcontext.String()
+	}
+
+	router.SendToStream(logger, channelStream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
+
+	return reaction, nil
+}
+
+// ChannelMessageReactionsList returns the aggregated reactions on a set of messages in a channel, keyed by message
+// ID, most popular first. Messages without reactions are omitted. If the caller is not nil they must be allowed to
+// read the channel, and the reactions they added are marked.
+func ChannelMessageReactionsList(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream, messageIDs []string) (map[string][]*ChannelMessageReaction, error) {
+	reactions := make(map[string][]*ChannelMessageReaction, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
+	for _, messageID := range messageIDs {
+		if _, err := uuid.FromString(messageID); err != nil {
+			return nil, errChannelMessageIdInvalid
+		}
+	}
+
+	if err := channelCheckReadPermission(ctx, logger, db, caller, stream); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT r.message_id, r.emoji, count(*), bool_or(r.user_id = $1) FROM message_reaction r
+JOIN message m ON m.id = r.message_id
+WHERE r.message_id = ANY($2::UUID[]) AND m.stream_mode = $3 AND m.stream_subject = $4::UUID AND m.stream_descriptor = $5::UUID AND m.stream_label = $6
+GROUP BY r.message_id, r.emoji
+ORDER BY r.message_id, count(*) DESC, min(r.create_time)`
+	rows, err := db.QueryContext(ctx, query, caller, messageIDs, stream.Mode, stream.Subject, stream.Subcontext, stream.Label)
+	if err != nil {
+		logger.Error("Error listing channel message reactions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dbMessageID string
+	for rows.Next() {
+		reaction := &ChannelMessageReaction{}
+		if err := rows.Scan(&dbMessageID, &reaction.Emoji, &reaction.Count, &reaction.Reacted); err != nil {
+			logger.Error("Error parsing channel message reactions", zap.Error(err))
+			return nil, err
+		}
+		reactions[dbMessageID] = append(reactions[dbMessageID], reaction)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error listing channel message reactions", zap.Error(err))
+		return nil, err
+	}
+
+	return reactions, nil
+}
@@ -0,0 +1,251 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var (
+	ErrChannelMessageSearchEmpty = errors.New("channel message search text is required")
+	ErrChannelMessagePurgeRange  = errors.New("channel message purge end time must be after start time")
+)
+
+type channelMessageSearchCursor struct {
+	StreamMode       uint8
+	StreamSubject    string
+	StreamSubcontext string
+	StreamLabel      string
+	Text             string
+	CreateTime       int64
+	Id               string
+}
+
+// ChannelMessagesSearch lists the chat messages and thread replies in a channel containing all the words in the search text, newest first.
+// If the caller is not nil they must be allowed to read the channel.
+func ChannelMessagesSearch(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream, channelID, text string, limit int, cursor string) (*api.ChannelMessageList, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, ErrChannelMessageSearchEmpty
+	}
+
+	var incomingCursor *channelMessageSearchCursor
+	if cursor != "" {
+		cb, err := base64.StdEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+		incomingCursor = &channelMessageSearchCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+		if incomingCursor.StreamMode != stream.Mode || incomingCursor.StreamSubject != stream.Subject.String() || incomingCursor.StreamSubcontext != stream.Subcontext.String() || incomingCursor.StreamLabel != stream.Label || incomingCursor.Text != text {
+			// Cursor is for a different channel or search.
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+	}
+
+	if err := channelCheckReadPermission(ctx, logger, db, caller, stream); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, code, sender_id, username, content, create_time, update_time FROM message
+WHERE stream_mode = $1 AND stream_subject = $2::UUID AND stream_descriptor = $3::UUID AND stream_label = $4
+AND code IN ($5, $8) AND jsonb_to_tsvector('simple', content, '["string"]') @@ plainto_tsquery('simple', $6)`
+	params := []interface{}{stream.Mode, stream.Subject, stream.Subcontext, stream.Label, ChannelMessageTypeChat, text, limit + 1, ChannelMessageTypeChatReply}
+	if incomingCursor != nil {
+		query += " AND (create_time, id) < ($9, $10)"
+		params = append(params, time.Unix(incomingCursor.CreateTime, 0).UTC(), incomingCursor.Id)
+	}
+	query += " ORDER BY create_time DESC, id DESC LIMIT $7"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error searching channel messages", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*api.ChannelMessage, 0, limit)
+	var nextCursor *channelMessageSearchCursor
+
+	var dbID string
+	var dbCode int32
+	var dbSenderID string
+	var dbUsername string
+	var dbContent string
+	var dbCreateTime pgtype.Timestamptz
+	var dbUpdateTime pgtype.Timestamptz
+	for rows.Next() {
+		if len(messages) >= limit {
+			last := messages[len(messages)-1]
+			nextCursor = &channelMessageSearchCursor{
+				StreamMode:       stream.Mode,
+				StreamSubject:    stream.Subject.String(),
+				StreamSubcontext: stream.Subcontext.String(),
+				StreamLabel:      stream.Label,
+				Text:             text,
+				CreateTime:       last.CreateTime.Seconds,
+				Id:               last.MessageId,
+			}
+			break
+		}
+
+		if err := rows.Scan(&dbID, &dbCode, &dbSenderID, &dbUsername, &dbContent, &dbCreateTime, &dbUpdateTime); err != nil {
+			logger.Error("Error parsing searched channel messages", zap.Error(err))
+			return nil, err
+		}
+
+		message := &api.ChannelMessage{
+			ChannelId:  channelID,
+			MessageId:  dbID,
+			Code:       &wrapperspb.Int32Value{Value: dbCode},
+			SenderId:   dbSenderID,
+			Username:   dbUsername,
+			Content:    dbContent,
+			CreateTime: &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+			UpdateTime: &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
+			Persistent: &wrapperspb.BoolValue{Value: true},
+		}
+		switch stream.Mode {
+		case StreamModeChannel:
+			message.RoomName = stream.Label
+		case StreamModeGroup:
+			message.GroupId = stream.Subject.String()
+		case StreamModeDM:
+			message.UserIdOne = stream.Subject.String()
+			message.UserIdTwo = stream.Subcontext.String()
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error searching channel messages", zap.Error(err))
+		return nil, err
+	}
+
+	var nextCursorStr string
+	if nextCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(nextCursor); err != nil {
+			logger.Error("Error creating channel messages search cursor", zap.Error(err))
+			return nil, err
+		}
+		nextCursorStr = base64.StdEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return &api.ChannelMessageList{
+		Messages:   messages,
+		NextCursor: nextCursorStr,
+	}, nil
+}
+
+// ChannelMessagesPurge deletes the messages a user sent from the start time up to the end time, in one channel or in
+// all channels if the stream is nil, and tells connected channel members they were removed. A zero end time purges
+// messages up to now. It returns the number of messages deleted.
+func ChannelMessagesPurge(ctx context.Context, logger *zap.Logger, db *sql.DB, router MessageRouter, userID uuid.UUID, stream *PresenceStream, start, end time.Time) (int, error) {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !end.After(start) {
+		return 0, ErrChannelMessagePurgeRange
+	}
+
+	query := `DELETE FROM message WHERE sender_id = $1 AND create_time >= $2 AND create_time < $3`
+	params := []interface{}{userID, start.UTC(), end.UTC()}
+	if stream != nil {
+		query += " AND stream_mode = $4 AND stream_subject = $5::UUID AND stream_descriptor = $6::UUID AND stream_label = $7"
+		params = append(params, stream.Mode, stream.Subject, stream.Subcontext, stream.Label)
+	}
+	query += " RETURNING id, username, stream_mode, stream_subject, stream_descriptor, stream_label, create_time"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error purging channel messages", zap.String("user_id", userID.String()), zap.Error(err))
+		return 0, err
+	}
+	defer rows.Close()
+
+	ts := time.Now().Unix()
+	var purged int
+	var dbID string
+	var dbUsername string
+	var dbStreamMode uint8
+	var dbStreamSubject string
+	var dbStreamSubcontext string
+	var dbStreamLabel string
+	var dbCreateTime pgtype.Timestamptz
+	for rows.Next() {
+		if err := rows.Scan(&dbID, &dbUsername, &dbStreamMode, &dbStreamSubject, &dbStreamSubcontext, &dbStreamLabel, &dbCreateTime); err != nil {
+			logger.Error("Error parsing purged channel messages", zap.String("user_id", userID.String()), zap.Error(err))
+			return purged, err
+		}
+		purged++
+
+		channelStream := PresenceStream{
+			Mode:       dbStreamMode,
+			Subject:    uuid.FromStringOrNil(dbStreamSubject),
+			Subcontext: uuid.FromStringOrNil(dbStreamSubcontext),
+			Label:      dbStreamLabel,
+		}
+		channelID, err := StreamToChannelId(channelStream)
+		if err != nil {
+			continue
+		}
+		message := &api.ChannelMessage{
+			ChannelId:  channelID,
+			MessageId:  dbID,
+			Code:       &wrapperspb.Int32Value{Value: ChannelMessageTypeChatRemove},
+			SenderId:   userID.String(),
+			Username:   dbUsername,
+			Content:    "{}",
+			CreateTime: &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+			UpdateTime: &timestamppb.Timestamp{Seconds: ts},
+			Persistent: &wrapperspb.BoolValue{Value: true},
+		}
+		switch channelStream.Mode {
+		case StreamModeChannel:
+			message.RoomName = channelStream.Label
+		case StreamModeGroup:
+			message.GroupId = channelStream.Subject.String()
+		case StreamModeDM:
+			message.UserIdOne = channelStream.Subject.String()
+			message.UserIdTwo = channelStream.Subcontext.String()
+		}
+		router.SendToStream(logger, channelStream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error purging channel messages", zap.String("user_id", userID.String()), zap.Error(err))
+		return purged, err
+	}
+
+	logger.Info("Purged channel messages.", zap.String("user_id", userID.String()), zap.Int("count", purged), zap.Time("start", start), zap.Time("end", end))
+	return purged, nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/rtapi"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// channelMessageReplyContent is the content of a thread reply message, wrapping the sender's content with the ID
+// of the message replied to so clients can place it in its thread.
+type channelMessageReplyContent struct {
+	ParentId string          `json:"parent_id"`
+	Content  json.RawMessage `json:"content"`
+}
+
+type channelMessageThreadCursor struct {
+	StreamMode       uint8
+	StreamSubject    string
+	StreamSubcontext string
+	StreamLabel      string
+	ParentId         string
+	CreateTime       int64
+	Id               string
+}
+
+func channelMessageReplyWrap(parentID, content string) (string, error) {
+	wrapped, err := json.Marshal(&channelMessageReplyContent{ParentId: parentID, Content: json.RawMessage(content)})
+	if err != nil {
+		return "", err
+	}
+	return string(wrapped), nil
+}
+
+// ChannelMessageReply sends and persists a reply to a chat message, starting or continuing the message's thread.
+// Replies are sent with the chat reply code, and their content holds the parent message ID and the sender's content.
+// Only top level chat messages may be replied to. If the sender is a user they must be allowed to send to the channel.
+func ChannelMessageReply(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, filter *ChannelMessageFilter, channelStream PresenceStream, channelId, parentId, content, senderId, senderUsername string) (*rtapi.ChannelMessageAck, error) {
+	if _, err := uuid.FromString(parentId); err != nil {
+		return nil, errChannelMessageIdInvalid
+	}
+
+	content, err := filter.Apply(content)
+	if err != nil {
+		return nil, err
+	}
+	content, err = channelMessageReplyWrap(parentId, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := channelCheckSendPermission(ctx, logger, db, tracker, uuid.FromStringOrNil(senderId), channelStream); err != nil {
+		return nil, err
+	}
+
+	ts := time.Now().Unix()
+	message := &api.ChannelMessage{
+		ChannelId:  channelId,
+		MessageId:  uuid.Must(uuid.NewV4()).String(),
+		Code:       &wrapperspb.Int32Value{Value: ChannelMessageTypeChatReply},
+		SenderId:   senderId,
+		Username:   senderUsername,
+		Content:    content,
+		CreateTime: &timestamppb.Timestamp{Seconds: ts},
+		UpdateTime: &timestamppb.Timestamp{Seconds: ts},
+		Persistent: &wrapperspb.BoolValue{Value: true},
+	}
+
+	ack := &rtapi.ChannelMessageAck{
+		ChannelId:  message.ChannelId,
+		MessageId:  message.MessageId,
+		Code:       message.Code,
+		Username:   message.Username,
+		CreateTime: message.CreateTime,
+		UpdateTime: message.UpdateTime,
+		Persistent: message.Persistent,
+	}
+	switch channelStream.Mode {
+	case StreamModeChannel:
+		message.RoomName, ack.RoomName = channelStream.Label, channelStream.Label
+	case StreamModeGroup:
+		message.GroupId, ack.GroupId = channelStream.Subject.String(), channelStream.Subject.String()
+	case StreamModeDM:
+		message.UserIdOne, ack.UserIdOne = channelStream.Subject.String(), channelStream.Subject.String()
+		message.UserIdTwo, ack.UserIdTwo = channelStream.Subcontext.String(), channelStream.Subcontext.String()
+	}
+
+	// The parent must be a chat message in the same channel.
+	query := `INSERT INTO message (id, code, sender_id, username, stream_mode, stream_subject, stream_descriptor, stream_label, content, create_time, update_time, parent_id)
+SELECT $1, $2, $3, $4, $5, $6::UUID, $7::UUID, $8, $9, $10, $10, $11
+WHERE EXISTS (SELECT 1 FROM message WHERE id = $11 AND stream_mode = $5 AND stream_subject = $6::UUID AND stream_descriptor = $7::UUID AND stream_label = $8 AND code = $12)`
+	res, err := db.ExecContext(ctx, query, message.MessageId, message.Code.Value, message.SenderId, message.Username, channelStream.Mode, channelStream.Subject, channelStream.Subcontext, channelStream.Label, message.Content, time.Unix(message.CreateTime.Seconds, 0).UTC(), parentId, ChannelMessageTypeChat)
+	if err != nil {
+		logger.Error("Error persisting channel message reply", zap.Error(err))
+		return nil, errChannelMessagePersist
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected != 1 {
+		return nil, errChannelMessageNotFound
+	}
+
+	router.SendToStream(logger, channelStream, &rtapi.Envelope{Message: &rtapi.Envelope_ChannelMessage{ChannelMessage: message}}, true)
+
+	return ack, nil
+}
+
+// channelCheckSendPermission checks the sender may send messages to the channel. Group members and direct message
+// participants may, rooms require the sender to have joined them, as the pipeline requires of senders. A nil sender
+// is always allowed.
+func channelCheckSendPermission(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, sender uuid.UUID, stream PresenceStream) error {
+	if sender == uuid.Nil {
+		return nil
+	}
+	if stream.Mode != StreamModeChannel {
+		return channelCheckReadPermission(ctx, logger, db, sender, stream)
+	}
+
+	for _, presence := range tracker.ListByStream(stream, true, true) {
+		if presence.UserID == sender {
+			return nil
+		}
+	}
+	return errChannelMessageNotJoined
+}
+
+// ChannelMessageThreadList lists the replies to a chat message, oldest first.
+// If the caller is not nil they must be allowed to read the channel.
+func ChannelMessageThreadList(ctx context.Context, logger *zap.Logger, db *sql.DB, caller uuid.UUID, stream PresenceStream, channelID, parentID string, limit int, cursor string) (*api.ChannelMessageList, error) {
+	if _, err := uuid.FromString(parentID); err != nil {
+		return nil, errChannelMessageIdInvalid
+	}
+
+	var incomingCursor *channelMessageThreadCursor
+	if cursor != "" {
+		cb, err := base64.StdEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+		incomingCursor = &channelMessageThreadCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+		if incomingCursor.StreamMode != stream.Mode || incomingCursor.StreamSubject != stream.Subject.String() || incomingCursor.StreamSubcontext != stream.Subcontext.String() || incomingCursor.StreamLabel != stream.Label || incomingCursor.ParentId != parentID {
+			// Cursor is for a different channel or thread.
+			return nil, runtime.ErrChannelCursorInvalid
+		}
+	}
+
+	if err := channelCheckReadPermission(ctx, logger, db, caller, stream); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT id, code, sender_id, username, content, create_time, update_time FROM message
+WHERE parent_id = $1 AND stream_mode = $2 AND stream_subject = $3::UUID AND stream_descriptor = $4::UUID AND stream_label = $5`
+	params := []interface{}{parentID, stream.Mode, stream.Subject, stream.Subcontext, stream.Label, limit + 1}
+	if incomingCursor != nil {
+		query += " AND (create_time, id) > ($7, $8)"
+		params = append(params, time.Unix(incomingCursor.CreateTime, 0).UTC(), incomingCursor.Id)
+	}
+	query += " ORDER BY create_time ASC, id ASC LIMIT $6"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error listing channel message thread", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*api.ChannelMessage, 0, limit)
+	var nextCursor *channelMessageThreadCursor
+
+	var dbID string
+	var dbCode int32
+	var dbSenderID string
+	var dbUsername string
+	var dbContent string
+	var dbCreateTime pgtype.Timestamptz
+	var dbUpdateTime pgtype.Timestamptz
+	for rows.Next() {
+		if len(messages) >= limit {
+			last := messages[len(messages)-1]
+			nextCursor = &channelMessageThreadCursor{
+				StreamMode:       stream.Mode,
+				StreamSubject:    stream.Subject.String(),
+				StreamSubcontext: stream.Subcontext.String(),
+				StreamLabel:      stream.Label,
+				ParentId:         parentID,
+				CreateTime:       last.CreateTime.Seconds,
+				Id:               last.MessageId,
+			}
+			break
+		}
+
+		if err := rows.Scan(&dbID, &dbCode, &dbSenderID, &dbUsername, &dbContent, &dbCreateTime, &dbUpdateTime); err != nil {
+			logger.Error("Error parsing channel message thread", zap.Error(err))
+			return nil, err
+		}
+
+		message := &api.ChannelMessage{
+			ChannelId:  channelID,
+			MessageId:  dbID,
+			Code:       &wrapperspb.Int32Value{Value: dbCode},
+			SenderId:   dbSenderID,
+			Username:   dbUsername,
+			Content:    dbContent,
+			CreateTime: &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+			UpdateTime: &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
+			Persistent: &wrapperspb.BoolValue{Value: true},
+		}
+		switch stream.Mode {
+		case StreamModeChannel:
+			message.RoomName = stream.Label
+		case StreamModeGroup:
+			message.GroupId = stream.Subject.String()
+		case StreamModeDM:
+			message.UserIdOne = stream.Subject.String()
+			message.UserIdTwo = stream.Subcontext.String()
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error listing channel message thread", zap.Error(err))
+		return nil, err
+	}
+
+	var nextCursorStr string
+	if nextCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(nextCursor); err != nil {
+			logger.Error("Error creating channel message thread cursor", zap.Error(err))
+			return nil, err
+		}
+		nextCursorStr = base64.StdEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return &api.ChannelMessageList{
+		Messages:   messages,
+		NextCursor: nextCursorStr,
+	}, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMessageReplyWrap(t *testing.T) {
+	content, err := channelMessageReplyWrap("6d3b1bd0-3ab8-4e5e-a8c5-5a4a1f2b9e11", `{"msg": "hi"}`)
+	if err != nil {
+		t.Fatalf("error wrapping reply: %v", err)
+	}
+	if want := `{"parent_id":"6d3b1bd0-3ab8-4e5e-a8c5-5a4a1f2b9e11","content":{"msg":"hi"}}`; content != want {
+		t.Fatalf("expected %s, got %s", want, content)
+	}
+
+	if _, err := channelMessageReplyWrap("6d3b1bd0-3ab8-4e5e-a8c5-5a4a1f2b9e11", `{"msg":`); err == nil {
+		t.Fatalf("expected error for invalid content")
+	}
+}
+
+func TestChannelMessageReactionValid(t *testing.T) {
+	tests := map[string]bool{
+		"👍":                     true,
+		":thumbs_up:":           true,
+		"👨‍👩‍👧":                 true,
+		"":                      false,
+		"thumbs up":             false,
+		"\t":                    false,
+		"\x01":                  false,
+		"\xff":                  false,
+		strings.Repeat("a", 64): true,
+		strings.Repeat("a", 65): false,
+	}
+	for emoji, want := range tests {
+		if got := channelMessageReactionValid(emoji); got != want {
+			t.Errorf("channelMessageReactionValid(%q) = %v, expected %v", emoji, got, want)
+		}
+	}
+}
+
+func createThreadTestChannel(t *testing.T, ctx context.Context) (*sql.DB, PresenceStream, string, uuid.UUID, uuid.UUID) {
+	db := NewDB(t)
+	member, outsider := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	InsertUser(t, db, member)
+	InsertUser(t, db, outsider)
+
+	group, err := CreateGroup(ctx, logger, db, member, member, GenerateString(), "en", "", "", "{}", false, 10)
+	require.NoError(t, err)
+	stream := PresenceStream{Mode: StreamModeGroup, Subject: uuid.Must(uuid.FromString(group.Id))}
+	channelID, err := StreamToChannelId(stream)
+	require.NoError(t, err)
+	return db, stream, channelID, member, outsider
+}
+
+func TestChannelMessageReply(t *testing.T) {
+	ctx := context.Background()
+	db, stream, channelID, member, outsider := createThreadTestChannel(t, ctx)
+	router := &DummyMessageRouter{}
+
+	parent, err := ChannelMessageSend(ctx, logger, db, router, nil, stream, channelID, `{"msg":"parent"}`, member.String(), member.String(), true)
+	require.NoError(t, err)
+
+	replies := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		ack, err := ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, parent.MessageId, `{"msg":"reply"}`, member.String(), member.String())
+		require.NoError(t, err)
+		assert.EqualValues(t, ChannelMessageTypeChatReply, ack.Code.Value)
+		replies = append(replies, ack.MessageId)
+	}
+
+	// Only group members may reply, and only to top level chat messages in the same channel.
+	_, err = ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, parent.MessageId, `{"msg":"reply"}`, outsider.String(), outsider.String())
+	assert.ErrorIs(t, err, runtime.ErrChannelGroupNotFound)
+	_, err = ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, replies[0], `{"msg":"reply"}`, member.String(), member.String())
+	assert.ErrorIs(t, err, errChannelMessageNotFound)
+	_, err = ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, uuid.Must(uuid.NewV4()).String(), `{"msg":"reply"}`, member.String(), member.String())
+	assert.ErrorIs(t, err, errChannelMessageNotFound)
+
+	// Rooms require the sender to have joined them.
+	room := PresenceStream{Mode: StreamModeChannel, Label: GenerateString()}
+	tracker := StartLocalTracker(logger, NewConfig(logger), nil, nil, metrics, nil)
+	defer tracker.Stop()
+	_, err = ChannelMessageReply(ctx, logger, db, tracker, router, nil, room, "2..."+room.Label, parent.MessageId, `{"msg":"reply"}`, member.String(), member.String())
+	assert.ErrorIs(t, err, errChannelMessageNotJoined)
+}
+
+func TestChannelMessageThreadList(t *testing.T) {
+	ctx := context.Background()
+	db, stream, channelID, member, outsider := createThreadTestChannel(t, ctx)
+	router := &DummyMessageRouter{}
+
+	parent, err := ChannelMessageSend(ctx, logger, db, router, nil, stream, channelID, `{"msg":"parent"}`, member.String(), member.String(), true)
+	require.NoError(t, err)
+	other, err := ChannelMessageSend(ctx, logger, db, router, nil, stream, channelID, `{"msg":"other"}`, member.String(), member.String(), true)
+	require.NoError(t, err)
+	replies := make(map[string]bool, 3)
+	for i := 0; i < 3; i++ {
+		ack, err := ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, parent.MessageId, `{"msg":"reply"}`, member.String(), member.String())
+		require.NoError(t, err)
+		replies[ack.MessageId] = true
+	}
+	_, err = ChannelMessageReply(ctx, logger, db, nil, router, nil, stream, channelID, other.MessageId, `{"msg":"elsewhere"}`, member.String(), member.String())
+	require.NoError(t, err)
+
+	// Page through the thread, which holds only the parent's replies.
+	listed := make(map[string]bool, 3)
+	var cursor string
+	for page := 0; page < 3; page++ {
+		list, err := ChannelMessageThreadList(ctx, logger, db, member, stream, channelID, parent.MessageId, 2, cursor)
+		require.NoError(t, err)
+		for _, message := range list.Messages {
+			assert.True(t, replies[message.MessageId], "unexpected message %v in thread", message.MessageId)
+			assert.Contains(t, message.Content, parent.MessageId)
+			listed[message.MessageId] = true
+		}
+		if cursor = list.NextCursor; cursor == "" {
+			break
+		}
+	}
+	assert.Len(t, listed, 3)
+
+	_, err = ChannelMessageThreadList(ctx, logger, db, outsider, stream, channelID, parent.MessageId, 2, "")
+	assert.ErrorIs(t, err, runtime.ErrChannelGroupNotFound)
+	_, err = ChannelMessageThreadList(ctx, logger, db, member, stream, channelID, other.MessageId, 2, cursor+"x")
+	assert.ErrorIs(t, err, runtime.ErrChannelCursorInvalid)
+}
+
+func TestChannelMessageReactionCounts(t *testing.T) {
+	ctx := context.Background()
+	db, stream, channelID, member, _ := createThreadTestChannel(t, ctx)
+	router := &DummyMessageRouter{}
+	second := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, second)
+	_, err := groupAddUser(ctx, db, nil, stream.Subject, second, 2)
+	require.NoError(t, err)
+
+	message, err := ChannelMessageSend(ctx, logger, db, router, nil, stream, channelID, `{"msg":"react"}`, member.String(), member.String(), true)
+	require.NoError(t, err)
+
+	reaction, err := ChannelMessageReactionAdd(ctx, logger, db, nil, router, stream, channelID, message.MessageId, "👍", member, member.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reaction.Count)
+	reaction, err = ChannelMessageReactionAdd(ctx, logger, db, nil, router, stream, channelID, message.MessageId, "👍", second, second.String())
+	require.NoError(t, err)
+	assert.Equal(t, 2, reaction.Count)
+	// Reacting again with the same emoji changes nothing.
+	reaction, err = ChannelMessageReactionAdd(ctx, logger, db, nil, router, stream, channelID, message.MessageId, "👍", second, second.String())
+	require.NoError(t, err)
+	assert.Equal(t, 2, reaction.Count)
+	_, err = ChannelMessageReactionAdd(ctx, logger, db, nil, router, stream, channelID, message.MessageId, "🎉", second, second.String())
+	require.NoError(t, err)
+
+	reactions, err := ChannelMessageReactionsList(ctx, logger, db, member, stream, []string{message.MessageId})
+	require.NoError(t, err)
+	require.Len(t, reactions[message.MessageId], 2)
+	assert.Equal(t, &ChannelMessageReaction{Emoji: "👍", Count: 2, Reacted: true}, reactions[message.MessageId][0])
+	assert.Equal(t, &ChannelMessageReaction{Emoji: "🎉", Count: 1, Reacted: false}, reactions[message.MessageId][1])
+
+	reaction, err = ChannelMessageReactionRemove(ctx, logger, db, nil, router, stream, channelID, message.MessageId, "👍", member, member.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, reaction.Count)
+	reactions, err = ChannelMessageReactionsList(ctx, logger, db, member, stream, []string{message.MessageId})
+	require.NoError(t, err)
+	assert.Equal(t, 1, reactions[message.MessageId][0].Count)
+	assert.False(t, reactions[message.MessageId][0].Reacted)
+
+	// Rooms require the reacting user to have joined them.
+	room := PresenceStream{Mode: StreamModeChannel, Label: GenerateString()}
+	tracker := StartLocalTracker(logger, NewConfig(logger), nil, nil, metrics, nil)
+	defer tracker.Stop()
+	_, err = ChannelMessageReactionAdd(ctx, logger, db, tracker, router, room, "2..."+room.Label, message.MessageId, "👍", member, member.String())
+	assert.ErrorIs(t, err, errChannelMessageNotJoined)
+}
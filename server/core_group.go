@@ -280,6 +280,18 @@ func DeleteGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uu
 }
 
 func JoinGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, groupID uuid.UUID, userID uuid.UUID, username string) error {
+	// The client API, console and Lua and JavaScript runtimes have no way to collect answers. Their requests to join
+	// closed groups are stored without answers, for admins to review like any other request.
+	return joinGroup(ctx, logger, db, tracker, router, groupID, userID, username, nil, false)
+}
+
+// JoinGroupWithAnswers joins a group like JoinGroup, but requests to join closed groups must answer the group's join
+// questionnaire.
+func JoinGroupWithAnswers(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, groupID uuid.UUID, userID uuid.UUID, username string, answers map[string]string) error {
+	return joinGroup(ctx, logger, db, tracker, router, groupID, userID, username, answers, true)
+}
+
+func joinGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, groupID uuid.UUID, userID uuid.UUID, username string, answers map[string]string, validateAnswers bool) error {
 	query := `
 SELECT id, creator_id, name, description, avatar_url, state, edge_count, lang_tag, max_count, metadata, create_time, update_time
 FROM groups
@@ -311,8 +323,23 @@ WHERE (id = $1) AND (disable_time = '1970-01-01 00:00:00 UTC')`
 	state := 2
 	if !group.Open.Value {
 		state = 3
-		_, err = groupAddUser(ctx, db, nil, uuid.Must(uuid.FromString(group.Id)), userID, state)
+
+		questionnaire, err := GroupJoinQuestionnaireGet(ctx, logger, db, groupID)
 		if err != nil {
+			return err
+		}
+		if validateAnswers {
+			if err = groupJoinAnswersValidate(questionnaire.Questions, answers); err != nil {
+				return err
+			}
+		}
+
+		if err = ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := groupAddUser(ctx, db, tx, groupID, userID, state); err != nil {
+				return err
+			}
+			return groupJoinRequestAdd(ctx, tx, groupID, userID, answers, questionnaire.RequestExpirySec)
+		}); err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) && pgErr.Code == dbErrorUniqueViolation {
 				logger.Info("Could not add user to group as relationship already exists.", zap.String("group_id", groupID.String()), zap.String("user_id", userID.String()))
@@ -325,7 +352,10 @@ WHERE (id = $1) AND (disable_time = '1970-01-01 00:00:00 UTC')`
 
 		// If it's a private group notify superadmins/admins that someone has requested to join.
 		// Prepare notification data.
-		notificationContentBytes, err := json.Marshal(map[string]string{"group_id": groupID.String(), "username": username})
+		if answers == nil {
+			answers = map[string]string{}
+		}
+		notificationContentBytes, err := json.Marshal(map[string]interface{}{"group_id": groupID.String(), "username": username, "user_id": userID.String(), "answers": answers})
 		if err != nil {
 			logger.Error("Could not encode notification content.", zap.Error(err))
 		} else {
@@ -0,0 +1,590 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	groupJoinQuestionsMax          = 20
+	groupJoinQuestionIdMaxLength   = 64
+	groupJoinQuestionTextMaxLength = 512
+	groupJoinQuestionOptionsMax    = 25
+	groupJoinAnswerMaxLength       = 1024
+
+	groupJoinRequestReapInterval  = time.Minute
+	groupJoinRequestReapBatchSize = 1000
+)
+
+var (
+	ErrGroupJoinQuestionsInvalid = errors.New("invalid group join questionnaire")
+	ErrGroupJoinAnswersInvalid   = errors.New("group join questions not answered correctly")
+)
+
+// GroupJoinQuestion is a question users answer when requesting to join a closed group. Questions with options only
+// accept one of the options as an answer.
+type GroupJoinQuestion struct {
+	Id       string   `json:"id"`
+	Text     string   `json:"text"`
+	Required bool     `json:"required,omitempty"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// GroupJoinQuestionnaire is the questions a closed group asks of users requesting to join, and how long their
+// requests last.
+type GroupJoinQuestionnaire struct {
+	Questions []*GroupJoinQuestion `json:"questions"`
+	// Seconds after which join requests expire if they are not approved or rejected. Zero never expires.
+	RequestExpirySec int `json:"request_expiry_sec"`
+}
+
+// GroupJoinRequest is a pending request to join a closed group, with the user's answers to its questionnaire.
+type GroupJoinRequest struct {
+	UserId     string            `json:"user_id"`
+	Username   string            `json:"username"`
+	Answers    map[string]string `json:"answers"`
+	CreateTime int64             `json:"create_time"`
+	ExpireTime int64             `json:"expire_time,omitempty"`
+}
+
+type groupJoinRequestListCursor struct {
+	GroupId  string
+	Position int64
+	UserId   string
+}
+
+func groupJoinQuestionnaireValidate(questionnaire *GroupJoinQuestionnaire) error {
+	if len(questionnaire.Questions) > groupJoinQuestionsMax {
+		return fmt.Errorf("%w: at most %d questions may be asked", ErrGroupJoinQuestionsInvalid, groupJoinQuestionsMax)
+	}
+	if questionnaire.RequestExpirySec < 0 {
+		return fmt.Errorf("%w: request expiry must be >= 0", ErrGroupJoinQuestionsInvalid)
+	}
+
+	ids := make(map[string]struct{}, len(questionnaire.Questions))
+	for _, question := range questionnaire.Questions {
+		if question == nil || question.Id == "" || len(question.Id) > groupJoinQuestionIdMaxLength {
+			return fmt.Errorf("%w: question IDs must be 1-%d bytes", ErrGroupJoinQuestionsInvalid, groupJoinQuestionIdMaxLength)
+		}
+		if _, found := ids[question.Id]; found {
+			return fmt.Errorf("%w: question ID '%s' is not unique", ErrGroupJoinQuestionsInvalid, question.Id)
+		}
+		ids[question.Id] = struct{}{}
+		if strings.TrimSpace(question.Text) == "" || len(question.Text) > groupJoinQuestionTextMaxLength {
+			return fmt.Errorf("%w: question text must be 1-%d bytes", ErrGroupJoinQuestionsInvalid, groupJoinQuestionTextMaxLength)
+		}
+		if len(question.Options) > groupJoinQuestionOptionsMax {
+			return fmt.Errorf("%w: questions may have at most %d options", ErrGroupJoinQuestionsInvalid, groupJoinQuestionOptionsMax)
+		}
+		for _, option := range question.Options {
+			if option == "" || len(option) > groupJoinAnswerMaxLength {
+				return fmt.Errorf("%w: question options must be 1-%d bytes", ErrGroupJoinQuestionsInvalid, groupJoinAnswerMaxLength)
+			}
+		}
+	}
+	return nil
+}
+
+func groupJoinAnswersValidate(questions []*GroupJoinQuestion, answers map[string]string) error {
+	asked := make(map[string]*GroupJoinQuestion, len(questions))
+	for _, question := range questions {
+		asked[question.Id] = question
+	}
+	for id, answer := range answers {
+		question, found := asked[id]
+		if !found {
+			return fmt.Errorf("%w: question '%s' is not asked", ErrGroupJoinAnswersInvalid, id)
+		}
+		if len(answer) > groupJoinAnswerMaxLength {
+			return fmt.Errorf("%w: answers must be at most %d bytes", ErrGroupJoinAnswersInvalid, groupJoinAnswerMaxLength)
+		}
+		if answer != "" && len(question.Options) > 0 {
+			valid := false
+			for _, option := range question.Options {
+				if answer == option {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("%w: answer to question '%s' must be one of its options", ErrGroupJoinAnswersInvalid, id)
+			}
+		}
+	}
+	for _, question := range questions {
+		if question.Required && strings.TrimSpace(answers[question.Id]) == "" {
+			return fmt.Errorf("%w: question '%s' is required", ErrGroupJoinAnswersInvalid, question.Id)
+		}
+	}
+	return nil
+}
+
+// GroupJoinQuestionnaireGet returns the questions asked of users requesting to join a group.
+func GroupJoinQuestionnaireGet(ctx context.Context, logger *zap.Logger, db *sql.DB, groupID uuid.UUID) (*GroupJoinQuestionnaire, error) {
+	var dbQuestions []byte
+	questionnaire := &GroupJoinQuestionnaire{}
+	query := "SELECT join_questions, join_request_expiry_sec FROM groups WHERE id = $1 AND disable_time = '1970-01-01 00:00:00 UTC'"
+	if err := db.QueryRowContext(ctx, query, groupID).Scan(&dbQuestions, &questionnaire.RequestExpirySec); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, runtime.ErrGroupNotFound
+		}
+		logger.Error("Could not look up group join questionnaire.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, err
+	}
+	if err := json.Unmarshal(dbQuestions, &questionnaire.Questions); err != nil {
+		logger.Error("Could not parse group join questionnaire.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, err
+	}
+	return questionnaire, nil
+}
+
+// GroupJoinQuestionnaireSet replaces the questions asked of users requesting to join a group. If the caller is not
+// nil they must be a group superadmin or admin. Existing join requests keep their answers and expiry.
+func GroupJoinQuestionnaireSet(ctx context.Context, logger *zap.Logger, db *sql.DB, caller, groupID uuid.UUID, questionnaire *GroupJoinQuestionnaire) error {
+	if questionnaire.Questions == nil {
+		questionnaire.Questions = []*GroupJoinQuestion{}
+	}
+	if err := groupJoinQuestionnaireValidate(questionnaire); err != nil {
+		return err
+	}
+
+	if caller != uuid.Nil {
+		allowed, err := groupCheckUserPermission(ctx, logger, db, groupID, caller, 1)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return runtime.ErrGroupPermissionDenied
+		}
+	}
+
+	questions, err := json.Marshal(questionnaire.Questions)
+	if err != nil {
+		return err
+	}
+	query := "UPDATE groups SET join_questions = $2, join_request_expiry_sec = $3, update_time = now() WHERE id = $1 AND disable_time = '1970-01-01 00:00:00 UTC'"
+	res, err := db.ExecContext(ctx, query, groupID, questions, questionnaire.RequestExpirySec)
+	if err != nil {
+		logger.Error("Could not update group join questionnaire.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected != 1 {
+		return runtime.ErrGroupNotFound
+	}
+	return nil
+}
+
+// groupJoinRequestAdd stores a user's answers to a group's questionnaire alongside their join request.
+func groupJoinRequestAdd(ctx context.Context, tx *sql.Tx, groupID, userID uuid.UUID, answers map[string]string, expirySec int) error {
+	if answers == nil {
+		answers = map[string]string{}
+	}
+	answersBytes, err := json.Marshal(answers)
+	if err != nil {
+		return err
+	}
+	var expireTime *time.Time
+	if expirySec > 0 {
+		t := time.Now().UTC().Add(time.Duration(expirySec) * time.Second)
+		expireTime = &t
+	}
+
+	query := `INSERT INTO group_join_request (group_id, user_id, answers, expire_time) VALUES ($1, $2, $3, $4)
+ON CONFLICT (group_id, user_id) DO UPDATE SET answers = $3, create_time = now(), expire_time = $4`
+	_, err = tx.ExecContext(ctx, query, groupID, userID, answersBytes, expireTime)
+	return err
+}
+
+// GroupJoinRequestsList lists a group's pending join requests oldest first, with the questionnaire answers given.
+// If the caller is not nil they must be a group superadmin or admin.
+func GroupJoinRequestsList(ctx context.Context, logger *zap.Logger, db *sql.DB, caller, groupID uuid.UUID, limit int, cursor string) ([]*GroupJoinRequest, string, error) {
+	var incomingCursor *groupJoinRequestListCursor
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", runtime.ErrGroupUserInvalidCursor
+		}
+		incomingCursor = &groupJoinRequestListCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil || incomingCursor.GroupId != groupID.String() {
+			return nil, "", runtime.ErrGroupUserInvalidCursor
+		}
+	}
+
+	if caller != uuid.Nil {
+		allowed, err := groupCheckUserPermission(ctx, logger, db, groupID, caller, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		if !allowed {
+			return nil, "", runtime.ErrGroupPermissionDenied
+		}
+	}
+
+	query := `SELECT ge.destination_id, ge.position, ge.update_time, u.username, r.answers, r.create_time, r.expire_time
+FROM group_edge ge
+JOIN users u ON u.id = ge.destination_id
+LEFT JOIN group_join_request r ON r.group_id = ge.source_id AND r.user_id = ge.destination_id
+WHERE ge.source_id = $1 AND ge.state = $2 AND (r.expire_time IS NULL OR r.expire_time > now())`
+	params := []interface{}{groupID, api.GroupUserList_GroupUser_JOIN_REQUEST, limit + 1}
+	if incomingCursor != nil {
+		query += " AND (ge.position, ge.destination_id) > ($4, $5)"
+		params = append(params, incomingCursor.Position, incomingCursor.UserId)
+	}
+	query += " ORDER BY ge.position ASC, ge.destination_id ASC LIMIT $3"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Could not list group join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	requests := make([]*GroupJoinRequest, 0, limit)
+	var nextCursor *groupJoinRequestListCursor
+	var lastPosition int64
+	for rows.Next() {
+		if len(requests) >= limit {
+			nextCursor = &groupJoinRequestListCursor{
+				GroupId:  groupID.String(),
+				Position: lastPosition,
+				UserId:   requests[len(requests)-1].UserId,
+			}
+			break
+		}
+
+		var dbAnswers []byte
+		var dbEdgeUpdateTime, dbCreateTime, dbExpireTime pgtype.Timestamptz
+		request := &GroupJoinRequest{Answers: map[string]string{}}
+		if err := rows.Scan(&request.UserId, &lastPosition, &dbEdgeUpdateTime, &request.Username, &dbAnswers, &dbCreateTime, &dbExpireTime); err != nil {
+			logger.Error("Could not parse group join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+			return nil, "", err
+		}
+		if dbAnswers != nil {
+			if err := json.Unmarshal(dbAnswers, &request.Answers); err != nil {
+				logger.Error("Could not parse group join request answers.", zap.Error(err), zap.String("group_id", groupID.String()))
+				return nil, "", err
+			}
+		}
+		// Join requests made before questionnaires were introduced have no answers.
+		request.CreateTime = dbEdgeUpdateTime.Time.Unix()
+		if dbCreateTime.Status == pgtype.Present {
+			request.CreateTime = dbCreateTime.Time.Unix()
+		}
+		if dbExpireTime.Status == pgtype.Present {
+			request.ExpireTime = dbExpireTime.Time.Unix()
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Could not list group join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, "", err
+	}
+
+	var nextCursorStr string
+	if nextCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(nextCursor); err != nil {
+			logger.Error("Could not create group join request list cursor.", zap.Error(err))
+			return nil, "", err
+		}
+		nextCursorStr = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return requests, nextCursorStr, nil
+}
+
+// groupJoinRequestsPending returns those of the users who have a pending, unexpired join request to a group.
+func groupJoinRequestsPending(ctx context.Context, db *sql.DB, groupID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	ids := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ids = append(ids, userID.String())
+	}
+
+	query := `SELECT ge.destination_id FROM group_edge ge
+LEFT JOIN group_join_request r ON r.group_id = ge.source_id AND r.user_id = ge.destination_id
+WHERE ge.source_id = $1 AND ge.state = $2 AND ge.destination_id = ANY($3::UUID[]) AND (r.expire_time IS NULL OR r.expire_time > now())`
+	rows, err := db.QueryContext(ctx, query, groupID, api.GroupUserList_GroupUser_JOIN_REQUEST, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make([]uuid.UUID, 0, len(userIDs))
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		pending = append(pending, userID)
+	}
+	return pending, rows.Err()
+}
+
+func groupJoinRequestsCheckPermission(ctx context.Context, logger *zap.Logger, db *sql.DB, caller, groupID uuid.UUID) error {
+	if caller == uuid.Nil {
+		return nil
+	}
+	allowed, err := groupCheckUserPermission(ctx, logger, db, groupID, caller, 1)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return runtime.ErrGroupPermissionDenied
+	}
+	return nil
+}
+
+// GroupJoinRequestsApprove makes the users with pending join requests to a group members of it, and returns the IDs of
+// the users approved. Users without a pending request are skipped. If the caller is not nil they must be a group
+// superadmin or admin.
+func GroupJoinRequestsApprove(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, caller, groupID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if err := groupJoinRequestsCheckPermission(ctx, logger, db, caller, groupID); err != nil {
+		return nil, err
+	}
+
+	pending, err := groupJoinRequestsPending(ctx, db, groupID, userIDs)
+	if err != nil {
+		logger.Error("Could not look up group join requests to approve.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return pending, nil
+	}
+
+	if err := AddGroupUsers(ctx, logger, db, tracker, router, caller, groupID, pending); err != nil {
+		return nil, err
+	}
+
+	if err := groupJoinRequestsDelete(ctx, db, groupID, pending); err != nil {
+		// The requests were approved, their answers are cleaned up by the reaper.
+		logger.Warn("Could not delete approved group join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+	}
+	return pending, nil
+}
+
+// GroupJoinRequestsReject removes the pending join requests of the users to a group, notifies them, and returns the
+// IDs of the users rejected. Users without a pending request are skipped. If the caller is not nil they must be a
+// group superadmin or admin.
+func GroupJoinRequestsReject(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, caller, groupID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if err := groupJoinRequestsCheckPermission(ctx, logger, db, caller, groupID); err != nil {
+		return nil, err
+	}
+
+	var groupName sql.NullString
+	query := "SELECT name FROM groups WHERE id = $1 AND disable_time = '1970-01-01 00:00:00 UTC'"
+	if err := db.QueryRowContext(ctx, query, groupID).Scan(&groupName); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, runtime.ErrGroupNotFound
+		}
+		logger.Error("Could not look up group when rejecting join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ids = append(ids, userID.String())
+	}
+
+	var rejected []uuid.UUID
+	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+		rejected = make([]uuid.UUID, 0, len(userIDs))
+
+		query := "DELETE FROM group_edge WHERE source_id = $1 AND destination_id = ANY($2::UUID[]) AND state = $3 RETURNING destination_id"
+		rows, err := tx.QueryContext(ctx, query, groupID, ids, api.GroupUserList_GroupUser_JOIN_REQUEST)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var userID uuid.UUID
+			if err := rows.Scan(&userID); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			rejected = append(rejected, userID)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(rejected) == 0 {
+			return nil
+		}
+
+		rejectedIDs := make([]string, 0, len(rejected))
+		for _, userID := range rejected {
+			rejectedIDs = append(rejectedIDs, userID.String())
+		}
+		query = "DELETE FROM group_edge WHERE source_id = ANY($1::UUID[]) AND destination_id = $2 AND state = $3"
+		if _, err := tx.ExecContext(ctx, query, rejectedIDs, groupID, api.GroupUserList_GroupUser_JOIN_REQUEST); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM group_join_request WHERE group_id = $1 AND user_id = ANY($2::UUID[])", groupID, rejectedIDs)
+		return err
+	}); err != nil {
+		logger.Error("Could not reject group join requests.", zap.Error(err), zap.String("group_id", groupID.String()))
+		return nil, err
+	}
+
+	if len(rejected) > 0 {
+		content, err := json.Marshal(map[string]string{"group_id": groupID.String(), "name": groupName.String})
+		if err != nil {
+			logger.Error("Could not encode notification content.", zap.Error(err))
+			return rejected, nil
+		}
+		notifications := make(map[uuid.UUID][]*api.Notification, len(rejected))
+		for _, userID := range rejected {
+			notifications[userID] = []*api.Notification{{
+				Id:         uuid.Must(uuid.NewV4()).String(),
+				Subject:    fmt.Sprintf("Your request to join group %v was declined", groupName.String),
+				Content:    string(content),
+				SenderId:   caller.String(),
+				Code:       NotificationCodeGroupJoinReject,
+				Persistent: true,
+				CreateTime: &timestamppb.Timestamp{Seconds: time.Now().UTC().Unix()},
+			}}
+		}
+		// Any error is already logged before it's returned here.
+		_ = NotificationSend(ctx, logger, db, tracker, router, notifications)
+	}
+
+	return rejected, nil
+}
+
+func groupJoinRequestsDelete(ctx context.Context, db *sql.DB, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	ids := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		ids = append(ids, userID.String())
+	}
+	_, err := db.ExecContext(ctx, "DELETE FROM group_join_request WHERE group_id = $1 AND user_id = ANY($2::UUID[])", groupID, ids)
+	return err
+}
+
+// groupJoinRequestsExpire removes a batch of expired join requests along with their group relationships, and returns
+// the number removed.
+func groupJoinRequestsExpire(ctx context.Context, db *sql.DB, limit int) (int, error) {
+	query := `WITH expired AS (
+	DELETE FROM group_join_request
+	WHERE (group_id, user_id) IN (SELECT group_id, user_id FROM group_join_request WHERE expire_time <= now() LIMIT $1)
+	RETURNING group_id, user_id
+), edges AS (
+	DELETE FROM group_edge ge USING expired e
+	WHERE ge.state = $2 AND ((ge.source_id = e.group_id AND ge.destination_id = e.user_id) OR (ge.source_id = e.user_id AND ge.destination_id = e.group_id))
+	RETURNING 1
+)
+SELECT (SELECT count(*) FROM expired), (SELECT count(*) FROM edges)`
+
+	var expired, edges int
+	if err := db.QueryRowContext(ctx, query, limit, api.GroupUserList_GroupUser_JOIN_REQUEST).Scan(&expired, &edges); err != nil {
+		return 0, err
+	}
+	return expired, nil
+}
+
+// groupJoinRequestsPrune removes the answers of join requests that were accepted, rejected or withdrawn through other
+// group operations.
+func groupJoinRequestsPrune(ctx context.Context, db *sql.DB) (int64, error) {
+	query := `DELETE FROM group_join_request r
+WHERE NOT EXISTS (SELECT 1 FROM group_edge ge WHERE ge.source_id = r.group_id AND ge.destination_id = r.user_id AND ge.state = $1)`
+	res, err := db.ExecContext(ctx, query, api.GroupUserList_GroupUser_JOIN_REQUEST)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type GroupJoinRequestReaper interface {
+	Start()
+	Stop()
+}
+
+// LocalGroupJoinRequestReaper periodically removes expired group join requests, and the answers left behind by join
+// requests resolved without the approve and reject operations.
+type LocalGroupJoinRequestReaper struct {
+	logger *zap.Logger
+	db     *sql.DB
+
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+}
+
+func NewLocalGroupJoinRequestReaper(logger *zap.Logger, db *sql.DB) GroupJoinRequestReaper {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	return &LocalGroupJoinRequestReaper{
+		logger: logger,
+		db:     db,
+
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+	}
+}
+
+func (r *LocalGroupJoinRequestReaper) Start() {
+	go func() {
+		ticker := time.NewTicker(groupJoinRequestReapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Reap(r.ctx); err != nil && r.ctx.Err() == nil {
+					r.logger.Error("Failed to reap group join requests", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (r *LocalGroupJoinRequestReaper) Stop() {
+	r.ctxCancelFn()
+}
+
+// Reap removes expired join requests until none remain, then prunes resolved ones.
+func (r *LocalGroupJoinRequestReaper) Reap(ctx context.Context) error {
+	for {
+		count, err := groupJoinRequestsExpire(ctx, r.db, groupJoinRequestReapBatchSize)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			r.logger.Debug("Expired group join requests", zap.Int("count", count))
+		}
+		if count < groupJoinRequestReapBatchSize {
+			break
+		}
+	}
+
+	_, err := groupJoinRequestsPrune(ctx, r.db)
+	return err
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupJoinQuestionnaireValidate(t *testing.T) {
+	tooMany := make([]*GroupJoinQuestion, 0, groupJoinQuestionsMax+1)
+	for i := 0; i <= groupJoinQuestionsMax; i++ {
+		tooMany = append(tooMany, &GroupJoinQuestion{Id: strings.Repeat("q", i+1), Text: "Why?"})
+	}
+
+	tests := []struct {
+		name          string
+		questionnaire *GroupJoinQuestionnaire
+		invalid       bool
+	}{
+		{
+			name:          "no questions",
+			questionnaire: &GroupJoinQuestionnaire{},
+		},
+		{
+			name: "questions with expiry",
+			questionnaire: &GroupJoinQuestionnaire{Questions: []*GroupJoinQuestion{
+				{Id: "reason", Text: "Why do you want to join?", Required: true},
+				{Id: "region", Text: "Where do you play?", Options: []string{"EU", "NA"}},
+			}, RequestExpirySec: 86400},
+		},
+		{
+			name:          "too many questions",
+			questionnaire: &GroupJoinQuestionnaire{Questions: tooMany},
+			invalid:       true,
+		},
+		{
+			name:          "negative expiry",
+			questionnaire: &GroupJoinQuestionnaire{RequestExpirySec: -1},
+			invalid:       true,
+		},
+		{
+			name:          "missing ID",
+			questionnaire: &GroupJoinQuestionnaire{Questions: []*GroupJoinQuestion{{Text: "Why?"}}},
+			invalid:       true,
+		},
+		{
+			name:          "duplicate ID",
+			questionnaire: &GroupJoinQuestionnaire{Questions: []*GroupJoinQuestion{{Id: "a", Text: "Why?"}, {Id: "a", Text: "Where?"}}},
+			invalid:       true,
+		},
+		{
+			name:          "blank text",
+			questionnaire: &GroupJoinQuestionnaire{Questions: []*GroupJoinQuestion{{Id: "a", Text: " "}}},
+			invalid:       true,
+		},
+		{
+			name:          "empty option",
+			questionnaire: &GroupJoinQuestionnaire{Questions: []*GroupJoinQuestion{{Id: "a", Text: "Why?", Options: []string{""}}}},
+			invalid:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := groupJoinQuestionnaireValidate(tt.questionnaire)
+			if tt.invalid {
+				assert.True(t, errors.Is(err, ErrGroupJoinQuestionsInvalid), "expected invalid questionnaire error, got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGroupJoinAnswersValidate(t *testing.T) {
+	questions := []*GroupJoinQuestion{
+		{Id: "reason", Text: "Why do you want to join?", Required: true},
+		{Id: "region", Text: "Where do you play?", Options: []string{"EU", "NA"}},
+	}
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		invalid bool
+	}{
+		{
+			name:    "required answered",
+			answers: map[string]string{"reason": "To play"},
+		},
+		{
+			name:    "option chosen",
+			answers: map[string]string{"reason": "To play", "region": "EU"},
+		},
+		{
+			name:    "no answers",
+			invalid: true,
+		},
+		{
+			name:    "blank required answer",
+			answers: map[string]string{"reason": "  "},
+			invalid: true,
+		},
+		{
+			name:    "unknown option",
+			answers: map[string]string{"reason": "To play", "region": "APAC"},
+			invalid: true,
+		},
+		{
+			name:    "unknown question",
+			answers: map[string]string{"reason": "To play", "age": "30"},
+			invalid: true,
+		},
+		{
+			name:    "answer too long",
+			answers: map[string]string{"reason": strings.Repeat("a", groupJoinAnswerMaxLength+1)},
+			invalid: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := groupJoinAnswersValidate(questions, tt.answers)
+			if tt.invalid {
+				assert.True(t, errors.Is(err, ErrGroupJoinAnswersInvalid), "expected invalid answers error, got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	// Groups without questions accept joins without answers.
+	assert.NoError(t, groupJoinAnswersValidate(nil, nil))
+}
+
+func createJoinRequestTestGroup(t *testing.T, ctx context.Context, db *sql.DB, expirySec int) (uuid.UUID, uuid.UUID) {
+	admin := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, admin)
+	group, err := CreateGroup(ctx, logger, db, admin, admin, GenerateString(), "en", "", "", "{}", false, 10)
+	require.NoError(t, err)
+	groupID := uuid.Must(uuid.FromString(group.Id))
+
+	questionnaire := &GroupJoinQuestionnaire{
+		Questions:        []*GroupJoinQuestion{{Id: "reason", Text: "Why do you want to join?", Required: true}},
+		RequestExpirySec: expirySec,
+	}
+	require.NoError(t, GroupJoinQuestionnaireSet(ctx, logger, db, admin, groupID, questionnaire))
+	return groupID, admin
+}
+
+func groupJoinTestState(t *testing.T, db *sql.DB, groupID, userID uuid.UUID) int {
+	var state sql.NullInt64
+	err := db.QueryRow("SELECT state FROM group_edge WHERE source_id = $1 AND destination_id = $2", groupID, userID).Scan(&state)
+	if err == sql.ErrNoRows {
+		return -1
+	}
+	require.NoError(t, err)
+	return int(state.Int64)
+}
+
+func TestGroupJoinRequestsApproveReject(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	defer db.Close()
+	tracker := StartLocalTracker(logger, NewConfig(logger), nil, nil, metrics, nil)
+	defer tracker.Stop()
+	router := &DummyMessageRouter{}
+
+	groupID, admin := createJoinRequestTestGroup(t, ctx, db, 0)
+	users := make([]uuid.UUID, 3)
+	for i := range users {
+		users[i] = uuid.Must(uuid.NewV4())
+		InsertUser(t, db, users[i])
+	}
+
+	// Requests must answer the questionnaire, unless the caller can't collect answers.
+	err := JoinGroupWithAnswers(ctx, logger, db, tracker, router, groupID, users[0], users[0].String(), nil)
+	assert.ErrorIs(t, err, ErrGroupJoinAnswersInvalid)
+	require.NoError(t, JoinGroupWithAnswers(ctx, logger, db, tracker, router, groupID, users[0], users[0].String(), map[string]string{"reason": "To play"}))
+	require.NoError(t, JoinGroupWithAnswers(ctx, logger, db, tracker, router, groupID, users[1], users[1].String(), map[string]string{"reason": "Friends"}))
+	require.NoError(t, JoinGroup(ctx, logger, db, tracker, router, groupID, users[2], users[2].String()))
+
+	requests, _, err := GroupJoinRequestsList(ctx, logger, db, admin, groupID, 10, "")
+	require.NoError(t, err)
+	require.Len(t, requests, 3)
+	answers := make(map[string]map[string]string, len(requests))
+	for _, request := range requests {
+		answers[request.UserId] = request.Answers
+	}
+	assert.Equal(t, map[string]string{"reason": "To play"}, answers[users[0].String()])
+	assert.Empty(t, answers[users[2].String()])
+
+	// Only admins may approve or reject, and users without a pending request are skipped.
+	_, err = GroupJoinRequestsApprove(ctx, logger, db, tracker, router, users[1], groupID, []uuid.UUID{users[0]})
+	assert.ErrorIs(t, err, runtime.ErrGroupPermissionDenied)
+	approved, err := GroupJoinRequestsApprove(ctx, logger, db, tracker, router, admin, groupID, []uuid.UUID{users[0], uuid.Must(uuid.NewV4())})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{users[0]}, approved)
+	assert.Equal(t, int(api.GroupUserList_GroupUser_MEMBER), groupJoinTestState(t, db, groupID, users[0]))
+
+	rejected, err := GroupJoinRequestsReject(ctx, logger, db, tracker, router, admin, groupID, []uuid.UUID{users[0], users[1]})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{users[1]}, rejected)
+	assert.Equal(t, -1, groupJoinTestState(t, db, groupID, users[1]))
+	assert.Equal(t, int(api.GroupUserList_GroupUser_MEMBER), groupJoinTestState(t, db, groupID, users[0]))
+
+	requests, _, err = GroupJoinRequestsList(ctx, logger, db, admin, groupID, 10, "")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, users[2].String(), requests[0].UserId)
+}
+
+func TestGroupJoinRequestReaper(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	defer db.Close()
+	tracker := StartLocalTracker(logger, NewConfig(logger), nil, nil, metrics, nil)
+	defer tracker.Stop()
+	router := &DummyMessageRouter{}
+
+	groupID, _ := createJoinRequestTestGroup(t, ctx, db, 3600)
+	expired, pending, resolved := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	for _, userID := range []uuid.UUID{expired, pending, resolved} {
+		InsertUser(t, db, userID)
+		require.NoError(t, JoinGroupWithAnswers(ctx, logger, db, tracker, router, groupID, userID, userID.String(), map[string]string{"reason": "To play"}))
+	}
+
+	// Expire one request, and resolve another outside the approve and reject operations.
+	_, err := db.Exec("UPDATE group_join_request SET expire_time = now() - INTERVAL '1 minute' WHERE group_id = $1 AND user_id = $2", groupID, expired)
+	require.NoError(t, err)
+	_, err = db.Exec("DELETE FROM group_edge WHERE (source_id = $1 AND destination_id = $2) OR (source_id = $2 AND destination_id = $1)", groupID, resolved)
+	require.NoError(t, err)
+
+	reaper := NewLocalGroupJoinRequestReaper(logger, db).(*LocalGroupJoinRequestReaper)
+	require.NoError(t, reaper.Reap(ctx))
+
+	assert.Equal(t, -1, groupJoinTestState(t, db, groupID, expired))
+	assert.Equal(t, int(api.GroupUserList_GroupUser_JOIN_REQUEST), groupJoinTestState(t, db, groupID, pending))
+	var requestUserIDs []string
+	rows, err := db.Query("SELECT user_id FROM group_join_request WHERE group_id = $1", groupID)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var userID string
+		require.NoError(t, rows.Scan(&userID))
+		requestUserIDs = append(requestUserIDs, userID)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{pending.String()}, requestUserIDs)
+}
@@ -40,6 +40,11 @@ var (
 	ErrLeaderboardAuthoritative = errors.New("leaderboard only allows authoritative submissions")
 	ErrLeaderboardInvalidCursor = errors.New("leaderboard cursor invalid")
 	ErrInvalidOperator          = errors.New("invalid operator")
+
+	ErrLeaderboardRecordRateLimited  = errors.New("leaderboard record submissions too frequent")
+	ErrLeaderboardRecordQuarantined  = errors.New("leaderboard record held for review")
+	ErrLeaderboardQuarantineNotFound = errors.New("quarantined leaderboard record not found")
+	ErrLeaderboardQuarantineExpired  = errors.New("quarantined leaderboard record period has ended")
 )
 
 type leaderboardRecordListCursor struct {
@@ -411,6 +416,12 @@ func unmarshalLeaderboardRecordsListCursor(leaderboardId string, expiryTime int6
 }
 
 func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string, overrideOperator api.Operator) (*api.LeaderboardRecord, error) {
+	return leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, caller, leaderboardId, ownerID, username, score, subscore, metadata, overrideOperator, true)
+}
+
+// leaderboardRecordWrite writes a record, optionally skipping validation for writes the server itself computed or a
+// moderator approved.
+func leaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, caller uuid.UUID, leaderboardId, ownerID, username string, score, subscore int64, metadata string, overrideOperator api.Operator, validate bool) (*api.LeaderboardRecord, error) {
 	leaderboard := leaderboardCache.Get(leaderboardId)
 	if leaderboard == nil {
 		return nil, ErrLeaderboardNotFound
@@ -441,6 +452,12 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		}
 	}
 
+	if validate {
+		if err := leaderboardRecordValidate(ctx, logger, db, leaderboardCache, leaderboard, ownerID, username, score, subscore, metadata, operator, expiryTime); err != nil {
+			return nil, err
+		}
+	}
+
 	var opSQL string
 	var filterSQL string
 	var scoreDelta int64
@@ -573,6 +590,10 @@ func LeaderboardRecordWrite(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		record.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
 	}
 
+	if !unchanged {
+		leaderboardDerivedUpdate(ctx, logger, db, leaderboardCache, rankCache, leaderboardId, ownerID, username)
+	}
+
 	return record, nil
 }
 
@@ -601,6 +622,8 @@ func LeaderboardRecordDelete(ctx context.Context, logger *zap.Logger, db *sql.DB
 
 	rankCache.Delete(leaderboardId, expiryTime, uuid.Must(uuid.FromString(ownerID)))
 
+	leaderboardDerivedUpdate(ctx, logger, db, leaderboardCache, rankCache, leaderboardId, ownerID, "")
+
 	return nil
 }
 
@@ -0,0 +1,250 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+const leaderboardDerivedBackfillBatchSize = 1000
+
+// leaderboardDerivedUpdate recomputes an owner's records in every derived leaderboard sourced from the given
+// leaderboard, after that owner's record in it has changed. Failures are logged, and never fail the source write.
+func leaderboardDerivedUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, sourceId, ownerID, username string) {
+	for _, derived := range leaderboardCache.ListDerived(sourceId) {
+		score, found, err := leaderboardDerivedScore(ctx, db, leaderboardCache, derived, ownerID)
+		if err != nil {
+			logger.Error("Error computing derived leaderboard score", zap.String("leaderboard_id", derived.Id), zap.String("source_id", sourceId), zap.String("owner_id", ownerID), zap.Error(err))
+			continue
+		}
+
+		if !found {
+			// The owner no longer has a record in any source leaderboard.
+			if err := LeaderboardRecordDelete(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, derived.Id, ownerID); err != nil {
+				logger.Error("Error deleting derived leaderboard record", zap.String("leaderboard_id", derived.Id), zap.String("owner_id", ownerID), zap.Error(err))
+			}
+			continue
+		}
+
+		if _, err := leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, derived.Id, ownerID, username, score, 0, "", api.Operator_SET, false); err != nil {
+			logger.Error("Error writing derived leaderboard record", zap.String("leaderboard_id", derived.Id), zap.String("owner_id", ownerID), zap.Error(err))
+		}
+	}
+}
+
+// LeaderboardDerivedBackfill computes records in a newly created derived leaderboard for every owner that already
+// has a record in the current period of one of its sources. Returns the number of records written.
+func LeaderboardDerivedBackfill(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, derivedId string) (int, error) {
+	derived := leaderboardCache.Get(derivedId)
+	if derived == nil || !derived.IsDerived() {
+		return 0, ErrLeaderboardNotFound
+	}
+
+	sourceIds, weights, expiryTimes := leaderboardDerivedSourceParams(leaderboardCache, derived)
+	if len(sourceIds) == 0 {
+		return 0, nil
+	}
+
+	query := `
+SELECT owner_id, max(username), sum(weight * score::FLOAT8) FROM leaderboard_record NATURAL JOIN ROWS FROM (
+  unnest($1::TEXT[]),
+  unnest($2::FLOAT8[]),
+  unnest($3::TIMESTAMPTZ[])
+) t(leaderboard_id, weight, expiry_time)
+WHERE owner_id > $4
+GROUP BY owner_id
+ORDER BY owner_id
+LIMIT $5`
+
+	var count int
+	cursor := uuid.Nil
+	for {
+		type derivedRecord struct {
+			ownerID  uuid.UUID
+			username sql.NullString
+			total    float64
+		}
+		records := make([]*derivedRecord, 0, leaderboardDerivedBackfillBatchSize)
+
+		rows, err := db.QueryContext(ctx, query, sourceIds, weights, expiryTimes, cursor, leaderboardDerivedBackfillBatchSize)
+		if err != nil {
+			return count, err
+		}
+		for rows.Next() {
+			record := &derivedRecord{}
+			if err := rows.Scan(&record.ownerID, &record.username, &record.total); err != nil {
+				_ = rows.Close()
+				return count, err
+			}
+			records = append(records, record)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return count, err
+		}
+
+		// Records are written after the batch is read, so the query does not hold a connection during the writes.
+		for _, record := range records {
+			if _, err := leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, derived.Id, record.ownerID.String(), record.username.String, leaderboardDerivedClamp(record.total), 0, "", api.Operator_SET, false); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(records) < leaderboardDerivedBackfillBatchSize {
+			return count, nil
+		}
+		cursor = records[len(records)-1].ownerID
+	}
+}
+
+// LeaderboardDerivedRecompute recomputes the records in the current period of a derived leaderboard, after it or one
+// of its sources resets. Records of owners with no record left in the current period of any source are removed, and
+// owners with a source record are backfilled. Returns the number of records written or removed.
+func LeaderboardDerivedRecompute(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, derivedId string) (int, error) {
+	derived := leaderboardCache.Get(derivedId)
+	if derived == nil || !derived.IsDerived() {
+		return 0, ErrLeaderboardNotFound
+	}
+
+	expiryTime := int64(0)
+	if derived.ResetSchedule != nil {
+		expiryTime = derived.ResetSchedule.Next(time.Now().UTC()).UTC().Unix()
+	}
+
+	query := `
+SELECT owner_id FROM leaderboard_record
+WHERE leaderboard_id = $1 AND expiry_time = $2 AND owner_id > $3
+ORDER BY owner_id
+LIMIT $4`
+
+	var count int
+	cursor := uuid.Nil
+	for {
+		ownerIDs := make([]uuid.UUID, 0, leaderboardDerivedBackfillBatchSize)
+
+		rows, err := db.QueryContext(ctx, query, derived.Id, time.Unix(expiryTime, 0).UTC(), cursor, leaderboardDerivedBackfillBatchSize)
+		if err != nil {
+			return count, err
+		}
+		for rows.Next() {
+			var ownerID uuid.UUID
+			if err := rows.Scan(&ownerID); err != nil {
+				_ = rows.Close()
+				return count, err
+			}
+			ownerIDs = append(ownerIDs, ownerID)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return count, err
+		}
+
+		// Only stale records need to be removed here, the backfill below rewrites the rest.
+		for _, ownerID := range ownerIDs {
+			_, found, err := leaderboardDerivedScore(ctx, db, leaderboardCache, derived, ownerID.String())
+			if err != nil {
+				return count, err
+			}
+			if found {
+				continue
+			}
+			if err := LeaderboardRecordDelete(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, derived.Id, ownerID.String()); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(ownerIDs) < leaderboardDerivedBackfillBatchSize {
+			break
+		}
+		cursor = ownerIDs[len(ownerIDs)-1]
+	}
+
+	backfilled, err := LeaderboardDerivedBackfill(ctx, logger, db, leaderboardCache, rankCache, derived.Id)
+	return count + backfilled, err
+}
+
+// leaderboardDerivedScore computes the weighted sum of an owner's scores in the current period of each source
+// leaderboard, rounded and floored at 0 as scores cannot be negative. Sources deleted since the derived leaderboard
+// was created are skipped.
+func leaderboardDerivedScore(ctx context.Context, db *sql.DB, leaderboardCache LeaderboardCache, derived *Leaderboard, ownerID string) (int64, bool, error) {
+	sourceIds, weights, expiryTimes := leaderboardDerivedSourceParams(leaderboardCache, derived)
+	if len(sourceIds) == 0 {
+		return 0, false, nil
+	}
+
+	// One query for all sources, see StorageReadObjects for the ROWS FROM pattern.
+	query := `
+SELECT count(*), coalesce(sum(weight * score::FLOAT8), 0::FLOAT8) FROM leaderboard_record NATURAL JOIN ROWS FROM (
+  unnest($1::TEXT[]),
+  unnest($2::FLOAT8[]),
+  unnest($3::TIMESTAMPTZ[])
+) t(leaderboard_id, weight, expiry_time)
+WHERE owner_id = $4`
+
+	var count int
+	var total float64
+	if err := db.QueryRowContext(ctx, query, sourceIds, weights, expiryTimes, ownerID).Scan(&count, &total); err != nil {
+		return 0, false, err
+	}
+	if count == 0 {
+		return 0, false, nil
+	}
+	return leaderboardDerivedClamp(total), true, nil
+}
+
+// leaderboardDerivedSourceParams returns the ID, weight and current period expiry time of each source of a derived
+// leaderboard that still exists, as query parameters.
+func leaderboardDerivedSourceParams(leaderboardCache LeaderboardCache, derived *Leaderboard) ([]string, []float64, []time.Time) {
+	now := time.Now().UTC()
+
+	sourceIds := make([]string, 0, len(derived.DerivedSources))
+	weights := make([]float64, 0, len(derived.DerivedSources))
+	expiryTimes := make([]time.Time, 0, len(derived.DerivedSources))
+	for _, source := range derived.DerivedSources {
+		sourceLeaderboard := leaderboardCache.Get(source.LeaderboardId)
+		if sourceLeaderboard == nil {
+			continue
+		}
+
+		expiryTime := int64(0)
+		if sourceLeaderboard.ResetSchedule != nil {
+			expiryTime = sourceLeaderboard.ResetSchedule.Next(now).UTC().Unix()
+		}
+
+		sourceIds = append(sourceIds, source.LeaderboardId)
+		weights = append(weights, source.Weight)
+		expiryTimes = append(expiryTimes, time.Unix(expiryTime, 0).UTC())
+	}
+	return sourceIds, weights, expiryTimes
+}
+
+func leaderboardDerivedClamp(total float64) int64 {
+	if total < 0 {
+		return 0
+	}
+	if total > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(math.Round(total))
+}
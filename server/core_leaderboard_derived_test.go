@@ -0,0 +1,162 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardDerivedRecompute(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	goalsId, assistsId, derivedId := GenerateString(), GenerateString(), GenerateString()
+	_, _, err := lbCache.Create(ctx, goalsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+	_, _, err = lbCache.Create(ctx, assistsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+
+	_, _, err = lbCache.CreateDerived(ctx, derivedId, LeaderboardSortOrderDescending, "", "{}", []*LeaderboardDerivedSource{{LeaderboardId: derivedId, Weight: 1}})
+	assert.Error(t, err, "derived leaderboard must not be its own source")
+
+	_, created, err := lbCache.CreateDerived(ctx, derivedId, LeaderboardSortOrderDescending, "", "{}", []*LeaderboardDerivedSource{
+		{LeaderboardId: goalsId, Weight: 3},
+		{LeaderboardId: assistsId, Weight: 1.5},
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	ownerID := uuid.Must(uuid.NewV4())
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, goalsId, ownerID.String(), "", 2, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, assistsId, ownerID.String(), "", 3, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+
+	list, err := LeaderboardRecordsList(ctx, logger, db, lbCache, lbRankCache, derivedId, nil, "", []string{ownerID.String()}, 0)
+	require.NoError(t, err)
+	require.Len(t, list.OwnerRecords, 1)
+	assert.EqualValues(t, 11, list.OwnerRecords[0].Score, "score should be round(2*3 + 3*1.5)")
+	assert.EqualValues(t, 1, list.OwnerRecords[0].Rank)
+
+	// Client writes to derived leaderboards are rejected.
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, ownerID, derivedId, ownerID.String(), "", 100, 0, "", api.Operator_NO_OVERRIDE)
+	assert.ErrorIs(t, err, ErrLeaderboardAuthoritative)
+
+	require.NoError(t, LeaderboardRecordDelete(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, goalsId, ownerID.String()))
+	require.NoError(t, LeaderboardRecordDelete(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, assistsId, ownerID.String()))
+
+	list, err = LeaderboardRecordsList(ctx, logger, db, lbCache, lbRankCache, derivedId, nil, "", []string{ownerID.String()}, 0)
+	require.NoError(t, err)
+	assert.Len(t, list.OwnerRecords, 0, "derived record should be removed with the last source record")
+}
+
+func TestLeaderboardDerivedBackfill(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	goalsId, assistsId, derivedId := GenerateString(), GenerateString(), GenerateString()
+	_, _, err := lbCache.Create(ctx, goalsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+	_, _, err = lbCache.Create(ctx, assistsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+
+	// Source records written before the derived leaderboard exists.
+	u1, u2 := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, goalsId, u1.String(), "", 2, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, assistsId, u1.String(), "", 3, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, assistsId, u2.String(), "", 4, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+
+	_, created, err := lbCache.CreateDerived(ctx, derivedId, LeaderboardSortOrderDescending, "", "{}", []*LeaderboardDerivedSource{
+		{LeaderboardId: goalsId, Weight: 3},
+		{LeaderboardId: assistsId, Weight: 1.5},
+	})
+	require.NoError(t, err)
+	require.True(t, created)
+	assert.Len(t, lbCache.ListDerived(goalsId), 1)
+	assert.Len(t, lbCache.ListDerived(assistsId), 1)
+
+	count, err := LeaderboardDerivedBackfill(ctx, logger, db, lbCache, lbRankCache, derivedId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	list, err := LeaderboardRecordsList(ctx, logger, db, lbCache, lbRankCache, derivedId, nil, "", []string{u1.String(), u2.String()}, 0)
+	require.NoError(t, err)
+	require.Len(t, list.OwnerRecords, 2)
+	scores := map[string]int64{}
+	for _, record := range list.OwnerRecords {
+		scores[record.OwnerId] = record.Score
+	}
+	assert.EqualValues(t, 11, scores[u1.String()])
+	assert.EqualValues(t, 6, scores[u2.String()])
+
+	lbCache.Remove(derivedId)
+	assert.Len(t, lbCache.ListDerived(goalsId), 0, "removed derived leaderboards should no longer be indexed by source")
+}
+
+func TestLeaderboardDerivedRecomputeAfterReset(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	goalsId, assistsId, derivedId := GenerateString(), GenerateString(), GenerateString()
+	_, _, err := lbCache.Create(ctx, goalsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+	_, _, err = lbCache.Create(ctx, assistsId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}")
+	require.NoError(t, err)
+	_, _, err = lbCache.CreateDerived(ctx, derivedId, LeaderboardSortOrderDescending, "", "{}", []*LeaderboardDerivedSource{
+		{LeaderboardId: goalsId, Weight: 3},
+		{LeaderboardId: assistsId, Weight: 1.5},
+	})
+	require.NoError(t, err)
+
+	u1, u2 := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, goalsId, u1.String(), "", 2, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, assistsId, u1.String(), "", 3, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, goalsId, u2.String(), "", 1, 0, "", api.Operator_NO_OVERRIDE)
+	require.NoError(t, err)
+
+	// Drop the goals records without going through the derived update, as a reset of that source would.
+	_, err = db.ExecContext(ctx, "DELETE FROM leaderboard_record WHERE leaderboard_id = $1", goalsId)
+	require.NoError(t, err)
+
+	count, err := LeaderboardDerivedRecompute(ctx, logger, db, lbCache, lbRankCache, derivedId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "u2 should be removed and u1 rewritten")
+
+	list, err := LeaderboardRecordsList(ctx, logger, db, lbCache, lbRankCache, derivedId, nil, "", []string{u1.String(), u2.String()}, 0)
+	require.NoError(t, err)
+	require.Len(t, list.OwnerRecords, 1)
+	assert.Equal(t, u1.String(), list.OwnerRecords[0].OwnerId)
+	assert.EqualValues(t, 5, list.OwnerRecords[0].Score, "score should be round(3*1.5) from the assists source only")
+}
@@ -0,0 +1,204 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"go.uber.org/zap"
+)
+
+// LeaderboardRecordPercentile is an owner's standing in the current period of a leaderboard.
+type LeaderboardRecordPercentile struct {
+	LeaderboardId string  `json:"leaderboard_id"`
+	OwnerId       string  `json:"owner_id"`
+	Rank          int64   `json:"rank"`
+	Count         int64   `json:"count"`
+	Percentile    float64 `json:"percentile"` // Percentage of ranked records at or above this one, 5 means top 5%.
+	ExpiryTime    int64   `json:"expiry_time"`
+}
+
+// LeaderboardRankHistory is an owner's final rank in a past leaderboard period, snapshotted when the period expired.
+type LeaderboardRankHistory struct {
+	LeaderboardId string  `json:"leaderboard_id"`
+	OwnerId       string  `json:"owner_id"`
+	Username      string  `json:"username"`
+	Score         int64   `json:"score"`
+	Subscore      int64   `json:"subscore"`
+	NumScore      int32   `json:"num_score"`
+	Rank          int64   `json:"rank"`
+	Count         int64   `json:"count"`
+	Percentile    float64 `json:"percentile"`
+	ExpiryTime    int64   `json:"expiry_time"`
+}
+
+type leaderboardRankHistoryListCursor struct {
+	LeaderboardId string
+	OwnerId       string
+	ExpiryTime    int64
+}
+
+func LeaderboardRecordPercentileGet(leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardId string, ownerID uuid.UUID, overrideExpiry int64) (*LeaderboardRecordPercentile, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	percentile := &LeaderboardRecordPercentile{
+		LeaderboardId: leaderboardId,
+		OwnerId:       ownerID.String(),
+	}
+
+	expiryTime, recordsPossible := calculateExpiryOverride(overrideExpiry, leaderboard)
+	if !recordsPossible {
+		// If the expiry time is in the past there are no ranked records.
+		return percentile, nil
+	}
+	percentile.ExpiryTime = expiryTime
+
+	percentile.Rank, percentile.Percentile = rankCache.GetPercentile(leaderboardId, expiryTime, ownerID)
+	percentile.Count = rankCache.Count(leaderboardId, expiryTime)
+
+	return percentile, nil
+}
+
+// LeaderboardRankHistorySnapshot records the final rank of every record in a leaderboard period that has just expired.
+// Snapshots are idempotent, repeating one for the same period leaves the existing history untouched.
+func LeaderboardRankHistorySnapshot(ctx context.Context, logger *zap.Logger, db *sql.DB, config *LeaderboardConfig, leaderboard *Leaderboard, expiryUnix int64) (int64, error) {
+	if !leaderboardRankHistoryEnabled(config, leaderboard.Id) {
+		return 0, nil
+	}
+
+	// Order records as the rank cache does, with ties broken by owner ID.
+	orderBy := "score DESC, subscore DESC, owner_id DESC"
+	if leaderboard.SortOrder == LeaderboardSortOrderAscending {
+		orderBy = "score ASC, subscore ASC, owner_id ASC"
+	}
+
+	query := `
+INSERT INTO leaderboard_rank_history (leaderboard_id, owner_id, expiry_time, username, score, subscore, num_score, rank, total)
+SELECT leaderboard_id, owner_id, expiry_time, username, score, subscore, num_score, row_number() OVER (ORDER BY ` + orderBy + `), count(*) OVER ()
+FROM leaderboard_record
+WHERE leaderboard_id = $1 AND expiry_time = $2
+ON CONFLICT (owner_id, leaderboard_id, expiry_time) DO NOTHING`
+
+	res, err := db.ExecContext(ctx, query, leaderboard.Id, time.Unix(expiryUnix, 0).UTC())
+	if err != nil {
+		logger.Error("Could not snapshot leaderboard rank history", zap.String("leaderboard_id", leaderboard.Id), zap.Int64("expiry", expiryUnix), zap.Error(err))
+		return 0, err
+	}
+	count, _ := res.RowsAffected()
+
+	return count, nil
+}
+
+func LeaderboardRankHistoryList(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboardId string, ownerID uuid.UUID, limit int, cursor string) ([]*LeaderboardRankHistory, string, error) {
+	if leaderboardCache.Get(leaderboardId) == nil {
+		return nil, "", ErrLeaderboardNotFound
+	}
+
+	var incomingCursor *leaderboardRankHistoryListCursor
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+		incomingCursor = &leaderboardRankHistoryListCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+		if incomingCursor.LeaderboardId != leaderboardId || incomingCursor.OwnerId != ownerID.String() {
+			// Cursor is for a different leaderboard or owner.
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+	}
+
+	// Most recent periods first.
+	query := `
+SELECT leaderboard_id, owner_id, expiry_time, username, score, subscore, num_score, rank, total
+FROM leaderboard_rank_history
+WHERE owner_id = $1 AND leaderboard_id = $2`
+	params := []interface{}{ownerID, leaderboardId}
+	if incomingCursor != nil {
+		query += " AND expiry_time < $3"
+		params = append(params, time.Unix(incomingCursor.ExpiryTime, 0).UTC())
+	}
+	params = append(params, limit+1)
+	query += " ORDER BY expiry_time DESC LIMIT $" + strconv.Itoa(len(params))
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Could not list leaderboard rank history", zap.Error(err))
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	history := make([]*LeaderboardRankHistory, 0, limit)
+	var nextCursor string
+	for rows.Next() {
+		var dbOwnerID uuid.UUID
+		var dbExpiryTime time.Time
+		var dbUsername sql.NullString
+		h := &LeaderboardRankHistory{}
+		if err := rows.Scan(&h.LeaderboardId, &dbOwnerID, &dbExpiryTime, &dbUsername, &h.Score, &h.Subscore, &h.NumScore, &h.Rank, &h.Count); err != nil {
+			logger.Error("Could not scan leaderboard rank history", zap.Error(err))
+			return nil, "", err
+		}
+		h.OwnerId = dbOwnerID.String()
+		h.Username = dbUsername.String
+		h.ExpiryTime = dbExpiryTime.Unix()
+		if h.Count > 0 {
+			h.Percentile = float64(h.Rank) / float64(h.Count) * 100
+		}
+
+		if len(history) >= limit {
+			cursorBuf := new(bytes.Buffer)
+			if err := gob.NewEncoder(cursorBuf).Encode(&leaderboardRankHistoryListCursor{
+				LeaderboardId: leaderboardId,
+				OwnerId:       ownerID.String(),
+				ExpiryTime:    history[len(history)-1].ExpiryTime,
+			}); err != nil {
+				logger.Error("Could not create leaderboard rank history cursor", zap.Error(err))
+				return nil, "", err
+			}
+			nextCursor = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+			break
+		}
+
+		history = append(history, h)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Could not list leaderboard rank history", zap.Error(err))
+		return nil, "", err
+	}
+
+	return history, nextCursor, nil
+}
+
+func leaderboardRankHistoryEnabled(config *LeaderboardConfig, leaderboardId string) bool {
+	for _, id := range config.BlacklistRankHistory {
+		if id == "*" || id == leaderboardId {
+			return false
+		}
+	}
+	return true
+}
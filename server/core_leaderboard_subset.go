@@ -0,0 +1,183 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// The user and their mutual friends.
+	leaderboardSubsetFriendsSQL = "SELECT destination_id FROM user_edge WHERE source_id = $3 AND state = 0 UNION ALL SELECT $3::UUID"
+	// Group superadmins, admins and members, but not pending join requests.
+	leaderboardSubsetGroupSQL = "SELECT destination_id FROM group_edge WHERE source_id = $3 AND state >= 0 AND state <= 2"
+)
+
+type leaderboardSubsetListCursor struct {
+	LeaderboardId string
+	ExpiryTime    int64
+	SubjectId     string
+	Rank          int64
+}
+
+// LeaderboardRecordsListFriends lists records owned by the user and their friends, ranked relative to each other.
+func LeaderboardRecordsListFriends(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboardId string, userID uuid.UUID, limit int, cursor string, overrideExpiry int64) (*api.LeaderboardRecordList, error) {
+	return leaderboardRecordsListSubset(ctx, logger, db, leaderboardCache, leaderboardId, leaderboardSubsetFriendsSQL, userID, limit, cursor, overrideExpiry)
+}
+
+// LeaderboardRecordsListGroup lists records owned by members of the group, ranked relative to each other.
+func LeaderboardRecordsListGroup(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboardId string, groupID uuid.UUID, limit int, cursor string, overrideExpiry int64) (*api.LeaderboardRecordList, error) {
+	return leaderboardRecordsListSubset(ctx, logger, db, leaderboardCache, leaderboardId, leaderboardSubsetGroupSQL, groupID, limit, cursor, overrideExpiry)
+}
+
+// leaderboardRecordsListSubset lists the records of the owners selected by subsetSQL, with ranks counted only within
+// that subset. The rank count is the number of records in the subset.
+func leaderboardRecordsListSubset(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboardId, subsetSQL string, subjectID uuid.UUID, limit int, cursor string, overrideExpiry int64) (*api.LeaderboardRecordList, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	expiryTime, recordsPossible := calculateExpiryOverride(overrideExpiry, leaderboard)
+	if !recordsPossible {
+		// If the expiry time is in the past, we won't have any records to return.
+		return &api.LeaderboardRecordList{Records: []*api.LeaderboardRecord{}}, nil
+	}
+
+	var afterRank int64
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, ErrLeaderboardInvalidCursor
+		}
+		incomingCursor := &leaderboardSubsetListCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, ErrLeaderboardInvalidCursor
+		}
+		if incomingCursor.LeaderboardId != leaderboardId || incomingCursor.SubjectId != subjectID.String() {
+			// Cursor is for a different leaderboard, user or group.
+			return nil, ErrLeaderboardInvalidCursor
+		} else if incomingCursor.ExpiryTime != expiryTime {
+			// Leaderboard expiry has rolled over since this cursor was generated.
+			return nil, ErrLeaderboardInvalidCursor
+		}
+		afterRank = incomingCursor.Rank
+	}
+
+	// Same ordering as the rank cache, so ties are broken consistently with global ranks.
+	orderBy := "score DESC, subscore DESC, owner_id DESC"
+	if leaderboard.SortOrder == LeaderboardSortOrderAscending {
+		orderBy = "score ASC, subscore ASC, owner_id ASC"
+	}
+
+	query := `
+WITH ranked AS (
+	SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time,
+		row_number() OVER (ORDER BY ` + orderBy + `) AS rank, count(*) OVER () AS total
+	FROM leaderboard_record
+	WHERE leaderboard_id = $1 AND expiry_time = $2 AND owner_id IN (` + subsetSQL + `)
+)
+SELECT leaderboard_id, owner_id, username, score, subscore, num_score, max_num_score, metadata, create_time, update_time, expiry_time, rank, total
+FROM ranked
+WHERE rank > $4
+ORDER BY rank ASC
+LIMIT $5`
+
+	rows, err := db.QueryContext(ctx, query, leaderboardId, time.Unix(expiryTime, 0).UTC(), subjectID, afterRank, limit+1)
+	if err != nil {
+		logger.Error("Error listing leaderboard records subset", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := &api.LeaderboardRecordList{
+		Records:      make([]*api.LeaderboardRecord, 0, limit),
+		OwnerRecords: []*api.LeaderboardRecord{},
+	}
+
+	var dbLeaderboardID string
+	var dbOwnerID string
+	var dbUsername sql.NullString
+	var dbScore int64
+	var dbSubscore int64
+	var dbNumScore int32
+	var dbMaxNumScore int32
+	var dbMetadata string
+	var dbCreateTime pgtype.Timestamptz
+	var dbUpdateTime pgtype.Timestamptz
+	var dbExpiryTime pgtype.Timestamptz
+	var dbRank int64
+	var dbTotal int64
+	for rows.Next() {
+		if err := rows.Scan(&dbLeaderboardID, &dbOwnerID, &dbUsername, &dbScore, &dbSubscore, &dbNumScore, &dbMaxNumScore, &dbMetadata, &dbCreateTime, &dbUpdateTime, &dbExpiryTime, &dbRank, &dbTotal); err != nil {
+			logger.Error("Could not scan leaderboard records subset", zap.Error(err))
+			return nil, err
+		}
+		list.RankCount = dbTotal
+
+		if len(list.Records) >= limit {
+			cursorBuf := new(bytes.Buffer)
+			if err := gob.NewEncoder(cursorBuf).Encode(&leaderboardSubsetListCursor{
+				LeaderboardId: leaderboardId,
+				ExpiryTime:    expiryTime,
+				SubjectId:     subjectID.String(),
+				Rank:          list.Records[len(list.Records)-1].Rank,
+			}); err != nil {
+				logger.Error("Error creating leaderboard records subset cursor", zap.Error(err))
+				return nil, err
+			}
+			list.NextCursor = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+			break
+		}
+
+		record := &api.LeaderboardRecord{
+			LeaderboardId: dbLeaderboardID,
+			OwnerId:       dbOwnerID,
+			Score:         dbScore,
+			Subscore:      dbSubscore,
+			NumScore:      dbNumScore,
+			MaxNumScore:   uint32(dbMaxNumScore),
+			Metadata:      dbMetadata,
+			CreateTime:    &timestamppb.Timestamp{Seconds: dbCreateTime.Time.Unix()},
+			UpdateTime:    &timestamppb.Timestamp{Seconds: dbUpdateTime.Time.Unix()},
+			Rank:          dbRank,
+		}
+		if dbUsername.Valid {
+			record.Username = &wrapperspb.StringValue{Value: dbUsername.String}
+		}
+		if expiryTime := dbExpiryTime.Time.Unix(); expiryTime != 0 {
+			record.ExpiryTime = &timestamppb.Timestamp{Seconds: expiryTime}
+		}
+		list.Records = append(list.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error listing leaderboard records subset", zap.Error(err))
+		return nil, err
+	}
+
+	return list, nil
+}
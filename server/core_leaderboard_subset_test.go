@@ -0,0 +1,115 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderboardRecordsListFriends(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	leaderboardId := GenerateString()
+	_, _, err := lbCache.Create(ctx, leaderboardId, false, LeaderboardSortOrderDescending, LeaderboardOperatorBest, "", "{}")
+	require.NoError(t, err)
+
+	user, friend, invited, stranger := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	for i, id := range []uuid.UUID{user, friend, invited, stranger} {
+		InsertUser(t, db, id)
+		_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, leaderboardId, id.String(), "", int64(10*(i+1)), 0, "", api.Operator_NO_OVERRIDE)
+		require.NoError(t, err)
+	}
+	insertUserEdge(t, db, user, friend, 0)
+	insertUserEdge(t, db, user, invited, 1)
+
+	list, err := LeaderboardRecordsListFriends(ctx, logger, db, lbCache, leaderboardId, user, 1, "", 0)
+	require.NoError(t, err)
+	require.Len(t, list.Records, 1)
+	assert.Equal(t, friend.String(), list.Records[0].OwnerId)
+	assert.EqualValues(t, 1, list.Records[0].Rank, "ranks should only count the user and their friends")
+	assert.EqualValues(t, 2, list.RankCount)
+	require.NotEmpty(t, list.NextCursor)
+
+	list, err = LeaderboardRecordsListFriends(ctx, logger, db, lbCache, leaderboardId, user, 1, list.NextCursor, 0)
+	require.NoError(t, err)
+	require.Len(t, list.Records, 1)
+	assert.Equal(t, user.String(), list.Records[0].OwnerId)
+	assert.EqualValues(t, 2, list.Records[0].Rank)
+	assert.Empty(t, list.NextCursor)
+
+	// Cursors are bound to the user they were issued for.
+	first, err := LeaderboardRecordsListFriends(ctx, logger, db, lbCache, leaderboardId, user, 1, "", 0)
+	require.NoError(t, err)
+	_, err = LeaderboardRecordsListFriends(ctx, logger, db, lbCache, leaderboardId, friend, 1, first.NextCursor, 0)
+	assert.ErrorIs(t, err, ErrLeaderboardInvalidCursor)
+}
+
+func TestLeaderboardRecordsListGroup(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	leaderboardId := GenerateString()
+	_, _, err := lbCache.Create(ctx, leaderboardId, false, LeaderboardSortOrderAscending, LeaderboardOperatorBest, "", "{}")
+	require.NoError(t, err)
+
+	creator, member, pending, outsider := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	for i, id := range []uuid.UUID{creator, member, pending, outsider} {
+		InsertUser(t, db, id)
+		_, err = LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, uuid.Nil, leaderboardId, id.String(), "", int64(10*(i+1)), 0, "", api.Operator_NO_OVERRIDE)
+		require.NoError(t, err)
+	}
+
+	group, err := CreateGroup(ctx, logger, db, creator, creator, GenerateString(), "en", "", "", "{}", false, 10)
+	require.NoError(t, err)
+	groupID := uuid.Must(uuid.FromString(group.Id))
+	_, err = groupAddUser(ctx, db, nil, groupID, member, 2)
+	require.NoError(t, err)
+	_, err = groupAddUser(ctx, db, nil, groupID, pending, 3)
+	require.NoError(t, err)
+
+	list, err := LeaderboardRecordsListGroup(ctx, logger, db, lbCache, leaderboardId, groupID, 10, "", 0)
+	require.NoError(t, err)
+	require.Len(t, list.Records, 2, "join requests and non-members should be excluded")
+	assert.Equal(t, creator.String(), list.Records[0].OwnerId)
+	assert.EqualValues(t, 1, list.Records[0].Rank)
+	assert.Equal(t, member.String(), list.Records[1].OwnerId)
+	assert.EqualValues(t, 2, list.Records[1].Rank)
+	assert.EqualValues(t, 2, list.RankCount)
+	assert.Empty(t, list.NextCursor)
+}
+
+func insertUserEdge(t *testing.T, db *sql.DB, source, destination uuid.UUID, state int) {
+	position := time.Now().UTC().UnixNano()
+	if _, err := db.Exec(`
+INSERT INTO user_edge (source_id, destination_id, state, position)
+VALUES ($1, $2, $3, $4)`, source, destination, state, position); err != nil {
+		t.Fatal("Could not insert user edge.", err)
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+const leaderboardQuarantineReasonMaxLength = 512
+
+// LeaderboardValidation holds the anti-cheat rules of a leaderboard, and the rate limit state of its owners. Rate
+// limit state is kept in memory, so each node enforces the limit on the submissions it receives independently.
+type LeaderboardValidation struct {
+	MaxScoreDelta        int64 // Largest score change a single submission may make before it's quarantined, 0 for no limit.
+	RateLimitCount       int   // Submissions allowed per owner in each interval, 0 for no limit.
+	RateLimitIntervalSec int
+
+	mu          sync.Mutex
+	submissions map[string][]time.Time
+	lastSweep   time.Time
+}
+
+func NewLeaderboardValidation(maxScoreDelta int64, rateLimitCount, rateLimitIntervalSec int) *LeaderboardValidation {
+	return &LeaderboardValidation{
+		MaxScoreDelta:        maxScoreDelta,
+		RateLimitCount:       rateLimitCount,
+		RateLimitIntervalSec: rateLimitIntervalSec,
+
+		submissions: make(map[string][]time.Time),
+	}
+}
+
+// allow records a submission by the owner, unless they have used up their submissions in the current interval.
+func (v *LeaderboardValidation) allow(ownerID string, now time.Time) bool {
+	if v.RateLimitCount == 0 {
+		return true
+	}
+
+	interval := time.Duration(v.RateLimitIntervalSec) * time.Second
+	windowStart := now.Add(-interval)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.lastSweep) >= interval {
+		// Drop owners with no submissions left in the window, so idle owners don't accumulate.
+		for id, times := range v.submissions {
+			if !times[len(times)-1].After(windowStart) {
+				delete(v.submissions, id)
+			}
+		}
+		v.lastSweep = now
+	}
+
+	times := v.submissions[ownerID]
+	i := 0
+	for i < len(times) && !times[i].After(windowStart) {
+		i++
+	}
+	times = times[i:]
+	if len(times) >= v.RateLimitCount {
+		v.submissions[ownerID] = times
+		return false
+	}
+	v.submissions[ownerID] = append(times, now)
+	return true
+}
+
+// LeaderboardRecordSubmission is a leaderboard record write awaiting validation.
+type LeaderboardRecordSubmission struct {
+	LeaderboardId    string
+	OwnerId          string
+	Username         string
+	Score            int64
+	Subscore         int64
+	Metadata         string
+	Operator         int
+	HasPrevious      bool // Whether the owner already has a record in the current period.
+	PreviousScore    int64
+	PreviousSubscore int64
+}
+
+// LeaderboardValidationFunction inspects a leaderboard record submission before it's written. An error rejects the
+// write, and a non-empty reason quarantines the submission for moderator review.
+type LeaderboardValidationFunction func(ctx context.Context, submission *LeaderboardRecordSubmission) (reason string, err error)
+
+// LeaderboardQuarantinedRecord is a suspicious submission held back from its leaderboard until a moderator approves or
+// deletes it. Each owner has at most one, the latest suspicious submission.
+type LeaderboardQuarantinedRecord struct {
+	LeaderboardId string `json:"leaderboard_id"`
+	OwnerId       string `json:"owner_id"`
+	Username      string `json:"username"`
+	Score         int64  `json:"score"`
+	Subscore      int64  `json:"subscore"`
+	Metadata      string `json:"metadata"`
+	Operator      int    `json:"operator"`
+	Reason        string `json:"reason"`
+	ExpiryTime    int64  `json:"expiry_time"`
+	CreateTime    int64  `json:"create_time"`
+}
+
+type leaderboardQuarantineListCursor struct {
+	LeaderboardId string
+	CreateTime    time.Time
+	OwnerId       string
+}
+
+// leaderboardRecordValidate applies the leaderboard's validation rules and the runtime validation function, if any, to
+// a submission. Submissions over the rate limit are rejected, and suspicious ones are quarantined instead of written.
+func leaderboardRecordValidate(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboard *Leaderboard, ownerID, username string, score, subscore int64, metadata string, operator int, expiryTime int64) error {
+	validation := leaderboardCache.GetValidation(leaderboard.Id)
+	fn := leaderboardCache.GetValidationFunction()
+	if validation == nil && fn == nil {
+		return nil
+	}
+
+	if validation != nil && !validation.allow(ownerID, time.Now()) {
+		return ErrLeaderboardRecordRateLimited
+	}
+
+	submission := &LeaderboardRecordSubmission{
+		LeaderboardId: leaderboard.Id,
+		OwnerId:       ownerID,
+		Username:      username,
+		Score:         score,
+		Subscore:      subscore,
+		Metadata:      metadata,
+		Operator:      operator,
+	}
+	query := "SELECT score, subscore FROM leaderboard_record WHERE leaderboard_id = $1 AND owner_id = $2 AND expiry_time = $3"
+	err := db.QueryRowContext(ctx, query, leaderboard.Id, ownerID, time.Unix(expiryTime, 0).UTC()).Scan(&submission.PreviousScore, &submission.PreviousSubscore)
+	switch err {
+	case nil:
+		submission.HasPrevious = true
+	case sql.ErrNoRows:
+	default:
+		logger.Error("Error reading leaderboard record for validation", zap.Error(err))
+		return err
+	}
+
+	var reason string
+	if validation != nil && validation.MaxScoreDelta > 0 {
+		if delta := leaderboardScoreDelta(leaderboard.SortOrder, submission); delta > validation.MaxScoreDelta {
+			reason = fmt.Sprintf("score change %d exceeds maximum %d", delta, validation.MaxScoreDelta)
+		}
+	}
+	if reason == "" && fn != nil {
+		if reason, err = fn(ctx, submission); err != nil {
+			return err
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	if len(reason) > leaderboardQuarantineReasonMaxLength {
+		reason = reason[:leaderboardQuarantineReasonMaxLength]
+	}
+
+	query = `INSERT INTO leaderboard_record_quarantine (leaderboard_id, owner_id, username, score, subscore, metadata, operator, reason, expiry_time)
+VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::JSONB), $7, $8, $9)
+ON CONFLICT (leaderboard_id, owner_id)
+DO UPDATE SET username = $3, score = $4, subscore = $5, metadata = COALESCE($6, '{}'::JSONB), operator = $7, reason = $8, expiry_time = $9, create_time = now()`
+	var usernameParam, metadataParam interface{}
+	if username != "" {
+		usernameParam = username
+	}
+	if metadata != "" {
+		metadataParam = metadata
+	}
+	if _, err := db.ExecContext(ctx, query, leaderboard.Id, ownerID, usernameParam, score, subscore, metadataParam, operator, reason, time.Unix(expiryTime, 0).UTC()); err != nil {
+		logger.Error("Error quarantining leaderboard record", zap.Error(err))
+		return err
+	}
+	logger.Info("Quarantined leaderboard record.", zap.String("leaderboard_id", leaderboard.Id), zap.String("owner_id", ownerID), zap.String("reason", reason))
+
+	return ErrLeaderboardRecordQuarantined
+}
+
+// leaderboardScoreDelta is how far a submission would move the owner's score. A first submission to an ascending
+// leaderboard has no meaningful baseline, so it never counts as a change.
+func leaderboardScoreDelta(sortOrder int, submission *LeaderboardRecordSubmission) int64 {
+	if !submission.HasPrevious && sortOrder == LeaderboardSortOrderAscending {
+		return 0
+	}
+
+	switch submission.Operator {
+	case LeaderboardOperatorIncrement, LeaderboardOperatorDecrement:
+		return leaderboardAbs(submission.Score)
+	case LeaderboardOperatorSet:
+		return leaderboardAbs(submission.Score - submission.PreviousScore)
+	default:
+		// Best, only improvements change the score.
+		if !submission.HasPrevious {
+			return leaderboardAbs(submission.Score)
+		}
+		if sortOrder == LeaderboardSortOrderAscending {
+			if submission.Score < submission.PreviousScore {
+				return submission.PreviousScore - submission.Score
+			}
+		} else if submission.Score > submission.PreviousScore {
+			return submission.Score - submission.PreviousScore
+		}
+		return 0
+	}
+}
+
+func leaderboardAbs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func LeaderboardQuarantineList(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, leaderboardId string, limit int, cursor string) ([]*LeaderboardQuarantinedRecord, string, error) {
+	if leaderboardCache.Get(leaderboardId) == nil {
+		return nil, "", ErrLeaderboardNotFound
+	}
+
+	query := `SELECT leaderboard_id, owner_id, username, score, subscore, metadata, operator, reason, expiry_time, create_time
+FROM leaderboard_record_quarantine
+WHERE leaderboard_id = $1`
+	params := []interface{}{leaderboardId, limit + 1}
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+		incomingCursor := &leaderboardQuarantineListCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil {
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+		if incomingCursor.LeaderboardId != leaderboardId {
+			return nil, "", ErrLeaderboardInvalidCursor
+		}
+		query += " AND (create_time, owner_id) > ($3, $4)"
+		params = append(params, incomingCursor.CreateTime, incomingCursor.OwnerId)
+	}
+	query += " ORDER BY create_time ASC, owner_id ASC LIMIT $2"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Could not list quarantined leaderboard records", zap.Error(err))
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	records := make([]*LeaderboardQuarantinedRecord, 0, limit)
+	var lastCreateTime time.Time
+	var nextCursor string
+	for rows.Next() {
+		if len(records) >= limit {
+			cursorBuf := new(bytes.Buffer)
+			if err := gob.NewEncoder(cursorBuf).Encode(&leaderboardQuarantineListCursor{
+				LeaderboardId: leaderboardId,
+				CreateTime:    lastCreateTime,
+				OwnerId:       records[len(records)-1].OwnerId,
+			}); err != nil {
+				logger.Error("Could not create quarantined leaderboard records cursor", zap.Error(err))
+				return nil, "", err
+			}
+			nextCursor = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+			break
+		}
+
+		var dbOwnerID uuid.UUID
+		var dbUsername sql.NullString
+		var dbExpiryTime time.Time
+		r := &LeaderboardQuarantinedRecord{}
+		if err := rows.Scan(&r.LeaderboardId, &dbOwnerID, &dbUsername, &r.Score, &r.Subscore, &r.Metadata, &r.Operator, &r.Reason, &dbExpiryTime, &lastCreateTime); err != nil {
+			logger.Error("Could not scan quarantined leaderboard records", zap.Error(err))
+			return nil, "", err
+		}
+		r.OwnerId = dbOwnerID.String()
+		r.Username = dbUsername.String
+		r.ExpiryTime = dbExpiryTime.Unix()
+		r.CreateTime = lastCreateTime.Unix()
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Could not list quarantined leaderboard records", zap.Error(err))
+		return nil, "", err
+	}
+
+	return records, nextCursor, nil
+}
+
+// LeaderboardQuarantineApprove writes an owner's quarantined submission to its leaderboard, bypassing validation. A
+// submission for a period that has since ended can't be written, it's discarded and ErrLeaderboardQuarantineExpired
+// returned.
+func LeaderboardQuarantineApprove(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardCache LeaderboardCache, rankCache LeaderboardRankCache, leaderboardId, ownerID string) (*api.LeaderboardRecord, error) {
+	leaderboard := leaderboardCache.Get(leaderboardId)
+	if leaderboard == nil {
+		return nil, ErrLeaderboardNotFound
+	}
+
+	// Lock the submission until it's written, so concurrent approvals write it at most once, and a failed write leaves
+	// it quarantined. The write itself uses its own connection, it only touches leaderboard records.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("Could not begin database transaction.", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := "SELECT username, score, subscore, metadata, operator, expiry_time FROM leaderboard_record_quarantine WHERE leaderboard_id = $1 AND owner_id = $2 FOR UPDATE"
+	var dbUsername sql.NullString
+	var score, subscore int64
+	var metadata string
+	var operator int
+	var expiryTime time.Time
+	if err := tx.QueryRowContext(ctx, query, leaderboardId, ownerID).Scan(&dbUsername, &score, &subscore, &metadata, &operator, &expiryTime); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrLeaderboardQuarantineNotFound
+		}
+		logger.Error("Error claiming quarantined leaderboard record", zap.Error(err))
+		return nil, err
+	}
+
+	currentExpiry := int64(0)
+	if leaderboard.ResetSchedule != nil {
+		currentExpiry = leaderboard.ResetSchedule.Next(time.Now().UTC()).UTC().Unix()
+	}
+
+	var record *api.LeaderboardRecord
+	if expiryTime.Unix() == currentExpiry {
+		overrideOperator, ok := OperatorIntToEnum[operator]
+		if !ok {
+			overrideOperator = api.Operator_NO_OVERRIDE
+		}
+		record, err = leaderboardRecordWrite(ctx, logger, db, leaderboardCache, rankCache, uuid.Nil, leaderboardId, ownerID, dbUsername.String, score, subscore, metadata, overrideOperator, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM leaderboard_record_quarantine WHERE leaderboard_id = $1 AND owner_id = $2", leaderboardId, ownerID); err != nil {
+		logger.Error("Error deleting approved leaderboard record from quarantine", zap.Error(err))
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Error("Error deleting approved leaderboard record from quarantine", zap.Error(err))
+		return nil, err
+	}
+
+	if record == nil {
+		return nil, ErrLeaderboardQuarantineExpired
+	}
+	return record, nil
+}
+
+// LeaderboardQuarantineDelete discards an owner's quarantined submission, leaving their record untouched.
+func LeaderboardQuarantineDelete(ctx context.Context, logger *zap.Logger, db *sql.DB, leaderboardId, ownerID string) error {
+	res, err := db.ExecContext(ctx, "DELETE FROM leaderboard_record_quarantine WHERE leaderboard_id = $1 AND owner_id = $2", leaderboardId, ownerID)
+	if err != nil {
+		logger.Error("Error deleting quarantined leaderboard record", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		return ErrLeaderboardQuarantineNotFound
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+)
+
+func TestLeaderboardValidationRateLimit(t *testing.T) {
+	v := NewLeaderboardValidation(0, 2, 10)
+	now := time.Unix(1_000_000, 0)
+
+	if !v.allow("a", now) || !v.allow("a", now.Add(time.Second)) {
+		t.Fatal("expected first two submissions to be allowed")
+	}
+	if v.allow("a", now.Add(2*time.Second)) {
+		t.Fatal("expected third submission in the interval to be rejected")
+	}
+	if !v.allow("b", now.Add(2*time.Second)) {
+		t.Fatal("expected other owners to have their own limit")
+	}
+	if !v.allow("a", now.Add(10*time.Second+time.Millisecond)) {
+		t.Fatal("expected a submission once the first left the interval")
+	}
+	if v.allow("a", now.Add(10*time.Second+2*time.Millisecond)) {
+		t.Fatal("expected rejected submissions not to free up the limit")
+	}
+
+	// Idle owners are swept once an interval has passed.
+	v.allow("c", now.Add(time.Hour))
+	if _, ok := v.submissions["b"]; ok {
+		t.Fatal("expected idle owner to be swept")
+	}
+
+	unlimited := NewLeaderboardValidation(100, 0, 0)
+	for i := 0; i < 100; i++ {
+		if !unlimited.allow("a", now) {
+			t.Fatal("expected no rate limit")
+		}
+	}
+}
+
+func TestLeaderboardScoreDelta(t *testing.T) {
+	tests := []struct {
+		name       string
+		sortOrder  int
+		submission LeaderboardRecordSubmission
+		want       int64
+	}{
+		{"increment", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorIncrement, Score: 50, HasPrevious: true, PreviousScore: 1000}, 50},
+		{"decrement", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorDecrement, Score: 20, HasPrevious: true, PreviousScore: 1000}, 20},
+		{"set lower", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorSet, Score: 10, HasPrevious: true, PreviousScore: 100}, 90},
+		{"set first", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorSet, Score: 10}, 10},
+		{"best improves", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 150, HasPrevious: true, PreviousScore: 100}, 50},
+		{"best worse", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 50, HasPrevious: true, PreviousScore: 100}, 0},
+		{"best first", LeaderboardSortOrderDescending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 70}, 70},
+		{"ascending best improves", LeaderboardSortOrderAscending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 40, HasPrevious: true, PreviousScore: 100}, 60},
+		{"ascending best worse", LeaderboardSortOrderAscending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 140, HasPrevious: true, PreviousScore: 100}, 0},
+		{"ascending first", LeaderboardSortOrderAscending, LeaderboardRecordSubmission{Operator: LeaderboardOperatorBest, Score: 40}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leaderboardScoreDelta(tt.sortOrder, &tt.submission); got != tt.want {
+				t.Errorf("leaderboardScoreDelta() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardQuarantineApprove(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	cfg := NewConfig(logger)
+	lbCache := NewLocalLeaderboardCache(ctx, logger, logger, db)
+	lbRankCache := NewLocalLeaderboardRankCache(ctx, logger, db, cfg.Leaderboard, lbCache)
+
+	leaderboardId := GenerateString()
+	if _, _, err := lbCache.Create(ctx, leaderboardId, false, LeaderboardSortOrderDescending, LeaderboardOperatorIncrement, "", "{}"); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := lbCache.SetValidation(ctx, leaderboardId, 10, 0, 0); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ownerID := uuid.Must(uuid.NewV4())
+	if _, err := LeaderboardRecordWrite(ctx, logger, db, lbCache, lbRankCache, ownerID, leaderboardId, ownerID.String(), "", 100, 0, "", api.Operator_NO_OVERRIDE); !errors.Is(err, ErrLeaderboardRecordQuarantined) {
+		t.Fatalf("expected submission to be quarantined, got %v", err)
+	}
+
+	record, err := LeaderboardQuarantineApprove(ctx, logger, db, lbCache, lbRankCache, leaderboardId, ownerID.String())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if record.Score != 100 {
+		t.Fatalf("expected approved score 100, got %d", record.Score)
+	}
+
+	// The submission is removed once written, so it can't be applied twice.
+	if _, err := LeaderboardQuarantineApprove(ctx, logger, db, lbCache, lbRankCache, leaderboardId, ownerID.String()); !errors.Is(err, ErrLeaderboardQuarantineNotFound) {
+		t.Fatalf("expected quarantined record to be gone, got %v", err)
+	}
+}
@@ -31,6 +31,7 @@ func MultiUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, metrics Me
 
 	var storageWriteAcks []*api.StorageObjectAck
 	var storageWriteOps StorageOpWrites
+	var storageDeleted StorageOpDeletes
 	var walletUpdateResults []*runtime.WalletUpdateResult
 
 	if err := ExecuteInTxPgx(ctx, db, func(tx pgx.Tx) error {
@@ -50,7 +51,8 @@ func MultiUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, metrics Me
 		}
 
 		// Execute any storage deletes.
-		deleteErr := storageDeleteObjects(ctx, logger, tx, true, storageDeletes)
+		var deleteErr error
+		storageDeleted, deleteErr = storageDeleteObjects(ctx, logger, tx, true, storageDeletes)
 		if deleteErr != nil {
 			return deleteErr
 		}
@@ -72,7 +74,7 @@ func MultiUpdate(ctx context.Context, logger *zap.Logger, db *sql.DB, metrics Me
 
 	// Update storage index.
 	storageIndexWrite(ctx, storageIndex, storageWriteOps, storageWriteAcks)
-	storageIndex.Delete(ctx, storageDeletes)
+	storageIndex.Delete(ctx, storageDeleted)
 
 	return storageWriteAcks, walletUpdateResults, nil
 }
@@ -40,6 +40,7 @@ const (
 	NotificationCodeFriendJoinGame   int32 = -6
 	NotificationCodeSingleSocket     int32 = -7
 	NotificationCodeUserBanned       int32 = -8
+	NotificationCodeGroupJoinReject  int32 = -9
 )
 
 type notificationCacheableCursor struct {
@@ -48,6 +49,11 @@ type notificationCacheableCursor struct {
 }
 
 func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, notifications map[uuid.UUID][]*api.Notification) error {
+	return notificationSend(ctx, logger, db, tracker, messageRouter, notifications, true)
+}
+
+// notificationSend stores and delivers notifications, and pushes them to users who are not connected if push is set.
+func notificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, tracker Tracker, messageRouter MessageRouter, notifications map[uuid.UUID][]*api.Notification, push bool) error {
 	persistentNotifications := make(map[uuid.UUID][]*api.Notification, len(notifications))
 	for userID, ns := range notifications {
 		for _, userNotification := range ns {
@@ -75,9 +81,17 @@ func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, track
 	}
 	tracker.ListPresenceIDByStreams(recipients)
 
-	// Deliver live notifications to connected users.
+	// Deliver live notifications to connected users, and push them to the others.
+	var offlineNotifications map[uuid.UUID][]*api.Notification
 	for stream, presenceIDs := range recipients {
 		if len(presenceIDs) == 0 {
+			if !push {
+				continue
+			}
+			if offlineNotifications == nil {
+				offlineNotifications = make(map[uuid.UUID][]*api.Notification, len(recipients))
+			}
+			offlineNotifications[stream.Subject] = notifications[stream.Subject]
 			continue
 		}
 		ns, found := notifications[stream.Subject]
@@ -94,6 +108,10 @@ func NotificationSend(ctx context.Context, logger *zap.Logger, db *sql.DB, track
 		}, true)
 	}
 
+	if len(offlineNotifications) > 0 {
+		messageRouter.PushNotifications(logger, offlineNotifications)
+	}
+
 	return nil
 }
 
@@ -48,8 +48,17 @@ type storageCursor struct {
 type StorageOpWrites []*StorageOpWrite
 
 type StorageOpWrite struct {
-	OwnerID string
-	Object  *api.WriteStorageObject
+	OwnerID    string
+	Object     *api.WriteStorageObject
+	ExpireTime time.Time // When the object is deleted by the expiry reaper. Zero never expires.
+}
+
+// Desired `expire_time` after this Op completes, nil if the object does not expire.
+func (op *StorageOpWrite) expireTime() *time.Time {
+	if op.ExpireTime.IsZero() {
+		return nil
+	}
+	return &op.ExpireTime
 }
 
 // Desired `read` persmission after this Op completes
@@ -191,14 +200,14 @@ func StorageListObjectsAll(ctx context.Context, logger *zap.Logger, db *sql.DB,
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1` + cursorQuery + `
+WHERE collection = $1 AND ` + storageNotExpiredCondition + cursorQuery + `
 ORDER BY read ASC, key ASC, user_id ASC
 LIMIT $2`
 	} else {
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND read >= 2` + cursorQuery + `
+WHERE collection = $1 AND read >= 2 AND ` + storageNotExpiredCondition + cursorQuery + `
 ORDER BY read ASC, key ASC, user_id ASC
 LIMIT $2`
 	}
@@ -239,7 +248,7 @@ func StorageListObjectsPublicReadUser(ctx context.Context, logger *zap.Logger, d
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND read = 2 AND user_id = $2 ` + cursorQuery + `
+WHERE collection = $1 AND read = 2 AND user_id = $2 AND ` + storageNotExpiredCondition + cursorQuery + `
 ORDER BY key ASC
 LIMIT $3`
 
@@ -279,7 +288,7 @@ func StorageListObjectsUser(ctx context.Context, logger *zap.Logger, db *sql.DB,
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND user_id = $2 AND read >= 1 ` + cursorQuery + `
+WHERE collection = $1 AND user_id = $2 AND read >= 1 AND ` + storageNotExpiredCondition + cursorQuery + `
 ORDER BY read ASC, key ASC
 LIMIT $3`
 	if authoritative {
@@ -287,7 +296,7 @@ LIMIT $3`
 		query = `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE collection = $1 AND user_id = $2 AND read >= 0 ` + cursorQuery + `
+WHERE collection = $1 AND user_id = $2 AND read >= 0 AND ` + storageNotExpiredCondition + cursorQuery + `
 ORDER BY read ASC, key ASC
 LIMIT $3`
 	}
@@ -320,7 +329,7 @@ func StorageReadAllUserObjects(ctx context.Context, logger *zap.Logger, db *sql.
 	query := `
 SELECT collection, key, user_id, value, version, read, write, create_time, update_time
 FROM storage
-WHERE user_id = $1`
+WHERE user_id = $1 AND ` + storageNotExpiredCondition
 
 	var objects []*api.StorageObject
 	err := ExecuteRetryable(func() error {
@@ -533,12 +542,15 @@ SELECT collection, key, user_id, value, version, read, write, create_time, updat
 		return nil, errors.New("unexpected code path")
 	}
 
+	if len(distinctArgs) == 3 {
+		query += ` WHERE `
+	} else {
+		query += ` AND `
+	}
+	query += storageNotExpiredCondition
+
 	if caller != uuid.Nil {
-		if len(distinctArgs) == 3 {
-			query += ` WHERE `
-		} else {
-			query += ` AND `
-		}
+		query += ` AND `
 		// Caller is not nil: either read public (read=2) object from requested user
 		// or private (read=1) object owned by caller
 		query += `(read = 2 or (read = 1 and storage.user_id = $4))`
@@ -688,7 +700,7 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 	newPermissionRead := op.permissionRead()
 	newPermissionWrite := op.permissionWrite()
 
-	params := []interface{}{object.Collection, object.Key, ownerID, object.Value, newVersion, newPermissionRead, newPermissionWrite}
+	params := []interface{}{object.Collection, object.Key, ownerID, object.Value, newVersion, newPermissionRead, newPermissionWrite, op.expireTime()}
 	var query string
 
 	writeCheck := ""
@@ -708,8 +720,8 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 		// That is returned values are final state of the row regardless of UPDATE success
 		query = `
 		WITH upd AS (
-			UPDATE storage SET value = $4, version = $5, read = $6, write = $7, update_time = now()
-			WHERE collection = $1 AND key = $2 AND user_id = $3 AND version = $8
+			UPDATE storage SET value = $4, version = $5, read = $6, write = $7, expire_time = $8, update_time = now()
+			WHERE collection = $1 AND key = $2 AND user_id = $3 AND version = $9
 		` + writeCheck + `
 			RETURNING read, write, version, create_time, update_time
 		)
@@ -734,12 +746,12 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 		// check for existing row.
 		query = `
 		WITH upd AS (
-			INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time)
-				VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+			INSERT INTO storage (collection, key, user_id, value, version, read, write, expire_time, create_time, update_time)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now(), now())
 			ON CONFLICT (collection, key, user_id) DO
-				UPDATE SET value = $4, version = $5, read = $6, write = $7, update_time = now()
+				UPDATE SET value = $4, version = $5, read = $6, write = $7, expire_time = $8, update_time = now()
 				WHERE TRUE` + writeCheck + `
-				AND NOT (storage.version = $5 AND storage.read = $6 AND storage.write = $7) -- micro optimization: don't update row unnecessarily
+				AND NOT (storage.version = $5 AND storage.read = $6 AND storage.write = $7 AND storage.expire_time IS NOT DISTINCT FROM $8) -- micro optimization: don't update row unnecessarily
 			RETURNING read, write, version, create_time, update_time
 		)
 		(SELECT read, write, version, create_time, update_time, true AS upsert FROM upd)
@@ -755,8 +767,8 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 		// OCC if-not-exists, and all other non-OCC cases.
 		// Existing permission checks are not applicable for new storage objects.
 		query = `
-		INSERT INTO storage (collection, key, user_id, value, version, read, write, create_time, update_time)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, now(), now())
+		INSERT INTO storage (collection, key, user_id, value, version, read, write, expire_time, create_time, update_time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, now(), now())
 		RETURNING read, write, version, create_time, update_time, true AS upsert`
 
 		// Outcomes:
@@ -767,8 +779,10 @@ func storagePrepBatch(batch *pgx.Batch, authoritativeWrite bool, op *StorageOpWr
 }
 
 func StorageDeleteObjects(ctx context.Context, logger *zap.Logger, db *sql.DB, storageIndex StorageIndex, authoritativeDelete bool, ops StorageOpDeletes) (codes.Code, error) {
+	var deleted StorageOpDeletes
 	if err := ExecuteInTxPgx(ctx, db, func(tx pgx.Tx) error {
-		deleteErr := storageDeleteObjects(ctx, logger, tx, authoritativeDelete, ops)
+		var deleteErr error
+		deleted, deleteErr = storageDeleteObjects(ctx, logger, tx, authoritativeDelete, ops)
 		if deleteErr != nil {
 			return deleteErr
 		}
@@ -781,15 +795,17 @@ func StorageDeleteObjects(ctx context.Context, logger *zap.Logger, db *sql.DB, s
 		return codes.Internal, err
 	}
 
-	storageIndex.Delete(ctx, ops)
+	storageIndex.Delete(ctx, deleted)
 
 	return codes.OK, nil
 }
 
-func storageDeleteObjects(ctx context.Context, logger *zap.Logger, tx pgx.Tx, authoritativeDelete bool, ops StorageOpDeletes) error {
+// storageDeleteObjects deletes the storage objects, and returns the deletes that removed an object.
+func storageDeleteObjects(ctx context.Context, logger *zap.Logger, tx pgx.Tx, authoritativeDelete bool, ops StorageOpDeletes) (StorageOpDeletes, error) {
 	// Ensure deletes are processed in a consistent order.
 	sort.Sort(ops)
 
+	deleted := make(StorageOpDeletes, 0, len(ops))
 	for _, op := range ops {
 		params := []interface{}{op.ObjectID.Collection, op.ObjectID.Key, op.OwnerID}
 		var query string
@@ -809,26 +825,57 @@ func storageDeleteObjects(ctx context.Context, logger *zap.Logger, tx pgx.Tx, au
 		result, err := tx.Exec(ctx, query, params...)
 		if err != nil {
 			logger.Debug("Could not delete storage object.", zap.Error(err), zap.String("query", query), zap.Any("object_id", op.ObjectID))
-			return err
+			return nil, err
 		}
 
+		if rowsAffected := result.RowsAffected(); rowsAffected > 0 {
+			deleted = append(deleted, op)
+			continue
+		}
 		if authoritativeDelete && op.ObjectID.GetVersion() == "" {
 			// If it's an authoritative delete and there is no OCC, the only reason rows affected would be 0 is having
 			// nothing to delete. In that case it's safe to assume the deletion was just a no-op and there's no need
 			// to check anything further. Should apply something similar to non-authoritative deletes too.
 			continue
 		}
-		if rowsAffected := result.RowsAffected(); rowsAffected == 0 {
-			return StatusError(codes.InvalidArgument, "Storage delete rejected.", errors.New("Storage delete rejected - not found, version check failed, or permission denied."))
+		return nil, StatusError(codes.InvalidArgument, "Storage delete rejected.", errors.New("Storage delete rejected - not found, version check failed, or permission denied."))
+	}
+
+	return deleted, nil
+}
+
+// storageDeleteUserObjects deletes all storage objects owned by the user, and returns them as deletes. Used to
+// account for the objects removed along with the user, which would otherwise only be deleted by the foreign key cascade.
+func storageDeleteUserObjects(ctx context.Context, tx *sql.Tx, userID uuid.UUID) (StorageOpDeletes, error) {
+	rows, err := tx.QueryContext(ctx, "DELETE FROM storage WHERE user_id = $1 RETURNING collection, key", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deleted := make(StorageOpDeletes, 0)
+	for rows.Next() {
+		var collection, key string
+		if err := rows.Scan(&collection, &key); err != nil {
+			return nil, err
 		}
+		deleted = append(deleted, &StorageOpDelete{
+			OwnerID: userID.String(),
+			ObjectID: &api.DeleteStorageObjectId{
+				Collection: collection,
+				Key:        key,
+			},
+		})
 	}
 
-	return nil
+	return deleted, rows.Err()
 }
 
 func storageIndexWrite(ctx context.Context, storageIndex StorageIndex, ops StorageOpWrites, acks []*api.StorageObjectAck) {
 	sw := make([]*api.StorageObject, 0, len(ops))
+	expireTimes := make([]time.Time, 0, len(ops))
 	for i, o := range ops {
+		expireTimes = append(expireTimes, o.ExpireTime)
 		sw = append(sw, &api.StorageObject{
 			Collection:      o.Object.Collection,
 			Key:             o.Object.Key,
@@ -842,5 +889,5 @@ func storageIndexWrite(ctx context.Context, storageIndex StorageIndex, ops Stora
 		})
 	}
 
-	storageIndex.Write(ctx, sw)
+	storageIndex.WriteExpiring(ctx, sw, expireTimes)
 }
@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama-common/api"
@@ -2758,3 +2759,98 @@ func TestStorageReadObjectsAllDistinctArgs(t *testing.T) {
 	assert.ElementsMatch(t, []string{key1, key2}, []string{readData.Objects[0].Key, readData.Objects[1].Key}, "key did not match")
 	assert.ElementsMatch(t, []string{uid1.String(), uid2.String()}, []string{readData.Objects[0].UserId, readData.Objects[1].UserId}, "user id did not match")
 }
+
+func TestStorageExpiryReap(t *testing.T) {
+	db := NewDB(t)
+
+	collection := GenerateString()
+	ops := StorageOpWrites{
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection: collection,
+				Key:        "expired",
+				Value:      "{\"foo\":\"bar\"}",
+			},
+			ExpireTime: time.Now().Add(-time.Minute),
+		},
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection: collection,
+				Key:        "live",
+				Value:      "{\"foo\":\"bar\"}",
+			},
+			ExpireTime: time.Now().Add(time.Hour),
+		},
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection: collection,
+				Key:        "forever",
+				Value:      "{\"foo\":\"bar\"}",
+			},
+		},
+	}
+	_, _, err := StorageWriteObjects(context.Background(), logger, db, metrics, storageIdx, true, ops)
+	assert.Nil(t, err, "err was not nil")
+
+	reaper := NewLocalStorageExpiryReaper(logger, db, metrics, storageIdx, &StorageConfig{ExpiryReapBatchSize: 1}).(*LocalStorageExpiryReaper)
+	_, err = reaper.Reap(context.Background())
+	assert.Nil(t, err, "err was not nil")
+
+	readData, err := StorageReadObjects(context.Background(), logger, db, uuid.Nil, []*api.ReadStorageObjectId{
+		{Collection: collection, Key: "expired"},
+		{Collection: collection, Key: "live"},
+		{Collection: collection, Key: "forever"},
+	})
+	assert.Nil(t, err, "err was not nil")
+	keys := make([]string, 0, len(readData.Objects))
+	for _, o := range readData.Objects {
+		keys = append(keys, o.Key)
+	}
+	assert.ElementsMatch(t, []string{"live", "forever"}, keys, "only the expired object should be deleted")
+}
+
+func TestStorageReadExpiredBeforeReap(t *testing.T) {
+	db := NewDB(t)
+
+	collection := GenerateString()
+	ops := StorageOpWrites{
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection: collection,
+				Key:        "expired",
+				Value:      "{\"foo\":\"bar\"}",
+			},
+			ExpireTime: time.Now().Add(-time.Minute),
+		},
+		&StorageOpWrite{
+			OwnerID: uuid.Nil.String(),
+			Object: &api.WriteStorageObject{
+				Collection: collection,
+				Key:        "live",
+				Value:      "{\"foo\":\"bar\"}",
+			},
+			ExpireTime: time.Now().Add(time.Hour),
+		},
+	}
+	_, _, err := StorageWriteObjects(context.Background(), logger, db, metrics, storageIdx, true, ops)
+	assert.Nil(t, err, "err was not nil")
+
+	readData, err := StorageReadObjects(context.Background(), logger, db, uuid.Nil, []*api.ReadStorageObjectId{
+		{Collection: collection, Key: "expired"},
+		{Collection: collection, Key: "live"},
+	})
+	assert.Nil(t, err, "err was not nil")
+	if assert.Len(t, readData.Objects, 1, "expired objects should not be read") {
+		assert.Equal(t, "live", readData.Objects[0].Key)
+	}
+
+	listData, _, err := StorageListObjects(context.Background(), logger, db, uuid.Nil, nil, collection, 10, "")
+	assert.Nil(t, err, "err was not nil")
+	if assert.Len(t, listData.Objects, 1, "expired objects should not be listed") {
+		assert.Equal(t, "live", listData.Objects[0].Key)
+	}
+}
@@ -31,6 +31,7 @@ const (
 
 	LinkTicketCollection         = "LinkTickets"
 	LinkTicketIndex              = "Index_" + LinkTicketCollection
+	LinkTicketTTL                = 24 * time.Hour // Unused link tickets are deleted after this long.
 	DiscordAccessTokenCollection = "DiscordAccessTokens"
 	DiscordAccessTokenKey        = "accessToken"
 	SuspensionStatusCollection   = "SuspensionStatus"
@@ -126,7 +127,7 @@ func (p *EvrPipeline) linkTicket(session *sessionWS, deviceId *DeviceId, loginDa
 	ctx := session.Context()
 
 	// Check if a link ticket already exists for the provided xplatformId and hmdSerialNumber
-	objectIds, err := session.storageIndex.List(ctx, uuid.Nil, LinkTicketIndex, fmt.Sprintf("+value.evrid_token:%s", deviceId.EvrId.String()), 1)
+	objectIds, _, _, err := session.storageIndex.List(ctx, uuid.Nil, LinkTicketIndex, fmt.Sprintf("+value.evrid_token:%s", deviceId.EvrId.String()), 1, nil, "")
 	if err != nil {
 		return nil, fmt.Errorf(fmt.Sprintf("error listing link tickets: `%q`  %v", deviceId.EvrId.String(), err))
 	}
@@ -168,6 +169,7 @@ func (p *EvrPipeline) linkTicket(session *sessionWS, deviceId *DeviceId, loginDa
 				PermissionRead:  &wrapperspb.Int32Value{Value: int32(0)},
 				PermissionWrite: &wrapperspb.Int32Value{Value: int32(0)},
 			},
+			ExpireTime: time.Now().Add(LinkTicketTTL),
 		}}
 		_, _, err = StorageWriteObjects(ctx, session.logger, session.pipeline.db, session.metrics, session.storageIndex, false, ops)
 		if err != nil {
@@ -556,6 +558,8 @@ type GroupMetadata struct {
 	BroadcasterHostRole    string   `json:"broadcaster_group_role" validate:"required,numeric"` // The rules that have access to serverdb
 	ModeratorGroupId       string   `json:"moderator_group_id" validate:"required,uuid"`        // The group UUID that has access to moderation tools
 	BroadcasterHostGroupId string   `json:"broadcaster_group_id" validate:"required,uuid"`      // The group UUID that has access to serverdb
+
+	TeamBalance *TeamBalanceSettings `json:"team_balance,omitempty"` // The team balancing policy for matches on this channel
 }
 
 type AccountUserMetadata struct {
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/heroiclabs/nakama/v3/server/evr"
+	"go.uber.org/zap"
+)
+
+const (
+	DrainPollInterval = 5 * time.Second // How often the node drain checks for active matches.
+)
+
+var (
+	ErrNodeDraining = errors.New("node is draining")
+)
+
+// DrainedQuery returns the query clause that excludes draining broadcasters.
+func DrainedQuery() string {
+	return "-label.broadcaster.draining:T"
+}
+
+// drain marks the broadcaster as draining. It returns true if the match can be ended immediately.
+func (s *EvrMatchState) drain() bool {
+	s.Broadcaster.Draining = true
+	return s.LobbyType == UnassignedLobby
+}
+
+// DrainBroadcasters signals every match matching the query to drain.
+// Parking matches end immediately, and active matches end naturally. It returns the number of matches signaled.
+func DrainBroadcasters(ctx context.Context, nk runtime.NakamaModule, query string) (int, error) {
+	minSize, maxSize := 1, MatchMaxSize
+	matches, err := nk.MatchList(ctx, 1000, true, "", &minSize, &maxSize, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list matches: %w", err)
+	}
+
+	signal := EvrSignal{
+		UserId: SystemUserID,
+		Signal: SignalDrain,
+		Data:   []byte{},
+	}
+	count := 0
+	for _, match := range matches {
+		if _, err := nk.MatchSignal(ctx, match.GetMatchId(), signal.String()); err != nil {
+			// The match may have ended.
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DrainOperator drains all of the operator's broadcasters.
+func DrainOperator(ctx context.Context, nk runtime.NakamaModule, operatorID string) (int, error) {
+	return DrainBroadcasters(ctx, nk, fmt.Sprintf("+label.broadcaster.oper:%s", queryEscape(operatorID)))
+}
+
+// Drain stops the node from accepting broadcasters, and drains the broadcasters on it.
+// The returned channel is closed once all of the active matches on the node have ended.
+func (p *EvrPipeline) Drain() <-chan struct{} {
+	p.draining.Store(true)
+
+	ch := make(chan struct{})
+	query := fmt.Sprintf("+label.node:%s", queryEscape(p.node))
+	go func() {
+		defer close(ch)
+		for {
+			if _, err := DrainBroadcasters(p.ctx, p.runtimeModule, query); err != nil {
+				p.logger.Warn("Failed to drain broadcasters", zap.Error(err))
+			}
+
+			// Wait for the active matches to end.
+			minSize, maxSize := 1, MatchMaxSize
+			matches, err := p.runtimeModule.MatchList(p.ctx, 1, true, "", &minSize, &maxSize, query+" "+LobbyType(evr.UnassignedLobby).Query(MustNot, 0))
+			if err == nil && len(matches) == 0 {
+				return
+			}
+
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(DrainPollInterval):
+			}
+		}
+	}()
+	return ch
+}
+
+type broadcasterDrainRequest struct {
+	OperatorID string `json:"operator_id"`
+}
+
+type broadcasterDrainResponse struct {
+	OperatorID string `json:"operator_id"`
+	Count      int    `json:"count"`
+}
+
+// broadcasterDrainRpc drains the caller's broadcasters. Global developers may drain any operator's broadcasters.
+func broadcasterDrainRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+
+	request := &broadcasterDrainRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return "", err
+		}
+	}
+	if request.OperatorID == "" {
+		request.OperatorID = userID
+	}
+
+	if request.OperatorID != userID {
+		isDeveloper, err := checkIfGlobalDeveloper(ctx, nk, userID)
+		if err != nil {
+			return "", runtime.NewError(err.Error(), StatusInternalError)
+		}
+		if !isDeveloper {
+			return "", runtime.NewError("only global developers may drain other operators", StatusPermissionDenied)
+		}
+	}
+
+	count, err := DrainOperator(ctx, nk, request.OperatorID)
+	if err != nil {
+		return "", runtime.NewError(err.Error(), StatusInternalError)
+	}
+	logger.Info("Draining %d broadcasters for operator %s", count, request.OperatorID)
+
+	jsonData, err := json.Marshal(broadcasterDrainResponse{
+		OperatorID: request.OperatorID,
+		Count:      count,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+func checkIfGlobalDeveloper(ctx context.Context, nk runtime.NakamaModule, userID string) (bool, error) {
+	groups, _, err := nk.UserGroupsList(ctx, userID, 100, nil, "")
+	if err != nil {
+		return false, fmt.Errorf("failed to list user groups: %w", err)
+	}
+	for _, g := range groups {
+		if g.GetGroup().GetName() == GroupGlobalDevelopers && g.GetState().GetValue() <= int32(api.GroupUserList_GroupUser_MEMBER) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,346 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/samber/lo"
+)
+
+const (
+	BroadcasterUsageCollection  = "BroadcasterUsage"
+	BroadcasterUsageDateFormat  = "2006-01-02"
+	BroadcasterUsageDefaultDays = 7
+	BroadcasterUsageMaxDays     = 90
+)
+
+// BroadcasterRTTBuckets are the upper bounds of the player RTT histogram. The last bucket holds everything above.
+var BroadcasterRTTBuckets = []time.Duration{
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	75 * time.Millisecond,
+	100 * time.Millisecond,
+	150 * time.Millisecond,
+	200 * time.Millisecond,
+	300 * time.Millisecond,
+}
+
+// BroadcasterMatchUsage is a single match's contribution to the operator's usage.
+type BroadcasterMatchUsage struct {
+	OperatorID   string
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Hosted       bool                     // The broadcaster hosted a match (not just a parking match).
+	Disconnected bool                     // The broadcaster disconnected without ending the session.
+	Players      map[string]time.Duration // [userID]RTT
+}
+
+func newBroadcasterMatchUsage(operatorID string, now time.Time) *BroadcasterMatchUsage {
+	return &BroadcasterMatchUsage{
+		OperatorID: operatorID,
+		StartedAt:  now,
+		Hosted:     true,
+		Players:    make(map[string]time.Duration, MatchMaxSize),
+	}
+}
+
+// addPlayer records a player in the match. RTT is zero if the player has not pinged the broadcaster.
+func (u *BroadcasterMatchUsage) addPlayer(userID string, rtt time.Duration) {
+	if rtt == 0 {
+		if _, ok := u.Players[userID]; ok {
+			return
+		}
+	}
+	u.Players[userID] = rtt
+}
+
+// recordUsage stores the match's usage for the operator, once.
+func (m *EvrMatch) recordUsage(logger runtime.Logger, nk runtime.NakamaModule, state *EvrMatchState, disconnected bool) {
+	usage := state.usage
+	state.usage = nil
+	if usage == nil {
+		if !disconnected {
+			return
+		}
+		// A parking match only records the disconnect.
+		usage = &BroadcasterMatchUsage{OperatorID: state.Broadcaster.OperatorID}
+	}
+	usage.EndedAt = time.Now()
+	usage.Disconnected = disconnected
+
+	go func() {
+		if err := RecordBroadcasterUsage(context.Background(), nk, usage); err != nil {
+			logger.Warn("failed to record broadcaster usage: %v", err)
+		}
+	}()
+}
+
+// BroadcasterUsage is an operator's usage for a single day (UTC). It is stored by the operator's user ID and date.
+type BroadcasterUsage struct {
+	Date          string   `json:"date"`
+	HostedSeconds float64  `json:"hosted_seconds"`
+	Matches       int      `json:"matches"`
+	UniquePlayers int      `json:"unique_players"`
+	Crashes       int      `json:"crashes"`     // The broadcaster disconnected during a match.
+	Disconnects   int      `json:"disconnects"` // The broadcaster disconnected (including crashes).
+	RTTBuckets    []int    `json:"rtt_buckets"` // The player RTT histogram (see BroadcasterRTTBuckets).
+	PlayerIDs     []string `json:"player_ids,omitempty"`
+}
+
+func (u *BroadcasterUsage) add(m *BroadcasterMatchUsage) {
+	if len(u.RTTBuckets) != len(BroadcasterRTTBuckets)+1 {
+		buckets := make([]int, len(BroadcasterRTTBuckets)+1)
+		copy(buckets, u.RTTBuckets)
+		u.RTTBuckets = buckets
+	}
+
+	if m.Hosted {
+		u.Matches++
+		if !m.StartedAt.IsZero() && m.EndedAt.After(m.StartedAt) {
+			u.HostedSeconds += m.EndedAt.Sub(m.StartedAt).Seconds()
+		}
+	}
+	if m.Disconnected {
+		u.Disconnects++
+		if m.Hosted {
+			u.Crashes++
+		}
+	}
+
+	for userID, rtt := range m.Players {
+		if !lo.Contains(u.PlayerIDs, userID) {
+			u.PlayerIDs = append(u.PlayerIDs, userID)
+		}
+		if rtt > 0 {
+			u.RTTBuckets[rttBucket(rtt)]++
+		}
+	}
+	u.UniquePlayers = len(u.PlayerIDs)
+}
+
+func rttBucket(rtt time.Duration) int {
+	return sort.Search(len(BroadcasterRTTBuckets), func(i int) bool { return rtt <= BroadcasterRTTBuckets[i] })
+}
+
+// rttPercentile returns the upper bound (in milliseconds) of the bucket containing the percentile (0-1), or zero if there are no samples.
+// It returns -1 if the percentile is above the last bound.
+func rttPercentile(buckets []int, p float64) int64 {
+	total := lo.Sum(buckets)
+	if total == 0 {
+		return 0
+	}
+	target := int(float64(total)*p + 0.5)
+	if target < 1 {
+		target = 1
+	}
+	n := 0
+	for i, c := range buckets {
+		n += c
+		if n >= target {
+			if i < len(BroadcasterRTTBuckets) {
+				return BroadcasterRTTBuckets[i].Milliseconds()
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+// RecordBroadcasterUsage adds the match's usage to the operator's daily record.
+func RecordBroadcasterUsage(ctx context.Context, nk runtime.NakamaModule, m *BroadcasterMatchUsage) error {
+	if m.OperatorID == "" {
+		return nil
+	}
+	key := m.EndedAt.UTC().Format(BroadcasterUsageDateFormat)
+
+	var err error
+	// Retry on a concurrent update by another match.
+	for i := 0; i < 5; i++ {
+		var objs []*api.StorageObject
+		if objs, err = nk.StorageRead(ctx, []*runtime.StorageRead{
+			{
+				Collection: BroadcasterUsageCollection,
+				Key:        key,
+				UserID:     m.OperatorID,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to read broadcaster usage: %w", err)
+		}
+
+		usage := &BroadcasterUsage{Date: key}
+		version := "*"
+		if len(objs) > 0 {
+			if err := json.Unmarshal([]byte(objs[0].GetValue()), usage); err != nil {
+				return fmt.Errorf("failed to unmarshal broadcaster usage: %w", err)
+			}
+			version = objs[0].GetVersion()
+		}
+		usage.add(m)
+
+		var data []byte
+		if data, err = json.Marshal(usage); err != nil {
+			return fmt.Errorf("failed to marshal broadcaster usage: %w", err)
+		}
+		if _, err = nk.StorageWrite(ctx, []*runtime.StorageWrite{
+			{
+				Collection:      BroadcasterUsageCollection,
+				Key:             key,
+				UserID:          m.OperatorID,
+				Value:           string(data),
+				Version:         version,
+				PermissionRead:  1,
+				PermissionWrite: 0,
+			},
+		}); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to write broadcaster usage: %w", err)
+}
+
+// BroadcasterUsageSummary is the operator's usage over a range of days.
+type BroadcasterUsageSummary struct {
+	OperatorID    string              `json:"operator_id"`
+	HostedHours   float64             `json:"hosted_hours"`
+	Matches       int                 `json:"matches"`
+	UniquePlayers int                 `json:"unique_players"`
+	Crashes       int                 `json:"crashes"`
+	Disconnects   int                 `json:"disconnects"`
+	RTTBuckets    []int               `json:"rtt_buckets"`
+	RTTP50        int64               `json:"rtt_p50_ms"` // -1 if above the last bucket
+	RTTP95        int64               `json:"rtt_p95_ms"` // -1 if above the last bucket
+	Days          []*BroadcasterUsage `json:"days"`
+}
+
+// LoadBroadcasterUsage returns the operator's usage for the last number of days (including today).
+func LoadBroadcasterUsage(ctx context.Context, nk runtime.NakamaModule, operatorID string, days int, now time.Time) (*BroadcasterUsageSummary, error) {
+	reads := make([]*runtime.StorageRead, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		reads = append(reads, &runtime.StorageRead{
+			Collection: BroadcasterUsageCollection,
+			Key:        now.UTC().AddDate(0, 0, -i).Format(BroadcasterUsageDateFormat),
+			UserID:     operatorID,
+		})
+	}
+	objs, err := nk.StorageRead(ctx, reads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read broadcaster usage: %w", err)
+	}
+
+	usages := make([]*BroadcasterUsage, 0, len(objs))
+	for _, obj := range objs {
+		usage := &BroadcasterUsage{}
+		if err := json.Unmarshal([]byte(obj.GetValue()), usage); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal broadcaster usage: %w", err)
+		}
+		usages = append(usages, usage)
+	}
+	return summarizeBroadcasterUsage(operatorID, usages), nil
+}
+
+func summarizeBroadcasterUsage(operatorID string, usages []*BroadcasterUsage) *BroadcasterUsageSummary {
+	summary := &BroadcasterUsageSummary{
+		OperatorID: operatorID,
+		RTTBuckets: make([]int, len(BroadcasterRTTBuckets)+1),
+		Days:       usages,
+	}
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Date < usages[j].Date })
+
+	players := make(map[string]struct{})
+	for _, u := range usages {
+		summary.HostedHours += u.HostedSeconds / 3600
+		summary.Matches += u.Matches
+		summary.Crashes += u.Crashes
+		summary.Disconnects += u.Disconnects
+		for i, c := range u.RTTBuckets {
+			if i < len(summary.RTTBuckets) {
+				summary.RTTBuckets[i] += c
+			}
+		}
+		for _, id := range u.PlayerIDs {
+			players[id] = struct{}{}
+		}
+		// The player IDs are only needed to count unique players.
+		u.PlayerIDs = nil
+	}
+	summary.UniquePlayers = len(players)
+	summary.RTTP50 = rttPercentile(summary.RTTBuckets, 0.50)
+	summary.RTTP95 = rttPercentile(summary.RTTBuckets, 0.95)
+	return summary
+}
+
+// String returns a short, human readable report.
+func (s *BroadcasterUsageSummary) String() string {
+	rtt := func(ms int64) string {
+		switch {
+		case ms < 0:
+			return fmt.Sprintf(">%dms", BroadcasterRTTBuckets[len(BroadcasterRTTBuckets)-1].Milliseconds())
+		case ms == 0:
+			return "n/a"
+		default:
+			return fmt.Sprintf("<=%dms", ms)
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hosted: %.1f hours, %d matches, %d unique players\n", s.HostedHours, s.Matches, s.UniquePlayers)
+	fmt.Fprintf(&b, "Crashes: %d, Disconnects: %d\n", s.Crashes, s.Disconnects)
+	fmt.Fprintf(&b, "Player RTT: p50 %s, p95 %s", rtt(s.RTTP50), rtt(s.RTTP95))
+	return b.String()
+}
+
+type broadcasterUsageRequest struct {
+	OperatorID string `json:"operator_id"`
+	Days       int    `json:"days"`
+}
+
+// broadcasterUsageRpc returns the caller's broadcaster usage. Global developers may view any operator's usage.
+func broadcasterUsageRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+
+	request := &broadcasterUsageRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return "", err
+		}
+	}
+	if request.OperatorID == "" {
+		request.OperatorID = userID
+	}
+	if request.Days <= 0 {
+		request.Days = BroadcasterUsageDefaultDays
+	}
+	if request.Days > BroadcasterUsageMaxDays {
+		return "", runtime.NewError(fmt.Sprintf("days must be at most %d", BroadcasterUsageMaxDays), StatusInvalidArgument)
+	}
+
+	if request.OperatorID != userID {
+		isDeveloper, err := checkIfGlobalDeveloper(ctx, nk, userID)
+		if err != nil {
+			return "", runtime.NewError(err.Error(), StatusInternalError)
+		}
+		if !isDeveloper {
+			return "", runtime.NewError("only global developers may view other operators", StatusPermissionDenied)
+		}
+	}
+
+	summary, err := LoadBroadcasterUsage(ctx, nk, request.OperatorID, request.Days, time.Now())
+	if err != nil {
+		return "", runtime.NewError(err.Error(), StatusInternalError)
+	}
+
+	jsonData, err := json.Marshal(summary)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
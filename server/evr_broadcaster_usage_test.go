@@ -0,0 +1,74 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBroadcasterUsage_Add(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	match := newBroadcasterMatchUsage("operator", start)
+	match.addPlayer("a", 20*time.Millisecond)
+	match.addPlayer("b", 0)
+	match.addPlayer("c", 500*time.Millisecond)
+	match.addPlayer("a", 0) // Does not replace a known RTT
+	match.EndedAt = start.Add(30 * time.Minute)
+
+	usage := &BroadcasterUsage{}
+	usage.add(match)
+	usage.add(&BroadcasterMatchUsage{OperatorID: "operator", Disconnected: true})
+
+	crashed := newBroadcasterMatchUsage("operator", start)
+	crashed.addPlayer("b", 60*time.Millisecond)
+	crashed.EndedAt = start.Add(15 * time.Minute)
+	crashed.Disconnected = true
+	usage.add(crashed)
+
+	if usage.Matches != 2 {
+		t.Errorf("Matches = %d, want 2", usage.Matches)
+	}
+	if usage.HostedSeconds != 45*60 {
+		t.Errorf("HostedSeconds = %v, want %v", usage.HostedSeconds, 45*60)
+	}
+	if usage.UniquePlayers != 3 {
+		t.Errorf("UniquePlayers = %d, want 3", usage.UniquePlayers)
+	}
+	if usage.Disconnects != 2 || usage.Crashes != 1 {
+		t.Errorf("Disconnects, Crashes = %d, %d, want 2, 1", usage.Disconnects, usage.Crashes)
+	}
+	want := []int{1, 0, 1, 0, 0, 0, 0, 1}
+	for i := range want {
+		if usage.RTTBuckets[i] != want[i] {
+			t.Fatalf("RTTBuckets = %v, want %v", usage.RTTBuckets, want)
+		}
+	}
+}
+
+func TestSummarizeBroadcasterUsage(t *testing.T) {
+	usages := []*BroadcasterUsage{
+		{Date: "2024-05-02", HostedSeconds: 3600, Matches: 2, PlayerIDs: []string{"a", "b"}, RTTBuckets: []int{0, 8, 0, 0, 0, 0, 0, 0}},
+		{Date: "2024-05-01", HostedSeconds: 1800, Matches: 1, Crashes: 1, Disconnects: 1, PlayerIDs: []string{"b", "c"}, RTTBuckets: []int{0, 0, 0, 0, 0, 0, 0, 2}},
+	}
+
+	summary := summarizeBroadcasterUsage("operator", usages)
+
+	if summary.HostedHours != 1.5 {
+		t.Errorf("HostedHours = %v, want 1.5", summary.HostedHours)
+	}
+	if summary.Matches != 3 || summary.Crashes != 1 || summary.Disconnects != 1 {
+		t.Errorf("Matches, Crashes, Disconnects = %d, %d, %d", summary.Matches, summary.Crashes, summary.Disconnects)
+	}
+	if summary.UniquePlayers != 3 {
+		t.Errorf("UniquePlayers = %d, want 3", summary.UniquePlayers)
+	}
+	if summary.RTTP50 != 50 {
+		t.Errorf("RTTP50 = %d, want 50", summary.RTTP50)
+	}
+	if summary.RTTP95 != -1 {
+		t.Errorf("RTTP95 = %d, want -1", summary.RTTP95)
+	}
+	if summary.Days[0].Date != "2024-05-01" || summary.Days[0].PlayerIDs != nil {
+		t.Errorf("Days not sorted and stripped: %+v", summary.Days[0])
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+type channelMessageReplyRequest struct {
+	ChannelId string                 `json:"channel_id"`
+	ParentId  string                 `json:"parent_id"`
+	Content   map[string]interface{} `json:"content"`
+}
+
+type channelMessageThreadRequest struct {
+	ChannelId string `json:"channel_id"`
+	ParentId  string `json:"parent_id"`
+	Limit     int    `json:"limit,omitempty"`
+	Cursor    string `json:"cursor,omitempty"`
+}
+
+type channelMessageThreadResponse struct {
+	Messages []*api.ChannelMessage `json:"messages"`
+	Cursor   string                `json:"cursor,omitempty"`
+}
+
+type channelMessageReactionRequest struct {
+	ChannelId string `json:"channel_id"`
+	MessageId string `json:"message_id"`
+	Emoji     string `json:"emoji"`
+}
+
+type channelMessageReactionsRequest struct {
+	ChannelId  string   `json:"channel_id"`
+	MessageIds []string `json:"message_ids"`
+}
+
+// channelMessageReplyRpc replies to a chat message as the caller.
+func channelMessageReplyRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &channelMessageReplyRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "channel threads and reactions are", channelMessageRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return goNk.ChannelMessageReply(ctx, request.ChannelId, request.ParentId, request.Content, userID, username)
+	})
+}
+
+// channelMessageThreadRpc lists the replies in a chat message's thread, oldest first.
+func channelMessageThreadRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &channelMessageThreadRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "channel threads and reactions are", channelMessageRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if request.Limit == 0 {
+			request.Limit = 100
+		} else if request.Limit < 1 || request.Limit > 100 {
+			return nil, runtime.NewError("limit must be 1-100", StatusInvalidArgument)
+		}
+		messages, cursor, err := goNk.ChannelMessageThreadList(ctx, request.ChannelId, request.ParentId, request.Limit, request.Cursor, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &channelMessageThreadResponse{Messages: messages, Cursor: cursor}, nil
+	})
+}
+
+// channelMessageReactionAddRpc adds the caller's reaction to a chat message.
+func channelMessageReactionAddRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &channelMessageReactionRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "channel threads and reactions are", channelMessageRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return goNk.ChannelMessageReactionAdd(ctx, request.ChannelId, request.MessageId, request.Emoji, userID, username)
+	})
+}
+
+// channelMessageReactionRemoveRpc removes the caller's reaction from a chat message.
+func channelMessageReactionRemoveRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &channelMessageReactionRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "channel threads and reactions are", channelMessageRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return goNk.ChannelMessageReactionRemove(ctx, request.ChannelId, request.MessageId, request.Emoji, userID, username)
+	})
+}
+
+// channelMessageReactionsRpc lists the aggregated reactions on a set of chat messages.
+func channelMessageReactionsRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &channelMessageReactionsRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "channel threads and reactions are", channelMessageRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if len(request.MessageIds) > 100 {
+			return nil, runtime.NewError("at most 100 message IDs may be listed", StatusInvalidArgument)
+		}
+		return goNk.ChannelMessageReactionsList(ctx, request.ChannelId, request.MessageIds, userID)
+	})
+}
+
+func channelMessageRpcError(err error) error {
+	switch {
+	case errors.Is(err, errChannelMessageNotFound), errors.Is(err, runtime.ErrChannelGroupNotFound):
+		return runtime.NewError(err.Error(), StatusNotFound)
+	case errors.Is(err, errChannelMessageNotJoined):
+		return runtime.NewError(err.Error(), StatusPermissionDenied)
+	case errors.Is(err, errChannelMessageIdInvalid), errors.Is(err, errChannelMessageRejected), errors.Is(err, ErrChannelMessageReactionInvalid),
+		errors.Is(err, runtime.ErrChannelIDInvalid), errors.Is(err, runtime.ErrChannelCursorInvalid):
+		return runtime.NewError(err.Error(), StatusInvalidArgument)
+	default:
+		return runtime.NewError(err.Error(), StatusInternalError)
+	}
+}
@@ -0,0 +1,329 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type groupJoinQuestionnaireRequest struct {
+	GroupId       string                  `json:"group_id"`
+	Questionnaire *GroupJoinQuestionnaire `json:"questionnaire,omitempty"`
+}
+
+type groupJoinRequest struct {
+	GroupId string            `json:"group_id"`
+	Answers map[string]string `json:"answers,omitempty"`
+}
+
+type groupJoinRequestsListRequest struct {
+	GroupId string `json:"group_id"`
+	Limit   int    `json:"limit,omitempty"`
+	Cursor  string `json:"cursor,omitempty"`
+}
+
+type groupJoinRequestsListResponse struct {
+	Requests []*GroupJoinRequest `json:"requests"`
+	Cursor   string              `json:"cursor,omitempty"`
+}
+
+type groupJoinRequestsResolveRequest struct {
+	GroupId string   `json:"group_id"`
+	UserIds []string `json:"user_ids"`
+}
+
+type groupJoinRequestsResolveResponse struct {
+	UserIds []string `json:"user_ids"`
+}
+
+// groupJoinQuestionnaireRpc returns the questions asked of users requesting to join a group.
+func groupJoinQuestionnaireRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinQuestionnaireRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if _, err := uuid.FromString(request.GroupId); err != nil {
+			return nil, runtime.NewError("expects group ID to be a valid identifier", StatusInvalidArgument)
+		}
+		return goNk.GroupJoinQuestionnaireGet(ctx, request.GroupId)
+	})
+}
+
+// groupJoinQuestionnaireSetRpc replaces the questions asked of users requesting to join a group the caller administers.
+func groupJoinQuestionnaireSetRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinQuestionnaireRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if _, err := uuid.FromString(request.GroupId); err != nil {
+			return nil, runtime.NewError("expects group ID to be a valid identifier", StatusInvalidArgument)
+		}
+		callerID, err := groupJoinRequestCaller(ctx, goNk, request.GroupId, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := goNk.GroupJoinQuestionnaireSet(ctx, callerID, request.GroupId, request.Questionnaire); err != nil {
+			return nil, err
+		}
+		return goNk.GroupJoinQuestionnaireGet(ctx, request.GroupId)
+	})
+}
+
+// groupJoinRpc joins the caller to a group, or requests to join it with answers to its questionnaire if it's closed.
+func groupJoinRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		groupID, err := uuid.FromString(request.GroupId)
+		if err != nil {
+			return nil, runtime.NewError("expects group ID to be a valid identifier", StatusInvalidArgument)
+		}
+		userUUID := uuid.FromStringOrNil(userID)
+
+		// Only a new join request is announced to the guild's moderators.
+		var state int
+		err = goNk.db.QueryRowContext(ctx, "SELECT state FROM group_edge WHERE source_id = $1 AND destination_id = $2", groupID, userUUID).Scan(&state)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		isNew := err == sql.ErrNoRows
+
+		if err := goNk.GroupUserJoinWithAnswers(ctx, request.GroupId, userID, username, request.Answers); err != nil {
+			return nil, err
+		}
+
+		if isNew {
+			groupJoinRequestNotifyModerators(ctx, goNk, groupID, userUUID, username, request.Answers)
+		}
+		return struct{}{}, nil
+	})
+}
+
+// groupJoinRequestsRpc lists the pending requests to join a group the caller administers.
+func groupJoinRequestsRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinRequestsListRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if request.Limit == 0 {
+			request.Limit = 100
+		} else if request.Limit < 1 || request.Limit > 100 {
+			return nil, runtime.NewError("limit must be 1-100", StatusInvalidArgument)
+		}
+		if _, err := uuid.FromString(request.GroupId); err != nil {
+			return nil, runtime.NewError("expects group ID to be a valid identifier", StatusInvalidArgument)
+		}
+		callerID, err := groupJoinRequestCaller(ctx, goNk, request.GroupId, userID)
+		if err != nil {
+			return nil, err
+		}
+		requests, cursor, err := goNk.GroupJoinRequestsList(ctx, callerID, request.GroupId, request.Limit, request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &groupJoinRequestsListResponse{Requests: requests, Cursor: cursor}, nil
+	})
+}
+
+// groupJoinRequestsApproveRpc approves pending requests to join a group the caller administers.
+func groupJoinRequestsApproveRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinRequestsResolveRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return groupJoinRequestsResolve(ctx, goNk, request, userID, goNk.GroupJoinRequestsApprove)
+	})
+}
+
+// groupJoinRequestsRejectRpc rejects pending requests to join a group the caller administers.
+func groupJoinRequestsRejectRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &groupJoinRequestsResolveRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "group join requests are", groupJoinRequestRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return groupJoinRequestsResolve(ctx, goNk, request, userID, goNk.GroupJoinRequestsReject)
+	})
+}
+
+func groupJoinRequestsResolve(ctx context.Context, goNk *RuntimeGoNakamaModule, request *groupJoinRequestsResolveRequest, userID string, fn func(context.Context, string, string, []string) ([]string, error)) (interface{}, error) {
+	if len(request.UserIds) > 100 {
+		return nil, runtime.NewError("at most 100 user IDs may be resolved", StatusInvalidArgument)
+	}
+	if _, err := uuid.FromString(request.GroupId); err != nil {
+		return nil, runtime.NewError("expects group ID to be a valid identifier", StatusInvalidArgument)
+	}
+	callerID, err := groupJoinRequestCaller(ctx, goNk, request.GroupId, userID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs, err := fn(ctx, callerID, request.GroupId, request.UserIds)
+	if err != nil {
+		return nil, err
+	}
+	return &groupJoinRequestsResolveResponse{UserIds: userIDs}, nil
+}
+
+// groupJoinRequestCaller returns the caller ID to check group permissions with. Members of a guild's moderator group
+// manage join requests to the guild group as the system user.
+func groupJoinRequestCaller(ctx context.Context, goNk *RuntimeGoNakamaModule, groupID, userID string) (string, error) {
+	metadata, err := groupJoinRequestGuildMetadata(ctx, goNk, groupID)
+	if err != nil || metadata == nil || metadata.ModeratorGroupId == "" {
+		return userID, err
+	}
+
+	moderatorGroupID, err := uuid.FromString(metadata.ModeratorGroupId)
+	if err != nil {
+		return userID, nil
+	}
+	isModerator, err := groupCheckUserPermission(ctx, goNk.logger, goNk.db, moderatorGroupID, uuid.FromStringOrNil(userID), int(api.GroupUserList_GroupUser_MEMBER))
+	if err != nil {
+		return "", err
+	}
+	if isModerator {
+		return "", nil
+	}
+	return userID, nil
+}
+
+// groupJoinRequestGuildMetadata returns the guild metadata of a group, or nil if it's not a guild group.
+func groupJoinRequestGuildMetadata(ctx context.Context, goNk *RuntimeGoNakamaModule, groupID string) (*GroupMetadata, error) {
+	groups, err := goNk.GroupsGetId(ctx, []string{groupID})
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, runtime.ErrGroupNotFound
+	}
+	if groups[0].GetLangTag() != "guild" {
+		return nil, nil
+	}
+	metadata := &GroupMetadata{}
+	if err := json.Unmarshal([]byte(groups[0].GetMetadata()), metadata); err != nil {
+		return nil, nil
+	}
+	return metadata, nil
+}
+
+// groupJoinRequestNotifyModerators sends a join request to a guild group to the guild's moderators who are not
+// already notified as group admins. Offline moderators receive it through the push providers, such as Discord.
+func groupJoinRequestNotifyModerators(ctx context.Context, goNk *RuntimeGoNakamaModule, groupID, userID uuid.UUID, username string, answers map[string]string) {
+	metadata, err := groupJoinRequestGuildMetadata(ctx, goNk, groupID.String())
+	if err != nil || metadata == nil || metadata.ModeratorGroupId == "" {
+		return
+	}
+
+	// Open guild groups have no join requests.
+	pending, err := groupJoinRequestsPending(ctx, goNk.db, groupID, []uuid.UUID{userID})
+	if err != nil || len(pending) == 0 {
+		return
+	}
+
+	query := `SELECT m.destination_id FROM group_edge m
+WHERE m.source_id = $1 AND m.state <= $3
+AND NOT EXISTS (SELECT 1 FROM group_edge a WHERE a.source_id = $2 AND a.destination_id = m.destination_id AND a.state <= $4)`
+	rows, err := goNk.db.QueryContext(ctx, query, metadata.ModeratorGroupId, groupID, api.GroupUserList_GroupUser_MEMBER, api.GroupUserList_GroupUser_ADMIN)
+	if err != nil {
+		goNk.logger.Error("Error looking up guild moderators to notify of join request.", zap.Error(err))
+		return
+	}
+	defer rows.Close()
+
+	if answers == nil {
+		answers = map[string]string{}
+	}
+	content, err := json.Marshal(map[string]interface{}{"group_id": groupID.String(), "username": username, "user_id": userID.String(), "answers": answers, "guild_id": metadata.GuildId})
+	if err != nil {
+		goNk.logger.Error("Could not encode notification content.", zap.Error(err))
+		return
+	}
+
+	notifications := make(map[uuid.UUID][]*api.Notification)
+	for rows.Next() {
+		var moderatorID uuid.UUID
+		if err := rows.Scan(&moderatorID); err != nil {
+			goNk.logger.Error("Error reading guild moderators to notify of join request.", zap.Error(err))
+			return
+		}
+		notifications[moderatorID] = []*api.Notification{{
+			Id:         uuid.Must(uuid.NewV4()).String(),
+			Subject:    fmt.Sprintf("User %v wants to join your guild", username),
+			Content:    string(content),
+			SenderId:   userID.String(),
+			Code:       NotificationCodeGroupJoinRequest,
+			Persistent: true,
+			CreateTime: &timestamppb.Timestamp{Seconds: time.Now().UTC().Unix()},
+		}}
+	}
+	if len(notifications) > 0 {
+		// Any error is already logged before it's returned here.
+		_ = NotificationSend(ctx, goNk.logger, goNk.db, goNk.tracker, goNk.router, notifications)
+	}
+}
+
+// The prefix of the IDs of join questions taken from a guild's onboarding flow, which tells them apart from questions
+// set by group admins.
+const groupJoinQuestionOnboardingPrefix = "discord:"
+
+// groupJoinOnboardingEnabled reports whether a guild asks its onboarding questions of new members.
+func groupJoinOnboardingEnabled(onboarding *discordgo.GuildOnboarding) bool {
+	return onboarding != nil && onboarding.Enabled != nil && *onboarding.Enabled
+}
+
+// groupJoinQuestionnaireFromOnboarding maps the prompts of a guild's onboarding flow to join questions. Single select
+// prompts are answered with one of their option titles.
+func groupJoinQuestionnaireFromOnboarding(onboarding *discordgo.GuildOnboarding) []*GroupJoinQuestion {
+	questions := make([]*GroupJoinQuestion, 0)
+	if !groupJoinOnboardingEnabled(onboarding) || onboarding.Prompts == nil {
+		return questions
+	}
+
+	for _, prompt := range *onboarding.Prompts {
+		if !prompt.InOnboarding || prompt.Title == "" {
+			continue
+		}
+		if len(questions) >= groupJoinQuestionsMax {
+			break
+		}
+		question := &GroupJoinQuestion{
+			Id:       groupJoinQuestionOnboardingPrefix + prompt.ID,
+			Text:     prompt.Title,
+			Required: prompt.Required,
+		}
+		if prompt.SingleSelect {
+			for _, option := range prompt.Options {
+				if option.Title == "" || len(question.Options) >= groupJoinQuestionOptionsMax {
+					continue
+				}
+				question.Options = append(question.Options, option.Title)
+			}
+		}
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+// groupJoinQuestionnaireFromOnboardingOnly reports whether all of a group's join questions were taken from its guild's
+// onboarding flow, so they may be replaced when the flow changes. Groups without questions have none to keep.
+func groupJoinQuestionnaireFromOnboardingOnly(questions []*GroupJoinQuestion) bool {
+	for _, question := range questions {
+		if !strings.HasPrefix(question.Id, groupJoinQuestionOnboardingPrefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func groupJoinRequestRpcError(err error) error {
+	switch {
+	case errors.Is(err, runtime.ErrGroupNotFound):
+		return runtime.NewError(err.Error(), StatusNotFound)
+	case errors.Is(err, runtime.ErrGroupPermissionDenied):
+		return runtime.NewError(err.Error(), StatusPermissionDenied)
+	case errors.Is(err, runtime.ErrGroupFull):
+		return runtime.NewError(err.Error(), StatusResourceExhausted)
+	case errors.Is(err, ErrGroupJoinQuestionsInvalid), errors.Is(err, ErrGroupJoinAnswersInvalid), errors.Is(err, runtime.ErrGroupUserInvalidCursor):
+		return runtime.NewError(err.Error(), StatusInvalidArgument)
+	default:
+		return runtime.NewError(err.Error(), StatusInternalError)
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupJoinQuestionnaireFromOnboarding(t *testing.T) {
+	enabled, disabled := true, false
+	prompts := []discordgo.GuildOnboardingPrompt{
+		{
+			ID:           "1",
+			Title:        "Which region do you play in?",
+			SingleSelect: true,
+			Required:     true,
+			InOnboarding: true,
+			Options:      []discordgo.GuildOnboardingPromptOption{{Title: "EU"}, {Title: "NA"}},
+		},
+		{
+			ID:           "2",
+			Title:        "Which modes do you play?",
+			InOnboarding: true,
+			Options:      []discordgo.GuildOnboardingPromptOption{{Title: "Arena"}, {Title: "Combat"}},
+		},
+		{
+			ID:    "3",
+			Title: "Pick your colour",
+		},
+	}
+
+	questions := groupJoinQuestionnaireFromOnboarding(&discordgo.GuildOnboarding{Enabled: &enabled, Prompts: &prompts})
+	require.Len(t, questions, 2)
+	assert.Equal(t, &GroupJoinQuestion{Id: "discord:1", Text: "Which region do you play in?", Required: true, Options: []string{"EU", "NA"}}, questions[0])
+	// Multiple choice prompts are answered freely.
+	assert.Equal(t, &GroupJoinQuestion{Id: "discord:2", Text: "Which modes do you play?"}, questions[1])
+	assert.NoError(t, groupJoinQuestionnaireValidate(&GroupJoinQuestionnaire{Questions: questions}))
+
+	assert.Empty(t, groupJoinQuestionnaireFromOnboarding(&discordgo.GuildOnboarding{Enabled: &disabled, Prompts: &prompts}))
+	assert.Empty(t, groupJoinQuestionnaireFromOnboarding(nil))
+}
+
+func TestGroupJoinQuestionnaireFromOnboardingOnly(t *testing.T) {
+	assert.True(t, groupJoinQuestionnaireFromOnboardingOnly(nil))
+	assert.True(t, groupJoinQuestionnaireFromOnboardingOnly([]*GroupJoinQuestion{{Id: "discord:1"}, {Id: "discord:2"}}))
+	// A question added by an admin keeps the questionnaire from being replaced.
+	assert.False(t, groupJoinQuestionnaireFromOnboardingOnly([]*GroupJoinQuestion{{Id: "discord:1"}, {Id: "rules"}}))
+}
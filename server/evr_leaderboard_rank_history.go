@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const LeaderboardRankHistoryDefaultLimit = 10
+
+type leaderboardRankRequest struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	Limit         int    `json:"limit,omitempty"`
+	Cursor        string `json:"cursor,omitempty"`
+	Expiry        int64  `json:"expiry,omitempty"`
+}
+
+type leaderboardRankHistoryResponse struct {
+	History []*LeaderboardRankHistory `json:"history"`
+	Cursor  string                    `json:"cursor,omitempty"`
+}
+
+// leaderboardRecordPercentileRpc returns the caller's rank and percentile in a leaderboard period.
+func leaderboardRecordPercentileRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return goRuntimeRpc(ctx, nk, payload, nil, "leaderboard percentiles are", leaderboardRankError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		request, err := parseLeaderboardRankRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+		return goNk.LeaderboardRecordPercentile(ctx, request.LeaderboardID, userID, request.Expiry)
+	})
+}
+
+// leaderboardRankHistoryRpc lists the caller's final ranks in past periods of a leaderboard, most recent first.
+func leaderboardRankHistoryRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return goRuntimeRpc(ctx, nk, payload, nil, "leaderboard rank history is", leaderboardRankError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		request, err := parseLeaderboardRankRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+		if request.Limit < 1 || request.Limit > 100 {
+			return nil, runtime.NewError("limit must be 1-100", StatusInvalidArgument)
+		}
+		history, cursor, err := goNk.LeaderboardRankHistoryList(ctx, request.LeaderboardID, userID, request.Limit, request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		return &leaderboardRankHistoryResponse{History: history, Cursor: cursor}, nil
+	})
+}
+
+func parseLeaderboardRankRequest(payload string) (*leaderboardRankRequest, error) {
+	request := &leaderboardRankRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return nil, runtime.NewError(err.Error(), StatusInvalidArgument)
+		}
+	}
+	if request.LeaderboardID == "" {
+		return nil, runtime.NewError("leaderboard_id is required", StatusInvalidArgument)
+	}
+	if request.Expiry < 0 {
+		return nil, runtime.NewError("expiry must be a positive time since epoch in seconds", StatusInvalidArgument)
+	}
+	if request.Limit == 0 {
+		request.Limit = LeaderboardRankHistoryDefaultLimit
+	}
+	return request, nil
+}
+
+func leaderboardRankError(err error) error {
+	switch {
+	case errors.Is(err, ErrLeaderboardNotFound):
+		return runtime.NewError(err.Error(), StatusNotFound)
+	case errors.Is(err, ErrLeaderboardInvalidCursor):
+		return runtime.NewError(err.Error(), StatusInvalidArgument)
+	default:
+		return runtime.NewError(err.Error(), StatusInternalError)
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestParseLeaderboardRankRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    *leaderboardRankRequest
+		wantErr bool
+	}{
+		{
+			name:    "defaults limit",
+			payload: `{"leaderboard_id":"weekly"}`,
+			want:    &leaderboardRankRequest{LeaderboardID: "weekly", Limit: LeaderboardRankHistoryDefaultLimit},
+		},
+		{
+			name:    "keeps fields",
+			payload: `{"leaderboard_id":"weekly","limit":5,"cursor":"c","expiry":10}`,
+			want:    &leaderboardRankRequest{LeaderboardID: "weekly", Limit: 5, Cursor: "c", Expiry: 10},
+		},
+		{
+			name:    "negative expiry",
+			payload: `{"leaderboard_id":"weekly","expiry":-1}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing leaderboard",
+			payload: `{"limit":5}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			payload: `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLeaderboardRankRequest(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLeaderboardRankRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("parseLeaderboardRankRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
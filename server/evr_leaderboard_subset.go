@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const LeaderboardSubsetDefaultLimit = 25
+
+type leaderboardSubsetRequest struct {
+	LeaderboardID string `json:"leaderboard_id"`
+	GroupID       string `json:"group_id,omitempty"`
+	Limit         int    `json:"limit,omitempty"`
+	Cursor        string `json:"cursor,omitempty"`
+	Expiry        int64  `json:"expiry,omitempty"`
+}
+
+// leaderboardRecordsFriendsRpc lists the caller's and their friends' records, ranked among themselves.
+func leaderboardRecordsFriendsRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return goRuntimeRpc(ctx, nk, payload, nil, "leaderboard subsets are", leaderboardSubsetError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		request, err := parseLeaderboardSubsetRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+		return goNk.LeaderboardRecordsListFriends(ctx, request.LeaderboardID, userID, request.Limit, request.Cursor, request.Expiry)
+	})
+}
+
+// leaderboardRecordsGroupRpc lists the records of a group's members, ranked among themselves. Only members of the
+// group may list it.
+func leaderboardRecordsGroupRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return goRuntimeRpc(ctx, nk, payload, nil, "leaderboard subsets are", leaderboardSubsetError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		request, err := parseLeaderboardSubsetRequest(payload)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := uuid.FromString(request.GroupID); err != nil {
+			return nil, runtime.NewError("group_id must be a valid identifier", StatusInvalidArgument)
+		}
+
+		var state int
+		if err := db.QueryRowContext(ctx, "SELECT state FROM group_edge WHERE source_id = $1 AND destination_id = $2", request.GroupID, userID).Scan(&state); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, runtime.NewError("only group members may list group leaderboards", StatusPermissionDenied)
+			}
+			return nil, runtime.NewError(err.Error(), StatusInternalError)
+		}
+		if state > 2 {
+			// Join request not yet accepted.
+			return nil, runtime.NewError("only group members may list group leaderboards", StatusPermissionDenied)
+		}
+
+		return goNk.LeaderboardRecordsListGroup(ctx, request.LeaderboardID, request.GroupID, request.Limit, request.Cursor, request.Expiry)
+	})
+}
+
+func parseLeaderboardSubsetRequest(payload string) (*leaderboardSubsetRequest, error) {
+	request := &leaderboardSubsetRequest{}
+	if payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return nil, runtime.NewError(err.Error(), StatusInvalidArgument)
+		}
+	}
+	if request.LeaderboardID == "" {
+		return nil, runtime.NewError("leaderboard_id is required", StatusInvalidArgument)
+	}
+	if request.Limit == 0 {
+		request.Limit = LeaderboardSubsetDefaultLimit
+	} else if request.Limit < 1 || request.Limit > 100 {
+		return nil, runtime.NewError("limit must be 1-100", StatusInvalidArgument)
+	}
+	return request, nil
+}
+
+func leaderboardSubsetError(err error) error {
+	switch {
+	case errors.Is(err, ErrLeaderboardNotFound):
+		return runtime.NewError(err.Error(), StatusNotFound)
+	case errors.Is(err, ErrLeaderboardInvalidCursor):
+		return runtime.NewError(err.Error(), StatusInvalidArgument)
+	default:
+		return runtime.NewError(err.Error(), StatusInternalError)
+	}
+}
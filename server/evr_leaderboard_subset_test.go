@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+func TestParseLeaderboardSubsetRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    *leaderboardSubsetRequest
+		wantErr bool
+	}{
+		{
+			name:    "defaults limit",
+			payload: `{"leaderboard_id":"weekly"}`,
+			want:    &leaderboardSubsetRequest{LeaderboardID: "weekly", Limit: LeaderboardSubsetDefaultLimit},
+		},
+		{
+			name:    "keeps fields",
+			payload: `{"leaderboard_id":"weekly","group_id":"g","limit":5,"cursor":"c","expiry":10}`,
+			want:    &leaderboardSubsetRequest{LeaderboardID: "weekly", GroupID: "g", Limit: 5, Cursor: "c", Expiry: 10},
+		},
+		{
+			name:    "limit too high",
+			payload: `{"leaderboard_id":"weekly","limit":101}`,
+			wantErr: true,
+		},
+		{
+			name:    "negative limit",
+			payload: `{"leaderboard_id":"weekly","limit":-1}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing leaderboard",
+			payload: `{"limit":5}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty payload",
+			payload: "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			payload: `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLeaderboardSubsetRequest(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLeaderboardSubsetRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != *tt.want {
+				t.Errorf("parseLeaderboardSubsetRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeaderboardSubsetError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{ErrLeaderboardNotFound, StatusNotFound},
+		{ErrLeaderboardInvalidCursor, StatusInvalidArgument},
+		{errors.New("connection reset"), StatusInternalError},
+	}
+
+	for _, tt := range tests {
+		var runtimeErr *runtime.Error
+		if !errors.As(leaderboardSubsetError(tt.err), &runtimeErr) {
+			t.Fatalf("leaderboardSubsetError(%v) is not a runtime error", tt.err)
+		}
+		if runtimeErr.Code != tt.code {
+			t.Errorf("leaderboardSubsetError(%v) code = %d, want %d", tt.err, runtimeErr.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	LobbyCodeCollection = "LobbyCodes"
+	LobbyCodeIndex      = "Index_" + LobbyCodeCollection
+	LobbyCodeLength     = 6
+	LobbyCodeDefaultTTL = 2 * time.Hour
+	LobbyCodeMaxTTL     = 24 * time.Hour
+)
+
+var (
+	ErrLobbyCodeNotFound      = runtime.NewError("lobby code not found", StatusNotFound)
+	ErrLobbyCodeExpired       = runtime.NewError("lobby code expired", StatusNotFound)
+	ErrLobbyCodeWrongPassword = runtime.NewError("incorrect lobby password", StatusPermissionDenied)
+	ErrLobbyCodeNotOwner      = runtime.NewError("only the lobby owner can manage its codes", StatusPermissionDenied)
+	ErrLobbyCodeNotPrivate    = runtime.NewError("lobby codes are only available for private matches", StatusFailedPrecondition)
+)
+
+// LobbyCode maps a short, human friendly code to a private match.
+type LobbyCode struct {
+	Code         string    `json:"code"`
+	MatchID      string    `json:"match_id"`
+	CreatedBy    string    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	InviteOnly   bool      `json:"invite_only"`
+}
+
+func (c *LobbyCode) Expired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// LobbyInvite is the payload of a SignalLobbyInvite signal.
+type LobbyInvite struct {
+	UserIDs           []string `json:"user_ids,omitempty"`           // The users to add to (or remove from) the invite list.
+	Revoke            bool     `json:"revoke,omitempty"`             // Remove the users from the invite list.
+	InviteOnly        *bool    `json:"invite_only,omitempty"`        // Change whether the match is invite-only.
+	PasswordProtected bool     `json:"password_protected,omitempty"` // Require players to redeem a lobby code with its password.
+}
+
+// generateLobbyCode generates a random lobby code (excluding homoglyphs and vowels).
+func generateLobbyCode() (string, error) {
+	validChars := "ACDFGHJKLMNPRSTXYZ2345679"
+	code := make([]byte, LobbyCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(validChars))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = validChars[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func readLobbyCode(ctx context.Context, nk runtime.NakamaModule, code string) (*LobbyCode, error) {
+	objs, err := nk.StorageRead(ctx, []*runtime.StorageRead{
+		{
+			Collection: LobbyCodeCollection,
+			Key:        strings.ToUpper(code),
+			UserID:     SystemUserID,
+		},
+	})
+	if err != nil {
+		return nil, runtime.NewError("failed to read lobby code", StatusInternalError)
+	}
+	if len(objs) == 0 {
+		return nil, ErrLobbyCodeNotFound
+	}
+	lobbyCode := &LobbyCode{}
+	if err := json.Unmarshal([]byte(objs[0].Value), lobbyCode); err != nil {
+		return nil, runtime.NewError("failed to unmarshal lobby code", StatusInternalError)
+	}
+	if lobbyCode.Expired() {
+		deleteLobbyCode(ctx, nk, lobbyCode.Code)
+		return nil, ErrLobbyCodeExpired
+	}
+	return lobbyCode, nil
+}
+
+// deleteLobbyCode removes an expired code that the storage expiry reaper has not removed yet.
+func deleteLobbyCode(ctx context.Context, nk runtime.NakamaModule, code string) {
+	_ = nk.StorageDelete(ctx, []*runtime.StorageDelete{
+		{
+			Collection: LobbyCodeCollection,
+			Key:        code,
+			UserID:     SystemUserID,
+		},
+	})
+}
+
+// signalLobbyInvite updates the invite list of a match.
+func signalLobbyInvite(ctx context.Context, nk runtime.NakamaModule, matchID string, invite LobbyInvite) error {
+	data, err := json.Marshal(invite)
+	if err != nil {
+		return err
+	}
+	signal := EvrSignal{
+		Signal: SignalLobbyInvite,
+		Data:   data,
+	}
+	result, err := nk.MatchSignal(ctx, matchID, signal.String())
+	if err != nil {
+		return err
+	}
+	if result != "invites updated" {
+		return runtime.NewError(result, StatusFailedPrecondition)
+	}
+	return nil
+}
+
+// CreateLobbyCode creates a new lobby code for a private match owned by the user.
+func CreateLobbyCode(ctx context.Context, nk runtime.NakamaModule, userID, matchID string, ttl time.Duration, password string, inviteOnly bool) (*LobbyCode, error) {
+	match, err := nk.MatchGet(ctx, matchID)
+	if err != nil || match == nil {
+		return nil, runtime.NewError("match not found", StatusNotFound)
+	}
+	label, err := MatchStateFromLabel(match.GetLabel().GetValue())
+	if err != nil {
+		return nil, runtime.NewError("failed to parse match label", StatusInternalError)
+	}
+	if label.LobbyType != PrivateLobby {
+		return nil, ErrLobbyCodeNotPrivate
+	}
+	if label.SpawnedBy != userID {
+		return nil, ErrLobbyCodeNotOwner
+	}
+
+	if ttl <= 0 {
+		ttl = LobbyCodeDefaultTTL
+	} else if ttl > LobbyCodeMaxTTL {
+		ttl = LobbyCodeMaxTTL
+	}
+
+	lobbyCode := &LobbyCode{
+		MatchID:    matchID,
+		CreatedBy:  userID,
+		CreatedAt:  time.Now().UTC(),
+		ExpiresAt:  time.Now().UTC().Add(ttl),
+		InviteOnly: inviteOnly,
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, runtime.NewError("failed to hash password", StatusInternalError)
+		}
+		lobbyCode.PasswordHash = string(hash)
+	}
+
+	// Retry on the (unlikely) collision with an existing code.
+	for i := 0; i < 5; i++ {
+		if lobbyCode.Code, err = generateLobbyCode(); err != nil {
+			return nil, runtime.NewError("failed to generate lobby code", StatusInternalError)
+		}
+		var data []byte
+		if data, err = json.Marshal(lobbyCode); err != nil {
+			return nil, runtime.NewError("failed to marshal lobby code", StatusInternalError)
+		}
+		writes := []*runtime.StorageWrite{
+			{
+				Collection:      LobbyCodeCollection,
+				Key:             lobbyCode.Code,
+				UserID:          SystemUserID,
+				Value:           string(data),
+				Version:         "*", // Only write if the code does not exist.
+				PermissionRead:  0,
+				PermissionWrite: 0,
+			},
+		}
+		// Expired codes are deleted by the storage expiry reaper.
+		if goNk, ok := nk.(*RuntimeGoNakamaModule); ok {
+			_, err = goNk.StorageWriteExpiring(ctx, writes, lobbyCode.ExpiresAt)
+		} else {
+			_, err = nk.StorageWrite(ctx, writes)
+		}
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, runtime.NewError("failed to store lobby code", StatusInternalError)
+	}
+
+	if inviteOnly || password != "" {
+		invite := LobbyInvite{PasswordProtected: password != ""}
+		if inviteOnly {
+			invite.InviteOnly = &inviteOnly
+		}
+		if err := signalLobbyInvite(ctx, nk, matchID, invite); err != nil {
+			return nil, err
+		}
+	}
+	return lobbyCode, nil
+}
+
+// RedeemLobbyCode checks the code (and password), adds the user to the match's invite list, and returns the match ID.
+func RedeemLobbyCode(ctx context.Context, nk runtime.NakamaModule, userID, code, password string) (string, error) {
+	lobbyCode, err := readLobbyCode(ctx, nk, code)
+	if err != nil {
+		return "", err
+	}
+	if lobbyCode.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(lobbyCode.PasswordHash), []byte(password)); err != nil {
+			return "", ErrLobbyCodeWrongPassword
+		}
+	}
+	if err := signalLobbyInvite(ctx, nk, lobbyCode.MatchID, LobbyInvite{UserIDs: []string{userID}}); err != nil {
+		return "", err
+	}
+	return lobbyCode.MatchID, nil
+}
+
+// RevokeLobbyCode deletes a lobby code. Players that already redeemed it stay invited.
+func RevokeLobbyCode(ctx context.Context, nk runtime.NakamaModule, userID, code string) error {
+	lobbyCode, err := readLobbyCode(ctx, nk, code)
+	if err != nil {
+		return err
+	}
+	if lobbyCode.CreatedBy != userID {
+		return ErrLobbyCodeNotOwner
+	}
+	return nk.StorageDelete(ctx, []*runtime.StorageDelete{
+		{
+			Collection: LobbyCodeCollection,
+			Key:        lobbyCode.Code,
+			UserID:     SystemUserID,
+		},
+	})
+}
+
+// ListLobbyCodes returns the unexpired lobby codes created by the user.
+func ListLobbyCodes(ctx context.Context, nk runtime.NakamaModule, userID string) ([]*LobbyCode, error) {
+	objs, err := nk.StorageIndexList(ctx, SystemUserID, LobbyCodeIndex, fmt.Sprintf("+value.created_by:%s", queryEscape(userID)), 100)
+	if err != nil {
+		return nil, runtime.NewError("failed to list lobby codes", StatusInternalError)
+	}
+	codes := make([]*LobbyCode, 0, len(objs.GetObjects()))
+	for _, obj := range objs.GetObjects() {
+		lobbyCode := &LobbyCode{}
+		if err := json.Unmarshal([]byte(obj.GetValue()), lobbyCode); err != nil {
+			continue
+		}
+		if lobbyCode.Expired() {
+			deleteLobbyCode(ctx, nk, lobbyCode.Code)
+			continue
+		}
+		codes = append(codes, lobbyCode)
+	}
+	return codes, nil
+}
+
+type lobbyCodeCreateRequest struct {
+	MatchID    string   `json:"match_id"`
+	TTLSeconds int      `json:"ttl_seconds"`
+	Password   string   `json:"password"`
+	InviteOnly bool     `json:"invite_only"`
+	Invite     []string `json:"invite"` // User IDs to invite immediately.
+}
+
+type lobbyCodeResponse struct {
+	Code       string    `json:"code"`
+	MatchID    string    `json:"match_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	InviteOnly bool      `json:"invite_only"`
+	Password   bool      `json:"password"`
+}
+
+func newLobbyCodeResponse(c *LobbyCode) lobbyCodeResponse {
+	return lobbyCodeResponse{
+		Code:       c.Code,
+		MatchID:    c.MatchID,
+		ExpiresAt:  c.ExpiresAt,
+		InviteOnly: c.InviteOnly,
+		Password:   c.PasswordHash != "",
+	}
+}
+
+func lobbyCodeCreateRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+	request := &lobbyCodeCreateRequest{}
+	if err := json.Unmarshal([]byte(payload), request); err != nil {
+		return "", runtime.NewError("invalid request", StatusInvalidArgument)
+	}
+	lobbyCode, err := CreateLobbyCode(ctx, nk, userID, request.MatchID, time.Duration(request.TTLSeconds)*time.Second, request.Password, request.InviteOnly)
+	if err != nil {
+		return "", err
+	}
+	if len(request.Invite) > 0 {
+		if err := signalLobbyInvite(ctx, nk, lobbyCode.MatchID, LobbyInvite{UserIDs: request.Invite}); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.Marshal(newLobbyCodeResponse(lobbyCode))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+type lobbyCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password"`
+}
+
+func lobbyCodeRedeemRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+	request := &lobbyCodeRequest{}
+	if err := json.Unmarshal([]byte(payload), request); err != nil {
+		return "", runtime.NewError("invalid request", StatusInvalidArgument)
+	}
+	matchID, err := RedeemLobbyCode(ctx, nk, userID, request.Code, request.Password)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(map[string]string{"match_id": matchID})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func lobbyCodeRevokeRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+	request := &lobbyCodeRequest{}
+	if err := json.Unmarshal([]byte(payload), request); err != nil {
+		return "", runtime.NewError("invalid request", StatusInvalidArgument)
+	}
+	if err := RevokeLobbyCode(ctx, nk, userID, request.Code); err != nil {
+		return "", err
+	}
+	return "{}", nil
+}
+
+func lobbyCodeListRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+	codes, err := ListLobbyCodes(ctx, nk, userID)
+	if err != nil {
+		return "", err
+	}
+	responses := make([]lobbyCodeResponse, 0, len(codes))
+	for _, c := range codes {
+		responses = append(responses, newLobbyCodeResponse(c))
+	}
+	data, err := json.Marshal(map[string]interface{}{"codes": responses})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// applyLobbyInvite updates the match's invite list.
+func (s *EvrMatchState) applyLobbyInvite(invite LobbyInvite) {
+	if s.invites == nil {
+		s.invites = make(map[uuid.UUID]bool, len(invite.UserIDs))
+	}
+	if invite.InviteOnly != nil {
+		s.InviteOnly = *invite.InviteOnly
+	}
+	if invite.PasswordProtected {
+		s.passwordProtected = true
+	}
+	for _, id := range invite.UserIDs {
+		userID := uuid.FromStringOrNil(id)
+		if userID == uuid.Nil {
+			continue
+		}
+		if invite.Revoke {
+			delete(s.invites, userID)
+		} else {
+			s.invites[userID] = true
+		}
+	}
+}
+
+// isInvited returns true if the user may join an invite-only or password protected match. Players are invited by
+// redeeming a lobby code, which checks its password.
+func (s *EvrMatchState) isInvited(userID string) bool {
+	if (!s.InviteOnly && !s.passwordProtected) || userID == s.SpawnedBy {
+		return true
+	}
+	return s.invites[uuid.FromStringOrNil(userID)]
+}
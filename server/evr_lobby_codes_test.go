@@ -0,0 +1,80 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestGenerateLobbyCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateLobbyCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != LobbyCodeLength {
+			t.Errorf("generateLobbyCode() = %q, want %d characters", code, LobbyCodeLength)
+		}
+		if strings.ContainsAny(code, "EIOUB01") {
+			t.Errorf("generateLobbyCode() = %q, contains an ambiguous character", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) < 95 {
+		t.Errorf("generateLobbyCode() produced %d unique codes out of 100", len(seen))
+	}
+}
+
+func TestEvrMatchState_LobbyInvites(t *testing.T) {
+	owner, guest, other := uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String()
+	state := &EvrMatchState{
+		LobbyType: PrivateLobby,
+		SpawnedBy: owner,
+	}
+
+	if !state.isInvited(other) {
+		t.Error("isInvited() = false for an open match")
+	}
+
+	inviteOnly := true
+	state.applyLobbyInvite(LobbyInvite{InviteOnly: &inviteOnly, UserIDs: []string{guest, "not-a-uuid"}})
+
+	if !state.isInvited(owner) {
+		t.Error("isInvited() = false for the match owner")
+	}
+	if !state.isInvited(guest) {
+		t.Error("isInvited() = false for an invited player")
+	}
+	if state.isInvited(other) {
+		t.Error("isInvited() = true for a player without an invite")
+	}
+
+	state.applyLobbyInvite(LobbyInvite{UserIDs: []string{guest}, Revoke: true})
+	if state.isInvited(guest) {
+		t.Error("isInvited() = true after the invite was revoked")
+	}
+}
+
+func TestEvrMatchState_LobbyPassword(t *testing.T) {
+	owner, guest, other := uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String()
+	state := &EvrMatchState{
+		LobbyType: PrivateLobby,
+		SpawnedBy: owner,
+	}
+
+	// A password protected code makes joining by match ID alone fail.
+	state.applyLobbyInvite(LobbyInvite{PasswordProtected: true})
+	if state.isInvited(other) {
+		t.Error("isInvited() = true for a player who did not redeem the code")
+	}
+	if !state.isInvited(owner) {
+		t.Error("isInvited() = false for the match owner")
+	}
+
+	state.applyLobbyInvite(LobbyInvite{UserIDs: []string{guest}})
+	if !state.isInvited(guest) {
+		t.Error("isInvited() = false for a player who redeemed the code")
+	}
+}
@@ -30,6 +30,7 @@ const (
 
 	LevelSelectionFirst  MatchLevelSelection = "first"
 	LevelSelectionRandom MatchLevelSelection = "random"
+	LevelSelectionVote   MatchLevelSelection = "vote" // Players vote on the level, the mode is fixed by the lobby.
 
 	StatGroupArena  MatchStatGroup = "arena"
 	StatGroupCombat MatchStatGroup = "combat"
@@ -45,6 +46,11 @@ const (
 	SignalGetPresences
 	SignalPruneUnderutilized
 	SignalTerminate
+	SignalRoundBreak
+	SignalLevelVote
+	SignalLobbyInvite
+	SignalDrain
+	SignalBackfillReserve
 )
 
 var (
@@ -126,7 +132,10 @@ type EvrMatchPresence struct {
 	PartyID       uuid.UUID // The party id the player is in.
 	IPinfo        *ipinfo.Core
 	DiscordID     string
-	Query         string // Matchmaking query used to find this match.
+	Query         string        // Matchmaking query used to find this match.
+	Rating        float64       // The player's skill estimate, used for team balancing.
+	JoinedAt      time.Time     // When the player joined the match.
+	RTT           time.Duration // The player's RTT to the broadcaster (if known).
 }
 
 func (p *EvrMatchPresence) String() string {
@@ -200,6 +209,7 @@ type MatchBroadcaster struct {
 	ServerID      uint64       `json:"server_id,omitempty"`      // The server id of the broadcaster. (EVR)
 	PublisherLock bool         `json:"publisher_lock,omitempty"` // Publisher lock (EVR)
 	Tags          []string     `json:"tags,omitempty"`           // The tags given on the urlparam for the match.
+	Draining      bool         `json:"draining,omitempty"`       // The broadcaster is finishing its current match, and will not be allocated.
 }
 
 // The lobby state is used for the match label.
@@ -217,27 +227,37 @@ type EvrMatchState struct {
 	Channel     *uuid.UUID       `json:"channel,omitempty"`     // The channel id of the broadcaster. (EVR)
 	GuildID     string           `json:"guild_id,omitempty"`    // The guild id of the broadcaster. (EVR)
 	GuildName   string           `json:"guild_name,omitempty"`  // The guild name of the broadcaster. (EVR)
+	InviteOnly  bool             `json:"invite_only,omitempty"` // Whether only invited players may join. (Private only)
 
 	Mode            evr.Symbol           `json:"mode,omitempty"`             // The mode of the lobby (Arena, Combat, Social, etc.) (EVR)
 	Level           evr.Symbol           `json:"level,omitempty"`            // The level to play on (EVR).
 	Levels          []Level              `json:"levels,omitempty"`           // The levels to choose from (EVR).
 	LevelSelection  MatchLevelSelection  `json:"level_selection,omitempty"`  // The level selection method (EVR).
 	SessionSettings *evr.SessionSettings `json:"session_settings,omitempty"` // The session settings for the match (EVR).
+	VoteTally       map[string]int       `json:"vote_tally,omitempty"`       // The number of votes for each level (Vote only).
+	VoteEndsAt      *time.Time           `json:"vote_ends_at,omitempty"`     // When the level vote closes (Vote only).
 
 	MaxSize   uint8     `json:"limit,omitempty"`     // The total lobby size limit (players + specs)
 	Size      int       `json:"size"`                // The number of players (not including spectators) in the match.
 	TeamSize  int       `json:"team_size,omitempty"` // The size of each team in arena/combat (either 4 or 5)
 	TeamIndex TeamIndex `json:"team,omitempty"`      // What team index a player prefers (Used by Matching only)
 
-	Players                 []PlayerInfo                 `json:"players,omitempty"` // The displayNames of the players (by team name) in the match.
-	EvrIDs                  []evr.EvrId                  `json:"evrids,omitempty"`  // The evr ids of the players in the match.
-	UserIDs                 []string                     `json:"userids,omitempty"` // The user ids of the players in the match.
+	Players                 []PlayerInfo                 `json:"players,omitempty"`   // The displayNames of the players (by team name) in the match.
+	EvrIDs                  []evr.EvrId                  `json:"evrids,omitempty"`    // The evr ids of the players in the match.
+	UserIDs                 []string                     `json:"userids,omitempty"`   // The user ids of the players in the match.
+	Rebalance               *TeamRebalanceSuggestion     `json:"rebalance,omitempty"` // The latest team rebalance suggestion.
+	balance                 TeamBalanceSettings          // The team balancing policy of the channel.
+	votes                   map[string]evr.Symbol        // [userID]Level votes for the next level.
+	invites                 map[uuid.UUID]bool           // [userID] players invited to an invite-only match.
+	passwordProtected       bool                         // Whether a lobby code with a password was created for the match.
+	usage                   *BroadcasterMatchUsage       // The operator's usage for the current match.
 	teamAlignments          map[evr.EvrId]int            // [evrID]TeamIndex
 	presences               map[string]*EvrMatchPresence // [sessionId]EvrMatchPresence
 	broadcaster             runtime.Presence             // The broadcaster's presence
 	presenceByEvrId         map[string]*EvrMatchPresence // lookup table for EchoVR ID
 	presenceByPlayerSession map[string]*EvrMatchPresence // lookup table for match-scoped session id
 	presenceCache           map[string]*EvrMatchPresence // [sessionId]PlayerMeta cache for all players that have attempted to join the match.
+	backfillReservations    map[string]time.Time         // [sessionId]Expiry of the slots held for players placed by the matchmaker.
 	emptyTicks              int                          // The number of ticks the match has been empty.
 	tickRate                int                          // The number of ticks per second.
 }
@@ -426,12 +446,14 @@ func selectTeamForPlayer(logger runtime.Logger, presence *EvrMatchPresence, stat
 
 	blueTeam := teams[evr.TeamBlue]
 	orangeTeam := teams[evr.TeamOrange]
-	playerpop := len(blueTeam) + len(orangeTeam)
+	// Slots held for other players placed by the matchmaker are taken.
+	reserved := state.reservedSlots(presence.GetSessionId())
+	playerpop := len(blueTeam) + len(orangeTeam) + reserved
 	spectators := len(teams[evr.TeamSpectator]) + len(teams[evr.TeamModerator])
 	teamsFull := playerpop >= state.TeamSize*2
 	specsFull := spectators >= int(state.MaxSize)-state.TeamSize*2
 
-	if len(state.presences) >= MatchMaxSize {
+	if len(state.presences)+reserved >= MatchMaxSize {
 		// Lobby full, reject.
 		return evr.TeamUnassigned, false
 	}
@@ -481,12 +503,8 @@ func selectTeamForPlayer(logger runtime.Logger, presence *EvrMatchPresence, stat
 		}
 	}
 
-	// Assign them to the lowest population team
-	if len(blueTeam) < len(orangeTeam) {
-		t = evr.TeamBlue
-	} else {
-		t = evr.TeamOrange
-	}
+	// Assign them according to the channel's balancing policy.
+	t = state.balance.pickTeam(presence, teams, state.TeamSize)
 	logger.Debug("picked team", zap.Int("team", t))
 	return t, true
 }
@@ -496,6 +514,8 @@ const (
 	ErrJoinRejectedDuplicateJoin   = "duplicate join"
 	ErrJoinRejectedLobbyFull       = "lobby full"
 	ErrJoinRejectedNotModerator    = "not a moderator"
+	ErrJoinRejectedNotInvited      = "not invited"
+	ErrJoinRejectedPassword        = "lobby password required"
 )
 
 // MatchJoinAttempt decides whether to accept or deny the player session.
@@ -524,6 +544,14 @@ func (m *EvrMatch) MatchJoinAttempt(ctx context.Context, logger runtime.Logger,
 		return state, false, ErrJoinRejectedUnassignedLobby
 	}
 
+	// Invite-only and password protected matches require an invite (e.g. from redeeming a lobby code).
+	if !state.isInvited(presence.GetUserId()) {
+		if state.InviteOnly {
+			return state, false, ErrJoinRejectedNotInvited
+		}
+		return state, false, ErrJoinRejectedPassword
+	}
+
 	// Verify this isn't a duplicate. It will crash the server if they are allowed to join.
 	if _, ok := state.presences[presence.GetSessionId()]; ok {
 		logger.Warn("Duplicate join attempt.")
@@ -556,6 +584,8 @@ func (m *EvrMatch) MatchJoinAttempt(ctx context.Context, logger runtime.Logger,
 		}
 	}
 
+	mp.JoinedAt = time.Now()
+
 	if mp.TeamIndex, ok = selectTeamForPlayer(logger, mp, state); !ok {
 		// The lobby is full, reject the player.
 		return state, false, ErrJoinRejectedLobbyFull
@@ -576,7 +606,8 @@ func (m *EvrMatch) MatchJoinAttempt(ctx context.Context, logger runtime.Logger,
 			}
 		}
 	}
-	// Reserve this player's spot in the match.
+	// Reserve this player's spot in the match, releasing any slot held for them by the matchmaker.
+	delete(state.backfillReservations, mp.GetSessionId())
 	state.presences[mp.GetSessionId()] = mp
 	state.presenceByPlayerSession[mp.GetPlayerSession()] = mp
 	state.presenceByEvrId[mp.GetEvrId()] = mp
@@ -651,6 +682,18 @@ func (m *EvrMatch) MatchJoin(ctx context.Context, logger runtime.Logger, db *sql
 			return errors.New("player not in cache")
 		}
 
+		if state.levelVoteOpen() {
+			// The player will be started once the level has been chosen.
+			if err := m.notifyLevelVote(ctx, nk, state, matchPresence.GetUserId()); err != nil {
+				logger.Warn("failed to send level vote notification: %v", err)
+			}
+			continue
+		}
+
+		if state.usage != nil {
+			state.usage.addPlayer(matchPresence.GetUserId(), matchPresence.RTT)
+		}
+
 		// Send this after the function returns to ensure the match is ready to receive the player.
 		err := m.sendPlayerStart(ctx, logger, dispatcher, state, matchPresence)
 		if err != nil {
@@ -664,6 +707,7 @@ func (m *EvrMatch) MatchJoin(ctx context.Context, logger runtime.Logger, db *sql
 	if err != nil {
 		logger.Error("failed to update label: %v", err)
 	}
+	m.updateBackfill(logger, nk, state)
 	return state
 }
 
@@ -707,6 +751,8 @@ func (m *EvrMatch) MatchLeave(ctx context.Context, logger runtime.Logger, db *sq
 	for _, p := range presences {
 		if p.GetSessionId() == state.Broadcaster.SessionID {
 			logger.Debug("Broadcaster left the match. Shutting down.")
+			m.recordUsage(logger, nk, state, p.GetReason() == runtime.PresenceReasonDisconnect)
+			m.removeBackfill(logger, nk, state)
 			return nil
 		}
 	}
@@ -751,6 +797,7 @@ func (m *EvrMatch) MatchLeave(ctx context.Context, logger runtime.Logger, db *sq
 	if err != nil {
 		logger.Error("failed to update label: %v", err)
 	}
+	m.updateBackfill(logger, nk, state)
 
 	return state
 }
@@ -777,6 +824,11 @@ func (m *EvrMatch) MatchLoop(ctx context.Context, logger runtime.Logger, db *sql
 	}
 	emptySecs := state.emptyTicks / state.tickRate
 
+	// Offer the slots of placed players that did not join back to the matchmaker.
+	if int(tick)%state.tickRate == 0 && state.expireBackfillReservations(time.Now()) {
+		m.updateBackfill(logger, nk, state)
+	}
+
 	// Every 5 seconds, check the match state.
 	// If there are any missing or stale presences, shut down the match.
 	if int(tick)%(30*state.tickRate) == 0 {
@@ -785,6 +837,7 @@ func (m *EvrMatch) MatchLoop(ctx context.Context, logger runtime.Logger, db *sql
 		if state.StartedAt.Before(time.Now().Add(-60*time.Second)) && state.LobbyType != UnassignedLobby && len(state.presences) == 0 {
 			// If the match is not a parking match, and there are no players, shut down the match.
 			logger.Error("Match is empty. Shutting down.")
+			m.recordUsage(logger, nk, state, false)
 			return nil
 		}
 
@@ -802,6 +855,20 @@ func (m *EvrMatch) MatchLoop(ctx context.Context, logger runtime.Logger, db *sql
 		}
 	}
 
+	// Start the session once the level vote has finished.
+	if state.levelVoteComplete(time.Now()) {
+		state.Level = state.levelVoteWinner()
+		logger.Debug("Level vote complete, starting %s: %v", state.Level.Token(), state.VoteTally)
+		if state, err = m.StartSession(ctx, logger, nk, dispatcher, state); err != nil {
+			logger.Error("failed to start session: %v", err)
+		}
+		for _, presence := range state.presences {
+			if err := m.sendPlayerStart(ctx, logger, dispatcher, state, presence); err != nil {
+				logger.Error("failed to send player start: %v", err)
+			}
+		}
+	}
+
 	// Handle the messages, one by one
 	for _, in := range messages {
 		switch in.GetOpCode() {
@@ -867,6 +934,8 @@ func (m *EvrMatch) MatchTerminate(ctx context.Context, logger runtime.Logger, db
 		return nil
 	}
 	logger.Info("MatchTerminate called. %v", state)
+	m.recordUsage(logger, nk, state, false)
+	m.removeBackfill(logger, nk, state)
 	if state.broadcaster != nil {
 		// Disconnect the broadcasters session
 		//nk.SessionDisconnect(ctx, state.broadcaster.GetSessionId(), runtime.PresenceReasonDisconnect)
@@ -894,6 +963,20 @@ func (m *EvrMatch) MatchSignal(ctx context.Context, logger runtime.Logger, db *s
 	case SignalTerminate:
 		return nil, "terminating match"
 
+	case SignalDrain:
+		if state.drain() {
+			// Parking matches end immediately, disconnecting the broadcaster.
+			if err := nk.SessionDisconnect(ctx, state.Broadcaster.SessionID, runtime.PresenceReasonDisconnect); err != nil {
+				logger.Warn("failed to disconnect draining broadcaster: %v", err)
+			}
+			return nil, "drained"
+		}
+		if err := m.updateLabel(dispatcher, state); err != nil {
+			return state, fmt.Sprintf("failed to update label: %v", err)
+		}
+		m.updateBackfill(logger, nk, state)
+		return state, "draining"
+
 	case SignalPruneUnderutilized:
 		// Prune this match if it's utilization is low.
 		if len(state.presences) <= 3 {
@@ -947,7 +1030,22 @@ func (m *EvrMatch) MatchSignal(ctx context.Context, logger runtime.Logger, db *s
 		state.Open = newState.Open
 		state.SessionSettings = newState.SessionSettings
 		state.LevelSelection = newState.LevelSelection
+		state.Levels = newState.Levels
+		state.VoteTally = nil
+		state.VoteEndsAt = nil
+		state.votes = nil
+		state.InviteOnly = false
+		state.passwordProtected = false
+		state.invites = nil
 		state.teamAlignments = make(map[evr.EvrId]int, MatchMaxSize)
+		state.Rebalance = nil
+		state.usage = newBroadcasterMatchUsage(state.Broadcaster.OperatorID, time.Now())
+		state.balance = TeamBalanceSettings{}
+		if state.Channel != nil {
+			if state.balance, err = loadTeamBalanceSettings(ctx, nk, state.Channel.String()); err != nil {
+				logger.Warn("Failed to load team balance settings: %v", err)
+			}
+		}
 		if state.Level == 0xffffffffffffffff {
 			// The level is not set, set it to zero
 			state.Level = 0
@@ -960,8 +1058,80 @@ func (m *EvrMatch) MatchSignal(ctx context.Context, logger runtime.Logger, db *s
 
 		return state, "session prepared"
 
+	case SignalRoundBreak:
+		// Suggest moves to even out the teams between rounds.
+		state.Rebalance = state.balance.suggestRebalance(lo.Values(state.presences), state.TeamSize, time.Now())
+		if err := m.updateLabel(dispatcher, state); err != nil {
+			logger.Error("failed to update label: %v", err)
+		}
+		if state.Rebalance == nil {
+			return state, "teams balanced"
+		}
+		if err := m.notifyRebalance(ctx, nk, state); err != nil {
+			logger.Warn("Failed to send rebalance suggestion to the broadcaster's operator: %v", err)
+		}
+		jsonData, err := json.Marshal(state.Rebalance)
+		if err != nil {
+			return state, fmt.Sprintf("failed to marshal rebalance suggestion: %v", err)
+		}
+		return state, string(jsonData)
+
+	case SignalLevelVote:
+		vote := &LevelVote{}
+		if err := json.Unmarshal(signal.Data, vote); err != nil {
+			return state, fmt.Sprintf("failed to unmarshal vote: %v", err)
+		}
+		if err := state.castLevelVote(vote.UserID, vote.Level); err != nil {
+			return state, err.Error()
+		}
+		if err := m.updateLabel(dispatcher, state); err != nil {
+			logger.Error("failed to update label: %v", err)
+		}
+		jsonData, err := json.Marshal(state.VoteTally)
+		if err != nil {
+			return state, fmt.Sprintf("failed to marshal vote tally: %v", err)
+		}
+		return state, string(jsonData)
+
+	case SignalBackfillReserve:
+		reservation := BackfillReservation{}
+		if err := json.Unmarshal(signal.Data, &reservation); err != nil {
+			return state, fmt.Sprintf("failed to unmarshal reservation: %v", err)
+		}
+		state.reserveBackfill(reservation.SessionIDs, time.Now())
+		m.updateBackfill(logger, nk, state)
+		return state, "slots reserved"
+
+	case SignalLobbyInvite:
+		if state.LobbyType != PrivateLobby {
+			return state, "not a private match"
+		}
+		invite := LobbyInvite{}
+		if err := json.Unmarshal(signal.Data, &invite); err != nil {
+			return state, fmt.Sprintf("failed to unmarshal invite: %v", err)
+		}
+		state.applyLobbyInvite(invite)
+		if err := m.updateLabel(dispatcher, state); err != nil {
+			logger.Error("failed to update label: %v", err)
+		}
+		return state, "invites updated"
+
 	case SignalStartSession:
 
+		if state.LevelSelection == LevelSelectionVote && len(state.Levels) > 0 && !state.Started && state.VoteEndsAt == nil {
+			// Let the players choose the level before starting the session.
+			state.openLevelVote(time.Now())
+			if err := m.updateLabel(dispatcher, state); err != nil {
+				logger.Error("failed to update label: %v", err)
+			}
+			if userIDs := lo.Map(lo.Values(state.presences), func(p *EvrMatchPresence, _ int) string { return p.GetUserId() }); len(userIDs) > 0 {
+				if err := m.notifyLevelVote(ctx, nk, state, userIDs...); err != nil {
+					logger.Warn("failed to send level vote notification: %v", err)
+				}
+			}
+			return state, "vote started"
+		}
+
 		// Tell the broadcaster to start the session.
 		state, err := m.StartSession(ctx, logger, nk, dispatcher, state)
 		if err != nil {
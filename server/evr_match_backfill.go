@@ -0,0 +1,162 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/heroiclabs/nakama/v3/server/evr"
+	"go.uber.org/zap"
+)
+
+// BackfillReservationTimeout is how long a slot taken by a player the matchmaker placed into a match is held for
+// them to join.
+const BackfillReservationTimeout = 30 * time.Second
+
+// BackfillReservation is the payload of a SignalBackfillReserve signal.
+type BackfillReservation struct {
+	SessionIDs []string `json:"session_ids"` // The sessions the matchmaker placed into the match.
+}
+
+// OpenPlayerSlots returns the number of players that may still be added to a public match in progress by the
+// matchmaker, less the slots held for players already placed into it. Empty matches are left to the matchmaker to
+// fill with newly formed matches.
+func (s *EvrMatchState) OpenPlayerSlots() int {
+	if s.LobbyType != PublicLobby || s.Channel == nil || s.Broadcaster.Draining || s.Size == 0 {
+		return 0
+	}
+
+	limit := int(s.MaxSize)
+	if s.TeamSize > 0 {
+		limit = s.TeamSize * 2
+	}
+	reserved := s.reservedSlots("")
+	open := limit - s.Size - reserved
+	if free := MatchMaxSize - len(s.presences) - reserved; free < open {
+		open = free
+	}
+	if open < 0 {
+		return 0
+	}
+	return open
+}
+
+// reserveBackfill holds a slot for each placed session that has not joined yet, until it joins or the reservation
+// expires.
+func (s *EvrMatchState) reserveBackfill(sessionIDs []string, now time.Time) {
+	if s.backfillReservations == nil {
+		s.backfillReservations = make(map[string]time.Time, len(sessionIDs))
+	}
+	for _, sessionID := range sessionIDs {
+		if _, found := s.presences[sessionID]; found {
+			continue
+		}
+		s.backfillReservations[sessionID] = now.Add(BackfillReservationTimeout)
+	}
+}
+
+// expireBackfillReservations releases the slots held for placed sessions that did not join in time, and reports
+// whether any were released.
+func (s *EvrMatchState) expireBackfillReservations(now time.Time) bool {
+	var expired bool
+	for sessionID, expiry := range s.backfillReservations {
+		if now.After(expiry) {
+			delete(s.backfillReservations, sessionID)
+			expired = true
+		}
+	}
+	return expired
+}
+
+// reservedSlots returns the number of slots held for placed sessions, other than the given session's own.
+func (s *EvrMatchState) reservedSlots(sessionID string) int {
+	reserved := len(s.backfillReservations)
+	if _, found := s.backfillReservations[sessionID]; found {
+		reserved--
+	}
+	return reserved
+}
+
+// backfillProperties returns the match properties matched against matchmaking ticket queries. They mirror the
+// properties set on tickets in BuildQuery.
+func (s *EvrMatchState) backfillProperties() map[string]string {
+	stringProperties := map[string]string{
+		"mode":    s.Mode.Token().String(),
+		"channel": strings.ReplaceAll(s.Channel.String(), "-", ""),
+	}
+	for _, channel := range s.Broadcaster.Channels {
+		stringProperties[strings.ReplaceAll(channel.String(), "-", "")] = "T"
+	}
+	return stringProperties
+}
+
+// updateBackfill offers the match's open player slots to the matchmaker, or withdraws them if there are none.
+func (m *EvrMatch) updateBackfill(logger runtime.Logger, nk runtime.NakamaModule, state *EvrMatchState) {
+	slots := state.OpenPlayerSlots()
+	if slots == 0 {
+		m.removeBackfill(logger, nk, state)
+		return
+	}
+	goNk, ok := nk.(*RuntimeGoNakamaModule)
+	if !ok {
+		return
+	}
+	if err := goNk.MatchmakerBackfillSet(state.ID(), slots, "", state.backfillProperties(), nil); err != nil {
+		logger.Warn("failed to update matchmaker backfill: %v", err)
+	}
+}
+
+// removeBackfill withdraws the match's open player slots from the matchmaker.
+func (m *EvrMatch) removeBackfill(logger runtime.Logger, nk runtime.NakamaModule, state *EvrMatchState) {
+	goNk, ok := nk.(*RuntimeGoNakamaModule)
+	if !ok {
+		return
+	}
+	if err := goNk.MatchmakerBackfillRemove(state.ID()); err != nil {
+		logger.Warn("failed to remove matchmaker backfill: %v", err)
+	}
+}
+
+func (mr *MatchmakingRegistry) backfilledEntriesFn(backfilled []*MatchmakerBackfillMatch) {
+	for _, match := range backfilled {
+		// Hold the slots for the placed players until they join, so the match does not offer them again meanwhile.
+		reservation := BackfillReservation{SessionIDs: make([]string, 0, len(match.Entries))}
+		for _, entry := range match.Entries {
+			reservation.SessionIDs = append(reservation.SessionIDs, entry.Presence.SessionId)
+		}
+		if _, err := SignalMatch(mr.ctx, mr.matchRegistry, match.MatchId, SignalBackfillReserve, reservation); err != nil {
+			mr.logger.Warn("Failed to reserve backfill slots", zap.String("mid", match.MatchId), zap.Error(err))
+		}
+
+		for _, entry := range match.Entries {
+			logger := mr.logger.With(zap.String("mid", match.MatchId), zap.String("sessionID", entry.Presence.SessionID.String()))
+			s, ok := mr.GetMatchingBySessionId(entry.Presence.SessionID)
+			if !ok {
+				logger.Warn("Could not find matching session for user")
+				continue
+			}
+			ticketMeta, ok := s.Tickets[entry.GetTicket()]
+			if !ok {
+				logger.Warn("Could not find ticket metadata for user", zap.String("ticket", entry.GetTicket()))
+				continue
+			}
+
+			// The match confirms the player in its join attempt, a rejected player is put back into the queue.
+			foundMatch := FoundMatch{
+				MatchID:   match.MatchId,
+				Ticket:    ticketMeta.TicketID,
+				Query:     ticketMeta.Query,
+				TeamIndex: TeamIndex(evr.TeamUnassigned),
+				Backfill:  true,
+			}
+			select {
+			case <-s.Ctx.Done():
+				logger.Warn("Matchmaking session cancelled")
+			case s.MatchJoinCh <- foundMatch:
+				logger.Info("Sent backfill join instruction")
+			case <-time.After(2 * time.Second):
+				logger.Warn("Failed to send backfill join instruction")
+			}
+		}
+	}
+}
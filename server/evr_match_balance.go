@@ -0,0 +1,253 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/heroiclabs/nakama/v3/server/evr"
+	"github.com/samber/lo"
+)
+
+// The notification code used to send team rebalance suggestions to the broadcaster's operator.
+const TeamRebalanceNotificationCode = 1102
+
+type TeamBalanceMode string
+
+const (
+	TeamBalanceModeSize   TeamBalanceMode = "size"   // Fill the smaller team (default).
+	TeamBalanceModeRating TeamBalanceMode = "rating" // Fill the smaller team, breaking ties by team rating.
+)
+
+// TeamBalanceSettings is the per-channel team balancing policy. It is stored in the guild group's metadata.
+type TeamBalanceSettings struct {
+	Mode                TeamBalanceMode `json:"mode,omitempty"`                  // The balancing mode
+	KeepPartiesTogether bool            `json:"keep_parties_together,omitempty"` // Place party members on the same team when there is room
+	TenureWeight        float64         `json:"tenure_weight,omitempty"`         // Rating bonus per minute in the match (caps at 10 minutes)
+	RebalanceThreshold  float64         `json:"rebalance_threshold,omitempty"`   // The team rating difference that triggers a rebalance suggestion (0 disables)
+}
+
+// TeamRebalanceMove is a single player move suggested at a round break.
+type TeamRebalanceMove struct {
+	EvrID    evr.EvrId `json:"evr_id"`
+	UserID   string    `json:"user_id"`
+	FromTeam TeamIndex `json:"from"`
+	ToTeam   TeamIndex `json:"to"`
+}
+
+// TeamRebalanceSuggestion is computed at a round break, published in the match label and sent to the broadcaster's
+// operator. The game server protocol has no message for it, so tooling that isn't connected as the operator must
+// poll the label.
+type TeamRebalanceSuggestion struct {
+	CreatedAt time.Time           `json:"created_at"`
+	Before    float64             `json:"before"` // The team rating difference before the moves
+	After     float64             `json:"after"`  // The team rating difference after the moves
+	Moves     []TeamRebalanceMove `json:"moves"`
+}
+
+// profileRating returns a coarse skill estimate from the player's arena statistics.
+func profileRating(profile evr.ServerProfile) float64 {
+	arena := profile.Statistics.Arena
+	return float64(arena.Level.Value) + arena.ArenaWinPercentage.Value
+}
+
+// loadTeamBalanceSettings reads the team balancing policy from the channel's guild group metadata.
+func loadTeamBalanceSettings(ctx context.Context, nk runtime.NakamaModule, channelID string) (TeamBalanceSettings, error) {
+	settings := TeamBalanceSettings{}
+	groups, err := nk.GroupsGetId(ctx, []string{channelID})
+	if err != nil {
+		return settings, fmt.Errorf("failed to get group: %w", err)
+	}
+	if len(groups) == 0 {
+		return settings, fmt.Errorf("group not found: %s", channelID)
+	}
+	md := &GroupMetadata{}
+	if err := json.Unmarshal([]byte(groups[0].GetMetadata()), md); err != nil {
+		return settings, fmt.Errorf("failed to unmarshal group metadata: %w", err)
+	}
+	if md.TeamBalance != nil {
+		settings = *md.TeamBalance
+	}
+	return settings, nil
+}
+
+// weight returns the balancing weight of a player in the match.
+func (s TeamBalanceSettings) weight(p *EvrMatchPresence, now time.Time) float64 {
+	w := p.Rating
+	if s.TenureWeight > 0 && !p.JoinedAt.IsZero() {
+		w += s.TenureWeight * math.Min(now.Sub(p.JoinedAt).Minutes(), 10)
+	}
+	return w
+}
+
+func (s TeamBalanceSettings) teamWeight(team []*EvrMatchPresence, now time.Time) float64 {
+	total := 0.0
+	for _, p := range team {
+		total += s.weight(p, now)
+	}
+	return total
+}
+
+// pickTeam selects blue or orange for a player that may go on either team.
+func (s TeamBalanceSettings) pickTeam(presence *EvrMatchPresence, teams map[int][]*EvrMatchPresence, teamSize int) int {
+	blueTeam := teams[evr.TeamBlue]
+	orangeTeam := teams[evr.TeamOrange]
+
+	if s.Mode != TeamBalanceModeRating && !s.KeepPartiesTogether {
+		// Assign them to the lowest population team
+		if len(blueTeam) < len(orangeTeam) {
+			return evr.TeamBlue
+		}
+		return evr.TeamOrange
+	}
+
+	// Join the party's team if it has room, and it would not leave the teams lopsided.
+	if s.KeepPartiesTogether && !presence.PartyID.IsNil() {
+		for _, t := range []int{evr.TeamBlue, evr.TeamOrange} {
+			other := evr.TeamOrange
+			if t == evr.TeamOrange {
+				other = evr.TeamBlue
+			}
+			if len(teams[t]) >= teamSize || len(teams[t]) > len(teams[other]) {
+				continue
+			}
+			if lo.ContainsBy(teams[t], func(p *EvrMatchPresence) bool { return p.PartyID == presence.PartyID }) {
+				return t
+			}
+		}
+	}
+
+	switch {
+	case len(blueTeam) < len(orangeTeam):
+		return evr.TeamBlue
+	case len(orangeTeam) < len(blueTeam):
+		return evr.TeamOrange
+	}
+
+	if s.Mode == TeamBalanceModeRating {
+		now := time.Now()
+		blue, orange := s.teamWeight(blueTeam, now), s.teamWeight(orangeTeam, now)
+		if blue < orange {
+			return evr.TeamBlue
+		} else if orange < blue {
+			return evr.TeamOrange
+		}
+	}
+
+	if presence.TeamIndex == evr.TeamBlue || presence.TeamIndex == evr.TeamOrange {
+		return presence.TeamIndex
+	}
+	return evr.TeamOrange
+}
+
+// suggestRebalance proposes player moves that reduce the team rating difference.
+// Players that joined most recently are moved first, and parties are not split.
+// It returns nil if the teams are within the threshold.
+func (s TeamBalanceSettings) suggestRebalance(presences []*EvrMatchPresence, teamSize int, now time.Time) *TeamRebalanceSuggestion {
+	if s.RebalanceThreshold <= 0 {
+		return nil
+	}
+
+	teams := lo.GroupBy(presences, func(p *EvrMatchPresence) int { return p.TeamIndex })
+	blue, orange := teams[evr.TeamBlue], teams[evr.TeamOrange]
+	diff := s.teamWeight(blue, now) - s.teamWeight(orange, now)
+	if math.Abs(diff) < s.RebalanceThreshold {
+		return nil
+	}
+
+	suggestion := &TeamRebalanceSuggestion{
+		CreatedAt: now,
+		Before:    math.Abs(diff),
+		Moves:     make([]TeamRebalanceMove, 0),
+	}
+
+	// Consider the newest players first.
+	candidates := append(append(make([]*EvrMatchPresence, 0, len(blue)+len(orange)), blue...), orange...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].JoinedAt.After(candidates[j].JoinedAt)
+	})
+
+	// Swap pairs of players while it improves the difference.
+	moved := make(map[string]bool, len(candidates))
+	for _, a := range candidates {
+		if moved[a.GetSessionId()] || a.TeamIndex != evr.TeamBlue || !a.PartyID.IsNil() && s.KeepPartiesTogether {
+			continue
+		}
+		var best *EvrMatchPresence
+		bestDiff := math.Abs(diff)
+		for _, b := range candidates {
+			if moved[b.GetSessionId()] || b.TeamIndex != evr.TeamOrange || !b.PartyID.IsNil() && s.KeepPartiesTogether {
+				continue
+			}
+			d := math.Abs(diff - 2*(s.weight(a, now)-s.weight(b, now)))
+			if d < bestDiff {
+				best, bestDiff = b, d
+			}
+		}
+		if best == nil {
+			continue
+		}
+		diff -= 2 * (s.weight(a, now) - s.weight(best, now))
+		moved[a.GetSessionId()], moved[best.GetSessionId()] = true, true
+		suggestion.Moves = append(suggestion.Moves,
+			TeamRebalanceMove{EvrID: a.EvrID, UserID: a.GetUserId(), FromTeam: TeamIndex(evr.TeamBlue), ToTeam: TeamIndex(evr.TeamOrange)},
+			TeamRebalanceMove{EvrID: best.EvrID, UserID: best.GetUserId(), FromTeam: TeamIndex(evr.TeamOrange), ToTeam: TeamIndex(evr.TeamBlue)},
+		)
+		if math.Abs(diff) < s.RebalanceThreshold {
+			break
+		}
+	}
+
+	// Move a single player if the teams are uneven.
+	if math.Abs(float64(len(blue)-len(orange))) > 1 {
+		from, to := evr.TeamBlue, evr.TeamOrange
+		if len(orange) > len(blue) {
+			from, to = evr.TeamOrange, evr.TeamBlue
+		}
+		for _, p := range candidates {
+			if moved[p.GetSessionId()] || p.TeamIndex != from || len(teams[to]) >= teamSize {
+				continue
+			}
+			moved[p.GetSessionId()] = true
+			if from == evr.TeamBlue {
+				diff -= 2 * s.weight(p, now)
+			} else {
+				diff += 2 * s.weight(p, now)
+			}
+			suggestion.Moves = append(suggestion.Moves, TeamRebalanceMove{EvrID: p.EvrID, UserID: p.GetUserId(), FromTeam: TeamIndex(from), ToTeam: TeamIndex(to)})
+			break
+		}
+	}
+
+	if len(suggestion.Moves) == 0 {
+		return nil
+	}
+	suggestion.After = math.Abs(diff)
+	return suggestion
+}
+
+// TeamRebalanceNotification is sent to the broadcaster's operator when a rebalance is suggested.
+type TeamRebalanceNotification struct {
+	MatchID    string                   `json:"match_id"`
+	Suggestion *TeamRebalanceSuggestion `json:"suggestion"`
+}
+
+// notifyRebalance sends the match's rebalance suggestion to the broadcaster's operator.
+func (m *EvrMatch) notifyRebalance(ctx context.Context, nk runtime.NakamaModule, state *EvrMatchState) error {
+	if state.Rebalance == nil || state.Broadcaster.OperatorID == "" {
+		return nil
+	}
+	data, err := json.Marshal(TeamRebalanceNotification{MatchID: state.ID(), Suggestion: state.Rebalance})
+	if err != nil {
+		return err
+	}
+	content := make(map[string]interface{})
+	if err := json.Unmarshal(data, &content); err != nil {
+		return err
+	}
+	return nk.NotificationSend(ctx, state.Broadcaster.OperatorID, "team_rebalance", content, TeamRebalanceNotificationCode, "", false)
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/heroiclabs/nakama/v3/server/evr"
 )
 
@@ -292,3 +294,245 @@ func TestSelectTeamForPlayer(t *testing.T) {
 		})
 	}
 }
+
+func TestTeamBalanceSettings_PickTeam(t *testing.T) {
+	party := uuid.Must(uuid.NewV4())
+
+	tests := []struct {
+		name     string
+		settings TeamBalanceSettings
+		presence *EvrMatchPresence
+		teams    map[int][]*EvrMatchPresence
+		want     int
+	}{
+		{
+			name:     "default fills the smaller team",
+			settings: TeamBalanceSettings{},
+			presence: &EvrMatchPresence{TeamIndex: evr.TeamOrange},
+			teams: map[int][]*EvrMatchPresence{
+				evr.TeamBlue:   {{}},
+				evr.TeamOrange: {{}, {}},
+			},
+			want: evr.TeamBlue,
+		},
+		{
+			name:     "party members are kept together",
+			settings: TeamBalanceSettings{KeepPartiesTogether: true},
+			presence: &EvrMatchPresence{PartyID: party},
+			teams: map[int][]*EvrMatchPresence{
+				evr.TeamBlue:   {{}, {}},
+				evr.TeamOrange: {{}, {PartyID: party}},
+			},
+			want: evr.TeamOrange,
+		},
+		{
+			name:     "party members do not overfill a team",
+			settings: TeamBalanceSettings{KeepPartiesTogether: true},
+			presence: &EvrMatchPresence{PartyID: party},
+			teams: map[int][]*EvrMatchPresence{
+				evr.TeamBlue:   {{}},
+				evr.TeamOrange: {{}, {PartyID: party}},
+			},
+			want: evr.TeamBlue,
+		},
+		{
+			name:     "rating breaks ties",
+			settings: TeamBalanceSettings{Mode: TeamBalanceModeRating},
+			presence: &EvrMatchPresence{TeamIndex: evr.TeamOrange},
+			teams: map[int][]*EvrMatchPresence{
+				evr.TeamBlue:   {{Rating: 10}, {Rating: 20}},
+				evr.TeamOrange: {{Rating: 30}, {Rating: 40}},
+			},
+			want: evr.TeamBlue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.pickTeam(tt.presence, tt.teams, 4); got != tt.want {
+				t.Errorf("pickTeam() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamBalanceSettings_SuggestRebalance(t *testing.T) {
+	now := time.Now()
+	newPresence := func(team int, rating float64, joined time.Duration) *EvrMatchPresence {
+		return &EvrMatchPresence{
+			SessionID: uuid.Must(uuid.NewV4()),
+			UserID:    uuid.Must(uuid.NewV4()),
+			TeamIndex: team,
+			Rating:    rating,
+			JoinedAt:  now.Add(-joined),
+		}
+	}
+
+	presences := []*EvrMatchPresence{
+		newPresence(evr.TeamBlue, 50, 10*time.Minute),
+		newPresence(evr.TeamBlue, 40, time.Minute),
+		newPresence(evr.TeamOrange, 10, 10*time.Minute),
+		newPresence(evr.TeamOrange, 20, 2*time.Minute),
+	}
+
+	settings := TeamBalanceSettings{Mode: TeamBalanceModeRating}
+	if got := settings.suggestRebalance(presences, 4, now); got != nil {
+		t.Fatalf("suggestRebalance() with rebalancing disabled = %v, want nil", got)
+	}
+
+	settings.RebalanceThreshold = 15
+	got := settings.suggestRebalance(presences, 4, now)
+	if got == nil {
+		t.Fatal("suggestRebalance() = nil, want a suggestion")
+	}
+	if got.Before != 60 || got.After != 0 {
+		t.Errorf("suggestRebalance() before/after = %v/%v, want 60/0", got.Before, got.After)
+	}
+	if len(got.Moves) != 2 || got.Moves[0].UserID != presences[1].GetUserId() || got.Moves[1].UserID != presences[2].GetUserId() {
+		t.Errorf("suggestRebalance() moves = %+v, want the newest blue player swapped", got.Moves)
+	}
+
+	settings.RebalanceThreshold = 100
+	if got := settings.suggestRebalance(presences, 4, now); got != nil {
+		t.Errorf("suggestRebalance() within threshold = %v, want nil", got)
+	}
+}
+
+func TestEvrMatchState_LevelVote(t *testing.T) {
+	alice, bob := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	state := &EvrMatchState{
+		LevelSelection: LevelSelectionVote,
+		Levels:         []Level{Level(evr.LevelArena), Level(evr.LevelSocial)},
+		presences: map[string]*EvrMatchPresence{
+			"alice": {UserID: alice},
+			"bob":   {UserID: bob},
+		},
+	}
+
+	if err := state.castLevelVote(alice.String(), evr.LevelSocial); err != ErrLevelVoteNotOpen {
+		t.Fatalf("castLevelVote() before the vote opened = %v, want %v", err, ErrLevelVoteNotOpen)
+	}
+
+	now := time.Now()
+	state.openLevelVote(now)
+
+	if err := state.castLevelVote(uuid.Must(uuid.NewV4()).String(), evr.LevelSocial); err != ErrLevelVoteNotInMatch {
+		t.Errorf("castLevelVote() from outside the match = %v, want %v", err, ErrLevelVoteNotInMatch)
+	}
+	if err := state.castLevelVote(alice.String(), evr.LevelFission); err != ErrLevelVoteNotACandidate {
+		t.Errorf("castLevelVote() for a non-candidate = %v, want %v", err, ErrLevelVoteNotACandidate)
+	}
+
+	// A tie goes to the first candidate.
+	if err := state.castLevelVote(alice.String(), evr.LevelSocial); err != nil {
+		t.Fatal(err)
+	}
+	if state.levelVoteComplete(now) {
+		t.Error("levelVoteComplete() = true before everyone voted")
+	}
+	if err := state.castLevelVote(bob.String(), evr.LevelArena); err != nil {
+		t.Fatal(err)
+	}
+	if !state.levelVoteComplete(now) {
+		t.Error("levelVoteComplete() = false after everyone voted")
+	}
+	if got := state.levelVoteWinner(); got != evr.LevelArena {
+		t.Errorf("levelVoteWinner() with a tie = %s, want %s", got.Token(), evr.LevelArena.Token())
+	}
+
+	// Changing a vote moves it in the tally.
+	if err := state.castLevelVote(bob.String(), evr.LevelSocial); err != nil {
+		t.Fatal(err)
+	}
+	if got := state.VoteTally[evr.LevelSocial.Token().String()]; got != 2 {
+		t.Errorf("VoteTally[social] = %d, want 2", got)
+	}
+	if got := state.levelVoteWinner(); got != evr.LevelSocial {
+		t.Errorf("levelVoteWinner() = %s, want %s", got.Token(), evr.LevelSocial.Token())
+	}
+}
+
+func TestEvrMatchState_Drain(t *testing.T) {
+	parking := &EvrMatchState{LobbyType: UnassignedLobby}
+	if !parking.drain() {
+		t.Error("expected a parking match to end immediately")
+	}
+	if !parking.Broadcaster.Draining {
+		t.Error("expected the broadcaster to be draining")
+	}
+
+	active := &EvrMatchState{LobbyType: PublicLobby}
+	if active.drain() {
+		t.Error("expected an active match to end naturally")
+	}
+
+	data, err := json.Marshal(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := &EvrMatchState{}
+	if err := json.Unmarshal(data, label); err != nil {
+		t.Fatal(err)
+	}
+	if !label.Broadcaster.Draining {
+		t.Error("expected the label to include the draining flag")
+	}
+}
+
+func TestEvrMatchState_OpenPlayerSlots(t *testing.T) {
+	channel := uuid.Must(uuid.NewV4())
+	presences := func(n int) map[string]*EvrMatchPresence {
+		m := make(map[string]*EvrMatchPresence, n)
+		for i := 0; i < n; i++ {
+			m[uuid.Must(uuid.NewV4()).String()] = &EvrMatchPresence{}
+		}
+		return m
+	}
+
+	tests := []struct {
+		name  string
+		state *EvrMatchState
+		want  int
+	}{
+		{"arena", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 5, presences: presences(5)}, 3},
+		{"social", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, MaxSize: 12, Size: 10, presences: presences(10)}, 2},
+		{"spectators fill the lobby", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 6, presences: presences(11)}, 1},
+		{"empty", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12}, 0},
+		{"private", &EvrMatchState{LobbyType: PrivateLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 2, presences: presences(2)}, 0},
+		{"draining", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 2, presences: presences(2), Broadcaster: MatchBroadcaster{Draining: true}}, 0},
+		{"reserved", &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 5, presences: presences(5), backfillReservations: map[string]time.Time{"a": time.Now(), "b": time.Now()}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.OpenPlayerSlots(); got != tt.want {
+				t.Errorf("OpenPlayerSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvrMatchState_BackfillReservations(t *testing.T) {
+	channel := uuid.Must(uuid.NewV4())
+	state := &EvrMatchState{LobbyType: PublicLobby, Channel: &channel, TeamSize: 4, MaxSize: 12, Size: 1, presences: map[string]*EvrMatchPresence{"joined": {}}}
+	now := time.Now()
+
+	// Players that already joined are not held a second slot.
+	state.reserveBackfill([]string{"joined", "placed", "late"}, now)
+	if got := state.OpenPlayerSlots(); got != 5 {
+		t.Fatalf("OpenPlayerSlots() = %v, want 5", got)
+	}
+	if got := state.reservedSlots("placed"); got != 1 {
+		t.Errorf("reservedSlots() = %v, want 1", got)
+	}
+
+	delete(state.backfillReservations, "placed")
+	if state.expireBackfillReservations(now.Add(BackfillReservationTimeout / 2)) {
+		t.Error("expected the reservation to be held until it times out")
+	}
+	if !state.expireBackfillReservations(now.Add(BackfillReservationTimeout + time.Second)) {
+		t.Error("expected the reservation to expire")
+	}
+	if got := state.OpenPlayerSlots(); got != 7 {
+		t.Errorf("OpenPlayerSlots() = %v, want 7", got)
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+	"github.com/heroiclabs/nakama/v3/server/evr"
+	"github.com/samber/lo"
+)
+
+const (
+	LevelVoteDuration         = 30 * time.Second // How long players have to vote on the level.
+	LevelVoteNotificationCode = 1100             // The notification code used to send the candidate levels to players.
+)
+
+var (
+	ErrLevelVoteNotOpen       = errors.New("level vote is not open")
+	ErrLevelVoteNotInMatch    = errors.New("voter is not in the match")
+	ErrLevelVoteNotACandidate = errors.New("level is not a candidate")
+)
+
+// LevelVote is the payload of a SignalLevelVote signal.
+type LevelVote struct {
+	UserID string     `json:"user_id"`
+	Level  evr.Symbol `json:"level"`
+}
+
+// LevelVoteNotification is sent to players when a level vote opens.
+type LevelVoteNotification struct {
+	MatchID string       `json:"match_id"`
+	Levels  []evr.Symbol `json:"levels"`
+	EndsAt  time.Time    `json:"ends_at"`
+}
+
+// levelVoteOpen returns true if the match is collecting level votes.
+func (s *EvrMatchState) levelVoteOpen() bool {
+	return s.LevelSelection == LevelSelectionVote && !s.Started && s.VoteEndsAt != nil
+}
+
+// openLevelVote starts the level vote.
+func (s *EvrMatchState) openLevelVote(now time.Time) {
+	endsAt := now.Add(LevelVoteDuration)
+	s.VoteEndsAt = &endsAt
+	s.VoteTally = make(map[string]int, len(s.Levels))
+	for _, l := range s.Levels {
+		s.VoteTally[evr.Symbol(l).Token().String()] = 0
+	}
+	s.votes = make(map[string]evr.Symbol, MatchMaxSize)
+}
+
+// castLevelVote records (or replaces) a player's vote, and updates the tally.
+func (s *EvrMatchState) castLevelVote(userID string, level evr.Symbol) error {
+	if !s.levelVoteOpen() {
+		return ErrLevelVoteNotOpen
+	}
+	if !lo.ContainsBy(lo.Values(s.presences), func(p *EvrMatchPresence) bool { return p.GetUserId() == userID }) {
+		return ErrLevelVoteNotInMatch
+	}
+	if !lo.Contains(s.Levels, Level(level)) {
+		return ErrLevelVoteNotACandidate
+	}
+	if previous, ok := s.votes[userID]; ok {
+		s.VoteTally[previous.Token().String()]--
+	}
+	s.votes[userID] = level
+	s.VoteTally[level.Token().String()]++
+	return nil
+}
+
+// levelVoteComplete returns true once the vote has expired, or every player has voted.
+func (s *EvrMatchState) levelVoteComplete(now time.Time) bool {
+	if !s.levelVoteOpen() {
+		return false
+	}
+	if now.After(*s.VoteEndsAt) {
+		return true
+	}
+	return len(s.presences) > 0 && lo.EveryBy(lo.Values(s.presences), func(p *EvrMatchPresence) bool {
+		_, ok := s.votes[p.GetUserId()]
+		return ok
+	})
+}
+
+// levelVoteWinner returns the level with the most votes.
+// Ties are broken by the order of the candidate levels.
+func (s *EvrMatchState) levelVoteWinner() evr.Symbol {
+	if len(s.Levels) == 0 {
+		return s.Level
+	}
+	winner, most := evr.Symbol(s.Levels[0]), -1
+	for _, l := range s.Levels {
+		if n := s.VoteTally[evr.Symbol(l).Token().String()]; n > most {
+			winner, most = evr.Symbol(l), n
+		}
+	}
+	return winner
+}
+
+// notifyLevelVote sends the candidate levels to the given users.
+func (m *EvrMatch) notifyLevelVote(ctx context.Context, nk runtime.NakamaModule, state *EvrMatchState, userIDs ...string) error {
+	content := LevelVoteNotification{
+		MatchID: state.ID(),
+		Levels:  lo.Map(state.Levels, func(l Level, _ int) evr.Symbol { return evr.Symbol(l) }),
+		EndsAt:  lo.FromPtr(state.VoteEndsAt),
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	contentMap := make(map[string]interface{})
+	if err := json.Unmarshal(data, &contentMap); err != nil {
+		return err
+	}
+
+	notifications := make([]*runtime.NotificationSend, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notifications = append(notifications, &runtime.NotificationSend{
+			UserID:     userID,
+			Subject:    "level_vote",
+			Content:    contentMap,
+			Code:       LevelVoteNotificationCode,
+			Persistent: false,
+		})
+	}
+	return nk.NotificationsSend(ctx, notifications)
+}
+
+type levelVoteRequest struct {
+	MatchID string     `json:"match_id"`
+	Level   evr.Symbol `json:"level"`
+}
+
+type levelVoteResponse struct {
+	MatchID string         `json:"match_id"`
+	Tally   map[string]int `json:"tally"`
+}
+
+// levelVoteRpc submits the caller's vote for the level of a match. Only levels are voted on: the mode is set when the
+// lobby's session is prepared, and the levels offered are those of that mode.
+func levelVoteRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+
+	request := &levelVoteRequest{}
+	if err := json.Unmarshal([]byte(payload), request); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(LevelVote{UserID: userID, Level: request.Level})
+	if err != nil {
+		return "", err
+	}
+	signal := EvrSignal{
+		UserId: userID,
+		Signal: SignalLevelVote,
+		Data:   data,
+	}
+	result, err := nk.MatchSignal(ctx, request.MatchID, signal.String())
+	if err != nil {
+		return "", err
+	}
+
+	response := &levelVoteResponse{
+		MatchID: request.MatchID,
+	}
+	if err := json.Unmarshal([]byte(result), &response.Tally); err != nil {
+		return "", runtime.NewError(fmt.Sprintf("vote rejected: %s", result), StatusFailedPrecondition)
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
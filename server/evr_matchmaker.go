@@ -37,6 +37,9 @@ func (p *EvrPipeline) ListUnassignedLobbies(ctx context.Context, session *sessio
 	// MUST be an unassigned lobby
 	qparts = append(qparts, LobbyType(evr.UnassignedLobby).Query(Must, 0))
 
+	// MUST NOT be draining
+	qparts = append(qparts, DrainedQuery())
+
 	// MUST be one of the accessible channels (if provided)
 	if len(ml.Broadcaster.Channels) > 0 {
 		// Add the channels to the query
@@ -283,7 +286,7 @@ func (p *EvrPipeline) MatchMake(session *sessionWS, msession *MatchmakingSession
 
 	p.metrics.CustomCounter("matchmaker_tickets", tags, 1)
 	// Add the user to the matchmaker
-	ticket, _, err = session.matchmaker.Add(ctx, presences, sessionID.String(), pID, query, minCount, maxCount, countMultiple, stringProps, numericProps)
+	ticket, _, err = session.matchmaker.AddExpanding(ctx, presences, sessionID.String(), pID, query, gconfig.Expansion, minCount, maxCount, countMultiple, stringProps, numericProps)
 	if err != nil {
 		return "", fmt.Errorf("failed to add to matchmaker: %v", err)
 	}
@@ -649,7 +652,13 @@ func (p *EvrPipeline) JoinEvrMatch(ctx context.Context, logger *zap.Logger, sess
 		logger.Warn("Failed to add profile to cache", zap.Error(err))
 	}
 
-	// TODO FIXME Get the party id if the player is in a party
+	// Get the party id if the player is in a party group.
+	partyID := uuid.Nil
+	if config, err := p.matchmakingRegistry.LoadMatchmakingSettings(ctx, session.UserID().String()); err != nil {
+		logger.Warn("Failed to load matchmaking settings", zap.Error(err))
+	} else if config.GroupID != "" {
+		partyID = uuid.NewV5(uuid.Nil, config.GroupID)
+	}
 
 	// Prepare the player session metadata.
 
@@ -667,8 +676,13 @@ func (p *EvrPipeline) JoinEvrMatch(ctx context.Context, logger *zap.Logger, sess
 		EvrID:         evrID,
 		PlayerSession: uuid.Must(uuid.NewV4()),
 		TeamIndex:     int(teamIndex),
+		PartyID:       partyID,
 		DiscordID:     discordID,
 		Query:         query,
+		Rating:        profileRating(profile.GetServer()),
+	}
+	if latency, ok := p.matchmakingRegistry.GetLatencies(session.UserID(), []evr.Endpoint{label.Broadcaster.Endpoint})[label.Broadcaster.Endpoint.ID()]; ok {
+		mp.RTT = latency.RTT
 	}
 
 	// Marshal the player metadata into JSON.
@@ -688,7 +702,7 @@ func (p *EvrPipeline) JoinEvrMatch(ctx context.Context, logger *zap.Logger, sess
 			return status.Errorf(codes.FailedPrecondition, "join not allowed: %s", reason)
 		case ErrJoinRejectedDuplicateJoin:
 			return status.Errorf(codes.AlreadyExists, "join not allowed: %s", reason)
-		case ErrJoinRejectedNotModerator:
+		case ErrJoinRejectedNotModerator, ErrJoinRejectedNotInvited, ErrJoinRejectedPassword:
 			return status.Errorf(codes.PermissionDenied, "join not allowed: %s", reason)
 		case ErrJoinRejectedLobbyFull:
 			return status.Errorf(codes.ResourceExhausted, "join not allowed: %s", reason)
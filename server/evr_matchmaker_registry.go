@@ -141,6 +141,7 @@ type FoundMatch struct {
 	Query         string
 	TeamIndex     TeamIndex
 	KeepTeamIndex bool
+	Backfill      bool // Placed into a running match by the matchmaker.
 }
 
 type TicketMeta struct {
@@ -160,9 +161,11 @@ type MatchmakingSession struct {
 	PingResultsCh chan []evr.EndpointPingResult // Channel for ping completion.
 	Expiry        time.Time
 	Label         *EvrMatchState
-	Tickets       map[string]TicketMeta // map[ticketId]TicketMeta
+	Tickets       map[string]TicketMeta            // map[ticketId]TicketMeta
+	TicketStates  map[string]MatchmakerTicketState // The ticket states last sent to the player, by ticket. Replaced, never modified.
 	Party         *PartyHandler
 	LatencyCache  *LatencyCache
+	RequeueFn     func() error // Puts the player back into the matchmaker, if set.
 }
 
 func (s *MatchmakingSession) metricsTags() map[string]string {
@@ -333,8 +336,9 @@ func NewMatchmakingRegistry(logger *zap.Logger, matchRegistry MatchRegistry, mat
 		cacheByUserId:     &MapOf[uuid.UUID, *LatencyCache]{},
 		broadcasters:      &MapOf[string, evr.Endpoint]{},
 	}
-	// Set the matchmaker's OnMatchedEntries callback
+	// Set the matchmaker's OnMatchedEntries and OnBackfilledEntries callbacks
 	matchmaker.OnMatchedEntries(c.matchedEntriesFn)
+	matchmaker.OnBackfilledEntries(c.backfilledEntriesFn)
 	go c.rebuildBroadcasters()
 
 	return c
@@ -350,6 +354,8 @@ type MatchmakingSettings struct {
 	PriorityBroadcasters []string   `json:"priority_broadcasters"` // Prioritize these broadcasters
 	DisableBackfill      bool       `json:"disable_backfill"`      // Backfill matches
 	NextMatchToken       MatchToken `json:"next_match_id"`         // Try to join this match immediately when finding a match
+
+	Expansion []*MatchmakerExpansionStage `json:"expansion,omitempty"` // Widen the query as the ticket waits, only read from the global settings
 }
 
 func (r *MatchmakingRegistry) LoadMatchmakingSettings(ctx context.Context, userID string) (config MatchmakingSettings, err error) {
@@ -702,7 +708,7 @@ func (mr *MatchmakingRegistry) allocateBroadcaster(channel uuid.UUID, config Mat
 	if err != nil {
 		return "", fmt.Errorf("error signaling match: %s: %v", response, err)
 	}
-	if response != "session started" {
+	if response != "session started" && response != "vote started" {
 		return "", fmt.Errorf("error signaling match: %s", response)
 	}
 
@@ -747,6 +753,9 @@ func (c *MatchmakingRegistry) ListUnassignedLobbies(ctx context.Context, channel
 	// MUST be an unassigned lobby
 	qparts = append(qparts, LobbyType(evr.UnassignedLobby).Query(Must, 0))
 
+	// MUST NOT be draining
+	qparts = append(qparts, DrainedQuery())
+
 	if channel != uuid.Nil {
 		// MUST be hosting for this channel
 		qparts = append(qparts, HostedChannels([]uuid.UUID{channel}).Query(Must, 0))
@@ -949,6 +958,8 @@ func (c *MatchmakingRegistry) StoreLatencyCache(session *sessionWS) {
 				PermissionRead:  &wrapperspb.Int32Value{Value: int32(0)},
 				PermissionWrite: &wrapperspb.Int32Value{Value: int32(0)},
 			},
+			// The cached latencies are stale by the time they expire, so the object need not outlive them.
+			ExpireTime: time.Now().Add(LatencyCacheExpiry),
 		},
 	}
 	if _, _, err = StorageWriteObjects(context.Background(), session.logger, session.pipeline.db, session.metrics, session.storageIndex, true, ops); err != nil {
@@ -1021,19 +1032,7 @@ func (c *MatchmakingRegistry) Create(ctx context.Context, logger *zap.Logger, se
 	// listen for a match ID to join
 	go func() {
 		defer cancel(nil)
-		var err error
-		select {
-		case <-ctx.Done():
-			if ctx.Err() != nil {
-				err = context.Cause(ctx)
-			}
-		case <-time.After(timeout):
-			// Timeout
-			err = ErrMatchmakingTimeout
-		case matchFound := <-msession.MatchJoinCh:
-			// join the match
-			err = joinFn(matchFound.MatchID, matchFound.Query)
-		}
+		err := c.awaitMatch(ctx, logger, msession, timeout, joinFn)
 		if err != nil {
 			defer errorFn(err)
 		}
@@ -1047,6 +1046,31 @@ func (c *MatchmakingRegistry) Create(ctx context.Context, logger *zap.Logger, se
 	return msession, nil
 }
 
+// awaitMatch waits for a match to join and joins it. A player rejected by a match the matchmaker placed them into is
+// put back into the queue to wait for the next one.
+func (c *MatchmakingRegistry) awaitMatch(ctx context.Context, logger *zap.Logger, msession *MatchmakingSession, timeout time.Duration, joinFn func(matchId string, query string) error) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-timer.C:
+			return ErrMatchmakingTimeout
+		case matchFound := <-msession.MatchJoinCh:
+			err := joinFn(matchFound.MatchID, matchFound.Query)
+			if err == nil || !matchFound.Backfill || msession.RequeueFn == nil {
+				return err
+			}
+			logger.Warn("Backfill join rejected, requeueing", zap.String("mid", matchFound.MatchID), zap.Error(err))
+			msession.RemoveTicket(matchFound.Ticket)
+			if err := msession.RequeueFn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (c *MatchmakingRegistry) Cancel(sessionId uuid.UUID, reason error) {
 	if session, ok := c.GetMatchingBySessionId(sessionId); ok {
 		c.logger.Debug("Canceling matchmaking session", zap.String("reason", reason.Error()))
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_ipToKey(t *testing.T) {
@@ -96,3 +99,38 @@ func Test_distributeParties(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchmakingRegistry_AwaitMatchRequeue(t *testing.T) {
+	logger := loggerForTest(t)
+	msession := &MatchmakingSession{
+		MatchJoinCh: make(chan FoundMatch, 2),
+		Tickets:     map[string]TicketMeta{"ticket": {TicketID: "ticket"}},
+	}
+	var requeued int
+	msession.RequeueFn = func() error {
+		requeued++
+		return nil
+	}
+	joinFn := func(matchID string, query string) error {
+		if matchID == "full" {
+			return errors.New("join not allowed: lobby full")
+		}
+		return nil
+	}
+
+	// A rejected backfill puts the player back into the queue, the next match is joined.
+	msession.MatchJoinCh <- FoundMatch{MatchID: "full", Ticket: "ticket", Backfill: true}
+	msession.MatchJoinCh <- FoundMatch{MatchID: "open", Backfill: true}
+	if err := (&MatchmakingRegistry{}).awaitMatch(context.Background(), logger, msession, time.Second, joinFn); err != nil {
+		t.Fatalf("awaitMatch() error = %v", err)
+	}
+	if requeued != 1 || len(msession.Tickets) != 0 {
+		t.Errorf("expected 1 requeue and the placed ticket removed, got %d and %v", requeued, msession.Tickets)
+	}
+
+	// Matches the player was sent to directly are not retried.
+	msession.MatchJoinCh <- FoundMatch{MatchID: "full"}
+	if err := (&MatchmakingRegistry{}).awaitMatch(context.Background(), logger, msession, time.Second, joinFn); err == nil {
+		t.Error("expected the join error")
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const (
+	MatchmakerStatusNotificationCode = 1101 // The notification code used to send matchmaker ticket status to players.
+)
+
+type matchmakerTicketStatusRequest struct {
+	Ticket string `json:"ticket,omitempty"`
+}
+
+type matchmakerTicketStatusResponse struct {
+	Ticket *MatchmakerTicketStatus  `json:"ticket,omitempty"`
+	Queues []*MatchmakerQueueStatus `json:"queues"`
+}
+
+// matchmakerTicketStatusRpc reports the status of one of the caller's matchmaker tickets along with the queue
+// summaries, or only the queue summaries if no ticket is given.
+func matchmakerTicketStatusRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &matchmakerTicketStatusRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "matchmaker status is", nil, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		response := &matchmakerTicketStatusResponse{}
+		if request.Ticket != "" {
+			status, err := goNk.MatchmakerTicketStatus(request.Ticket)
+			if err == runtime.ErrMatchmakerTicketNotFound || (err == nil && !matchmakerTicketHasUser(status, userID)) {
+				// Other users' tickets are reported as not found.
+				return nil, runtime.NewError("ticket not found", StatusNotFound)
+			} else if err != nil {
+				return nil, err
+			}
+			response.Ticket = status
+		}
+
+		queues, err := goNk.MatchmakerQueueStatus()
+		if err != nil {
+			return nil, err
+		}
+		response.Queues = queues
+		return response, nil
+	})
+}
+
+func matchmakerTicketHasUser(status *MatchmakerTicketStatus, userID string) bool {
+	for _, presence := range status.Presences {
+		if presence.UserId == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchmakerTicketState is the part of a ticket's status that players are notified of changes to. The wait times are
+// left out, as they change on every poll.
+type MatchmakerTicketState struct {
+	Queue          string
+	Position       int
+	QueueSize      int
+	ExpansionStage int
+	Candidates     int
+}
+
+func NewMatchmakerTicketState(status *MatchmakerTicketStatus) MatchmakerTicketState {
+	return MatchmakerTicketState{
+		Queue:          status.Queue,
+		Position:       status.Position,
+		QueueSize:      status.QueueSize,
+		ExpansionStage: status.ExpansionStage,
+		Candidates:     status.Candidates,
+	}
+}
+
+// sendMatchmakerTicketStatus notifies the user of the status of each of their tickets that is still in the matchmaker,
+// unless its state is unchanged from the one previously sent. Returns the state of each ticket still in the matchmaker.
+func sendMatchmakerTicketStatus(ctx context.Context, nk runtime.NakamaModule, matchmaker Matchmaker, userID string, tickets []string, sent map[string]MatchmakerTicketState) (map[string]MatchmakerTicketState, error) {
+	states := make(map[string]MatchmakerTicketState, len(tickets))
+	notifications := make([]*runtime.NotificationSend, 0, len(tickets))
+	for _, ticket := range tickets {
+		status, err := matchmaker.TicketStatus(ticket)
+		if err == runtime.ErrMatchmakerTicketNotFound {
+			// Already matched or removed.
+			continue
+		} else if err != nil {
+			return sent, err
+		}
+
+		state := NewMatchmakerTicketState(status)
+		states[ticket] = state
+		if previous, found := sent[ticket]; found && previous == state {
+			continue
+		}
+
+		data, err := json.Marshal(status)
+		if err != nil {
+			return sent, err
+		}
+		content := make(map[string]interface{})
+		if err := json.Unmarshal(data, &content); err != nil {
+			return sent, err
+		}
+		notifications = append(notifications, &runtime.NotificationSend{
+			UserID:     userID,
+			Subject:    "matchmaker_status",
+			Content:    content,
+			Code:       MatchmakerStatusNotificationCode,
+			Persistent: false,
+		})
+	}
+	if len(notifications) == 0 {
+		return states, nil
+	}
+	if err := nk.NotificationsSend(ctx, notifications); err != nil {
+		return sent, err
+	}
+	return states, nil
+}
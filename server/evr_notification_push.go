@@ -0,0 +1,177 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+const NotificationPushProviderDiscord = "discord"
+
+var (
+	ErrNotificationPushDiscordTokenNotLinked = errors.New("discord push token must be the caller's linked discord id")
+	ErrNotificationPushDiscordTokenTaken     = errors.New("discord push token is registered to another user")
+)
+
+// discordNotificationPushProvider sends notifications for offline EVR users as Discord direct messages from the
+// registry bot. Users are linked to their Discord account by their custom ID, and opt in by registering their Discord
+// user ID as a push token for the provider.
+type discordNotificationPushProvider struct {
+	discordRegistry DiscordRegistry
+}
+
+func NewDiscordNotificationPushProvider(discordRegistry DiscordRegistry) NotificationPushProvider {
+	return &discordNotificationPushProvider{discordRegistry: discordRegistry}
+}
+
+func (p *discordNotificationPushProvider) Name() string {
+	return NotificationPushProviderDiscord
+}
+
+func (p *discordNotificationPushProvider) TokenRequired() bool {
+	return true
+}
+
+func (p *discordNotificationPushProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	bot := p.discordRegistry.GetBot()
+	if bot == nil {
+		return fmt.Errorf("%w: discord bot is not available", ErrNotificationPushRejected)
+	}
+	if notification.Subject == "" {
+		// There is nothing readable to send.
+		return nil
+	}
+
+	discordID, err := p.discordRegistry.GetDiscordIdByUserId(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if discordID == "" {
+		return fmt.Errorf("%w: user is not linked to discord", ErrNotificationPushRejected)
+	}
+	if discordID != token {
+		// The user was relinked to another Discord account since opting in.
+		return fmt.Errorf("%w: token is not the user's discord id", ErrNotificationPushTokenInvalid)
+	}
+
+	channel, err := bot.UserChannelCreate(discordID)
+	if err != nil {
+		return discordNotificationPushError(err)
+	}
+	if _, err := bot.ChannelMessageSend(channel.ID, notification.Subject); err != nil {
+		return discordNotificationPushError(err)
+	}
+	return nil
+}
+
+// discordNotificationPushError rejects pushes Discord refuses, such as to users who do not accept direct messages,
+// and retries the rest.
+func discordNotificationPushError(err error) error {
+	var restErr *discordgo.RESTError
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		if code := restErr.Response.StatusCode; code >= 400 && code < 500 && code != http.StatusTooManyRequests {
+			return fmt.Errorf("%w: %v", ErrNotificationPushRejected, err)
+		}
+	}
+	return err
+}
+
+type notificationPushTokenRequest struct {
+	Provider string `json:"provider"`
+	Token    string `json:"token"`
+}
+
+type notificationPushOptOutsRequest struct {
+	Codes *[]int `json:"codes,omitempty"`
+}
+
+type notificationPushOptOutsResponse struct {
+	Codes []int `json:"codes"`
+}
+
+// notificationPushTokenRegisterRpc registers one of the caller's device tokens for push notifications. Discord tokens
+// must be the caller's linked Discord ID.
+func notificationPushTokenRegisterRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return notificationPushTokenRpc(ctx, nk, payload, func(n *RuntimeGoNakamaModule, ctx context.Context, userId, provider, token string) error {
+		if provider == NotificationPushProviderDiscord {
+			return notificationPushDiscordTokenRegister(ctx, n, userId, token)
+		}
+		return n.NotificationPushTokenRegister(ctx, userId, provider, token)
+	})
+}
+
+// notificationPushDiscordTokenRegister registers the caller's Discord ID as their Discord push token. Unlike device
+// tokens, a Discord token identifies its user, so it is never moved from another user that registered it.
+func notificationPushDiscordTokenRegister(ctx context.Context, n *RuntimeGoNakamaModule, userId, token string) error {
+	if err := notificationPushTokenValidate(NotificationPushProviderDiscord, token); err != nil {
+		return err
+	}
+	account, err := n.AccountGetId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if account.GetCustomId() == "" || account.GetCustomId() != token {
+		return ErrNotificationPushDiscordTokenNotLinked
+	}
+
+	query := `INSERT INTO user_push_token (provider, token, user_id) VALUES ($1, $2, $3)
+ON CONFLICT (provider, token) DO UPDATE SET update_time = now() WHERE user_push_token.user_id = $3`
+	result, err := n.db.ExecContext(ctx, query, NotificationPushProviderDiscord, token, userId)
+	if err != nil {
+		return err
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return ErrNotificationPushDiscordTokenTaken
+	}
+	return nil
+}
+
+// notificationPushTokenUnregisterRpc unregisters one of the caller's device tokens.
+func notificationPushTokenUnregisterRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	return notificationPushTokenRpc(ctx, nk, payload, (*RuntimeGoNakamaModule).NotificationPushTokenUnregister)
+}
+
+func notificationPushTokenRpc(ctx context.Context, nk runtime.NakamaModule, payload string, fn func(n *RuntimeGoNakamaModule, ctx context.Context, userId, provider, token string) error) (string, error) {
+	request := &notificationPushTokenRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "push notifications are", notificationPushRpcError, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		return struct{}{}, fn(goNk, ctx, userID, request.Provider, request.Token)
+	})
+}
+
+func notificationPushRpcError(err error) error {
+	if errors.Is(err, ErrNotificationPushProviderInvalid) || errors.Is(err, ErrNotificationPushTokenLength) {
+		return runtime.NewError(err.Error(), StatusInvalidArgument)
+	}
+	if errors.Is(err, ErrNotificationPushDiscordTokenNotLinked) {
+		return runtime.NewError(err.Error(), StatusPermissionDenied)
+	}
+	if errors.Is(err, ErrNotificationPushDiscordTokenTaken) {
+		return runtime.NewError(err.Error(), StatusAlreadyExists)
+	}
+	return runtime.NewError(err.Error(), StatusInternalError)
+}
+
+// notificationPushOptOutsRpc replaces the notification codes the caller opted out of pushes for if codes are given,
+// and responds with the codes opted out of.
+func notificationPushOptOutsRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &notificationPushOptOutsRequest{}
+	return goRuntimeRpc(ctx, nk, payload, request, "push notifications are", nil, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+		if request.Codes != nil {
+			if err := goNk.NotificationPushOptOutsSet(ctx, userID, *request.Codes); err != nil {
+				return nil, err
+			}
+		}
+		codes, err := goNk.NotificationPushOptOutsGet(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return &notificationPushOptOutsResponse{Codes: codes}, nil
+	})
+}
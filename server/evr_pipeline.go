@@ -8,6 +8,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -69,12 +70,13 @@ type EvrPipeline struct {
 	matchByEvrID                     *MapOf[string, string]      // full match string by evrId token
 	backfillQueue                    *MapOf[string, *sync.Mutex] // A queue of backfills to avoid double backfill
 	placeholderEmail                 string
+	draining                         atomic.Bool // The node is draining, and will not accept new broadcasters.
 	linkDeviceURL                    string
 }
 
 type ctxDiscordBotTokenKey struct{}
 
-func NewEvrPipeline(logger *zap.Logger, startupLogger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, storageIndex StorageIndex, leaderboardScheduler LeaderboardScheduler, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, matchmaker Matchmaker, tracker Tracker, router MessageRouter, streamManager StreamManager, metrics Metrics, pipeline *Pipeline, runtime *Runtime) *EvrPipeline {
+func NewEvrPipeline(logger *zap.Logger, startupLogger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, config Config, version string, socialClient *social.Client, storageIndex StorageIndex, leaderboardScheduler LeaderboardScheduler, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, matchmaker Matchmaker, tracker Tracker, router MessageRouter, notificationPusher NotificationPusher, streamManager StreamManager, metrics Metrics, pipeline *Pipeline, runtime *Runtime) *EvrPipeline {
 	// The Evr pipeline is going to be a bit "different".
 	// It's going to get access to most components, because
 	// of the way EVR works, it's going to need to be able
@@ -88,6 +90,8 @@ func NewEvrPipeline(logger *zap.Logger, startupLogger *zap.Logger, db *sql.DB, p
 	ctx := context.WithValue(context.Background(), ctxDiscordBotTokenKey{}, vars["DISCORD_BOT_TOKEN"])
 	ctx = context.WithValue(ctx, ctxNodeKey{}, config.GetName())
 	nk := NewRuntimeGoNakamaModule(logger, db, protojsonMarshaler, config, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, storageIndex)
+	nk.SetMatchmaker(matchmaker)
+	nk.SetNotificationPusher(notificationPusher)
 
 	// TODO Add a symbol cache that gets populated and stored back occasionally
 
@@ -107,6 +111,11 @@ func NewEvrPipeline(logger *zap.Logger, startupLogger *zap.Logger, db *sql.DB, p
 		}
 	}
 	discordRegistry := NewLocalDiscordRegistry(ctx, nk, runtimeLogger, metrics, config, pipeline, dg)
+	if dg != nil {
+		if err := nk.NotificationPushProviderRegister(NewDiscordNotificationPushProvider(discordRegistry)); err != nil {
+			logger.Error("Failed to register discord push provider", zap.Error(err))
+		}
+	}
 
 	appBot := NewDiscordAppBot(nk, runtimeLogger, metrics, pipeline, config, discordRegistry, dg)
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
@@ -72,6 +73,15 @@ func (p *EvrPipeline) broadcasterSessionEnded(ctx context.Context, logger *zap.L
 			logger.Warn("Broadcaster session ended, but no match found")
 			return
 		}
+		// Check if the broadcaster is draining, before the match is gone.
+		draining := p.draining.Load()
+		if match, _, err := p.matchRegistry.GetMatch(ctx, matchID); err == nil && match != nil {
+			label := &EvrMatchState{}
+			if err := json.Unmarshal([]byte(match.GetLabel().GetValue()), label); err == nil && label.Broadcaster.Draining {
+				draining = true
+			}
+		}
+
 		// Leave the old match
 		leavemsg := &rtapi.Envelope{
 			Message: &rtapi.Envelope_MatchLeave{
@@ -84,7 +94,14 @@ func (p *EvrPipeline) broadcasterSessionEnded(ctx context.Context, logger *zap.L
 		if ok := session.pipeline.ProcessRequest(logger, session, leavemsg); !ok {
 			logger.Error("Failed to process leave request")
 		}
-		//
+
+		// A draining broadcaster is disconnected once its match has ended.
+		if draining {
+			logger.Info("Broadcaster drained, disconnecting")
+			session.Close("broadcaster drained", runtime.PresenceReasonDisconnect)
+			return
+		}
+
 		config, found := p.broadcasterRegistrationBySession.Load(session.ID().String())
 		if !found {
 			logger.Error("broadcaster session not found")
@@ -103,6 +120,10 @@ func (p *EvrPipeline) broadcasterRegistrationRequest(ctx context.Context, logger
 	request := in.(*evr.BroadcasterRegistrationRequest)
 	discordId := ""
 
+	if p.draining.Load() {
+		return errFailedRegistration(session, ErrNodeDraining, evr.BroadcasterRegistration_Failure)
+	}
+
 	// server connections are authenticated by discord ID and password.
 	// Get the discordId and password from the context
 	// Get the tags and guilds from the url params
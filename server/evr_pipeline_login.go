@@ -36,6 +36,7 @@ const (
 	GameProfileStorageCollection = "GameProfiles"
 	GameProfileStorageKey        = "gameProfile"
 	RemoteLogStorageCollection   = "RemoteLogs"
+	RemoteLogTTL                 = 7 * 24 * time.Hour // Stored remote logs are deleted after this long.
 )
 
 // errWithEvrIdFn prefixes an error with the EchoVR Id.
@@ -183,7 +184,7 @@ type EvrIDHistory struct {
 func (p *EvrPipeline) checkEvrIDOwner(ctx context.Context, evrId evr.EvrId) ([]EvrIDHistory, error) {
 
 	// Check the storage index for matching evrIDs
-	objectIds, err := p.storageIndex.List(ctx, uuid.Nil, EvrIDStorageIndex, fmt.Sprintf("+value.server.xplatformid:%s", evrId.String()), 1)
+	objectIds, _, _, err := p.storageIndex.List(ctx, uuid.Nil, EvrIDStorageIndex, fmt.Sprintf("+value.server.xplatformid:%s", evrId.String()), 1, nil, "")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list evrIDs: %w", err)
 	}
@@ -503,6 +504,7 @@ func (p *EvrPipeline) remoteLogSetv3(ctx context.Context, logger *zap.Logger, se
 						PermissionWrite: &wrapperspb.Int32Value{Value: int32(0)},
 						Version:         "",
 					},
+					ExpireTime: time.Now().Add(RemoteLogTTL),
 				},
 			}
 			if _, _, err := StorageWriteObjects(ctx, logger, session.pipeline.db, session.metrics, session.storageIndex, true, ops); err != nil {
@@ -579,6 +581,7 @@ func (p *EvrPipeline) remoteLogSetv3(ctx context.Context, logger *zap.Logger, se
 							PermissionWrite: &wrapperspb.Int32Value{Value: int32(0)},
 							Version:         "",
 						},
+						ExpireTime: time.Now().Add(RemoteLogTTL),
 					},
 				}
 				_, _, err := StorageWriteObjects(ctx, logger, session.pipeline.db, session.metrics, session.storageIndex, true, ops)
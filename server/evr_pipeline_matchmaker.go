@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,6 +27,24 @@ func (p *EvrPipeline) lobbyMatchmakerStatusRequest(ctx context.Context, logger *
 	if err != nil {
 		return fmt.Errorf("LobbyMatchmakerStatus: %v", err)
 	}
+
+	// The status response carries no detail, so send the ticket status as a notification when it changes.
+	if msession, ok := p.matchmakingRegistry.GetMatchingBySessionId(session.id); ok {
+		msession.Lock()
+		tickets := make([]string, 0, len(msession.Tickets))
+		for ticket := range msession.Tickets {
+			tickets = append(tickets, ticket)
+		}
+		sent := msession.TicketStates
+		msession.Unlock()
+		states, err := sendMatchmakerTicketStatus(ctx, p.runtimeModule, session.matchmaker, session.userID.String(), tickets, sent)
+		if err != nil {
+			logger.Warn("Failed to send matchmaker ticket status", zap.Error(err))
+		}
+		msession.Lock()
+		msession.TicketStates = states
+		msession.Unlock()
+	}
 	return nil
 }
 
@@ -399,12 +418,9 @@ func (p *EvrPipeline) MatchFind(parentCtx context.Context, logger *zap.Logger, s
 		// Replace the session
 		logger.Warn("Matchmaking session already exists", zap.Any("tickets", s.Tickets))
 	}
+	// Join errors are sent to the session by errorFn, unless the player is requeued.
 	joinFn := func(matchID string, query string) error {
-		err := p.JoinEvrMatch(parentCtx, logger, session, query, matchID, int(ml.TeamIndex))
-		if err != nil {
-			return NewMatchmakingResult(logger, ml.Mode, *ml.Channel).SendErrorToSession(session, err)
-		}
-		return nil
+		return p.JoinEvrMatch(parentCtx, logger, session, query, matchID, int(ml.TeamIndex))
 	}
 	errorFn := func(err error) error {
 		return NewMatchmakingResult(logger, ml.Mode, *ml.Channel).SendErrorToSession(session, err)
@@ -519,7 +535,11 @@ func (p *EvrPipeline) MatchFind(parentCtx context.Context, logger *zap.Logger, s
 		// Start the backfill loop
 		go p.MatchBackfillLoop(session, msession, skipBackfillDelay, false)
 
-		// Put a ticket in for matching
+		// Put a ticket in for matching, and again if a match the player is backfilled into rejects them.
+		msession.RequeueFn = func() error {
+			_, err := p.MatchMake(session, msession)
+			return err
+		}
 		_, err := p.MatchMake(session, msession)
 		if err != nil {
 			return err
@@ -34,12 +34,45 @@ func InitializeEvrRuntimeModule(ctx context.Context, logger runtime.Logger, db *
 		"signin/discord":       DiscordSignInRpc,
 		"match":                MatchRpc,
 		"match/prepare":        PrepareMatchRPC,
+		"match/roundbreak":     roundBreakRpc,
+		"match/vote":           levelVoteRpc,
+		"lobby/code/create":    lobbyCodeCreateRpc,
+		"lobby/code/redeem":    lobbyCodeRedeemRpc,
+		"lobby/code/revoke":    lobbyCodeRevokeRpc,
+		"lobby/code/list":      lobbyCodeListRpc,
+		"broadcaster/drain":    broadcasterDrainRpc,
+		"broadcaster/usage":    broadcasterUsageRpc,
 		"link":                 LinkingAppRpc,
 		"evr/servicestatus":    ServiceStatusRpc,
 		"importloadouts":       ImportLoadoutsRpc,
 		"terminateMatch":       terminateMatchRpc,
 		"matchmaker":           matchmakingStatusRpc,
 		"setmatchamakerstatus": setMatchmakingStatusRpc,
+
+		"leaderboard/percentile":   leaderboardRecordPercentileRpc,
+		"leaderboard/rank/history": leaderboardRankHistoryRpc,
+
+		"leaderboard/records/friends": leaderboardRecordsFriendsRpc,
+		"leaderboard/records/group":   leaderboardRecordsGroupRpc,
+
+		"matchmaker/status": matchmakerTicketStatusRpc,
+
+		"channel/reply":           channelMessageReplyRpc,
+		"channel/thread":          channelMessageThreadRpc,
+		"channel/reaction/add":    channelMessageReactionAddRpc,
+		"channel/reaction/remove": channelMessageReactionRemoveRpc,
+		"channel/reactions":       channelMessageReactionsRpc,
+
+		"push/token/register":   notificationPushTokenRegisterRpc,
+		"push/token/unregister": notificationPushTokenUnregisterRpc,
+		"push/optouts":          notificationPushOptOutsRpc,
+
+		"group/questionnaire":     groupJoinQuestionnaireRpc,
+		"group/questionnaire/set": groupJoinQuestionnaireSetRpc,
+		"group/join":              groupJoinRpc,
+		"group/requests":          groupJoinRequestsRpc,
+		"group/requests/approve":  groupJoinRequestsApproveRpc,
+		"group/requests/reject":   groupJoinRequestsRejectRpc,
 	}
 
 	for name, rpc := range rpcs {
@@ -145,6 +178,21 @@ func createCoreGroups(ctx context.Context, logger runtime.Logger, db *sql.DB, nk
 	return nil
 }
 
+// sortableStorageIndexRegisterer is implemented by initializers that can register storage indexes with sortable doc
+// values, which the display name, ghosted user and social group lookups need to order and aggregate their results.
+type sortableStorageIndexRegisterer interface {
+	RegisterStorageIndexSortable(name, collection, key string, fields []string, maxEntries int, indexOnly bool) error
+}
+
+// registerSortableStorageIndex registers a sortable storage index, falling back to a plain index if the initializer
+// does not support sortable indexes.
+func registerSortableStorageIndex(initializer runtime.Initializer, name, collection, key string, fields []string, maxEntries int, indexOnly bool) error {
+	if sortable, ok := initializer.(sortableStorageIndexRegisterer); ok {
+		return sortable.RegisterStorageIndexSortable(name, collection, key, fields, maxEntries, indexOnly)
+	}
+	return initializer.RegisterStorageIndex(name, collection, key, fields, maxEntries, indexOnly)
+}
+
 // Register Indexes for the login service
 func RegisterIndexes(initializer runtime.Initializer) error {
 	// Register the LinkTicket Index that prevents multiple LinkTickets with the same device_id_str
@@ -186,7 +234,7 @@ func RegisterIndexes(initializer runtime.Initializer) error {
 	key = ""                          // Set to empty string to match all keys instead
 	fields = []string{"display_name"} // index on these fields
 	maxEntries = 100000
-	if err := initializer.RegisterStorageIndex(name, collection, key, fields, maxEntries, indexOnly); err != nil {
+	if err := registerSortableStorageIndex(initializer, name, collection, key, fields, maxEntries, indexOnly); err != nil {
 		return err
 	}
 
@@ -195,6 +243,15 @@ func RegisterIndexes(initializer runtime.Initializer) error {
 	key = GameProfileStorageKey             // Set to empty string to match all keys instead
 	fields = []string{"client.ghost.users"} // index on these fields
 	maxEntries = 1000000
+	if err := registerSortableStorageIndex(initializer, name, collection, key, fields, maxEntries, indexOnly); err != nil {
+		return err
+	}
+
+	name = LobbyCodeIndex
+	collection = LobbyCodeCollection
+	key = ""                                    // Set to empty string to match all keys instead
+	fields = []string{"created_by", "match_id"} // index on these fields
+	maxEntries = 10000
 	if err := initializer.RegisterStorageIndex(name, collection, key, fields, maxEntries, indexOnly); err != nil {
 		return err
 	}
@@ -204,7 +261,7 @@ func RegisterIndexes(initializer runtime.Initializer) error {
 	key = GameProfileStorageKey              // Set to empty string to match all keys instead
 	fields = []string{"client.social.group"} // index on these fields
 	maxEntries = 100000
-	if err := initializer.RegisterStorageIndex(name, collection, key, fields, maxEntries, indexOnly); err != nil {
+	if err := registerSortableStorageIndex(initializer, name, collection, key, fields, maxEntries, indexOnly); err != nil {
 		return err
 	}
 
@@ -798,9 +798,39 @@ func (r *LocalDiscordRegistry) SynchronizeGroup(ctx context.Context, guild *disc
 		return fmt.Errorf("error updating guild group: %w", err)
 	}
 
+	// Ask users requesting to join the guild group the questions of the guild's onboarding flow.
+	if err := r.synchronizeJoinQuestionnaire(ctx, guild.ID, guildGroup.GetId()); err != nil {
+		return fmt.Errorf("error synchronizing guild join questionnaire: %w", err)
+	}
+
 	return nil
 }
 
+func (r *LocalDiscordRegistry) synchronizeJoinQuestionnaire(ctx context.Context, guildID, groupID string) error {
+	goNk, ok := r.nk.(*RuntimeGoNakamaModule)
+	if !ok {
+		return nil
+	}
+
+	// Guilds without onboarding, or that the bot can't manage, keep their current questions.
+	onboarding, err := r.bot.GuildOnboarding(guildID)
+	if err != nil || !groupJoinOnboardingEnabled(onboarding) {
+		return nil
+	}
+
+	questionnaire, err := goNk.GroupJoinQuestionnaireGet(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	// Questions set by the group's admins are kept.
+	if !groupJoinQuestionnaireFromOnboardingOnly(questionnaire.Questions) {
+		return nil
+	}
+	questionnaire.Questions = groupJoinQuestionnaireFromOnboarding(onboarding)
+
+	return goNk.GroupJoinQuestionnaireSet(ctx, "", groupID, questionnaire)
+}
+
 func (r *LocalDiscordRegistry) OnGuildMembersChunk(ctx context.Context, b *discordgo.Session, e *discordgo.GuildMembersChunk, logger runtime.Logger, nk runtime.NakamaModule, initializer runtime.Initializer) error {
 	// Get the nakama group for the guild
 
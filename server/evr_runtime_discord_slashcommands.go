@@ -183,6 +183,106 @@ var (
 			},
 		},
 
+		{
+			Name:        "lobby-code",
+			Description: "Manage codes for your private match.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "create",
+					Description: "Create a code for your current private match, and share it in this channel.",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "minutes",
+							Description: "How long the code is valid (default 120).",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "password",
+							Description: "Require a password to use the code.",
+							Required:    false,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "invite-only",
+							Description: "Only allow players with the code (or an invite) to join.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "join",
+					Description: "Use a lobby code.",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "code",
+							Description: "The lobby code.",
+							Required:    true,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "password",
+							Description: "The lobby password.",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "revoke",
+					Description: "Revoke a lobby code.",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "code",
+							Description: "The lobby code.",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List your active lobby codes.",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:        "drain-broadcasters",
+			Description: "Stop your broadcasters from taking new matches, and disconnect them when their matches end.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "operator",
+					Description: "The operator to drain (global developers only).",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "operator-stats",
+			Description: "Show the hosting statistics for your broadcasters.",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "days",
+					Description: "The number of days to include (default 7).",
+					Required:    false,
+					MinValue:    lo.ToPtr(1.0),
+					MaxValue:    BroadcasterUsageMaxDays,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "operator",
+					Description: "The operator to show (global developers only).",
+					Required:    false,
+				},
+			},
+		},
 		{
 			Name:        "badges",
 			Description: "manage badge entitlements",
@@ -1337,6 +1437,75 @@ func (d *DiscordAppBot) RegisterSlashCommands() error {
 				})
 			}
 		},
+		"lobby-code": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			user := getScopedUser(i)
+			if user == nil {
+				return
+			}
+			content, public, err := d.handleLobbyCodeCommand(ctx, i.ApplicationCommandData().Options[0], user.ID)
+			if err != nil {
+				content, public = err.Error(), false
+			}
+			var flags discordgo.MessageFlags
+			if !public {
+				flags = discordgo.MessageFlagsEphemeral
+			}
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Flags:   flags,
+					Content: content,
+				},
+			})
+		},
+		"operator-stats": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			user := getScopedUser(i)
+			if user == nil {
+				return
+			}
+			days := BroadcasterUsageDefaultDays
+			operatorDiscordID := user.ID
+			for _, o := range i.ApplicationCommandData().Options {
+				switch o.Name {
+				case "days":
+					days = int(o.IntValue())
+				case "operator":
+					operatorDiscordID = o.UserValue(s).ID
+				}
+			}
+			content, err := d.handleOperatorStatsCommand(ctx, user.ID, operatorDiscordID, days)
+			if err != nil {
+				content = err.Error()
+			}
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Flags:   discordgo.MessageFlagsEphemeral,
+					Content: content,
+				},
+			})
+		},
+		"drain-broadcasters": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			user := getScopedUser(i)
+			if user == nil {
+				return
+			}
+			operatorDiscordID := user.ID
+			if options := i.ApplicationCommandData().Options; len(options) > 0 {
+				operatorDiscordID = options[0].UserValue(s).ID
+			}
+			content, err := d.handleDrainBroadcastersCommand(ctx, user.ID, operatorDiscordID)
+			if err != nil {
+				content = err.Error()
+			}
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Flags:   discordgo.MessageFlagsEphemeral,
+					Content: content,
+				},
+			})
+		},
 	}
 
 	dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
@@ -2263,3 +2432,138 @@ func getScopedUser(i *discordgo.InteractionCreate) *discordgo.User {
 		return nil
 	}
 }
+
+// handleLobbyCodeCommand handles the lobby-code subcommands. It returns the response, and whether it should be shared with the channel.
+func (d *DiscordAppBot) handleLobbyCodeCommand(ctx context.Context, option *discordgo.ApplicationCommandInteractionDataOption, discordID string) (string, bool, error) {
+	nk := d.nk
+	userID, err := d.discordRegistry.GetUserIdByDiscordId(ctx, discordID, false)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	args := make(map[string]*discordgo.ApplicationCommandInteractionDataOption, len(option.Options))
+	for _, o := range option.Options {
+		args[o.Name] = o
+	}
+
+	switch option.Name {
+	case "create":
+		// Get the user's current match from their status presence.
+		presences, err := nk.StreamUserList(StreamModeStatus, userID.String(), "", "", true, true)
+		if err != nil {
+			return "", false, err
+		}
+		if len(presences) == 0 || presences[0].GetStatus() == "" {
+			return "", false, errors.New("you must be in a private match to create a lobby code")
+		}
+		matchID := presences[0].GetStatus()
+
+		ttl := LobbyCodeDefaultTTL
+		if o, ok := args["minutes"]; ok {
+			ttl = time.Duration(o.IntValue()) * time.Minute
+		}
+		password := ""
+		if o, ok := args["password"]; ok {
+			password = o.StringValue()
+		}
+		inviteOnly := false
+		if o, ok := args["invite-only"]; ok {
+			inviteOnly = o.BoolValue()
+		}
+
+		lobbyCode, err := CreateLobbyCode(ctx, nk, userID.String(), matchID, ttl, password, inviteOnly)
+		if err != nil {
+			return "", false, err
+		}
+		if password != "" {
+			// Do not share password protected codes with the channel.
+			return fmt.Sprintf("Lobby code `%s` (password protected) expires <t:%d:R>.", lobbyCode.Code, lobbyCode.ExpiresAt.Unix()), false, nil
+		}
+		return fmt.Sprintf("Join my private match with `/lobby-code join code:%s` (expires <t:%d:R>).", lobbyCode.Code, lobbyCode.ExpiresAt.Unix()), true, nil
+
+	case "join":
+		password := ""
+		if o, ok := args["password"]; ok {
+			password = o.StringValue()
+		}
+		matchID, err := RedeemLobbyCode(ctx, nk, userID.String(), args["code"].StringValue(), password)
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("You are invited: https://echo.taxi/spark://c/%s", matchID), false, nil
+
+	case "revoke":
+		if err := RevokeLobbyCode(ctx, nk, userID.String(), args["code"].StringValue()); err != nil {
+			return "", false, err
+		}
+		return "The lobby code has been revoked.", false, nil
+
+	case "list":
+		codes, err := ListLobbyCodes(ctx, nk, userID.String())
+		if err != nil {
+			return "", false, err
+		}
+		if len(codes) == 0 {
+			return "You have no active lobby codes.", false, nil
+		}
+		lines := lo.Map(codes, func(c *LobbyCode, _ int) string {
+			return fmt.Sprintf("`%s` expires <t:%d:R> (invite-only: %v, password: %v)", c.Code, c.ExpiresAt.Unix(), c.InviteOnly, c.PasswordHash != "")
+		})
+		return strings.Join(lines, "\n"), false, nil
+	}
+	return "", false, fmt.Errorf("unknown subcommand: %s", option.Name)
+}
+
+// handleDrainBroadcastersCommand drains the operator's broadcasters. Only global developers may drain other operators.
+func (d *DiscordAppBot) handleDrainBroadcastersCommand(ctx context.Context, discordID string, operatorDiscordID string) (string, error) {
+	userID, err := d.discordRegistry.GetUserIdByDiscordId(ctx, discordID, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	operatorID := userID
+	if operatorDiscordID != discordID {
+		isDeveloper, err := checkIfGlobalDeveloper(ctx, d.nk, userID.String())
+		if err != nil {
+			return "", err
+		}
+		if !isDeveloper {
+			return "", errors.New("only global developers may drain other operators")
+		}
+		if operatorID, err = d.discordRegistry.GetUserIdByDiscordId(ctx, operatorDiscordID, false); err != nil {
+			return "", fmt.Errorf("failed to get operator: %w", err)
+		}
+	}
+
+	count, err := DrainOperator(ctx, d.nk, operatorID.String())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Draining %d broadcaster(s). They will disconnect when their matches end.", count), nil
+}
+
+// handleOperatorStatsCommand reports the operator's broadcaster usage. Only global developers may view other operators.
+func (d *DiscordAppBot) handleOperatorStatsCommand(ctx context.Context, discordID string, operatorDiscordID string, days int) (string, error) {
+	userID, err := d.discordRegistry.GetUserIdByDiscordId(ctx, discordID, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	operatorID := userID
+	if operatorDiscordID != discordID {
+		isDeveloper, err := checkIfGlobalDeveloper(ctx, d.nk, userID.String())
+		if err != nil {
+			return "", err
+		}
+		if !isDeveloper {
+			return "", errors.New("only global developers may view other operators")
+		}
+		if operatorID, err = d.discordRegistry.GetUserIdByDiscordId(ctx, operatorDiscordID, false); err != nil {
+			return "", fmt.Errorf("failed to get operator: %w", err)
+		}
+	}
+
+	summary, err := LoadBroadcasterUsage(ctx, d.nk, operatorID.String(), days, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("<@%s> (last %d days)\n%s", operatorDiscordID, days, summary.String()), nil
+}
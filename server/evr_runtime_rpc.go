@@ -551,6 +551,49 @@ func terminateMatchRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, n
 	return string(jsonData), nil
 }
 
+type roundBreakRequest struct {
+	MatchID string `json:"match_id"`
+}
+
+type roundBreakResponse struct {
+	MatchID    string                   `json:"match_id"`
+	Suggestion *TeamRebalanceSuggestion `json:"suggestion,omitempty"`
+}
+
+// roundBreakRpc is called by the broadcaster's tooling between rounds, and returns any suggested team moves.
+func roundBreakRpc(ctx context.Context, logger runtime.Logger, db *sql.DB, nk runtime.NakamaModule, payload string) (string, error) {
+	request := &roundBreakRequest{}
+	if err := json.Unmarshal([]byte(payload), request); err != nil {
+		return "", err
+	}
+
+	signal := EvrSignal{
+		Signal: SignalRoundBreak,
+	}
+	result, err := nk.MatchSignal(ctx, request.MatchID, signal.String())
+	if err != nil {
+		return "", err
+	}
+
+	response := &roundBreakResponse{
+		MatchID: request.MatchID,
+	}
+	if result != "teams balanced" {
+		suggestion := &TeamRebalanceSuggestion{}
+		if err := json.Unmarshal([]byte(result), suggestion); err != nil {
+			return "", fmt.Errorf("match signal failed: %s", result)
+		}
+		response.Suggestion = suggestion
+	}
+
+	jsonData, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonData), nil
+}
+
 type matchmakingStatusRequest struct {
 }
 
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/heroiclabs/nakama-common/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// goRuntimeRpcFn handles a decoded RPC request as the calling user, and returns the response to encode.
+type goRuntimeRpcFn func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error)
+
+// goRuntimeRpc is the shared body of the RPCs exposing functions that only the Go runtime module provides. They are
+// extensions of RuntimeGoNakamaModule rather than the runtime.NakamaModule interface, which is defined outside this
+// repository, so the Lua and TypeScript/JavaScript runtimes don't have them and clients reach them through these RPCs.
+//
+// It checks the caller is a user, decodes the payload into request if there is one, runs fn and encodes its
+// response, as protobuf JSON for protobuf messages. Errors from fn that aren't runtime errors are mapped by errFn,
+// or reported as internal errors if it is nil. The feature names what isn't supported when the module isn't the Go
+// runtime's.
+func goRuntimeRpc(ctx context.Context, nk runtime.NakamaModule, payload string, request interface{}, feature string, errFn func(error) error, fn goRuntimeRpcFn) (string, error) {
+	userID, ok := ctx.Value(runtime.RUNTIME_CTX_USER_ID).(string)
+	if !ok || userID == "" {
+		return "", runtime.NewError("user id not found in context", StatusUnauthenticated)
+	}
+	username, _ := ctx.Value(runtime.RUNTIME_CTX_USERNAME).(string)
+
+	if request != nil && payload != "" {
+		if err := json.Unmarshal([]byte(payload), request); err != nil {
+			return "", runtime.NewError("error unmarshalling request: "+err.Error(), StatusInvalidArgument)
+		}
+	}
+
+	goNk, ok := nk.(*RuntimeGoNakamaModule)
+	if !ok {
+		return "", runtime.NewError(feature+" not supported by this runtime", StatusUnimplemented)
+	}
+
+	response, err := fn(goNk, userID, username)
+	if err != nil {
+		var runtimeErr *runtime.Error
+		switch {
+		case errors.As(err, &runtimeErr):
+			return "", err
+		case errFn != nil:
+			return "", errFn(err)
+		default:
+			return "", runtime.NewError(err.Error(), StatusInternalError)
+		}
+	}
+
+	var data []byte
+	if message, ok := response.(proto.Message); ok {
+		data, err = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(message)
+	} else {
+		data, err = json.Marshal(response)
+	}
+	if err != nil {
+		return "", runtime.NewError(err.Error(), StatusInternalError)
+	}
+	return string(data), nil
+}
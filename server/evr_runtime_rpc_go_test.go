@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+func TestGoRuntimeRpc(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), runtime.RUNTIME_CTX_USER_ID, "user")
+	errNotFound := errors.New("not found")
+	errFn := func(err error) error {
+		if errors.Is(err, errNotFound) {
+			return runtime.NewError(err.Error(), StatusNotFound)
+		}
+		return runtime.NewError(err.Error(), StatusInternalError)
+	}
+
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		nk       runtime.NakamaModule
+		payload  string
+		response interface{}
+		err      error
+		want     string
+		wantCode int
+	}{
+		{name: "no user", ctx: context.Background(), nk: &RuntimeGoNakamaModule{}, wantCode: StatusUnauthenticated},
+		{name: "invalid payload", ctx: userCtx, nk: &RuntimeGoNakamaModule{}, payload: "{", wantCode: StatusInvalidArgument},
+		{name: "other runtime", ctx: userCtx, payload: `{"name":"a"}`, wantCode: StatusUnimplemented},
+		{name: "json", ctx: userCtx, nk: &RuntimeGoNakamaModule{}, payload: `{"name":"a"}`, response: &request{Name: "a"}, want: `{"name":"a"}`},
+		{name: "protobuf", ctx: userCtx, nk: &RuntimeGoNakamaModule{}, response: &api.LeaderboardRecordList{}, want: `{"records":[],"owner_records":[],"next_cursor":"","prev_cursor":"","rank_count":"0"}`},
+		{name: "mapped error", ctx: userCtx, nk: &RuntimeGoNakamaModule{}, err: errNotFound, wantCode: StatusNotFound},
+		{name: "runtime error", ctx: userCtx, nk: &RuntimeGoNakamaModule{}, err: runtime.NewError("bad", StatusInvalidArgument), wantCode: StatusInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &request{}
+			got, err := goRuntimeRpc(tt.ctx, tt.nk, tt.payload, req, "tests are", errFn, func(goNk *RuntimeGoNakamaModule, userID, username string) (interface{}, error) {
+				if userID != "user" {
+					t.Errorf("userID = %q, want user", userID)
+				}
+				return tt.response, tt.err
+			})
+			if tt.wantCode != 0 {
+				var runtimeErr *runtime.Error
+				if !errors.As(err, &runtimeErr) || runtimeErr.Code != tt.wantCode {
+					t.Fatalf("goRuntimeRpc() error = %v, want code %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("goRuntimeRpc() error = %v", err)
+			}
+			// Protobuf JSON output varies its whitespace.
+			if got = strings.ReplaceAll(got, " ", ""); got != tt.want {
+				t.Errorf("goRuntimeRpc() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -63,11 +63,22 @@ type Leaderboard struct {
 	MaxNumScore      int
 	Title            string
 	StartTime        int64
+	DerivedSources   []*LeaderboardDerivedSource // Set only for derived leaderboards.
+}
+
+// LeaderboardDerivedSource is one term of a derived leaderboard's score, the weighted score of the same owner's record
+// in another leaderboard.
+type LeaderboardDerivedSource struct {
+	LeaderboardId string  `json:"leaderboard_id"`
+	Weight        float64 `json:"weight"`
 }
 
 func (l *Leaderboard) IsTournament() bool {
 	return l.Duration != 0
 }
+func (l *Leaderboard) IsDerived() bool {
+	return len(l.DerivedSources) != 0
+}
 func (l *Leaderboard) HasMaxSize() bool {
 	return l.MaxSize != math.MaxInt32
 }
@@ -153,6 +164,12 @@ type LeaderboardCache interface {
 	ListAll(limit int, reverse bool, cursor *LeaderboardAllCursor) ([]*Leaderboard, int, *LeaderboardAllCursor)
 	RefreshAllLeaderboards(ctx context.Context) error
 	Create(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string) (*Leaderboard, bool, error)
+	CreateDerived(ctx context.Context, id string, sortOrder int, resetSchedule, metadata string, sources []*LeaderboardDerivedSource) (*Leaderboard, bool, error)
+	ListDerived(sourceId string) []*Leaderboard
+	GetValidation(id string) *LeaderboardValidation
+	SetValidation(ctx context.Context, id string, maxScoreDelta int64, rateLimitCount, rateLimitIntervalSec int) error
+	GetValidationFunction() LeaderboardValidationFunction
+	SetValidationFunction(fn LeaderboardValidationFunction)
 	Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64)
 	List(limit int, cursor *LeaderboardListCursor) ([]*Leaderboard, *LeaderboardListCursor, error)
 	CreateTournament(ctx context.Context, id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata, title, description string, category, startTime, endTime, duration, maxSize, maxNumScore int, joinRequired bool) (*Leaderboard, bool, error)
@@ -168,10 +185,13 @@ type LocalLeaderboardCache struct {
 	logger       *zap.Logger
 	db           *sql.DB
 	leaderboards map[string]*Leaderboard
+	validations  map[string]*LeaderboardValidation
+	validationFn LeaderboardValidationFunction
 
 	allList         []*Leaderboard
 	leaderboardList []*Leaderboard // Non-tournament only
 	tournamentList  []*Leaderboard
+	derivedList     map[string][]*Leaderboard // Derived leaderboards keyed by each of their source leaderboard IDs.
 }
 
 func NewLocalLeaderboardCache(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.DB) LeaderboardCache {
@@ -180,10 +200,12 @@ func NewLocalLeaderboardCache(ctx context.Context, logger, startupLogger *zap.Lo
 		logger:       logger,
 		db:           db,
 		leaderboards: make(map[string]*Leaderboard),
+		validations:  make(map[string]*LeaderboardValidation),
 
 		allList:         make([]*Leaderboard, 0),
 		leaderboardList: make([]*Leaderboard, 0),
 		tournamentList:  make([]*Leaderboard, 0),
+		derivedList:     make(map[string][]*Leaderboard),
 	}
 
 	if err := l.RefreshAllLeaderboards(ctx); err != nil {
@@ -298,11 +320,67 @@ FROM leaderboard`
 
 	sort.Sort(OrderedTournaments(tournamentList))
 
+	rows, err := l.db.QueryContext(ctx, "SELECT leaderboard_id, source_id, weight FROM leaderboard_derived_source ORDER BY leaderboard_id, source_id")
+	if err != nil {
+		l.logger.Error("Error loading derived leaderboard sources from database", zap.Error(err))
+		return err
+	}
+	for rows.Next() {
+		var leaderboardId string
+		source := &LeaderboardDerivedSource{}
+		if err := rows.Scan(&leaderboardId, &source.LeaderboardId, &source.Weight); err != nil {
+			_ = rows.Close()
+			l.logger.Error("Error parsing derived leaderboard sources from database", zap.Error(err))
+			return err
+		}
+		if leaderboard, ok := leaderboards[leaderboardId]; ok {
+			leaderboard.DerivedSources = append(leaderboard.DerivedSources, source)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		l.logger.Error("Error loading derived leaderboard sources from database", zap.Error(err))
+		return err
+	}
+
+	derivedList := make(map[string][]*Leaderboard)
+	for _, leaderboard := range leaderboardList {
+		addDerived(derivedList, leaderboard)
+	}
+
+	validations := make(map[string]*LeaderboardValidation)
+	rows, err = l.db.QueryContext(ctx, "SELECT leaderboard_id, max_score_delta, rate_limit_count, rate_limit_interval_sec FROM leaderboard_validation")
+	if err != nil {
+		l.logger.Error("Error loading leaderboard validation from database", zap.Error(err))
+		return err
+	}
+	for rows.Next() {
+		var leaderboardId string
+		var maxScoreDelta int64
+		var rateLimitCount int
+		var rateLimitIntervalSec int
+		if err := rows.Scan(&leaderboardId, &maxScoreDelta, &rateLimitCount, &rateLimitIntervalSec); err != nil {
+			_ = rows.Close()
+			l.logger.Error("Error parsing leaderboard validation from database", zap.Error(err))
+			return err
+		}
+		if _, ok := leaderboards[leaderboardId]; ok {
+			validations[leaderboardId] = NewLeaderboardValidation(maxScoreDelta, rateLimitCount, rateLimitIntervalSec)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		l.logger.Error("Error loading leaderboard validation from database", zap.Error(err))
+		return err
+	}
+
 	l.Lock()
 	l.leaderboards = leaderboards
+	l.validations = validations
 	l.allList = allList
 	l.tournamentList = tournamentList
 	l.leaderboardList = leaderboardList
+	l.derivedList = derivedList
 	l.Unlock()
 
 	return nil
@@ -442,6 +520,208 @@ func (l *LocalLeaderboardCache) Create(ctx context.Context, id string, authorita
 	return leaderboard, true, nil
 }
 
+// CreateDerived creates an authoritative leaderboard whose records are kept up to date with the weighted sum of each
+// owner's scores in the source leaderboards. Like Create, creation is idempotent.
+func (l *LocalLeaderboardCache) CreateDerived(ctx context.Context, id string, sortOrder int, resetSchedule, metadata string, sources []*LeaderboardDerivedSource) (*Leaderboard, bool, error) {
+	if leaderboard := l.Get(id); leaderboard != nil {
+		// Creation is an idempotent operation.
+		return leaderboard, false, nil
+	}
+
+	if len(sources) == 0 {
+		return nil, false, errors.New("derived leaderboard requires at least one source leaderboard")
+	}
+	seen := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		if source.LeaderboardId == id {
+			return nil, false, errors.New("derived leaderboard cannot be its own source")
+		}
+		if _, ok := seen[source.LeaderboardId]; ok {
+			return nil, false, fmt.Errorf("duplicate source leaderboard: %s", source.LeaderboardId)
+		}
+		seen[source.LeaderboardId] = struct{}{}
+		sourceLeaderboard := l.Get(source.LeaderboardId)
+		if sourceLeaderboard == nil {
+			return nil, false, fmt.Errorf("source leaderboard not found: %s", source.LeaderboardId)
+		}
+		// Derived leaderboards are only recomputed on writes to regular leaderboards, which also rules out cycles.
+		if sourceLeaderboard.IsTournament() || sourceLeaderboard.IsDerived() {
+			return nil, false, fmt.Errorf("source leaderboard must not be a tournament or derived leaderboard: %s", source.LeaderboardId)
+		}
+		if source.Weight == 0 {
+			return nil, false, fmt.Errorf("source leaderboard weight must not be 0: %s", source.LeaderboardId)
+		}
+	}
+
+	var expr *cronexpr.Expression
+	var err error
+	if resetSchedule != "" {
+		expr, err = cronexpr.Parse(resetSchedule)
+		if err != nil {
+			l.logger.Error("Error parsing leaderboard reset schedule", zap.Error(err))
+			return nil, false, err
+		}
+	}
+
+	// Insert into database first, the leaderboard and its sources together.
+	var createTime pgtype.Timestamptz
+	if err = ExecuteInTx(ctx, l.db, func(tx *sql.Tx) error {
+		query := "INSERT INTO leaderboard (id, authoritative, sort_order, operator, metadata, reset_schedule) VALUES ($1, true, $2, $3, $4, $5) RETURNING create_time"
+		var resetScheduleParam interface{}
+		if resetSchedule != "" {
+			resetScheduleParam = resetSchedule
+		}
+		if err := tx.QueryRowContext(ctx, query, id, sortOrder, LeaderboardOperatorSet, metadata, resetScheduleParam).Scan(&createTime); err != nil {
+			return err
+		}
+		for _, source := range sources {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO leaderboard_derived_source (leaderboard_id, source_id, weight) VALUES ($1, $2, $3)", id, source.LeaderboardId, source.Weight); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == dbErrorUniqueViolation {
+			// Concurrent attempt at creating the leaderboard, reload to pick up the winning definition.
+			if err := l.RefreshAllLeaderboards(ctx); err != nil {
+				return nil, false, err
+			}
+			if leaderboard := l.Get(id); leaderboard != nil {
+				return leaderboard, false, nil
+			}
+		}
+		l.logger.Error("Error creating derived leaderboard", zap.Error(err))
+		return nil, false, err
+	}
+
+	// Then add to cache.
+	leaderboard := &Leaderboard{
+		Id:               id,
+		Authoritative:    true,
+		SortOrder:        sortOrder,
+		Operator:         LeaderboardOperatorSet,
+		ResetScheduleStr: resetSchedule,
+		ResetSchedule:    expr,
+		Metadata:         metadata,
+		CreateTime:       createTime.Time.Unix(),
+		DerivedSources:   sources,
+	}
+
+	l.Lock()
+	if leaderboard, ok := l.leaderboards[id]; ok {
+		// Maybe multiple concurrent creations for this ID.
+		l.Unlock()
+		return leaderboard, false, nil
+	}
+	l.leaderboards[id] = leaderboard
+	l.allList = append(l.allList, leaderboard)
+	l.leaderboardList = append(l.leaderboardList, leaderboard)
+	addDerived(l.derivedList, leaderboard)
+	l.Unlock()
+
+	return leaderboard, true, nil
+}
+
+// ListDerived returns the derived leaderboards that have the given leaderboard as one of their sources.
+func (l *LocalLeaderboardCache) ListDerived(sourceId string) []*Leaderboard {
+	l.RLock()
+	derived := l.derivedList[sourceId]
+	l.RUnlock()
+	// The slice is never modified in place, so it is safe to return without copying.
+	return derived
+}
+
+// addDerived indexes a derived leaderboard under each of its sources. Slices are replaced rather than appended to in
+// place so that callers of ListDerived can keep using a previously returned slice.
+func addDerived(derivedList map[string][]*Leaderboard, leaderboard *Leaderboard) {
+	for _, source := range leaderboard.DerivedSources {
+		existing := derivedList[source.LeaderboardId]
+		derived := make([]*Leaderboard, 0, len(existing)+1)
+		derived = append(derived, existing...)
+		derivedList[source.LeaderboardId] = append(derived, leaderboard)
+	}
+}
+
+// removeDerived removes a derived leaderboard from the index of each of its sources.
+func removeDerived(derivedList map[string][]*Leaderboard, leaderboard *Leaderboard) {
+	for _, source := range leaderboard.DerivedSources {
+		existing := derivedList[source.LeaderboardId]
+		derived := make([]*Leaderboard, 0, len(existing))
+		for _, d := range existing {
+			if d.Id != leaderboard.Id {
+				derived = append(derived, d)
+			}
+		}
+		if len(derived) == 0 {
+			delete(derivedList, source.LeaderboardId)
+		} else {
+			derivedList[source.LeaderboardId] = derived
+		}
+	}
+}
+
+func (l *LocalLeaderboardCache) GetValidation(id string) *LeaderboardValidation {
+	l.RLock()
+	validation := l.validations[id]
+	l.RUnlock()
+	return validation
+}
+
+// SetValidation replaces the validation rules of a leaderboard, all zero values remove them. Rate limit state is reset.
+func (l *LocalLeaderboardCache) SetValidation(ctx context.Context, id string, maxScoreDelta int64, rateLimitCount, rateLimitIntervalSec int) error {
+	leaderboard := l.Get(id)
+	if leaderboard == nil || leaderboard.IsTournament() {
+		return ErrLeaderboardNotFound
+	}
+	if maxScoreDelta < 0 || rateLimitCount < 0 || rateLimitIntervalSec < 0 {
+		return errors.New("leaderboard validation values must not be negative")
+	}
+	if (rateLimitCount == 0) != (rateLimitIntervalSec == 0) {
+		return errors.New("leaderboard rate limit count and interval must be set together")
+	}
+
+	if maxScoreDelta == 0 && rateLimitCount == 0 {
+		if _, err := l.db.ExecContext(ctx, "DELETE FROM leaderboard_validation WHERE leaderboard_id = $1", id); err != nil {
+			l.logger.Error("Error removing leaderboard validation", zap.Error(err))
+			return err
+		}
+		l.Lock()
+		delete(l.validations, id)
+		l.Unlock()
+		return nil
+	}
+
+	query := `INSERT INTO leaderboard_validation (leaderboard_id, max_score_delta, rate_limit_count, rate_limit_interval_sec)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (leaderboard_id) DO UPDATE SET max_score_delta = $2, rate_limit_count = $3, rate_limit_interval_sec = $4`
+	if _, err := l.db.ExecContext(ctx, query, id, maxScoreDelta, rateLimitCount, rateLimitIntervalSec); err != nil {
+		l.logger.Error("Error setting leaderboard validation", zap.Error(err))
+		return err
+	}
+
+	l.Lock()
+	if _, ok := l.leaderboards[id]; ok {
+		l.validations[id] = NewLeaderboardValidation(maxScoreDelta, rateLimitCount, rateLimitIntervalSec)
+	}
+	l.Unlock()
+	return nil
+}
+
+func (l *LocalLeaderboardCache) GetValidationFunction() LeaderboardValidationFunction {
+	l.RLock()
+	fn := l.validationFn
+	l.RUnlock()
+	return fn
+}
+
+// SetValidationFunction sets the runtime function consulted on every leaderboard record write, nil removes it.
+func (l *LocalLeaderboardCache) SetValidationFunction(fn LeaderboardValidationFunction) {
+	l.Lock()
+	l.validationFn = fn
+	l.Unlock()
+}
+
 func (l *LocalLeaderboardCache) Insert(id string, authoritative bool, sortOrder, operator int, resetSchedule, metadata string, createTime int64) {
 	var expr *cronexpr.Expression
 	var err error
@@ -770,6 +1050,7 @@ func (l *LocalLeaderboardCache) Delete(ctx context.Context, rankCache Leaderboar
 	l.Lock()
 	// Then delete from cache.
 	delete(l.leaderboards, id)
+	delete(l.validations, id)
 	for i, currentAll := range l.allList {
 		if currentAll.Id == id {
 			copy(l.allList[i:], l.allList[i+1:])
@@ -796,6 +1077,7 @@ func (l *LocalLeaderboardCache) Delete(ctx context.Context, rankCache Leaderboar
 				break
 			}
 		}
+		removeDerived(l.derivedList, leaderboard)
 	}
 	l.Unlock()
 
@@ -839,6 +1121,7 @@ func (l *LocalLeaderboardCache) Remove(id string) {
 					break
 				}
 			}
+			removeDerived(l.derivedList, leaderboard)
 		}
 	}
 	l.Unlock()
@@ -33,6 +33,8 @@ import (
 
 type LeaderboardRankCache interface {
 	Get(leaderboardId string, expiryUnix int64, ownerID uuid.UUID) int64
+	GetPercentile(leaderboardId string, expiryUnix int64, ownerID uuid.UUID) (rank int64, percentile float64)
+	Count(leaderboardId string, expiryUnix int64) int64
 	GetDataByRank(leaderboardId string, expiryUnix int64, sortOrder int, rank int64) (ownerID uuid.UUID, score, subscore int64, err error)
 	Fill(leaderboardId string, expiryUnix int64, records []*api.LeaderboardRecord) int64
 	Insert(leaderboardId string, sortOrder int, score, subscore int64, generation int32, expiryUnix int64, ownerID uuid.UUID) int64
@@ -232,6 +234,68 @@ func (l *LocalLeaderboardRankCache) Get(leaderboardId string, expiryUnix int64,
 	return int64(rank)
 }
 
+// GetPercentile returns the owner's rank and the percentage of ranked records at or above it, so the top record of
+// 20 is in the top 5%. Returns 0 for both if the owner is not ranked.
+func (l *LocalLeaderboardRankCache) GetPercentile(leaderboardId string, expiryUnix int64, ownerID uuid.UUID) (rank int64, percentile float64) {
+	if l.blacklistAll {
+		// If all rank caching is disabled.
+		return 0, 0
+	}
+	if _, ok := l.blacklistIds[leaderboardId]; ok {
+		// If rank caching is disabled for this particular leaderboard.
+		return 0, 0
+	}
+
+	// Find rank map for this leaderboard/expiry pair.
+	key := LeaderboardWithExpiry{LeaderboardId: leaderboardId, Expiry: expiryUnix}
+	l.RLock()
+	rankCache, ok := l.cache[key]
+	l.RUnlock()
+	if !ok {
+		return 0, 0
+	}
+
+	// Find rank data for this owner, reading the rank and count under the same lock so they are consistent.
+	rankCache.RLock()
+	rankData, ok := rankCache.owners[ownerID]
+	if !ok {
+		rankCache.RUnlock()
+		return 0, 0
+	}
+	rank = int64(rankCache.cache.GetRank(rankData.record))
+	count := rankCache.cache.Len()
+	rankCache.RUnlock()
+
+	if rank == 0 || count == 0 {
+		return 0, 0
+	}
+	return rank, float64(rank) / float64(count) * 100
+}
+
+// Count returns the number of ranked records for a leaderboard/expiry pair, or 0 if rank caching is disabled for it.
+func (l *LocalLeaderboardRankCache) Count(leaderboardId string, expiryUnix int64) int64 {
+	if l.blacklistAll {
+		return 0
+	}
+	if _, ok := l.blacklistIds[leaderboardId]; ok {
+		return 0
+	}
+
+	key := LeaderboardWithExpiry{LeaderboardId: leaderboardId, Expiry: expiryUnix}
+	l.RLock()
+	rankCache, ok := l.cache[key]
+	l.RUnlock()
+	if !ok {
+		return 0
+	}
+
+	rankCache.RLock()
+	count := rankCache.cache.Len()
+	rankCache.RUnlock()
+
+	return int64(count)
+}
+
 func (l *LocalLeaderboardRankCache) GetDataByRank(leaderboardId string, expiryUnix int64, sortOrder int, rank int64) (ownerID uuid.UUID, score, subscore int64, err error) {
 	if l.blacklistAll {
 		return uuid.Nil, 0, 0, errors.New("rank cache is disabled")
@@ -332,3 +332,32 @@ func TestLocalLeaderboardRankCache_Fill(t *testing.T) {
 	assert.EqualValues(t, 4, records[3].Rank)
 	assert.EqualValues(t, 2, records[4].Rank)
 }
+
+func TestLocalLeaderboardRankCache_GetPercentile(t *testing.T) {
+	cache := &LocalLeaderboardRankCache{
+		blacklistIds: make(map[string]struct{}, 0),
+		blacklistAll: false,
+		cache:        make(map[LeaderboardWithExpiry]*RankCache, 0),
+	}
+
+	owners := make([]uuid.UUID, 20)
+	for i := range owners {
+		owners[i] = uuid.Must(uuid.NewV4())
+		cache.Insert("lid", LeaderboardSortOrderDescending, int64(i), 0, 0, 0, owners[i])
+	}
+
+	assert.EqualValues(t, 20, cache.Count("lid", 0))
+	assert.EqualValues(t, 0, cache.Count("lid", 1))
+
+	rank, percentile := cache.GetPercentile("lid", 0, owners[19])
+	assert.EqualValues(t, 1, rank)
+	assert.InDelta(t, 5, percentile, 0.001)
+
+	rank, percentile = cache.GetPercentile("lid", 0, owners[10])
+	assert.EqualValues(t, 10, rank)
+	assert.InDelta(t, 50, percentile, 0.001)
+
+	rank, percentile = cache.GetPercentile("lid", 0, uuid.Must(uuid.NewV4()))
+	assert.EqualValues(t, 0, rank)
+	assert.EqualValues(t, 0, percentile)
+}
@@ -366,6 +366,41 @@ func (ls *LocalLeaderboardScheduler) queueExpiryElapse(t time.Time, ids []string
 
 	ls.logger.Info("Leaderboard scheduler expiry reset", zap.Int("count", len(ids)))
 
+	go func() {
+		// Snapshot final ranks for the periods that just ended. Runs separately so the snapshot queries never hold up
+		// the reset callbacks, and errors are logged by the snapshot itself.
+		for _, id := range ids {
+			if leaderboard := ls.cache.Get(id); leaderboard != nil {
+				_, _ = LeaderboardRankHistorySnapshot(ls.ctx, ls.logger, ls.db, ls.config.GetLeaderboard(), leaderboard, ts)
+			}
+		}
+	}()
+
+	go func() {
+		// Recompute the derived leaderboards that reset, or whose sources reset, so their records only sum the scores
+		// in the current period of each source.
+		recomputed := make(map[string]struct{})
+		for _, id := range ids {
+			leaderboard := ls.cache.Get(id)
+			if leaderboard == nil || leaderboard.IsTournament() {
+				continue
+			}
+			derived := ls.cache.ListDerived(id)
+			if leaderboard.IsDerived() {
+				derived = append([]*Leaderboard{leaderboard}, derived...)
+			}
+			for _, d := range derived {
+				if _, found := recomputed[d.Id]; found {
+					continue
+				}
+				recomputed[d.Id] = struct{}{}
+				if _, err := LeaderboardDerivedRecompute(ls.ctx, ls.logger, ls.db, ls.cache, ls.rankCache, d.Id); err != nil {
+					ls.logger.Error("Error recomputing derived leaderboard after reset", zap.String("leaderboard_id", d.Id), zap.String("reset_id", id), zap.Error(err))
+				}
+			}
+		}
+	}()
+
 	go func() {
 		// Queue the current set of leaderboard and tournament resets.
 		// Executes inside a goroutine to ensure further invocation timings are not skewed.
@@ -75,7 +75,18 @@ func IterateBlugeMatches(dmi search.DocumentMatchIterator, loadFields map[string
 	return rv, nil
 }
 
+// BlugeWalkDocument indexes the data for searching only, without the doc values needed to sort by each field.
 func BlugeWalkDocument(data interface{}, path []string, doc *bluge.Document) {
+	blugeWalkDocument(data, path, doc, false)
+}
+
+// BlugeWalkSortableDocument indexes the data like BlugeWalkDocument, and also stores the values needed to sort by each
+// field. Sortable fields take more space, so only indices that are sorted should use it.
+func BlugeWalkSortableDocument(data interface{}, path []string, doc *bluge.Document) {
+	blugeWalkDocument(data, path, doc, true)
+}
+
+func blugeWalkDocument(data interface{}, path []string, doc *bluge.Document, sortable bool) {
 	val := reflect.ValueOf(data)
 	if !val.IsValid() {
 		return
@@ -88,7 +99,7 @@ func BlugeWalkDocument(data interface{}, path []string, doc *bluge.Document) {
 			for _, key := range val.MapKeys() {
 				fieldName := key.String()
 				fieldVal := val.MapIndex(key).Interface()
-				blugeProcessProperty(fieldVal, append(path, fieldName), doc)
+				blugeProcessProperty(fieldVal, append(path, fieldName), doc, sortable)
 			}
 		}
 	case reflect.Struct:
@@ -117,35 +128,35 @@ func BlugeWalkDocument(data interface{}, path []string, doc *bluge.Document) {
 				if fieldName != "" {
 					newpath = append(path, fieldName)
 				}
-				blugeProcessProperty(fieldVal, newpath, doc)
+				blugeProcessProperty(fieldVal, newpath, doc, sortable)
 			}
 		}
 	case reflect.Slice, reflect.Array:
 		for i := 0; i < val.Len(); i++ {
 			if val.Index(i).CanInterface() {
 				fieldVal := val.Index(i).Interface()
-				blugeProcessProperty(fieldVal, path, doc)
+				blugeProcessProperty(fieldVal, path, doc, sortable)
 			}
 		}
 	case reflect.Ptr:
 		ptrElem := val.Elem()
 		if ptrElem.IsValid() && ptrElem.CanInterface() {
-			blugeProcessProperty(ptrElem.Interface(), path, doc)
+			blugeProcessProperty(ptrElem.Interface(), path, doc, sortable)
 		}
 	case reflect.String:
-		blugeProcessProperty(val.String(), path, doc)
+		blugeProcessProperty(val.String(), path, doc, sortable)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		blugeProcessProperty(float64(val.Int()), path, doc)
+		blugeProcessProperty(float64(val.Int()), path, doc, sortable)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		blugeProcessProperty(float64(val.Uint()), path, doc)
+		blugeProcessProperty(float64(val.Uint()), path, doc, sortable)
 	case reflect.Float32, reflect.Float64:
-		blugeProcessProperty(float64(val.Float()), path, doc)
+		blugeProcessProperty(float64(val.Float()), path, doc, sortable)
 	case reflect.Bool:
-		blugeProcessProperty(val.Bool(), path, doc)
+		blugeProcessProperty(val.Bool(), path, doc, sortable)
 	}
 }
 
-func blugeProcessProperty(property interface{}, path []string, doc *bluge.Document) {
+func blugeProcessProperty(property interface{}, path []string, doc *bluge.Document, sortable bool) {
 	pathString := strings.Join(path, ".")
 
 	propertyValue := reflect.ValueOf(property)
@@ -163,52 +174,59 @@ func blugeProcessProperty(property interface{}, path []string, doc *bluge.Docume
 		parsedDateTime, err := blugeParseDateTime(propertyValueString)
 		if err != nil {
 			// index as text
-			doc.AddField(bluge.NewKeywordField(pathString, propertyValueString))
+			doc.AddField(blugeSortable(bluge.NewKeywordField(pathString, propertyValueString), sortable))
 		} else {
 			// index as datetime
-			doc.AddField(bluge.NewDateTimeField(pathString, parsedDateTime))
+			doc.AddField(blugeSortable(bluge.NewDateTimeField(pathString, parsedDateTime), sortable))
 		}
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		blugeProcessProperty(float64(propertyValue.Int()), path, doc)
+		blugeProcessProperty(float64(propertyValue.Int()), path, doc, sortable)
 		return
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		blugeProcessProperty(float64(propertyValue.Uint()), path, doc)
+		blugeProcessProperty(float64(propertyValue.Uint()), path, doc, sortable)
 		return
 	case reflect.Float64, reflect.Float32:
 		propertyValFloat := propertyValue.Float()
 
 		// automatic indexing behavior
-		doc.AddField(bluge.NewNumericField(pathString, propertyValFloat))
+		doc.AddField(blugeSortable(bluge.NewNumericField(pathString, propertyValFloat), sortable))
 
 	case reflect.Bool:
 		propertyValBool := propertyValue.Bool()
 
 		// automatic indexing behavior
 		if propertyValBool {
-			doc.AddField(bluge.NewKeywordField(pathString, "T"))
+			doc.AddField(blugeSortable(bluge.NewKeywordField(pathString, "T"), sortable))
 		} else {
-			doc.AddField(bluge.NewKeywordField(pathString, "F"))
+			doc.AddField(blugeSortable(bluge.NewKeywordField(pathString, "F"), sortable))
 		}
 
 	case reflect.Struct:
 		switch property := property.(type) {
 		case time.Time:
 			// don't descend into the time struct
-			doc.AddField(bluge.NewDateTimeField(pathString, property))
+			doc.AddField(blugeSortable(bluge.NewDateTimeField(pathString, property), sortable))
 
 		default:
-			BlugeWalkDocument(property, path, doc)
+			blugeWalkDocument(property, path, doc, sortable)
 		}
 	case reflect.Map, reflect.Slice:
-		BlugeWalkDocument(property, path, doc)
+		blugeWalkDocument(property, path, doc, sortable)
 	case reflect.Ptr:
 		if !propertyValue.IsNil() {
-			BlugeWalkDocument(property, path, doc)
+			blugeWalkDocument(property, path, doc, sortable)
 		}
 	default:
-		BlugeWalkDocument(property, path, doc)
+		blugeWalkDocument(property, path, doc, sortable)
+	}
+}
+
+func blugeSortable(field *bluge.TermField, sortable bool) *bluge.TermField {
+	if sortable {
+		return field.Sortable()
 	}
+	return field
 }
 
 func blugeParseTagName(tag string) string {
@@ -266,3 +284,10 @@ func BlugeInMemoryConfig() bluge.Config {
 	cfg.DefaultSearchAnalyzer = BlugeKeywordAnalyzer
 	return cfg
 }
+
+func BlugeDiskConfig(path string) bluge.Config {
+	cfg := bluge.DefaultConfig(path)
+	cfg.DefaultSimilarity = constantSimilarity{}
+	cfg.DefaultSearchAnalyzer = BlugeKeywordAnalyzer
+	return cfg
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
 	"github.com/heroiclabs/nakama-common/rtapi"
 	"github.com/heroiclabs/nakama-common/runtime"
 	"go.uber.org/atomic"
@@ -180,6 +181,7 @@ func (s *testMetrics) GaugePresences(value float64)
 func (s *testMetrics) Matchmaker(tickets, activeTickets float64, processTime time.Duration) {}
 func (s *testMetrics) PresenceEvent(dequeueElapsed, processElapsed time.Duration)           {}
 func (s *testMetrics) StorageWriteRejectCount(tags map[string]string, delta int64)          {}
+func (s *testMetrics) StorageExpiredCount(tags map[string]string, delta int64)              {}
 func (s *testMetrics) CustomCounter(name string, tags map[string]string, delta int64)       {}
 func (s *testMetrics) CustomGauge(name string, tags map[string]string, value float64)       {}
 func (s *testMetrics) CustomTimer(name string, tags map[string]string, value time.Duration) {}
@@ -195,9 +197,10 @@ func (s *testMessageRouter) SendToPresenceIDs(_ *zap.Logger, presences []*Presen
 		s.sendToPresence(presences, envelope)
 	}
 }
-func (s *testMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool) {}
-func (s *testMessageRouter) SendDeferred(*zap.Logger, []*DeferredMessage)                    {}
-func (s *testMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool)                    {}
+func (s *testMessageRouter) SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool)  {}
+func (s *testMessageRouter) SendDeferred(*zap.Logger, []*DeferredMessage)                     {}
+func (s *testMessageRouter) SendToAll(*zap.Logger, *rtapi.Envelope, bool)                     {}
+func (s *testMessageRouter) PushNotifications(*zap.Logger, map[uuid.UUID][]*api.Notification) {}
 
 // testTracker implements the Tracker interface and does nothing
 type testTracker struct{}
@@ -105,6 +105,10 @@ type MatchmakerIndex struct {
 	NumericProperties map[string]float64  `json:"-"`
 	ParsedQuery       bluge.Query         `json:"-"`
 	Entries           []*MatchmakerEntry  `json:"-"`
+
+	// Expansion stages widening the query over time, ParsedQuery reflects the current one.
+	Expansion      []*MatchmakerExpansionStage `json:"-"`
+	ExpansionStage int                         `json:"-"`
 }
 
 type MatchmakerExtract struct {
@@ -122,6 +126,7 @@ type MatchmakerExtract struct {
 	Intervals         int
 	CreatedAt         int64
 	Node              string
+	Expansion         []*MatchmakerExpansionStage
 }
 
 type MatchmakerIndexGroup struct {
@@ -172,6 +177,7 @@ type Matchmaker interface {
 	Stop()
 	OnMatchedEntries(fn func(entries [][]*MatchmakerEntry))
 	Add(ctx context.Context, presences []*MatchmakerPresence, sessionID, partyId, query string, minCount, maxCount, countMultiple int, stringProperties map[string]string, numericProperties map[string]float64) (string, int64, error)
+	AddExpanding(ctx context.Context, presences []*MatchmakerPresence, sessionID, partyId, query string, expansion []*MatchmakerExpansionStage, minCount, maxCount, countMultiple int, stringProperties map[string]string, numericProperties map[string]float64) (string, int64, error)
 	Insert(extracts []*MatchmakerExtract) error
 	Extract() []*MatchmakerExtract
 	RemoveSession(sessionID, ticket string) error
@@ -180,6 +186,11 @@ type Matchmaker interface {
 	RemovePartyAll(partyID string) error
 	RemoveAll(node string)
 	Remove(tickets []string)
+	TicketStatus(ticket string) (*MatchmakerTicketStatus, error)
+	QueueStatus() []*MatchmakerQueueStatus
+	OnBackfilledEntries(fn func(backfilled []*MatchmakerBackfillMatch))
+	BackfillSet(matchID string, slots int, query string, stringProperties map[string]string, numericProperties map[string]float64) error
+	BackfillRemove(matchID string) error
 }
 
 type LocalMatchmaker struct {
@@ -209,14 +220,43 @@ type LocalMatchmaker struct {
 	// Reverse lookup cache for mutual matching.
 	revCache       *MapOf[string, map[string]bool]
 	revThresholdFn func() *time.Timer
+	// Recent wait times per queue.
+	queueStats map[string]*matchmakerQueueStats
+	// Open slots in running matches, indexed separately from tickets.
+	backfilledEntriesFn func([]*MatchmakerBackfillMatch)
+	backfillWriter      *bluge.Writer
+	backfills           map[string]*MatchmakerBackfill
 }
 
 func NewLocalMatchmaker(logger, startupLogger *zap.Logger, config Config, router MessageRouter, metrics Metrics, runtime *Runtime) Matchmaker {
+	m := newLocalMatchmaker(logger, startupLogger, config, router, metrics, runtime)
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(config.GetMatchmaker().IntervalSec) * time.Second)
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				m.Process()
+			}
+		}
+	}()
+
+	return m
+}
+
+// newLocalMatchmaker creates a matchmaker that only processes its pool when told to.
+func newLocalMatchmaker(logger, startupLogger *zap.Logger, config Config, router MessageRouter, metrics Metrics, runtime *Runtime) *LocalMatchmaker {
 	cfg := BlugeInMemoryConfig()
 	indexWriter, err := bluge.OpenWriter(cfg)
 	if err != nil {
 		startupLogger.Fatal("Failed to create matchmaker index", zap.Error(err))
 	}
+	backfillWriter, err := bluge.OpenWriter(BlugeInMemoryConfig())
+	if err != nil {
+		startupLogger.Fatal("Failed to create matchmaker backfill index", zap.Error(err))
+	}
 
 	ctx, ctxCancelFn := context.WithCancel(context.Background())
 
@@ -239,6 +279,9 @@ func NewLocalMatchmaker(logger, startupLogger *zap.Logger, config Config, router
 		indexes:        make(map[string]*MatchmakerIndex),
 		activeIndexes:  make(map[string]*MatchmakerIndex),
 		revCache:       &MapOf[string, map[string]bool]{},
+		queueStats:     make(map[string]*matchmakerQueueStats),
+		backfillWriter: backfillWriter,
+		backfills:      make(map[string]*MatchmakerBackfill),
 	}
 
 	if revThreshold := m.config.GetMatchmaker().RevThreshold; revThreshold > 0 && m.config.GetMatchmaker().RevPrecision {
@@ -247,18 +290,6 @@ func NewLocalMatchmaker(logger, startupLogger *zap.Logger, config Config, router
 		}
 	}
 
-	go func() {
-		ticker := time.NewTicker(time.Duration(config.GetMatchmaker().IntervalSec) * time.Second)
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				m.Process()
-			}
-		}
-	}()
-
 	return m
 }
 
@@ -286,54 +317,166 @@ func (m *LocalMatchmaker) Process() {
 		m.metrics.Matchmaker(float64(indexCount), float64(activeIndexCount), time.Since(startTime))
 	}()
 
+	var matchedEntries [][]*MatchmakerEntry
+	var backfilled []*MatchmakerBackfillMatch
+	matchedEntries, backfilled, activeIndexCount, indexCount = m.processMatches()
+
+	if len(backfilled) > 0 {
+		for _, match := range backfilled {
+			m.sendMatched(match.Entries, match.MatchId, true)
+		}
+		if m.backfilledEntriesFn != nil {
+			go m.backfilledEntriesFn(backfilled)
+		}
+	}
+
+	if matchedEntriesCount := len(matchedEntries); matchedEntriesCount > 0 {
+		wg := &sync.WaitGroup{}
+		wg.Add(matchedEntriesCount)
+		for _, entries := range matchedEntries {
+			go func(entries []*MatchmakerEntry) {
+				var tokenOrMatchID string
+				var isMatchID bool
+				var err error
+
+				// Check if there's a matchmaker matched runtime callback, call it, and see if it returns a match ID.
+				fn := m.runtime.MatchmakerMatched()
+				if fn != nil {
+					tokenOrMatchID, isMatchID, err = fn(context.Background(), entries)
+					if err != nil {
+						m.logger.Error("Error running Matchmaker Matched hook.", zap.Error(err))
+					}
+				}
+
+				if !isMatchID {
+					// If there was no callback or it didn't return a valid match ID always return at least a token.
+					token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+						"mid": fmt.Sprintf("%v.", uuid.Must(uuid.NewV4()).String()),
+						"exp": time.Now().UTC().Add(30 * time.Second).Unix(),
+					})
+					tokenOrMatchID, _ = token.SignedString([]byte(m.config.GetSession().EncryptionKey))
+				}
+
+				m.sendMatched(entries, tokenOrMatchID, isMatchID)
+				wg.Done()
+			}(entries)
+		}
+		wg.Wait()
+		if m.matchedEntriesFn != nil {
+			go m.matchedEntriesFn(matchedEntries)
+		}
+	}
+}
+
+// sendMatched notifies each matched entry of its match, identified either by a match ID or a token to create one.
+func (m *LocalMatchmaker) sendMatched(entries []*MatchmakerEntry, tokenOrMatchID string, isMatchID bool) {
+	users := make([]*rtapi.MatchmakerMatched_MatchmakerUser, 0, len(entries))
+	for _, entry := range entries {
+		users = append(users, &rtapi.MatchmakerMatched_MatchmakerUser{
+			Presence: &rtapi.UserPresence{
+				UserId:    entry.Presence.UserId,
+				SessionId: entry.Presence.SessionId,
+				Username:  entry.Presence.Username,
+			},
+			StringProperties:  entry.StringProperties,
+			NumericProperties: entry.NumericProperties,
+			PartyId:           entry.PartyId,
+		})
+	}
+	outgoing := &rtapi.Envelope{Message: &rtapi.Envelope_MatchmakerMatched{MatchmakerMatched: &rtapi.MatchmakerMatched{
+		// Ticket is set individually below for each recipient.
+		// Id set below to account for token or match ID case.
+		Users: users,
+		// Self is set individually below for each recipient.
+	}}}
+	if isMatchID {
+		outgoing.GetMatchmakerMatched().Id = &rtapi.MatchmakerMatched_MatchId{MatchId: tokenOrMatchID}
+	} else {
+		outgoing.GetMatchmakerMatched().Id = &rtapi.MatchmakerMatched_Token{Token: tokenOrMatchID}
+	}
+
+	for i, entry := range entries {
+		// Set per-recipient fields.
+		outgoing.GetMatchmakerMatched().Self = users[i]
+		outgoing.GetMatchmakerMatched().Ticket = entry.Ticket
+		// Route outgoing message.
+		m.router.SendToPresenceIDs(m.logger, []*PresenceID{{Node: entry.Presence.Node, SessionID: entry.Presence.SessionID}}, outgoing, true)
+	}
+}
+
+// processMatches runs one matchmaking pass over the pool, removes the tickets it matched, and returns their entries
+// along with the entries placed into running matches, and the active and total ticket counts before the pass.
+func (m *LocalMatchmaker) processMatches() ([][]*MatchmakerEntry, []*MatchmakerBackfillMatch, int, int) {
 	m.Lock()
 
-	activeIndexCount = len(m.activeIndexes)
-	indexCount = len(m.indexes)
+	activeIndexCount := len(m.activeIndexes)
+	indexCount := len(m.indexes)
 
-	// No active matchmaking tickets, the pool may be non-empty but there are no new tickets to check/query with.
-	if activeIndexCount == 0 {
+	// No active matchmaking tickets and no open slots to fill, the pool may be non-empty but there are no new tickets to
+	// check/query with. Tickets that are no longer active can still be placed into running matches.
+	if activeIndexCount == 0 && (len(m.backfills) == 0 || indexCount == 0) {
 		m.Unlock()
-		return
+		return nil, nil, activeIndexCount, indexCount
 	}
 
 	activeIndexesCopy := make(map[string]*MatchmakerIndex, activeIndexCount)
 	for ticket, activeIndex := range m.activeIndexes {
+		// Count this interval and widen the query if a new expansion stage was reached. Done under the lock, as ticket
+		// status reads these fields.
+		activeIndex.Intervals++
+		m.expandIndex(ticket, activeIndex)
 		activeIndexesCopy[ticket] = activeIndex
 	}
 	indexesCopy := make(map[string]*MatchmakerIndex, indexCount)
 	for ticket, index := range m.indexes {
 		indexesCopy[ticket] = index
 	}
+	backfillsCopy := make(map[string]*MatchmakerBackfill, len(m.backfills))
+	for matchID, backfill := range m.backfills {
+		backfillsCopy[matchID] = backfill
+	}
 
 	m.Unlock()
 
-	// Run the custom matching function if one is registered in the runtime, otherwise use the default process function.
+	// Fill open slots in running matches first from the whole pool, the remaining tickets are then considered for new
+	// matches.
+	var backfilled []*MatchmakerBackfillMatch
+	if len(backfillsCopy) > 0 {
+		backfilled = m.processBackfill(backfillsCopy, activeIndexesCopy, indexesCopy)
+	}
+	if len(activeIndexesCopy) == 0 {
+		m.Lock()
+		backfilled = m.applyBackfilled(backfilled, time.Now().UTC().UnixNano())
+		m.Unlock()
+		return nil, backfilled, activeIndexCount, indexCount
+	}
+
+	// Run the formation function if one is registered in the runtime. If there is none, or it fails, run the custom
+	// matching function if one is registered, otherwise use the default process function.
 	var matchedEntries [][]*MatchmakerEntry
 	var expiredActiveIndexes []string
-	if m.runtime.matchmakerOverrideFunction != nil {
-		matchedEntries, expiredActiveIndexes = m.processCustom(activeIndexesCopy, indexCount, indexesCopy)
-	} else {
-		matchedEntries, expiredActiveIndexes = m.processDefault(activeIndexCount, activeIndexesCopy, indexCount, indexesCopy)
+	var formed bool
+	if fn, budget := m.runtime.matchmakerFormation.Get(); fn != nil {
+		matchedEntries, expiredActiveIndexes, formed = m.processFormation(fn, budget, activeIndexesCopy, indexCount, indexesCopy)
+	}
+	if !formed {
+		if m.runtime.matchmakerOverrideFunction != nil {
+			matchedEntries, expiredActiveIndexes = m.processCustom(activeIndexesCopy, indexCount, indexesCopy)
+		} else {
+			matchedEntries, expiredActiveIndexes = m.processDefault(activeIndexCount, activeIndexesCopy, indexCount, indexesCopy)
+		}
 	}
 
 	m.Lock()
 
+	now := time.Now().UTC().UnixNano()
 	for _, ticket := range expiredActiveIndexes {
 		delete(m.activeIndexes, ticket)
 	}
 
 	for i := 0; i < len(matchedEntries); i++ {
 		// Check that the current matched entries are all still present and eligible for the match to be formed.
-		currentMatchedEntries := matchedEntries[i]
-		var incomplete bool
-		for _, entry := range currentMatchedEntries {
-			if _, found := m.indexes[entry.Ticket]; !found {
-				incomplete = true
-				break
-			}
-		}
-		if incomplete {
+		if !m.entriesPresent(matchedEntries[i]) {
 			matchedEntries[i] = matchedEntries[len(matchedEntries)-1]
 			matchedEntries[len(matchedEntries)-1] = nil
 			matchedEntries = matchedEntries[:len(matchedEntries)-1]
@@ -342,118 +485,101 @@ func (m *LocalMatchmaker) Process() {
 		}
 
 		// Remove all entries/indexes that have just matched.
-		ticketsToDelete := make(map[string]struct{}, len(currentMatchedEntries))
-		for _, entry := range currentMatchedEntries {
-			if _, ok := ticketsToDelete[entry.Ticket]; !ok {
-				ticketsToDelete[entry.Ticket] = struct{}{}
-			}
-			delete(m.indexes, entry.Ticket)
-			delete(m.activeIndexes, entry.Ticket)
-			m.revCache.Delete(entry.Ticket)
-			if sessionTickets, ok := m.sessionTickets[entry.Presence.SessionId]; ok {
-				if l := len(sessionTickets); l <= 1 {
-					delete(m.sessionTickets, entry.Presence.SessionId)
-				} else {
-					delete(sessionTickets, entry.Ticket)
-				}
-			}
-			if entry.PartyId != "" {
-				if partyTickets, ok := m.partyTickets[entry.PartyId]; ok {
-					if l := len(partyTickets); l <= 1 {
-						delete(m.partyTickets, entry.PartyId)
-					} else {
-						delete(partyTickets, entry.Ticket)
-					}
-				}
-			}
-		}
+		m.removeMatched(matchedEntries[i], now)
 	}
 
+	backfilled = m.applyBackfilled(backfilled, now)
+
 	m.Unlock()
 
-	if matchedEntriesCount := len(matchedEntries); matchedEntriesCount > 0 {
-		wg := &sync.WaitGroup{}
-		wg.Add(matchedEntriesCount)
-		for _, entries := range matchedEntries {
-			go func(entries []*MatchmakerEntry) {
-				var tokenOrMatchID string
-				var isMatchID bool
-				var err error
+	return matchedEntries, backfilled, activeIndexCount, indexCount
+}
 
-				// Check if there's a matchmaker matched runtime callback, call it, and see if it returns a match ID.
-				fn := m.runtime.MatchmakerMatched()
-				if fn != nil {
-					tokenOrMatchID, isMatchID, err = fn(context.Background(), entries)
-					if err != nil {
-						m.logger.Error("Error running Matchmaker Matched hook.", zap.Error(err))
-					}
-				}
+// applyBackfilled removes the tickets placed into running matches during a pass and takes their slots, returning the
+// placements that still hold entries. Must be called with the lock held.
+func (m *LocalMatchmaker) applyBackfilled(backfilled []*MatchmakerBackfillMatch, now int64) []*MatchmakerBackfillMatch {
+	for i := 0; i < len(backfilled); i++ {
+		// Entries whose tickets were removed during the pass are dropped, the rest still take their slots.
+		entries := make([]*MatchmakerEntry, 0, len(backfilled[i].Entries))
+		for _, entry := range backfilled[i].Entries {
+			if _, found := m.indexes[entry.Ticket]; found {
+				entries = append(entries, entry)
+			}
+		}
+		m.removeMatched(entries, now)
+		m.backfillUsed(backfilled[i].MatchId, len(entries))
+		if len(entries) == 0 {
+			backfilled[i] = backfilled[len(backfilled)-1]
+			backfilled[len(backfilled)-1] = nil
+			backfilled = backfilled[:len(backfilled)-1]
+			i--
+			continue
+		}
+		backfilled[i].Entries = entries
+	}
 
-				if !isMatchID {
-					// If there was no callback or it didn't return a valid match ID always return at least a token.
-					token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-						"mid": fmt.Sprintf("%v.", uuid.Must(uuid.NewV4()).String()),
-						"exp": time.Now().UTC().Add(30 * time.Second).Unix(),
-					})
-					tokenOrMatchID, _ = token.SignedString([]byte(m.config.GetSession().EncryptionKey))
-				}
+	return backfilled
+}
 
-				users := make([]*rtapi.MatchmakerMatched_MatchmakerUser, 0, len(entries))
-				for _, entry := range entries {
-					users = append(users, &rtapi.MatchmakerMatched_MatchmakerUser{
-						Presence: &rtapi.UserPresence{
-							UserId:    entry.Presence.UserId,
-							SessionId: entry.Presence.SessionId,
-							Username:  entry.Presence.Username,
-						},
-						StringProperties:  entry.StringProperties,
-						NumericProperties: entry.NumericProperties,
-						PartyId:           entry.PartyId,
-					})
-				}
-				outgoing := &rtapi.Envelope{Message: &rtapi.Envelope_MatchmakerMatched{MatchmakerMatched: &rtapi.MatchmakerMatched{
-					// Ticket is set individually below for each recipient.
-					// Id set below to account for token or match ID case.
-					Users: users,
-					// Self is set individually below for each recipient.
-				}}}
-				if isMatchID {
-					outgoing.GetMatchmakerMatched().Id = &rtapi.MatchmakerMatched_MatchId{MatchId: tokenOrMatchID}
-				} else {
-					outgoing.GetMatchmakerMatched().Id = &rtapi.MatchmakerMatched_Token{Token: tokenOrMatchID}
-				}
+// entriesPresent reports whether all the entries' tickets are still in the pool. Must be called with the lock held.
+func (m *LocalMatchmaker) entriesPresent(entries []*MatchmakerEntry) bool {
+	for _, entry := range entries {
+		if _, found := m.indexes[entry.Ticket]; !found {
+			return false
+		}
+	}
+	return true
+}
 
-				for i, entry := range entries {
-					// Set per-recipient fields.
-					outgoing.GetMatchmakerMatched().Self = users[i]
-					outgoing.GetMatchmakerMatched().Ticket = entry.Ticket
-					// Route outgoing message.
-					m.router.SendToPresenceIDs(m.logger, []*PresenceID{{Node: entry.Presence.Node, SessionID: entry.Presence.SessionID}}, outgoing, true)
-				}
-				wg.Done()
-			}(entries)
+// removeMatched removes the tickets of matched entries from the pool. Must be called with the lock held.
+func (m *LocalMatchmaker) removeMatched(entries []*MatchmakerEntry, now int64) {
+	ticketsToDelete := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if _, ok := ticketsToDelete[entry.Ticket]; !ok {
+			ticketsToDelete[entry.Ticket] = struct{}{}
+			if index, found := m.indexes[entry.Ticket]; found {
+				m.recordMatched(index, now)
+			}
 		}
-		wg.Wait()
-		if m.matchedEntriesFn != nil {
-			go m.matchedEntriesFn(matchedEntries)
+		delete(m.indexes, entry.Ticket)
+		delete(m.activeIndexes, entry.Ticket)
+		m.revCache.Delete(entry.Ticket)
+		if sessionTickets, ok := m.sessionTickets[entry.Presence.SessionId]; ok {
+			if l := len(sessionTickets); l <= 1 {
+				delete(m.sessionTickets, entry.Presence.SessionId)
+			} else {
+				delete(sessionTickets, entry.Ticket)
+			}
+		}
+		if entry.PartyId != "" {
+			if partyTickets, ok := m.partyTickets[entry.PartyId]; ok {
+				if l := len(partyTickets); l <= 1 {
+					delete(m.partyTickets, entry.PartyId)
+				} else {
+					delete(partyTickets, entry.Ticket)
+				}
+			}
 		}
 	}
 }
 
 func (m *LocalMatchmaker) Add(ctx context.Context, presences []*MatchmakerPresence, sessionID, partyId, query string, minCount, maxCount, countMultiple int, stringProperties map[string]string, numericProperties map[string]float64) (string, int64, error) {
+	return m.AddExpanding(ctx, presences, sessionID, partyId, query, nil, minCount, maxCount, countMultiple, stringProperties, numericProperties)
+}
+
+// AddExpanding adds a ticket whose query is relaxed in stages as it waits, rather than fixed.
+func (m *LocalMatchmaker) AddExpanding(ctx context.Context, presences []*MatchmakerPresence, sessionID, partyId, query string, expansion []*MatchmakerExpansionStage, minCount, maxCount, countMultiple int, stringProperties map[string]string, numericProperties map[string]float64) (string, int64, error) {
 	// Check if the matchmaker has been stopped.
 	if m.stopped.Load() {
 		return "", 0, runtime.ErrMatchmakerNotAvailable
 	}
 
-	parsedQuery, err := ParseQueryString(query)
+	parsedQuery, err := parseMatchmakerQuery(query)
 	if err != nil {
-		return "", 0, runtime.ErrMatchmakerQueryInvalid
+		return "", 0, err
 	}
-	if parsedQuery, ok := parsedQuery.(ValidatableQuery); ok {
-		if parsedQuery.Validate() != nil {
-			return "", 0, runtime.ErrMatchmakerQueryInvalid
-		}
+	if err := validateMatchmakerExpansion(expansion, numericProperties); err != nil {
+		return "", 0, err
 	}
 
 	// Merge incoming properties.
@@ -494,6 +620,13 @@ func (m *LocalMatchmaker) Add(ctx context.Context, presences []*MatchmakerPresen
 		StringProperties:  stringProperties,
 		NumericProperties: numericProperties,
 		ParsedQuery:       parsedQuery,
+
+		Expansion:      expansion,
+		ExpansionStage: -1,
+	}
+	// Apply any stages that take effect from the start.
+	if _, err := index.expand(); err != nil {
+		return "", 0, err
 	}
 
 	m.Lock()
@@ -623,6 +756,13 @@ func (m *LocalMatchmaker) Insert(extracts []*MatchmakerExtract) error {
 			StringProperties:  extract.StringProperties,
 			NumericProperties: extract.NumericProperties,
 			ParsedQuery:       parsedQuery,
+
+			Expansion:      extract.Expansion,
+			ExpansionStage: -1,
+		}
+		if _, err := index.expand(); err != nil {
+			m.logger.Error("error expanding matchmaker query", zap.Error(err), zap.String("query", extract.Query))
+			continue
 		}
 
 		matchmakerIndexDoc, err := MapMatchmakerIndex(extract.Ticket, index)
@@ -657,7 +797,7 @@ func (m *LocalMatchmaker) Insert(extracts []*MatchmakerExtract) error {
 	for ticket, index := range indexes {
 		m.indexes[ticket] = index
 		m.revCache.Store(ticket, make(map[string]bool, 10))
-		if index.Intervals < m.config.GetMatchmaker().MaxIntervals {
+		if index.Intervals < m.config.GetMatchmaker().MaxIntervals || index.Expanding() {
 			m.activeIndexes[ticket] = index
 		}
 		if index.PartyId != "" {
@@ -709,6 +849,7 @@ func (m *LocalMatchmaker) Extract() []*MatchmakerExtract {
 			Intervals:         index.Intervals,
 			CreatedAt:         index.CreatedAt,
 			Node:              index.Node,
+			Expansion:         index.Expansion,
 		}
 		for _, entry := range index.Entries {
 			extract.Presences = append(extract.Presences, entry.Presence)
@@ -1033,7 +1174,7 @@ func MapMatchmakerIndex(id string, in *MatchmakerIndex) (*bluge.Document, error)
 	rv.AddField(bluge.NewNumericField("created_at", float64(in.CreatedAt)).StoreValue())
 
 	if in.Properties != nil {
-		BlugeWalkDocument(in.Properties, []string{"properties"}, rv)
+		BlugeWalkSortableDocument(in.Properties, []string{"properties"}, rv)
 	}
 
 	return rv, nil
@@ -0,0 +1,264 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/heroiclabs/nakama-common/runtime"
+	"go.uber.org/zap"
+)
+
+// MatchmakerBackfill is a set of open slots in a running match. Tickets whose query matches the backfill's properties,
+// and whose own properties match the backfill's query if it has one, are placed into the match instead of waiting
+// for a new one to form.
+type MatchmakerBackfill struct {
+	MatchId           string
+	Slots             int
+	Query             string
+	StringProperties  map[string]string
+	NumericProperties map[string]float64
+	CreatedAt         int64
+	ParsedQuery       bluge.Query
+}
+
+// MatchmakerBackfillMatch is a group of entries placed into an existing match in one matchmaker pass.
+type MatchmakerBackfillMatch struct {
+	MatchId string
+	Entries []*MatchmakerEntry
+}
+
+func (m *LocalMatchmaker) OnBackfilledEntries(fn func(backfilled []*MatchmakerBackfillMatch)) {
+	m.backfilledEntriesFn = fn
+}
+
+// BackfillSet registers or replaces the open slots of a match. Zero or fewer slots removes the match's backfill.
+func (m *LocalMatchmaker) BackfillSet(matchID string, slots int, query string, stringProperties map[string]string, numericProperties map[string]float64) error {
+	if m.stopped.Load() {
+		return runtime.ErrMatchmakerNotAvailable
+	}
+	if slots <= 0 {
+		return m.BackfillRemove(matchID)
+	}
+
+	var parsedQuery bluge.Query
+	if query != "" && query != "*" {
+		var err error
+		if parsedQuery, err = parseMatchmakerQuery(query); err != nil {
+			return err
+		}
+	}
+
+	backfill := &MatchmakerBackfill{
+		MatchId:           matchID,
+		Slots:             slots,
+		Query:             query,
+		StringProperties:  stringProperties,
+		NumericProperties: numericProperties,
+		CreatedAt:         time.Now().UTC().UnixNano(),
+		ParsedQuery:       parsedQuery,
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	if existing, found := m.backfills[matchID]; found {
+		backfill.CreatedAt = existing.CreatedAt
+	}
+	if err := m.backfillWriter.Update(bluge.Identifier(matchID), mapMatchmakerBackfill(backfill)); err != nil {
+		m.logger.Error("error indexing matchmaker backfill", zap.Error(err))
+		return runtime.ErrMatchmakerIndex
+	}
+	m.backfills[matchID] = backfill
+
+	return nil
+}
+
+// BackfillRemove removes any open slots registered for a match.
+func (m *LocalMatchmaker) BackfillRemove(matchID string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, found := m.backfills[matchID]; !found {
+		return nil
+	}
+	delete(m.backfills, matchID)
+	if err := m.backfillWriter.Delete(bluge.Identifier(matchID)); err != nil {
+		m.logger.Error("error deleting matchmaker backfill", zap.Error(err))
+		return runtime.ErrMatchmakerDelete
+	}
+
+	return nil
+}
+
+// mapMatchmakerBackfill indexes a backfill's properties the same way as a ticket's, so ticket queries apply unchanged.
+func mapMatchmakerBackfill(backfill *MatchmakerBackfill) *bluge.Document {
+	rv := bluge.NewDocument(backfill.MatchId)
+
+	rv.AddField(bluge.NewNumericField("slots", float64(backfill.Slots)).StoreValue())
+	rv.AddField(bluge.NewNumericField("created_at", float64(backfill.CreatedAt)).StoreValue())
+
+	properties := make(map[string]interface{}, len(backfill.StringProperties)+len(backfill.NumericProperties))
+	for k, v := range backfill.StringProperties {
+		properties[k] = v
+	}
+	for k, v := range backfill.NumericProperties {
+		properties[k] = v
+	}
+	BlugeWalkSortableDocument(properties, []string{"properties"}, rv)
+
+	return rv
+}
+
+// processBackfill places tickets from the whole pool into the open slots of running matches, longest waiting tickets
+// first, so tickets that are no longer active are still offered the slots. Placed tickets are removed from the copies
+// and the index so the rest of the pass does not consider them.
+func (m *LocalMatchmaker) processBackfill(backfills map[string]*MatchmakerBackfill, activeIndexesCopy, indexesCopy map[string]*MatchmakerIndex) []*MatchmakerBackfillMatch {
+	backfillReader, err := m.backfillWriter.Reader()
+	if err != nil {
+		m.logger.Error("error getting matchmaker backfill reader", zap.Error(err))
+		return nil
+	}
+	defer backfillReader.Close()
+
+	indexReader, err := m.indexWriter.Reader()
+	if err != nil {
+		m.logger.Error("error getting matchmaker index reader", zap.Error(err))
+		return nil
+	}
+	defer indexReader.Close()
+
+	// Slots remaining in this pass, the backfill index is only updated once the pass is complete.
+	slots := make(map[string]int, len(backfills))
+	for matchID, backfill := range backfills {
+		slots[matchID] = backfill.Slots
+	}
+
+	indexes := make([]*MatchmakerIndex, 0, len(indexesCopy))
+	for _, index := range indexesCopy {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].CreatedAt < indexes[j].CreatedAt
+	})
+
+	backfilled := make(map[string]*MatchmakerBackfillMatch)
+	order := make([]string, 0)
+	batch := bluge.NewBatch()
+	var batchSize int
+	for _, index := range indexes {
+		matchID, err := m.backfillFor(backfillReader, indexReader, backfills, slots, index)
+		if err != nil {
+			m.logger.Error("error searching matchmaker backfills", zap.Error(err))
+			continue
+		} else if matchID == "" {
+			continue
+		}
+
+		slots[matchID] -= index.Count
+		match, found := backfilled[matchID]
+		if !found {
+			match = &MatchmakerBackfillMatch{MatchId: matchID}
+			backfilled[matchID] = match
+			order = append(order, matchID)
+		}
+		match.Entries = append(match.Entries, index.Entries...)
+
+		delete(activeIndexesCopy, index.Ticket)
+		delete(indexesCopy, index.Ticket)
+		batch.Delete(bluge.Identifier(index.Ticket))
+		batchSize++
+	}
+	if batchSize > 0 {
+		if err := m.indexWriter.Batch(batch); err != nil {
+			m.logger.Error("error deleting matchmaker process entries batch", zap.Error(err))
+		}
+	}
+
+	results := make([]*MatchmakerBackfillMatch, 0, len(order))
+	for _, matchID := range order {
+		results = append(results, backfilled[matchID])
+	}
+	return results
+}
+
+// backfillFor returns the best scoring match with enough open slots for the ticket, or an empty string if none fit.
+func (m *LocalMatchmaker) backfillFor(backfillReader, indexReader *bluge.Reader, backfills map[string]*MatchmakerBackfill, slots map[string]int, index *MatchmakerIndex) (string, error) {
+	backfillQuery := bluge.NewBooleanQuery()
+	backfillQuery.AddMust(index.ParsedQuery)
+	backfillQuery.AddMust(bluge.NewNumericRangeInclusiveQuery(float64(index.Count), math.Inf(1), true, true).SetField("slots"))
+
+	searchRequest := bluge.NewTopNSearch(len(backfills), backfillQuery)
+	// Prefer the best matches, or if the matches are equivalent, the longest running backfills.
+	searchRequest.SortBy([]string{"-_score", "created_at"})
+
+	result, err := backfillReader.Search(m.ctx, searchRequest)
+	if err != nil {
+		return "", err
+	}
+	blugeMatches, err := IterateBlugeMatches(result, map[string]struct{}{}, m.logger)
+	if err != nil {
+		return "", err
+	}
+
+	for _, hit := range blugeMatches.Hits {
+		backfill, found := backfills[hit.ID]
+		if !found || slots[hit.ID] < index.Count {
+			continue
+		}
+		if backfill.ParsedQuery != nil {
+			// The match must also accept the ticket.
+			ticketQuery := bluge.NewBooleanQuery()
+			ticketQuery.AddMust(bluge.NewTermQuery(index.Ticket).SetField("_id"), backfill.ParsedQuery)
+			dmi, err := indexReader.Search(m.ctx, bluge.NewTopNSearch(0, ticketQuery).WithStandardAggregations())
+			if err != nil {
+				return "", err
+			}
+			if dmi.Aggregations().Count() != 1 {
+				continue
+			}
+		}
+		return hit.ID, nil
+	}
+
+	return "", nil
+}
+
+// backfillUsed takes slots from a match's backfill once tickets have been placed into it, removing the backfill when
+// no slots remain. Must be called with the lock held.
+func (m *LocalMatchmaker) backfillUsed(matchID string, used int) {
+	backfill, found := m.backfills[matchID]
+	if !found || used == 0 {
+		return
+	}
+
+	// Replace rather than modify, the backfill may still be referenced by an in progress pass.
+	updated := *backfill
+	updated.Slots -= used
+	if updated.Slots <= 0 {
+		delete(m.backfills, matchID)
+		if err := m.backfillWriter.Delete(bluge.Identifier(matchID)); err != nil {
+			m.logger.Error("error deleting matchmaker backfill", zap.Error(err))
+		}
+		return
+	}
+	m.backfills[matchID] = &updated
+	if err := m.backfillWriter.Update(bluge.Identifier(matchID), mapMatchmakerBackfill(&updated)); err != nil {
+		m.logger.Error("error indexing matchmaker backfill", zap.Error(err))
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func TestMatchmakerBackfill(t *testing.T) {
+	logger := loggerForTest(t)
+	cfg := NewConfig(logger)
+	cfg.Matchmaker.RevThreshold = 0
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{})
+	t.Cleanup(m.Stop)
+
+	add := func(mode string, players int, stringProperties map[string]string) string {
+		presences := make([]*MatchmakerPresence, 0, players)
+		for i := 0; i < players; i++ {
+			sessionID := uuid.Must(uuid.NewV4())
+			presences = append(presences, &MatchmakerPresence{
+				UserId:    sessionID.String(),
+				SessionId: sessionID.String(),
+				Username:  sessionID.String(),
+				Node:      m.node,
+				SessionID: sessionID,
+			})
+		}
+		var partyID string
+		if players > 1 {
+			partyID = uuid.Must(uuid.NewV4()).String()
+		}
+		stringProperties["mode"] = mode
+		ticket, _, err := m.Add(context.Background(), presences, presences[0].SessionId, partyID, "+properties.mode:"+mode, 4, 4, 1, stringProperties, nil)
+		if err != nil {
+			t.Fatalf("error adding ticket: %v", err)
+		}
+		return ticket
+	}
+
+	if err := m.BackfillSet("arena-match", 3, "", map[string]string{"mode": "arena"}, nil); err != nil {
+		t.Fatalf("error setting backfill: %v", err)
+	}
+	if err := m.BackfillSet("ranked-match", 4, "+properties.ranked:T", map[string]string{"mode": "arena"}, nil); err != nil {
+		t.Fatalf("error setting backfill: %v", err)
+	}
+
+	solo := add("arena", 1, map[string]string{})
+	party := add("arena", 2, map[string]string{})
+	waiting := add("arena", 1, map[string]string{}) // No slots left in the arena match, and not accepted by the ranked match.
+	ranked := add("arena", 1, map[string]string{"ranked": "T"})
+	add("ctf", 1, map[string]string{})
+
+	matchedEntries, backfilled, _, _ := m.processMatches()
+	if len(matchedEntries) != 0 {
+		t.Fatalf("expected no new matches, got %d", len(matchedEntries))
+	}
+	if len(backfilled) != 2 {
+		t.Fatalf("expected 2 backfilled matches, got %d", len(backfilled))
+	}
+
+	tickets := make(map[string]map[string]int, len(backfilled))
+	for _, match := range backfilled {
+		tickets[match.MatchId] = make(map[string]int)
+		for _, entry := range match.Entries {
+			tickets[match.MatchId][entry.Ticket]++
+		}
+	}
+	if len(tickets["arena-match"]) != 2 || tickets["arena-match"][solo] != 1 || tickets["arena-match"][party] != 2 {
+		t.Fatalf("unexpected arena match backfill: %v", tickets["arena-match"])
+	}
+	if len(tickets["ranked-match"]) != 1 || tickets["ranked-match"][ranked] != 1 {
+		t.Fatalf("unexpected ranked match backfill: %v", tickets["ranked-match"])
+	}
+
+	// Backfilled tickets leave the pool, and used slots are taken from the backfills.
+	if _, found := m.indexes[solo]; found {
+		t.Fatalf("expected backfilled ticket to be removed")
+	}
+	if len(m.indexes) != 2 {
+		t.Fatalf("expected 2 tickets left, got %d", len(m.indexes))
+	}
+	if _, found := m.backfills["arena-match"]; found {
+		t.Fatalf("expected full backfill to be removed")
+	}
+	if backfill := m.backfills["ranked-match"]; backfill == nil || backfill.Slots != 3 {
+		t.Fatalf("expected 3 ranked slots left, got %+v", backfill)
+	}
+
+	// Tickets that are no longer active are still placed into newly opened slots.
+	m.Lock()
+	m.activeIndexes = make(map[string]*MatchmakerIndex)
+	m.Unlock()
+	if err := m.BackfillSet("open-match", 1, "", map[string]string{"mode": "arena"}, nil); err != nil {
+		t.Fatalf("error setting backfill: %v", err)
+	}
+	_, backfilled, _, _ = m.processMatches()
+	if len(backfilled) != 1 || backfilled[0].MatchId != "open-match" || len(backfilled[0].Entries) != 1 || backfilled[0].Entries[0].Ticket != waiting {
+		t.Fatalf("expected the waiting ticket to be backfilled, got %+v", backfilled)
+	}
+
+	if err := m.BackfillSet("ranked-match", 0, "", nil, nil); err != nil {
+		t.Fatalf("error removing backfill: %v", err)
+	}
+	if len(m.backfills) != 0 {
+		t.Fatalf("expected no backfills, got %d", len(m.backfills))
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"errors"
+
+	"github.com/blugelabs/bluge"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+var ErrMatchmakerExpansionInvalid = errors.New("matchmaker expansion invalid")
+
+// MatchmakerExpansionStage widens a ticket's matching criteria once it has waited a number of process intervals, so a
+// single ticket can start strict and relax over time, for example rating ±100 then ±300.
+//
+// Stages inherit anything they don't set from the stages before them, and from the ticket itself.
+type MatchmakerExpansionStage struct {
+	// Process intervals the ticket must have waited before this stage applies, 0 applies it from the start.
+	Interval int `json:"interval"`
+	// Replaces the ticket's query if set.
+	Query string `json:"query,omitempty"`
+	// Other tickets must have each numeric property within this distance of the ticket's own value of it.
+	NumericTolerances map[string]float64 `json:"numeric_tolerances,omitempty"`
+}
+
+// validateMatchmakerExpansion checks that stages are in strictly increasing interval order, that their queries are
+// valid, and that tolerances are for numeric properties the ticket has.
+func validateMatchmakerExpansion(expansion []*MatchmakerExpansionStage, numericProperties map[string]float64) error {
+	lastInterval := -1
+	for _, stage := range expansion {
+		if stage == nil || stage.Interval <= lastInterval {
+			return ErrMatchmakerExpansionInvalid
+		}
+		lastInterval = stage.Interval
+
+		if stage.Query != "" {
+			if _, err := parseMatchmakerQuery(stage.Query); err != nil {
+				return err
+			}
+		}
+		for name, tolerance := range stage.NumericTolerances {
+			if _, found := numericProperties[name]; !found || tolerance < 0 {
+				return ErrMatchmakerExpansionInvalid
+			}
+		}
+	}
+	return nil
+}
+
+func parseMatchmakerQuery(query string) (bluge.Query, error) {
+	parsedQuery, err := ParseQueryString(query)
+	if err != nil {
+		return nil, runtime.ErrMatchmakerQueryInvalid
+	}
+	if parsedQuery, ok := parsedQuery.(ValidatableQuery); ok {
+		if parsedQuery.Validate() != nil {
+			return nil, runtime.ErrMatchmakerQueryInvalid
+		}
+	}
+	return parsedQuery, nil
+}
+
+// Expanding reports whether the ticket still has expansion stages it hasn't reached.
+func (m *MatchmakerIndex) Expanding() bool {
+	return m.ExpansionStage+1 < len(m.Expansion)
+}
+
+// expand moves the ticket to the latest expansion stage its interval count has reached, and reports whether its query
+// changed as a result.
+func (m *MatchmakerIndex) expand() (bool, error) {
+	stage := m.ExpansionStage
+	for stage+1 < len(m.Expansion) && m.Expansion[stage+1].Interval <= m.Intervals {
+		stage++
+	}
+	if stage == m.ExpansionStage {
+		return false, nil
+	}
+
+	parsedQuery, err := m.stageQuery(stage)
+	if err != nil {
+		return false, err
+	}
+
+	m.ExpansionStage = stage
+	m.ParsedQuery = parsedQuery
+	return true, nil
+}
+
+// stageQuery builds the ticket's query as of the given expansion stage, or its own query for stage -1. Each call returns
+// a new query, so it can be searched with outside the process loop without sharing the ticket's parsed query.
+func (m *MatchmakerIndex) stageQuery(stage int) (bluge.Query, error) {
+	query := m.Query
+	tolerances := make(map[string]float64, 2)
+	if stage >= 0 {
+		for _, s := range m.Expansion[:stage+1] {
+			if s.Query != "" {
+				query = s.Query
+			}
+			for name, tolerance := range s.NumericTolerances {
+				tolerances[name] = tolerance
+			}
+		}
+	}
+
+	parsedQuery, err := parseMatchmakerQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tolerances) != 0 {
+		expandedQuery := bluge.NewBooleanQuery()
+		expandedQuery.AddMust(parsedQuery)
+		for name, tolerance := range tolerances {
+			value := m.NumericProperties[name]
+			expandedQuery.AddMust(bluge.NewNumericRangeInclusiveQuery(value-tolerance, value+tolerance, true, true).SetField("properties." + name))
+		}
+		parsedQuery = expandedQuery
+	}
+	return parsedQuery, nil
+}
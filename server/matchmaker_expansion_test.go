@@ -0,0 +1,137 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/blugelabs/bluge"
+)
+
+func TestValidateMatchmakerExpansion(t *testing.T) {
+	numericProperties := map[string]float64{"rating": 1500}
+	tests := []struct {
+		name      string
+		expansion []*MatchmakerExpansionStage
+		wantErr   bool
+	}{
+		{"none", nil, false},
+		{"valid", []*MatchmakerExpansionStage{{Interval: 0, NumericTolerances: map[string]float64{"rating": 100}}, {Interval: 3, Query: "+properties.mode:arena"}}, false},
+		{"unordered", []*MatchmakerExpansionStage{{Interval: 3}, {Interval: 1}}, true},
+		{"duplicate interval", []*MatchmakerExpansionStage{{Interval: 1}, {Interval: 1}}, true},
+		{"unknown property", []*MatchmakerExpansionStage{{Interval: 1, NumericTolerances: map[string]float64{"skill": 100}}}, true},
+		{"negative tolerance", []*MatchmakerExpansionStage{{Interval: 1, NumericTolerances: map[string]float64{"rating": -1}}}, true},
+		{"invalid query", []*MatchmakerExpansionStage{{Interval: 1, Query: "+properties.mode:\"arena"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMatchmakerExpansion(tt.expansion, numericProperties); (err != nil) != tt.wantErr {
+				t.Errorf("validateMatchmakerExpansion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchmakerIndexExpand(t *testing.T) {
+	indexWriter, err := bluge.OpenWriter(BlugeInMemoryConfig())
+	if err != nil {
+		t.Fatalf("error opening index: %v", err)
+	}
+	defer indexWriter.Close()
+
+	// Candidates at increasing rating distances from the expanding ticket.
+	for ticket, rating := range map[string]float64{"near": 1550, "mid": 1750, "far": 2500} {
+		doc, err := MapMatchmakerIndex(ticket, &MatchmakerIndex{
+			Ticket:     ticket,
+			Properties: map[string]interface{}{"mode": "arena", "rating": rating},
+		})
+		if err != nil {
+			t.Fatalf("error mapping index: %v", err)
+		}
+		if err := indexWriter.Update(bluge.Identifier(ticket), doc); err != nil {
+			t.Fatalf("error indexing: %v", err)
+		}
+	}
+	reader, err := indexWriter.Reader()
+	if err != nil {
+		t.Fatalf("error opening reader: %v", err)
+	}
+	defer reader.Close()
+
+	parsedQuery, err := parseMatchmakerQuery("+properties.mode:arena")
+	if err != nil {
+		t.Fatalf("error parsing query: %v", err)
+	}
+	index := &MatchmakerIndex{
+		Ticket:            "self",
+		Query:             "+properties.mode:arena",
+		NumericProperties: map[string]float64{"rating": 1500},
+		ParsedQuery:       parsedQuery,
+		Expansion: []*MatchmakerExpansionStage{
+			{Interval: 0, NumericTolerances: map[string]float64{"rating": 100}},
+			{Interval: 2, NumericTolerances: map[string]float64{"rating": 300}},
+			{Interval: 4, Query: "*"},
+		},
+		ExpansionStage: -1,
+	}
+
+	matches := func() map[string]bool {
+		revCache := &MapOf[string, map[string]bool]{}
+		revCache.Store(index.Ticket, make(map[string]bool))
+		seen := make(map[string]bool, 3)
+		for _, ticket := range []string{"near", "mid", "far"} {
+			valid, err := validateMatch(context.Background(), revCache, reader, index.ParsedQuery, index.Ticket, ticket)
+			if err != nil {
+				t.Fatalf("error validating match: %v", err)
+			}
+			seen[ticket] = valid
+		}
+		return seen
+	}
+
+	steps := []struct {
+		intervals   int
+		wantChanged bool
+		want        map[string]bool
+		expanding   bool
+	}{
+		{0, true, map[string]bool{"near": true, "mid": false, "far": false}, true},
+		{1, false, map[string]bool{"near": true, "mid": false, "far": false}, true},
+		{2, true, map[string]bool{"near": true, "mid": true, "far": false}, true},
+		// The last stage replaces the query, but keeps the tolerance from the stage before it.
+		{5, true, map[string]bool{"near": true, "mid": true, "far": false}, false},
+	}
+	for _, step := range steps {
+		index.Intervals = step.intervals
+		changed, err := index.expand()
+		if err != nil {
+			t.Fatalf("error expanding at interval %d: %v", step.intervals, err)
+		}
+		if changed != step.wantChanged {
+			t.Fatalf("expected changed %v at interval %d, got %v", step.wantChanged, step.intervals, changed)
+		}
+		if index.Expanding() != step.expanding {
+			t.Fatalf("expected expanding %v at interval %d", step.expanding, step.intervals)
+		}
+		got := matches()
+		for ticket, want := range step.want {
+			if got[ticket] != want {
+				t.Fatalf("expected match with %s to be %v at interval %d", ticket, want, step.intervals)
+			}
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"go.uber.org/zap"
+)
+
+const MatchmakerFormationDefaultBudget = 500 * time.Millisecond
+
+// MatchmakerFormationTicket is a matchmaker ticket offered to a formation function. The same ticket may appear in
+// several pools, always as the same pointer.
+type MatchmakerFormationTicket struct {
+	Ticket        string
+	PartyId       string
+	Entries       []*MatchmakerEntry
+	Count         int
+	MinCount      int
+	MaxCount      int
+	CountMultiple int
+	Intervals     int
+	CreatedAt     int64
+}
+
+// MatchmakerFormationPool is an active ticket together with every ticket in the pool that it could match with, as
+// found by its query, count ranges and, if enabled, mutual matching.
+type MatchmakerFormationPool struct {
+	Ticket     *MatchmakerFormationTicket
+	Candidates []*MatchmakerFormationTicket
+}
+
+// MatchmakerFormationFunction receives the candidate pools for one matchmaker interval and returns the groups of tickets
+// that should become matches. Groups may combine tickets from different pools. Each group must satisfy the count
+// constraints of every ticket in it, and a ticket may only be used once, groups that do not are discarded. Tickets left
+// out of every group stay in the matchmaker. The context is cancelled when the time budget runs out.
+type MatchmakerFormationFunction func(ctx context.Context, pools []*MatchmakerFormationPool) ([][]*MatchmakerFormationTicket, error)
+
+// MatchmakerFormation holds the formation function registered by the runtime, if any.
+type MatchmakerFormation struct {
+	sync.RWMutex
+	fn     MatchmakerFormationFunction
+	budget time.Duration
+}
+
+// Set registers the formation function and its time budget per interval, a nil function removes it.
+func (f *MatchmakerFormation) Set(fn MatchmakerFormationFunction, budget time.Duration) {
+	if budget <= 0 {
+		budget = MatchmakerFormationDefaultBudget
+	}
+	f.Lock()
+	f.fn = fn
+	f.budget = budget
+	f.Unlock()
+}
+
+// Get returns the registered formation function, or nil if there is none.
+func (f *MatchmakerFormation) Get() (MatchmakerFormationFunction, time.Duration) {
+	if f == nil {
+		return nil, 0
+	}
+	f.RLock()
+	defer f.RUnlock()
+	return f.fn, f.budget
+}
+
+// processFormation gathers candidate pools and runs the formation function over them. It returns false if the function
+// failed or ran out of time, so the fallback process function can run in its place over the same interval.
+func (m *LocalMatchmaker) processFormation(fn MatchmakerFormationFunction, budget time.Duration, activeIndexesCopy map[string]*MatchmakerIndex, indexCount int, indexesCopy map[string]*MatchmakerIndex) ([][]*MatchmakerEntry, []string, bool) {
+	expiredActiveIndexes := make([]string, 0, 10)
+
+	// The budget covers gathering the candidates as well as the function itself.
+	ctx, ctxCancelFn := context.WithTimeout(m.ctx, budget)
+	defer ctxCancelFn()
+
+	var threshold bool
+	var timer *time.Timer
+	if m.revThresholdFn != nil {
+		timer = m.revThresholdFn()
+		defer timer.Stop()
+	}
+
+	tickets := make(map[string]*MatchmakerFormationTicket, indexCount)
+	formationTicket := func(index *MatchmakerIndex) *MatchmakerFormationTicket {
+		t, found := tickets[index.Ticket]
+		if !found {
+			t = &MatchmakerFormationTicket{
+				Ticket:        index.Ticket,
+				PartyId:       index.PartyId,
+				Entries:       index.Entries,
+				Count:         index.Count,
+				MinCount:      index.MinCount,
+				MaxCount:      index.MaxCount,
+				CountMultiple: index.CountMultiple,
+				Intervals:     index.Intervals,
+				CreatedAt:     index.CreatedAt,
+			}
+			tickets[index.Ticket] = t
+		}
+		return t
+	}
+
+	pools := make([]*MatchmakerFormationPool, 0, len(activeIndexesCopy))
+	for ticket, index := range activeIndexesCopy {
+		if !threshold && timer != nil {
+			select {
+			case <-timer.C:
+				threshold = true
+			default:
+			}
+		}
+
+		lastInterval := index.Intervals >= m.config.GetMatchmaker().MaxIntervals || index.MinCount == index.MaxCount
+		if lastInterval && !index.Expanding() {
+			// Drop from active indexes if it has reached its max intervals, or if its min/max counts are equal. In the
+			// latter case keeping it active would have the same result as leaving it in the pool, so this saves work.
+			// Tickets with expansion stages still to come stay active, as their query has yet to widen.
+			expiredActiveIndexes = append(expiredActiveIndexes, ticket)
+		}
+
+		if m.active.Load() != 1 {
+			continue
+		}
+
+		candidates, err := m.formationCandidates(ctx, ticket, index, indexCount, indexesCopy, threshold)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			m.logger.Error("error gathering matchmaker formation candidates", zap.String("ticket", ticket), zap.Error(err))
+			continue
+		}
+		pool := &MatchmakerFormationPool{
+			Ticket:     formationTicket(index),
+			Candidates: make([]*MatchmakerFormationTicket, 0, len(candidates)),
+		}
+		for _, candidate := range candidates {
+			pool.Candidates = append(pool.Candidates, formationTicket(candidate))
+		}
+		pools = append(pools, pool)
+	}
+
+	type formationResult struct {
+		groups [][]*MatchmakerFormationTicket
+		err    error
+	}
+	var result *formationResult
+	if err := ctx.Err(); err != nil {
+		// Out of time before the function could run.
+		result = &formationResult{err: err}
+	} else if len(pools) == 0 {
+		return nil, expiredActiveIndexes, true
+	} else {
+		resultCh := make(chan *formationResult, 1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					resultCh <- &formationResult{err: fmt.Errorf("formation function panic: %v", r)}
+				}
+			}()
+			groups, err := fn(ctx, pools)
+			resultCh <- &formationResult{groups: groups, err: err}
+		}()
+
+		select {
+		case result = <-resultCh:
+		case <-ctx.Done():
+			result = &formationResult{err: ctx.Err()}
+		}
+	}
+	if result.err != nil {
+		m.logger.Warn("matchmaker formation function failed, falling back to default matching", zap.Duration("budget", budget), zap.Error(result.err))
+		return nil, nil, false
+	}
+
+	matchedEntries, rejected := matchmakerFormationGroups(result.groups, tickets)
+	if rejected > 0 {
+		m.logger.Warn("matchmaker formation function returned invalid groups", zap.Int("rejected", rejected))
+	}
+
+	var batchSize int
+	batch := bluge.NewBatch()
+	// Mark tickets as unavailable for further use in this process iteration.
+	for _, currentMatchedEntries := range matchedEntries {
+		selectedTickets := make(map[string]struct{}, len(currentMatchedEntries))
+		for _, entry := range currentMatchedEntries {
+			if _, found := selectedTickets[entry.Ticket]; found {
+				continue
+			}
+			selectedTickets[entry.Ticket] = struct{}{}
+			batchSize++
+			batch.Delete(bluge.Identifier(entry.Ticket))
+		}
+	}
+	if batchSize > 0 {
+		if err := m.indexWriter.Batch(batch); err != nil {
+			m.logger.Error("error deleting matchmaker process entries batch", zap.Error(err))
+		}
+	}
+
+	return matchedEntries, expiredActiveIndexes, true
+}
+
+// formationCandidates returns the tickets an active ticket could form a match with, excluding itself.
+func (m *LocalMatchmaker) formationCandidates(ctx context.Context, ticket string, index *MatchmakerIndex, indexCount int, indexesCopy map[string]*MatchmakerIndex, threshold bool) ([]*MatchmakerIndex, error) {
+	indexQuery := matchmakerCandidateQuery(index, index.ParsedQuery)
+
+	searchRequest := bluge.NewTopNSearch(indexCount, indexQuery)
+	// Offer the best matches first, or if the matches are equivalent, the longest waiting tickets.
+	searchRequest.SortBy([]string{"-_score", "created_at"})
+
+	indexReader, err := m.indexWriter.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer indexReader.Close()
+
+	result, err := indexReader.Search(ctx, searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	blugeMatches, err := IterateBlugeMatches(result, map[string]struct{}{}, m.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*MatchmakerIndex, 0, len(blugeMatches.Hits))
+	for _, hit := range blugeMatches.Hits {
+		if hit.ID == ticket {
+			// Remove the current ticket.
+			continue
+		}
+
+		hitIndex, ok := indexesCopy[hit.ID]
+		if !ok {
+			// Ticket did not exist, should not happen.
+			m.logger.Warn("matchmaker process missing index", zap.String("ticket", hit.ID))
+			continue
+		}
+
+		if !threshold && m.config.GetMatchmaker().RevPrecision {
+			outerMutualMatch, err := validateMatch(ctx, m.revCache, indexReader, hitIndex.ParsedQuery, hit.ID, ticket)
+			if err != nil {
+				m.logger.Error("error validating mutual match", zap.Error(err))
+				continue
+			} else if !outerMutualMatch {
+				// This search hit is not a mutual match with the outer ticket.
+				continue
+			}
+		}
+
+		// Check if there are overlapping session IDs, and if so these tickets are ineligible to match together.
+		var sessionIdConflict bool
+		for sessionID := range index.SessionIDs {
+			if _, found := hitIndex.SessionIDs[sessionID]; found {
+				sessionIdConflict = true
+				break
+			}
+		}
+		if sessionIdConflict {
+			continue
+		}
+
+		candidates = append(candidates, hitIndex)
+	}
+
+	return candidates, nil
+}
+
+// matchmakerCandidateQuery matches the tickets an index could form a match with: those matching the given query, with a
+// compatible count range, and outside its party. Bluge queries are not safe to search with concurrently, callers
+// outside the process loop must pass a query of their own rather than the index's parsed query.
+func matchmakerCandidateQuery(index *MatchmakerIndex, parsedQuery bluge.Query) *bluge.BooleanQuery {
+	indexQuery := bluge.NewBooleanQuery()
+
+	// Results must match the query string.
+	indexQuery.AddMust(parsedQuery)
+
+	// Results must also have compatible min/max ranges, for example 2-4 must not match with 6-8.
+	minCountRange := bluge.NewNumericRangeInclusiveQuery(
+		float64(index.MinCount), math.Inf(1), true, true).
+		SetField("min_count")
+	indexQuery.AddMust(minCountRange)
+	maxCountRange := bluge.NewNumericRangeInclusiveQuery(
+		math.Inf(-1), float64(index.MaxCount), true, true).
+		SetField("max_count")
+	indexQuery.AddMust(maxCountRange)
+
+	// Results must not include the current party, if any.
+	if index.PartyId != "" {
+		partyIdQuery := bluge.NewTermQuery(index.PartyId)
+		partyIdQuery.SetField("party_id")
+		indexQuery.AddMustNot(partyIdQuery)
+	}
+
+	return indexQuery
+}
+
+// matchmakerFormationGroups turns the groups chosen by a formation function into matched entries, discarding any group
+// that reuses a ticket, uses a ticket that was not offered, repeats a session, or breaks a ticket's count constraints.
+func matchmakerFormationGroups(groups [][]*MatchmakerFormationTicket, offered map[string]*MatchmakerFormationTicket) ([][]*MatchmakerEntry, int) {
+	matchedEntries := make([][]*MatchmakerEntry, 0, len(groups))
+	usedTickets := make(map[string]struct{}, len(offered))
+	var rejected int
+
+group:
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+
+		groupTickets := make(map[string]struct{}, len(group))
+		sessionIDs := make(map[string]struct{}, len(group))
+		var count int
+		for _, t := range group {
+			if t == nil || offered[t.Ticket] != t {
+				rejected++
+				continue group
+			}
+			if _, found := usedTickets[t.Ticket]; found {
+				rejected++
+				continue group
+			}
+			if _, found := groupTickets[t.Ticket]; found {
+				rejected++
+				continue group
+			}
+			groupTickets[t.Ticket] = struct{}{}
+			for _, entry := range t.Entries {
+				if _, found := sessionIDs[entry.Presence.SessionId]; found {
+					rejected++
+					continue group
+				}
+				sessionIDs[entry.Presence.SessionId] = struct{}{}
+			}
+			count += t.Count
+		}
+
+		for _, t := range group {
+			if count < t.MinCount || count > t.MaxCount || count%t.CountMultiple != 0 {
+				rejected++
+				continue group
+			}
+		}
+
+		entries := make([]*MatchmakerEntry, 0, count)
+		for _, t := range group {
+			usedTickets[t.Ticket] = struct{}{}
+			entries = append(entries, t.Entries...)
+		}
+		matchedEntries = append(matchedEntries, entries)
+	}
+
+	return matchedEntries, rejected
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+func createFormationTestMatchmaker(t *testing.T, fn MatchmakerFormationFunction, budget time.Duration) *LocalMatchmaker {
+	logger := loggerForTest(t)
+	cfg := NewConfig(logger)
+	cfg.Matchmaker.RevThreshold = 0
+	formation := &MatchmakerFormation{}
+	formation.Set(fn, budget)
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{matchmakerFormation: formation})
+	t.Cleanup(m.Stop)
+	return m
+}
+
+func addFormationTestTicket(t *testing.T, m *LocalMatchmaker, role string) string {
+	sessionID := uuid.Must(uuid.NewV4())
+	presences := []*MatchmakerPresence{{
+		UserId:    sessionID.String(),
+		SessionId: sessionID.String(),
+		Username:  sessionID.String(),
+		Node:      m.node,
+		SessionID: sessionID,
+	}}
+	ticket, _, err := m.Add(context.Background(), presences, sessionID.String(), "", "+properties.mode:hockey", 2, 2, 1, map[string]string{"mode": "hockey", "role": role}, nil)
+	if err != nil {
+		t.Fatalf("error adding ticket: %v", err)
+	}
+	return ticket
+}
+
+func TestMatchmakerFormationRoles(t *testing.T) {
+	// Pair each goalie with a forward, something a query can't express since both roles want the other.
+	m := createFormationTestMatchmaker(t, func(ctx context.Context, pools []*MatchmakerFormationPool) ([][]*MatchmakerFormationTicket, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			return nil, errors.New("expected the context to carry the time budget")
+		}
+		used := make(map[*MatchmakerFormationTicket]bool)
+		groups := make([][]*MatchmakerFormationTicket, 0, len(pools))
+		for _, pool := range pools {
+			if used[pool.Ticket] {
+				continue
+			}
+			for _, candidate := range pool.Candidates {
+				if !used[candidate] && candidate.Entries[0].StringProperties["role"] != pool.Ticket.Entries[0].StringProperties["role"] {
+					used[pool.Ticket], used[candidate] = true, true
+					groups = append(groups, []*MatchmakerFormationTicket{pool.Ticket, candidate})
+					break
+				}
+			}
+		}
+		return groups, nil
+	}, time.Second)
+
+	roles := make(map[string]string)
+	for _, role := range []string{"goalie", "goalie", "forward", "forward"} {
+		roles[addFormationTestTicket(t, m, role)] = role
+	}
+
+	matchedEntries, _, _, _ := m.processMatches()
+	if len(matchedEntries) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matchedEntries))
+	}
+	for _, entries := range matchedEntries {
+		if len(entries) != 2 || roles[entries[0].Ticket] == roles[entries[1].Ticket] {
+			t.Fatalf("expected a goalie and a forward, got %v and %v", roles[entries[0].Ticket], roles[entries[1].Ticket])
+		}
+	}
+	if len(m.indexes) != 0 {
+		t.Fatalf("expected matched tickets to be removed, %d remain", len(m.indexes))
+	}
+}
+
+func TestMatchmakerFormationFallback(t *testing.T) {
+	// A formation function that overruns its budget leaves the interval to the default algorithm.
+	m := createFormationTestMatchmaker(t, func(ctx context.Context, pools []*MatchmakerFormationPool) ([][]*MatchmakerFormationTicket, error) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	}, 10*time.Millisecond)
+
+	addFormationTestTicket(t, m, "goalie")
+	addFormationTestTicket(t, m, "goalie")
+
+	matchedEntries, _, _, _ := m.processMatches()
+	if len(matchedEntries) != 1 || len(matchedEntries[0]) != 2 {
+		t.Fatalf("expected the default algorithm to form 1 match, got %v", matchedEntries)
+	}
+}
+
+func TestMatchmakerFormationGroups(t *testing.T) {
+	ticket := func(id, sessionID string, count, minCount, maxCount, countMultiple int) *MatchmakerFormationTicket {
+		entries := make([]*MatchmakerEntry, 0, count)
+		for i := 0; i < count; i++ {
+			entries = append(entries, &MatchmakerEntry{Ticket: id, Presence: &MatchmakerPresence{SessionId: sessionID + string(rune('a'+i))}})
+		}
+		return &MatchmakerFormationTicket{Ticket: id, Entries: entries, Count: count, MinCount: minCount, MaxCount: maxCount, CountMultiple: countMultiple}
+	}
+	a := ticket("a", "a", 1, 2, 4, 1)
+	b := ticket("b", "b", 1, 2, 4, 1)
+	c := ticket("c", "c", 2, 2, 4, 2)
+	d := ticket("d", "a", 1, 2, 4, 1) // Shares a session with a.
+	e := ticket("e", "e", 1, 3, 4, 1)
+	offered := map[string]*MatchmakerFormationTicket{"a": a, "b": b, "c": c, "d": d, "e": e}
+
+	tests := []struct {
+		name     string
+		groups   [][]*MatchmakerFormationTicket
+		matches  int
+		rejected int
+	}{
+		{"valid", [][]*MatchmakerFormationTicket{{a, b}, {c, e, d}}, 2, 0},
+		{"reused ticket", [][]*MatchmakerFormationTicket{{a, b}, {b, c}}, 1, 1},
+		{"session conflict", [][]*MatchmakerFormationTicket{{a, d}}, 0, 1},
+		{"below min count", [][]*MatchmakerFormationTicket{{a, e}}, 0, 1},
+		{"count multiple", [][]*MatchmakerFormationTicket{{a, c}}, 0, 1},
+		{"not offered", [][]*MatchmakerFormationTicket{{a, ticket("b", "b", 1, 2, 4, 1)}}, 0, 1},
+		{"empty", [][]*MatchmakerFormationTicket{{}}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchedEntries, rejected := matchmakerFormationGroups(tt.groups, offered)
+			if len(matchedEntries) != tt.matches || rejected != tt.rejected {
+				t.Errorf("matchmakerFormationGroups() matches = %d, rejected = %d, want %d, %d", len(matchedEntries), rejected, tt.matches, tt.rejected)
+			}
+		})
+	}
+}
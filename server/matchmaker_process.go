@@ -50,11 +50,11 @@ func (m *LocalMatchmaker) processDefault(activeIndexCount int, activeIndexesCopy
 			continue
 		}
 
-		activeIndex.Intervals++
 		lastInterval := activeIndex.Intervals >= m.config.GetMatchmaker().MaxIntervals || activeIndex.MinCount == activeIndex.MaxCount
-		if lastInterval {
+		if lastInterval && !activeIndex.Expanding() {
 			// Drop from active indexes if it has reached its max intervals, or if its min/max counts are equal. In the
 			// latter case keeping it active would have the same result as leaving it in the pool, so this saves work.
+			// Tickets with expansion stages still to come stay active, as their query has yet to widen.
 			expiredActiveIndexes = append(expiredActiveIndexes, ticket)
 		}
 
@@ -344,11 +344,6 @@ func (m *LocalMatchmaker) processCustom(activeIndexesCopy map[string]*Matchmaker
 		defer timer.Stop()
 	}
 
-	// Update all interval counts at once.
-	for _, index := range activeIndexesCopy {
-		index.Intervals++
-	}
-
 	for ticket, index := range activeIndexesCopy {
 		if !threshold && timer != nil {
 			select {
@@ -359,9 +354,10 @@ func (m *LocalMatchmaker) processCustom(activeIndexesCopy map[string]*Matchmaker
 		}
 
 		lastInterval := index.Intervals >= m.config.GetMatchmaker().MaxIntervals || index.MinCount == index.MaxCount
-		if lastInterval {
+		if lastInterval && !index.Expanding() {
 			// Drop from active indexes if it has reached its max intervals, or if its min/max counts are equal. In the
 			// latter case keeping it active would have the same result as leaving it in the pool, so this saves work.
+			// Tickets with expansion stages still to come stay active, as their query has yet to widen.
 			expiredActiveIndexes = append(expiredActiveIndexes, ticket)
 		}
 
@@ -630,3 +626,16 @@ func combineIndexes(from []*MatchmakerIndex, min, max int) <-chan []*MatchmakerI
 	}()
 	return c
 }
+
+// expandIndex widens the ticket's query if it has reached a new expansion stage. Mutual match results cached for the
+// ticket were computed against its old query, so they are discarded. Must be called with the matchmaker lock held.
+func (m *LocalMatchmaker) expandIndex(ticket string, index *MatchmakerIndex) {
+	changed, err := index.expand()
+	if err != nil {
+		m.logger.Error("error expanding matchmaker query", zap.String("ticket", ticket), zap.Error(err))
+		return
+	}
+	if changed {
+		m.revCache.Store(ticket, make(map[string]bool, 10))
+	}
+}
@@ -0,0 +1,449 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid/v5"
+	"go.uber.org/zap"
+)
+
+// MatchmakerSimTicket is a ticket submitted to the simulated matchmaker, as recorded or generated.
+type MatchmakerSimTicket struct {
+	ArrivalSec        float64                     `json:"arrival_sec"`
+	Players           int                         `json:"players"` // More than 1 submits a party ticket.
+	Party             string                      `json:"party,omitempty"`
+	Query             string                      `json:"query"`
+	MinCount          int                         `json:"min_count"`
+	MaxCount          int                         `json:"max_count"`
+	CountMultiple     int                         `json:"count_multiple"`
+	StringProperties  map[string]string           `json:"string_properties,omitempty"`
+	NumericProperties map[string]float64          `json:"numeric_properties,omitempty"`
+	Expansion         []*MatchmakerExpansionStage `json:"expansion,omitempty"`
+}
+
+// MatchmakerSimConfig controls how the simulated matchmaker runs. Party labels group separate tickets that want to play
+// together, their members landing in different matches or being left behind counts as a party split.
+type MatchmakerSimConfig struct {
+	IntervalSec      int    `json:"interval_sec"`
+	MaxIntervals     int    `json:"max_intervals"`
+	RevPrecision     bool   `json:"rev_precision"`
+	TicketTimeoutSec int    `json:"ticket_timeout_sec"` // Tickets unmatched this long are withdrawn, 0 to keep them until the end.
+	DrainSec         int    `json:"drain_sec"`          // Virtual time simulated after the last arrival.
+	RatingProperty   string `json:"rating_property"`    // Numeric property whose spread within each match is reported.
+}
+
+func NewMatchmakerSimConfig() *MatchmakerSimConfig {
+	return &MatchmakerSimConfig{
+		IntervalSec:    15,
+		MaxIntervals:   2,
+		DrainSec:       300,
+		RatingProperty: "rating",
+	}
+}
+
+// MatchmakerSimSyntheticConfig describes a synthetic ticket stream. Arrivals are a Poisson process, and ratings are
+// normally distributed.
+type MatchmakerSimSyntheticConfig struct {
+	Tickets            int      `json:"tickets"`
+	ArrivalsPerSec     float64  `json:"arrivals_per_sec"`
+	Modes              []string `json:"modes"`
+	RatingMean         float64  `json:"rating_mean"`
+	RatingStdDev       float64  `json:"rating_stddev"`
+	RatingTolerance    float64  `json:"rating_tolerance"`     // Initial allowed rating distance, 0 for no rating constraint.
+	RatingToleranceMax float64  `json:"rating_tolerance_max"` // Rating distance the tolerance widens to, if greater.
+	ExpansionInterval  int      `json:"expansion_interval"`   // Intervals before the tolerance widens.
+	PartyRate          float64  `json:"party_rate"`           // Chance a ticket is a party ticket.
+	PartySizeMax       int      `json:"party_size_max"`
+	GroupRate          float64  `json:"group_rate"` // Chance a solo ticket is joined by a friend's separate ticket.
+	MinCount           int      `json:"min_count"`
+	MaxCount           int      `json:"max_count"`
+	CountMultiple      int      `json:"count_multiple"`
+}
+
+func NewMatchmakerSimSyntheticConfig() *MatchmakerSimSyntheticConfig {
+	return &MatchmakerSimSyntheticConfig{
+		Tickets:            1000,
+		ArrivalsPerSec:     2,
+		Modes:              []string{"default"},
+		RatingMean:         1500,
+		RatingStdDev:       300,
+		RatingTolerance:    100,
+		RatingToleranceMax: 300,
+		ExpansionInterval:  2,
+		PartySizeMax:       2,
+		MinCount:           2,
+		MaxCount:           8,
+		CountMultiple:      2,
+	}
+}
+
+// MatchmakerSimReport summarises the outcome of a simulation. Wait times are in virtual seconds, and only cover
+// matched tickets.
+type MatchmakerSimReport struct {
+	Tickets          int         `json:"tickets"`
+	Players          int         `json:"players"`
+	MatchedTickets   int         `json:"matched_tickets"`
+	TimedOutTickets  int         `json:"timed_out_tickets"`
+	UnmatchedTickets int         `json:"unmatched_tickets"`
+	Matches          int         `json:"matches"`
+	WaitSecP50       float64     `json:"wait_sec_p50"`
+	WaitSecP90       float64     `json:"wait_sec_p90"`
+	WaitSecP99       float64     `json:"wait_sec_p99"`
+	WaitSecMax       float64     `json:"wait_sec_max"`
+	MatchSizes       map[int]int `json:"match_sizes"` // Players per match, to number of matches.
+	RatingSpreadMean float64     `json:"rating_spread_mean"`
+	RatingSpreadMax  float64     `json:"rating_spread_max"`
+	PartyGroups      int         `json:"party_groups"`
+	PartySplitRate   float64     `json:"party_split_rate"`
+	VirtualSec       float64     `json:"virtual_sec"`
+}
+
+type matchmakerSimState struct {
+	ticket   *MatchmakerSimTicket
+	id       string
+	match    int // 1-based, 0 while unmatched.
+	timedOut bool
+}
+
+// SimulateMatchmaker feeds tickets through a LocalMatchmaker on a virtual clock, processing the pool once per virtual
+// interval instead of waiting on real time. Results vary slightly between runs, as the matchmaker visits tickets in
+// map order.
+func SimulateMatchmaker(logger *zap.Logger, config *MatchmakerSimConfig, tickets []*MatchmakerSimTicket) (*MatchmakerSimReport, error) {
+	if config.IntervalSec < 1 {
+		return nil, errors.New("interval must be at least 1 second")
+	}
+	if config.MaxIntervals < 1 {
+		return nil, errors.New("max intervals must be at least 1")
+	}
+
+	cfg := NewConfig(logger)
+	cfg.Matchmaker.IntervalSec = config.IntervalSec
+	cfg.Matchmaker.MaxIntervals = config.MaxIntervals
+	cfg.Matchmaker.RevPrecision = config.RevPrecision
+	// The rev threshold runs on a real timer, which would make results depend on the host's speed.
+	cfg.Matchmaker.RevThreshold = 0
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{})
+	defer m.Stop()
+
+	sorted := make([]*MatchmakerSimTicket, len(tickets))
+	copy(sorted, tickets)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ArrivalSec < sorted[j].ArrivalSec })
+	var endSec float64
+	if len(sorted) > 0 {
+		endSec = sorted[len(sorted)-1].ArrivalSec
+	}
+	endSec += float64(config.DrainSec)
+
+	report := &MatchmakerSimReport{
+		Tickets:    len(sorted),
+		MatchSizes: make(map[int]int),
+	}
+	states := make([]*matchmakerSimState, 0, len(sorted))
+	waiting := make(map[string]*matchmakerSimState, 100)
+	waits := make([]float64, 0, len(sorted))
+	var ratingSpreadSum float64
+	var ratingSpreadCount int
+
+	interval := float64(config.IntervalSec)
+	var next int
+	var now float64
+	for now = interval; next < len(sorted) || (now <= endSec+interval && len(waiting) > 0); now += interval {
+		// Submit everything that has arrived since the last interval.
+		for ; next < len(sorted) && sorted[next].ArrivalSec <= now; next++ {
+			t := sorted[next]
+			players := t.Players
+			if players < 1 {
+				players = 1
+			}
+			report.Players += players
+			presences := make([]*MatchmakerPresence, 0, players)
+			for i := 0; i < players; i++ {
+				sessionID := uuid.Must(uuid.NewV4())
+				presences = append(presences, &MatchmakerPresence{
+					UserId:    sessionID.String(),
+					SessionId: sessionID.String(),
+					Username:  sessionID.String(),
+					Node:      cfg.GetName(),
+					SessionID: sessionID,
+				})
+			}
+			var partyID string
+			if players > 1 {
+				partyID = uuid.Must(uuid.NewV4()).String()
+			}
+			countMultiple := t.CountMultiple
+			if countMultiple < 1 {
+				countMultiple = 1
+			}
+			id, _, err := m.AddExpanding(context.Background(), presences, presences[0].SessionId, partyID, t.Query, t.Expansion, t.MinCount, t.MaxCount, countMultiple, t.StringProperties, t.NumericProperties)
+			if err != nil {
+				return nil, fmt.Errorf("ticket %d: %w", next, err)
+			}
+			state := &matchmakerSimState{ticket: t, id: id}
+			states = append(states, state)
+			waiting[id] = state
+		}
+
+		// Withdraw tickets that have waited too long.
+		if config.TicketTimeoutSec > 0 {
+			expired := make([]string, 0, 10)
+			for id, state := range waiting {
+				if now-state.ticket.ArrivalSec >= float64(config.TicketTimeoutSec) {
+					state.timedOut = true
+					expired = append(expired, id)
+					delete(waiting, id)
+				}
+			}
+			if len(expired) > 0 {
+				m.Remove(expired)
+				report.TimedOutTickets += len(expired)
+			}
+		}
+
+		matchedEntries, _, _, _ := m.processMatches()
+		for _, entries := range matchedEntries {
+			report.Matches++
+			report.MatchSizes[len(entries)]++
+
+			minRating, maxRating := math.Inf(1), math.Inf(-1)
+			for _, entry := range entries {
+				if rating, found := entry.NumericProperties[config.RatingProperty]; found {
+					minRating = math.Min(minRating, rating)
+					maxRating = math.Max(maxRating, rating)
+				}
+				state, found := waiting[entry.Ticket]
+				if !found {
+					// Party tickets have one entry per member, only count the ticket once.
+					continue
+				}
+				delete(waiting, entry.Ticket)
+				state.match = report.Matches
+				report.MatchedTickets++
+				waits = append(waits, now-state.ticket.ArrivalSec)
+			}
+			if maxRating >= minRating {
+				spread := maxRating - minRating
+				ratingSpreadSum += spread
+				ratingSpreadCount++
+				report.RatingSpreadMax = math.Max(report.RatingSpreadMax, spread)
+			}
+		}
+	}
+	report.VirtualSec = now - interval
+	report.UnmatchedTickets = len(waiting)
+
+	sort.Float64s(waits)
+	report.WaitSecP50 = matchmakerSimPercentile(waits, 50)
+	report.WaitSecP90 = matchmakerSimPercentile(waits, 90)
+	report.WaitSecP99 = matchmakerSimPercentile(waits, 99)
+	if len(waits) > 0 {
+		report.WaitSecMax = waits[len(waits)-1]
+	}
+	if ratingSpreadCount > 0 {
+		report.RatingSpreadMean = ratingSpreadSum / float64(ratingSpreadCount)
+	}
+
+	// A party is split if its tickets were matched into different matches, or only some of them were matched.
+	parties := make(map[string][]*matchmakerSimState)
+	for _, state := range states {
+		if state.ticket.Party != "" {
+			parties[state.ticket.Party] = append(parties[state.ticket.Party], state)
+		}
+	}
+	var splits int
+	for _, members := range parties {
+		matches := make(map[int]struct{}, len(members))
+		for _, member := range members {
+			matches[member.match] = struct{}{}
+		}
+		if _, unmatched := matches[0]; unmatched && len(matches) == 1 {
+			// Nobody in the party was matched, so it was never split.
+			continue
+		}
+		report.PartyGroups++
+		if len(matches) > 1 {
+			splits++
+		}
+	}
+	if report.PartyGroups > 0 {
+		report.PartySplitRate = float64(splits) / float64(report.PartyGroups)
+	}
+
+	return report, nil
+}
+
+// matchmakerSimPercentile returns the nearest-rank percentile of sorted values.
+func matchmakerSimPercentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// GenerateMatchmakerSimTickets creates a synthetic ticket stream. Tickets must share the mode and be within the rating
+// tolerance of each other, which widens after the configured number of intervals.
+func GenerateMatchmakerSimTickets(rng *rand.Rand, config *MatchmakerSimSyntheticConfig) []*MatchmakerSimTicket {
+	modes := config.Modes
+	if len(modes) == 0 {
+		modes = []string{"default"}
+	}
+
+	tickets := make([]*MatchmakerSimTicket, 0, config.Tickets)
+	var arrival float64
+	for i := 0; len(tickets) < config.Tickets; i++ {
+		if config.ArrivalsPerSec > 0 {
+			arrival += rng.ExpFloat64() / config.ArrivalsPerSec
+		}
+		mode := modes[rng.Intn(len(modes))]
+		rating := math.Max(0, math.Round(rng.NormFloat64()*config.RatingStdDev+config.RatingMean))
+
+		players := 1
+		if config.PartySizeMax > 1 && rng.Float64() < config.PartyRate {
+			players = 2 + rng.Intn(config.PartySizeMax-1)
+		}
+		var party string
+		companions := 0
+		if players == 1 && rng.Float64() < config.GroupRate {
+			party = strconv.Itoa(i)
+			companions = 1
+		}
+
+		for j := 0; j <= companions && len(tickets) < config.Tickets; j++ {
+			ticket := &MatchmakerSimTicket{
+				ArrivalSec:        arrival,
+				Players:           players,
+				Party:             party,
+				Query:             "+properties.mode:" + mode,
+				MinCount:          config.MinCount,
+				MaxCount:          config.MaxCount,
+				CountMultiple:     config.CountMultiple,
+				StringProperties:  map[string]string{"mode": mode},
+				NumericProperties: map[string]float64{"rating": rating},
+			}
+			if config.RatingTolerance > 0 {
+				ticket.Expansion = []*MatchmakerExpansionStage{{NumericTolerances: map[string]float64{"rating": config.RatingTolerance}}}
+				if config.RatingToleranceMax > config.RatingTolerance && config.ExpansionInterval > 0 {
+					ticket.Expansion = append(ticket.Expansion, &MatchmakerExpansionStage{Interval: config.ExpansionInterval, NumericTolerances: map[string]float64{"rating": config.RatingToleranceMax}})
+				}
+			}
+			tickets = append(tickets, ticket)
+		}
+	}
+
+	return tickets
+}
+
+// ReadMatchmakerSimTickets reads a recorded ticket stream, one JSON ticket per line.
+func ReadMatchmakerSimTickets(r io.Reader) ([]*MatchmakerSimTicket, error) {
+	tickets := make([]*MatchmakerSimTicket, 0, 100)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var line int
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		ticket := &MatchmakerSimTicket{}
+		if err := json.Unmarshal(scanner.Bytes(), ticket); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		tickets = append(tickets, ticket)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// MatchmakerSimParse runs the "matchmaker-sim" command, simulating either a recorded ticket stream or a synthetic one
+// and writing the report as JSON to stdout.
+func MatchmakerSimParse(args []string, logger *zap.Logger) {
+	config := NewMatchmakerSimConfig()
+	synthetic := NewMatchmakerSimSyntheticConfig()
+	var input, modes string
+	var seed int64
+	flags := flag.NewFlagSet("matchmaker-sim", flag.ExitOnError)
+	flags.StringVar(&input, "input", "", "JSON Lines file of recorded tickets to simulate. Synthetic tickets are generated if not set.")
+	flags.IntVar(&config.IntervalSec, "interval_sec", config.IntervalSec, "Virtual seconds between matchmaker processing intervals.")
+	flags.IntVar(&config.MaxIntervals, "max_intervals", config.MaxIntervals, "Intervals a ticket tries for its max count before allowing min count.")
+	flags.BoolVar(&config.RevPrecision, "rev_precision", config.RevPrecision, "Require mutual matches between tickets.")
+	flags.IntVar(&config.TicketTimeoutSec, "ticket_timeout_sec", config.TicketTimeoutSec, "Withdraw tickets unmatched after this many virtual seconds, 0 to never withdraw them.")
+	flags.IntVar(&config.DrainSec, "drain_sec", config.DrainSec, "Virtual seconds to keep processing after the last arrival.")
+	flags.StringVar(&config.RatingProperty, "rating_property", config.RatingProperty, "Numeric property whose spread within each match is reported.")
+	flags.Int64Var(&seed, "seed", 1, "Random seed for synthetic tickets.")
+	flags.IntVar(&synthetic.Tickets, "tickets", synthetic.Tickets, "Number of synthetic tickets.")
+	flags.Float64Var(&synthetic.ArrivalsPerSec, "arrivals_per_sec", synthetic.ArrivalsPerSec, "Average synthetic ticket arrivals per virtual second.")
+	flags.StringVar(&modes, "modes", strings.Join(synthetic.Modes, ","), "Comma separated synthetic game modes, tickets only match within a mode.")
+	flags.Float64Var(&synthetic.RatingMean, "rating_mean", synthetic.RatingMean, "Mean synthetic rating.")
+	flags.Float64Var(&synthetic.RatingStdDev, "rating_stddev", synthetic.RatingStdDev, "Standard deviation of synthetic ratings.")
+	flags.Float64Var(&synthetic.RatingTolerance, "rating_tolerance", synthetic.RatingTolerance, "Initial rating distance synthetic tickets accept, 0 for any.")
+	flags.Float64Var(&synthetic.RatingToleranceMax, "rating_tolerance_max", synthetic.RatingToleranceMax, "Rating distance synthetic tickets widen to.")
+	flags.IntVar(&synthetic.ExpansionInterval, "expansion_interval", synthetic.ExpansionInterval, "Intervals before synthetic tickets widen their rating tolerance.")
+	flags.Float64Var(&synthetic.PartyRate, "party_rate", synthetic.PartyRate, "Chance a synthetic ticket is a party ticket.")
+	flags.IntVar(&synthetic.PartySizeMax, "party_size_max", synthetic.PartySizeMax, "Largest synthetic party ticket.")
+	flags.Float64Var(&synthetic.GroupRate, "group_rate", synthetic.GroupRate, "Chance a synthetic solo ticket is joined by a friend's separate ticket.")
+	flags.IntVar(&synthetic.MinCount, "min_count", synthetic.MinCount, "Synthetic ticket min count.")
+	flags.IntVar(&synthetic.MaxCount, "max_count", synthetic.MaxCount, "Synthetic ticket max count.")
+	flags.IntVar(&synthetic.CountMultiple, "count_multiple", synthetic.CountMultiple, "Synthetic ticket count multiple.")
+	if err := flags.Parse(args); err != nil {
+		logger.Fatal("Could not parse matchmaker-sim flags.")
+	}
+
+	var tickets []*MatchmakerSimTicket
+	if input != "" {
+		f, err := os.Open(input)
+		if err != nil {
+			logger.Fatal("Could not open matchmaker simulation input.", zap.String("input", input), zap.Error(err))
+		}
+		tickets, err = ReadMatchmakerSimTickets(f)
+		_ = f.Close()
+		if err != nil {
+			logger.Fatal("Could not read matchmaker simulation input.", zap.String("input", input), zap.Error(err))
+		}
+	} else {
+		synthetic.Modes = strings.Split(modes, ",")
+		tickets = GenerateMatchmakerSimTickets(rand.New(rand.NewSource(seed)), synthetic)
+	}
+
+	report, err := SimulateMatchmaker(logger, config, tickets)
+	if err != nil {
+		logger.Fatal("Matchmaker simulation failed.", zap.Error(err))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		logger.Fatal("Could not write matchmaker simulation report.", zap.Error(err))
+	}
+}
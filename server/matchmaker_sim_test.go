@@ -0,0 +1,88 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSimulateMatchmakerSynthetic(t *testing.T) {
+	synthetic := NewMatchmakerSimSyntheticConfig()
+	synthetic.Tickets = 200
+	synthetic.Modes = []string{"arena", "ctf"}
+	synthetic.GroupRate = 0.2
+	tickets := GenerateMatchmakerSimTickets(rand.New(rand.NewSource(1)), synthetic)
+	if len(tickets) != synthetic.Tickets {
+		t.Fatalf("expected %d tickets, got %d", synthetic.Tickets, len(tickets))
+	}
+
+	report, err := SimulateMatchmaker(loggerForTest(t), NewMatchmakerSimConfig(), tickets)
+	if err != nil {
+		t.Fatalf("error simulating matchmaker: %v", err)
+	}
+
+	if report.Tickets != len(tickets) {
+		t.Fatalf("expected %d tickets in report, got %d", len(tickets), report.Tickets)
+	}
+	if report.MatchedTickets+report.TimedOutTickets+report.UnmatchedTickets != report.Tickets {
+		t.Fatalf("ticket outcomes do not add up: %+v", report)
+	}
+	if report.Matches == 0 {
+		t.Fatalf("expected matches, got none")
+	}
+	var players int
+	for size, count := range report.MatchSizes {
+		if size < synthetic.MinCount || size > synthetic.MaxCount || size%synthetic.CountMultiple != 0 {
+			t.Fatalf("unexpected match size %d", size)
+		}
+		players += size * count
+	}
+	if players > report.Players {
+		t.Fatalf("more players matched than submitted: %d > %d", players, report.Players)
+	}
+	if report.WaitSecP50 > report.WaitSecP90 || report.WaitSecP90 > report.WaitSecP99 || report.WaitSecP99 > report.WaitSecMax {
+		t.Fatalf("wait percentiles out of order: %+v", report)
+	}
+	if report.RatingSpreadMax > synthetic.RatingToleranceMax*2 {
+		t.Fatalf("rating spread %v exceeds widest tolerance", report.RatingSpreadMax)
+	}
+	if report.PartySplitRate < 0 || report.PartySplitRate > 1 {
+		t.Fatalf("party split rate out of range: %v", report.PartySplitRate)
+	}
+}
+
+func TestSimulateMatchmakerTimeout(t *testing.T) {
+	// Two tickets that can never match each other are withdrawn once they time out.
+	tickets, err := ReadMatchmakerSimTickets(strings.NewReader(`
+{"arrival_sec": 0, "players": 1, "query": "+properties.mode:arena", "min_count": 2, "max_count": 2, "string_properties": {"mode": "arena"}}
+{"arrival_sec": 5, "players": 1, "query": "+properties.mode:ctf", "min_count": 2, "max_count": 2, "string_properties": {"mode": "ctf"}}
+`))
+	if err != nil {
+		t.Fatalf("error reading tickets: %v", err)
+	}
+
+	config := NewMatchmakerSimConfig()
+	config.TicketTimeoutSec = 60
+	report, err := SimulateMatchmaker(loggerForTest(t), config, tickets)
+	if err != nil {
+		t.Fatalf("error simulating matchmaker: %v", err)
+	}
+
+	if report.Matches != 0 || report.TimedOutTickets != 2 || report.UnmatchedTickets != 0 {
+		t.Fatalf("expected both tickets to time out, got %+v", report)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+// MatchmakerTicketStatus describes a ticket's progress through the matchmaker. Tickets are grouped into queues by the
+// string property named in the matchmaker queue property config.
+type MatchmakerTicketStatus struct {
+	Ticket         string                `json:"ticket"`
+	PartyId        string                `json:"party_id,omitempty"`
+	Queue          string                `json:"queue"`
+	Position       int                   `json:"position"` // 1 for the longest waiting ticket in the queue.
+	QueueSize      int                   `json:"queue_size"`
+	Intervals      int                   `json:"intervals"`
+	ExpansionStage int                   `json:"expansion_stage"`
+	WaitSec        float64               `json:"wait_sec"`
+	Candidates     int                   `json:"candidates"` // Tickets that currently match this ticket's query and count range.
+	Presences      []*MatchmakerPresence `json:"presences"`
+	// Estimated seconds until matched, based on recent wait times in the queue, or -1 with no recent matches.
+	EstimatedWaitSec float64 `json:"estimated_wait_sec"`
+}
+
+// MatchmakerQueueStatus summarises a queue's waiting tickets and the wait times of its recently matched tickets.
+type MatchmakerQueueStatus struct {
+	Queue      string  `json:"queue"`
+	Tickets    int     `json:"tickets"`
+	Players    int     `json:"players"`
+	Samples    int     `json:"samples"`
+	WaitSecP50 float64 `json:"wait_sec_p50"`
+	WaitSecP90 float64 `json:"wait_sec_p90"`
+}
+
+// matchmakerQueueStats holds the wait times of a queue's most recently matched tickets.
+type matchmakerQueueStats struct {
+	waits   []float64
+	next    int
+	updated int64 // When a ticket in the queue was last matched, used to pick queues to evict.
+}
+
+func (s *matchmakerQueueStats) add(window int, wait float64) {
+	if len(s.waits) < window {
+		s.waits = append(s.waits, wait)
+		return
+	}
+	s.waits[s.next] = wait
+	s.next = (s.next + 1) % len(s.waits)
+}
+
+// percentiles returns the nearest-rank percentiles of the recorded wait times, in the order requested.
+func (s *matchmakerQueueStats) percentiles(ps ...float64) []float64 {
+	results := make([]float64, len(ps))
+	if s == nil || len(s.waits) == 0 {
+		return results
+	}
+	sorted := make([]float64, len(s.waits))
+	copy(sorted, s.waits)
+	sort.Float64s(sorted)
+	for i, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		results[i] = sorted[rank-1]
+	}
+	return results
+}
+
+// matchmakerEstimateWait returns the expected remaining wait for a ticket that has waited so far, judged against the
+// queue's typical wait times. Tickets already past the typical wait are expected to match within the next interval.
+func matchmakerEstimateWait(stats *matchmakerQueueStats, waited float64, intervalSec int) float64 {
+	if stats == nil || len(stats.waits) == 0 {
+		return -1
+	}
+	for _, expected := range stats.percentiles(50, 90) {
+		if waited < expected {
+			return expected - waited
+		}
+	}
+	return float64(intervalSec)
+}
+
+func (m *LocalMatchmaker) queueOf(index *MatchmakerIndex) string {
+	return index.StringProperties[m.config.GetMatchmaker().QueueProperty]
+}
+
+// recordMatched adds a matched ticket's wait time to its queue statistics. Queue names come from client ticket
+// properties, so past the configured number of queues the least recently matched one is dropped. Must be called with
+// the lock held.
+func (m *LocalMatchmaker) recordMatched(index *MatchmakerIndex, now int64) {
+	queue := m.queueOf(index)
+	stats, found := m.queueStats[queue]
+	if !found {
+		if len(m.queueStats) >= m.config.GetMatchmaker().StatsQueues {
+			var oldestQueue string
+			var oldest *matchmakerQueueStats
+			for q, s := range m.queueStats {
+				if oldest == nil || s.updated < oldest.updated {
+					oldestQueue, oldest = q, s
+				}
+			}
+			delete(m.queueStats, oldestQueue)
+		}
+		stats = &matchmakerQueueStats{}
+		m.queueStats[queue] = stats
+	}
+	stats.updated = now
+	stats.add(m.config.GetMatchmaker().StatsWindow, float64(now-index.CreatedAt)/float64(time.Second))
+}
+
+// TicketStatus reports a ticket's queue position, progress, current candidates and estimated remaining wait.
+func (m *LocalMatchmaker) TicketStatus(ticket string) (*MatchmakerTicketStatus, error) {
+	now := time.Now().UTC().UnixNano()
+
+	m.Lock()
+	index, found := m.indexes[ticket]
+	if !found {
+		m.Unlock()
+		return nil, runtime.ErrMatchmakerTicketNotFound
+	}
+	queue := m.queueOf(index)
+	status := &MatchmakerTicketStatus{
+		Ticket:         ticket,
+		PartyId:        index.PartyId,
+		Queue:          queue,
+		Position:       1,
+		Intervals:      index.Intervals,
+		ExpansionStage: index.ExpansionStage,
+		WaitSec:        float64(now-index.CreatedAt) / float64(time.Second),
+		Presences:      make([]*MatchmakerPresence, 0, len(index.Entries)),
+	}
+	for _, entry := range index.Entries {
+		status.Presences = append(status.Presences, entry.Presence)
+	}
+	for _, other := range m.indexes {
+		if m.queueOf(other) != queue {
+			continue
+		}
+		status.QueueSize++
+		if other.CreatedAt < index.CreatedAt || (other.CreatedAt == index.CreatedAt && other.Ticket < index.Ticket) {
+			status.Position++
+		}
+	}
+	status.EstimatedWaitSec = matchmakerEstimateWait(m.queueStats[queue], status.WaitSec, m.config.GetMatchmaker().IntervalSec)
+	// The ticket's parsed query may be in use by a matchmaker pass, and bluge queries can't be searched concurrently.
+	parsedQuery, err := index.stageQuery(index.ExpansionStage)
+	if err != nil {
+		m.Unlock()
+		return nil, err
+	}
+	indexQuery := matchmakerCandidateQuery(index, parsedQuery)
+	indexCount := len(m.indexes)
+	m.Unlock()
+
+	indexReader, err := m.indexWriter.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer indexReader.Close()
+
+	result, err := indexReader.Search(m.ctx, bluge.NewTopNSearch(indexCount, indexQuery))
+	if err != nil {
+		return nil, err
+	}
+	hit, err := result.Next()
+	for ; err == nil && hit != nil; hit, err = result.Next() {
+		var self bool
+		if err := hit.VisitStoredFields(func(field string, value []byte) bool {
+			if field == "_id" {
+				self = string(value) == ticket
+				return false
+			}
+			return true
+		}); err != nil {
+			return nil, err
+		}
+		if !self {
+			status.Candidates++
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// QueueStatus summarises every queue with waiting tickets or recent matches.
+func (m *LocalMatchmaker) QueueStatus() []*MatchmakerQueueStatus {
+	m.Lock()
+	defer m.Unlock()
+
+	queues := make(map[string]*MatchmakerQueueStatus, len(m.queueStats))
+	queueStatus := func(queue string) *MatchmakerQueueStatus {
+		status, found := queues[queue]
+		if !found {
+			status = &MatchmakerQueueStatus{Queue: queue}
+			queues[queue] = status
+		}
+		return status
+	}
+	for _, index := range m.indexes {
+		status := queueStatus(m.queueOf(index))
+		status.Tickets++
+		status.Players += index.Count
+	}
+	for queue, stats := range m.queueStats {
+		status := queueStatus(queue)
+		status.Samples = len(stats.waits)
+		p := stats.percentiles(50, 90)
+		status.WaitSecP50, status.WaitSecP90 = p[0], p[1]
+	}
+
+	statuses := make([]*MatchmakerQueueStatus, 0, len(queues))
+	for _, status := range queues {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Queue < statuses[j].Queue })
+	return statuses
+}
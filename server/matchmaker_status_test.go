@@ -0,0 +1,187 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/runtime"
+)
+
+func TestMatchmakerTicketStatus(t *testing.T) {
+	logger := loggerForTest(t)
+	cfg := NewConfig(logger)
+	cfg.Matchmaker.RevThreshold = 0
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{})
+	t.Cleanup(m.Stop)
+
+	add := func(mode string, minCount, maxCount int) string {
+		sessionID := uuid.Must(uuid.NewV4())
+		presences := []*MatchmakerPresence{{
+			UserId:    sessionID.String(),
+			SessionId: sessionID.String(),
+			Username:  sessionID.String(),
+			Node:      m.node,
+			SessionID: sessionID,
+		}}
+		ticket, _, err := m.Add(context.Background(), presences, sessionID.String(), "", "+properties.mode:"+mode, minCount, maxCount, 1, map[string]string{"mode": mode}, nil)
+		if err != nil {
+			t.Fatalf("error adding ticket: %v", err)
+		}
+		return ticket
+	}
+
+	first := add("arena", 4, 4)
+	second := add("arena", 4, 4)
+	add("arena", 6, 6) // Incompatible count range.
+	add("ctf", 4, 4)
+
+	status, err := m.TicketStatus(second)
+	if err != nil {
+		t.Fatalf("error getting ticket status: %v", err)
+	}
+	if status.Queue != "arena" || status.Position != 2 || status.QueueSize != 3 {
+		t.Fatalf("unexpected queue position: %+v", status)
+	}
+	if status.Candidates != 1 {
+		t.Fatalf("expected 1 candidate, got %d", status.Candidates)
+	}
+	if status.EstimatedWaitSec != -1 {
+		t.Fatalf("expected no estimate without recent matches, got %v", status.EstimatedWaitSec)
+	}
+	if len(status.Presences) != 1 {
+		t.Fatalf("expected 1 presence, got %d", len(status.Presences))
+	}
+
+	if _, err := m.TicketStatus(uuid.Must(uuid.NewV4()).String()); err != runtime.ErrMatchmakerTicketNotFound {
+		t.Fatalf("expected ticket not found, got %v", err)
+	}
+
+	// Fill the first match so the queue has a recorded wait time.
+	add("arena", 4, 4)
+	add("arena", 4, 4)
+	matchedEntries, _, _, _ := m.processMatches()
+	if len(matchedEntries) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matchedEntries))
+	}
+	if _, err := m.TicketStatus(first); err != runtime.ErrMatchmakerTicketNotFound {
+		t.Fatalf("expected matched ticket to be gone, got %v", err)
+	}
+
+	queues := m.QueueStatus()
+	if len(queues) != 2 || queues[0].Queue != "arena" || queues[1].Queue != "ctf" {
+		t.Fatalf("unexpected queues: %+v", queues)
+	}
+	if queues[0].Samples != 4 || queues[0].Tickets != 1 || queues[1].Samples != 0 || queues[1].Tickets != 1 {
+		t.Fatalf("unexpected queue stats: %+v %+v", queues[0], queues[1])
+	}
+}
+
+func TestMatchmakerQueueStats(t *testing.T) {
+	stats := &matchmakerQueueStats{}
+	for i := 1; i <= 5; i++ {
+		stats.add(4, float64(i*10))
+	}
+	// The oldest wait has been replaced once the window is full.
+	if len(stats.waits) != 4 {
+		t.Fatalf("expected 4 waits, got %d", len(stats.waits))
+	}
+	if p := stats.percentiles(0, 50, 100); p[0] != 20 || p[1] != 30 || p[2] != 50 {
+		t.Fatalf("unexpected percentiles: %v", p)
+	}
+
+	tests := []struct {
+		name     string
+		stats    *matchmakerQueueStats
+		waited   float64
+		expected float64
+	}{
+		{"no samples", nil, 5, -1},
+		{"before median", stats, 10, 20},
+		{"before p90", stats, 35, 15},
+		{"overdue", stats, 60, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if estimate := matchmakerEstimateWait(tt.stats, tt.waited, 15); estimate != tt.expected {
+				t.Errorf("matchmakerEstimateWait() = %v, want %v", estimate, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchmakerQueueStatsEviction(t *testing.T) {
+	logger := loggerForTest(t)
+	cfg := NewConfig(logger)
+	cfg.Matchmaker.StatsQueues = 2
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{})
+	t.Cleanup(m.Stop)
+
+	matched := func(mode string, now int64) {
+		m.Lock()
+		m.recordMatched(&MatchmakerIndex{StringProperties: map[string]string{"mode": mode}}, now)
+		m.Unlock()
+	}
+	matched("arena", 1)
+	matched("ctf", 2)
+	matched("arena", 3)
+	matched("combat", 4) // Evicts ctf, the least recently matched queue.
+
+	queues := m.QueueStatus()
+	if len(queues) != 2 || queues[0].Queue != "arena" || queues[1].Queue != "combat" {
+		t.Fatalf("unexpected queues: %+v", queues)
+	}
+	if queues[0].Samples != 2 {
+		t.Fatalf("expected 2 arena samples, got %d", queues[0].Samples)
+	}
+}
+
+func TestMatchmakerTicketStatusConcurrentProcess(t *testing.T) {
+	logger := loggerForTest(t)
+	cfg := NewConfig(logger)
+	m := newLocalMatchmaker(logger, logger, cfg, nil, nil, &Runtime{})
+	t.Cleanup(m.Stop)
+
+	sessionID := uuid.Must(uuid.NewV4())
+	presences := []*MatchmakerPresence{{
+		UserId:    sessionID.String(),
+		SessionId: sessionID.String(),
+		Username:  sessionID.String(),
+		Node:      m.node,
+		SessionID: sessionID,
+	}}
+	ticket, _, err := m.Add(context.Background(), presences, sessionID.String(), "", "+properties.mode:arena", 2, 2, 1, map[string]string{"mode": "arena"}, nil)
+	if err != nil {
+		t.Fatalf("error adding ticket: %v", err)
+	}
+
+	// Ticket status reads interval and expansion state that each pass updates, run with -race to check.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			if _, err := m.TicketStatus(ticket); err != nil {
+				t.Errorf("error getting ticket status: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		m.processMatches()
+	}
+	<-done
+}
@@ -15,6 +15,8 @@
 package server
 
 import (
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
 	"github.com/heroiclabs/nakama-common/rtapi"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -35,19 +37,23 @@ type MessageRouter interface {
 	SendToStream(*zap.Logger, PresenceStream, *rtapi.Envelope, bool)
 	SendDeferred(*zap.Logger, []*DeferredMessage)
 	SendToAll(*zap.Logger, *rtapi.Envelope, bool)
+	// PushNotifications delivers notifications to users who are not connected through the push providers.
+	PushNotifications(*zap.Logger, map[uuid.UUID][]*api.Notification)
 }
 
 type LocalMessageRouter struct {
 	protojsonMarshaler *protojson.MarshalOptions
 	sessionRegistry    SessionRegistry
 	tracker            Tracker
+	notificationPusher NotificationPusher
 }
 
-func NewLocalMessageRouter(sessionRegistry SessionRegistry, tracker Tracker, protojsonMarshaler *protojson.MarshalOptions) MessageRouter {
+func NewLocalMessageRouter(sessionRegistry SessionRegistry, tracker Tracker, notificationPusher NotificationPusher, protojsonMarshaler *protojson.MarshalOptions) MessageRouter {
 	return &LocalMessageRouter{
 		protojsonMarshaler: protojsonMarshaler,
 		sessionRegistry:    sessionRegistry,
 		tracker:            tracker,
+		notificationPusher: notificationPusher,
 	}
 }
 
@@ -179,3 +185,9 @@ func (r *LocalMessageRouter) SendToAll(logger *zap.Logger, envelope *rtapi.Envel
 		return true
 	})
 }
+
+func (r *LocalMessageRouter) PushNotifications(logger *zap.Logger, notifications map[uuid.UUID][]*api.Notification) {
+	if r.notificationPusher != nil {
+		r.notificationPusher.Push(logger, notifications)
+	}
+}
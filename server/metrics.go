@@ -62,6 +62,7 @@ type Metrics interface {
 	PresenceEvent(dequeueElapsed, processElapsed time.Duration)
 
 	StorageWriteRejectCount(tags map[string]string, delta int64)
+	StorageExpiredCount(tags map[string]string, delta int64)
 
 	CustomCounter(name string, tags map[string]string, delta int64)
 	CustomGauge(name string, tags map[string]string, value float64)
@@ -444,6 +445,14 @@ func (m *LocalMetrics) StorageWriteRejectCount(tags map[string]string, delta int
 	scope.Counter("storage_write_reject_count").Inc(delta)
 }
 
+func (m *LocalMetrics) StorageExpiredCount(tags map[string]string, delta int64) {
+	scope := m.PrometheusScope
+	if len(tags) != 0 {
+		scope = scope.Tagged(tags)
+	}
+	scope.Counter("storage_expired_count").Inc(delta)
+}
+
 // CustomCounter adds the given delta to a counter with the specified name and tags.
 func (m *LocalMetrics) CustomCounter(name string, tags map[string]string, delta int64) {
 	scope := m.prometheusCustomScope
@@ -0,0 +1,416 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+const (
+	notificationPushProviderMaxLength = 64
+	notificationPushTokenMaxLength    = 4096
+
+	// How often notifications dropped because the push queues are full are logged, as one count.
+	notificationPushDroppedLogInterval = 10 * time.Second
+)
+
+var (
+	// ErrNotificationPushTokenInvalid is returned by providers when a device token will never accept pushes again, so
+	// it is unregistered.
+	ErrNotificationPushTokenInvalid = errors.New("push token is no longer valid")
+	// ErrNotificationPushRejected is returned by providers when a push will not succeed if retried.
+	ErrNotificationPushRejected = errors.New("push rejected")
+
+	ErrNotificationPushProviderInvalid = errors.New("push provider must be 1-64 characters")
+	ErrNotificationPushTokenLength     = errors.New("push token must be 1-4096 characters")
+	ErrNotificationPushProviderExists  = errors.New("push provider already registered")
+)
+
+// NotificationPushProvider delivers notifications to users who are not connected, through a device push service or
+// another channel outside of the realtime socket.
+type NotificationPushProvider interface {
+	// Name identifies the provider, and the device tokens users register for it.
+	Name() string
+	// TokenRequired reports whether the provider pushes to device tokens users registered for it, or to users
+	// directly without a token.
+	TokenRequired() bool
+	// Push delivers a notification to a user, through one of their device tokens if the provider requires them.
+	// Errors other than ErrNotificationPushTokenInvalid and ErrNotificationPushRejected are retried.
+	Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error
+}
+
+// NotificationPusher sends notifications for users who are not connected to the registered push providers, honouring
+// each user's per-code opt-outs and retrying failed pushes with backoff.
+type NotificationPusher interface {
+	// Push queues notifications for delivery. It does not block.
+	Push(logger *zap.Logger, notifications map[uuid.UUID][]*api.Notification)
+	RegisterProvider(provider NotificationPushProvider) error
+	Stop()
+}
+
+type notificationPushJob struct {
+	logger       *zap.Logger
+	provider     NotificationPushProvider
+	userID       uuid.UUID
+	token        string
+	notification *api.Notification
+	attempts     int
+}
+
+type LocalNotificationPusher struct {
+	sync.RWMutex
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+	logger      *zap.Logger
+	db          *sql.DB
+	config      *PushConfig
+
+	providers map[string]NotificationPushProvider
+	batches   chan *notificationPushBatch
+	jobs      chan *notificationPushJob
+	dropped   atomic.Int64
+	wg        sync.WaitGroup
+}
+
+type notificationPushBatch struct {
+	logger        *zap.Logger
+	notifications map[uuid.UUID][]*api.Notification
+}
+
+func NewLocalNotificationPusher(logger *zap.Logger, db *sql.DB, config *PushConfig) *LocalNotificationPusher {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	p := &LocalNotificationPusher{
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+		logger:      logger,
+		db:          db,
+		config:      config,
+
+		providers: make(map[string]NotificationPushProvider),
+		batches:   make(chan *notificationPushBatch, config.QueueSize),
+		jobs:      make(chan *notificationPushJob, config.QueueSize),
+	}
+
+	for _, provider := range newNotificationPushConfigProviders(logger, config) {
+		p.providers[provider.Name()] = provider
+	}
+
+	p.wg.Add(2 + config.Workers)
+	go p.resolve()
+	go p.logDropped()
+	for i := 0; i < config.Workers; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+func (p *LocalNotificationPusher) RegisterProvider(provider NotificationPushProvider) error {
+	name := provider.Name()
+	if name == "" || len(name) > notificationPushProviderMaxLength {
+		return ErrNotificationPushProviderInvalid
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if _, found := p.providers[name]; found {
+		return ErrNotificationPushProviderExists
+	}
+	p.providers[name] = provider
+	p.logger.Info("Registered push provider", zap.String("provider", name))
+	return nil
+}
+
+func (p *LocalNotificationPusher) Push(logger *zap.Logger, notifications map[uuid.UUID][]*api.Notification) {
+	p.RLock()
+	providerCount := len(p.providers)
+	p.RUnlock()
+	if providerCount == 0 || len(notifications) == 0 {
+		return
+	}
+
+	select {
+	case p.batches <- &notificationPushBatch{logger: logger, notifications: notifications}:
+	default:
+		var count int
+		for _, userNotifications := range notifications {
+			count += len(userNotifications)
+		}
+		p.dropped.Add(int64(count))
+	}
+}
+
+func (p *LocalNotificationPusher) Stop() {
+	p.ctxCancelFn()
+	p.wg.Wait()
+}
+
+// resolve turns queued notifications into one push job per provider and device token, skipping the codes each user
+// opted out of.
+func (p *LocalNotificationPusher) resolve() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case batch := <-p.batches:
+			p.RLock()
+			providers := make([]NotificationPushProvider, 0, len(p.providers))
+			for _, provider := range p.providers {
+				providers = append(providers, provider)
+			}
+			p.RUnlock()
+
+			userIDs := make([]string, 0, len(batch.notifications))
+			for userID := range batch.notifications {
+				userIDs = append(userIDs, userID.String())
+			}
+			optOuts, tokens, err := notificationPushTargetsGet(p.ctx, p.db, userIDs)
+			if err != nil {
+				batch.logger.Error("Failed to load push targets, dropping notifications", zap.Int("users", len(userIDs)), zap.Error(err))
+				continue
+			}
+
+			for userID, notifications := range batch.notifications {
+				for _, notification := range notifications {
+					if optOuts[userID][notification.Code] {
+						continue
+					}
+					for _, provider := range providers {
+						if !provider.TokenRequired() {
+							p.enqueue(&notificationPushJob{logger: batch.logger, provider: provider, userID: userID, notification: notification})
+							continue
+						}
+						for _, token := range tokens[userID][provider.Name()] {
+							p.enqueue(&notificationPushJob{logger: batch.logger, provider: provider, userID: userID, token: token, notification: notification})
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func (p *LocalNotificationPusher) enqueue(job *notificationPushJob) {
+	select {
+	case p.jobs <- job:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+// logDropped periodically logs how many notifications were dropped because the push queues were full, rather than
+// logging each one while the pusher is overloaded.
+func (p *LocalNotificationPusher) logDropped() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(notificationPushDroppedLogInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			if dropped := p.dropped.Swap(0); dropped > 0 {
+				p.logger.Warn("Push queue full, dropped notifications", zap.Int64("count", dropped))
+			}
+			return
+		case <-ticker.C:
+			if dropped := p.dropped.Swap(0); dropped > 0 {
+				p.logger.Warn("Push queue full, dropped notifications", zap.Int64("count", dropped), zap.Duration("interval", notificationPushDroppedLogInterval))
+			}
+		}
+	}
+}
+
+func (p *LocalNotificationPusher) work() {
+	defer p.wg.Done()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case job := <-p.jobs:
+			p.send(job)
+		}
+	}
+}
+
+func (p *LocalNotificationPusher) send(job *notificationPushJob) {
+	job.attempts++
+	err := job.provider.Push(p.ctx, job.userID, job.token, job.notification)
+	if err == nil {
+		return
+	}
+
+	logger := job.logger.With(zap.String("provider", job.provider.Name()), zap.String("user_id", job.userID.String()), zap.String("notification_id", job.notification.Id), zap.Int("attempts", job.attempts))
+	switch {
+	case errors.Is(err, ErrNotificationPushTokenInvalid):
+		logger.Info("Push token no longer valid, unregistering", zap.Error(err))
+		if err := NotificationPushTokenUnregister(p.ctx, logger, p.db, job.userID, job.provider.Name(), job.token); err != nil {
+			logger.Error("Failed to unregister push token", zap.Error(err))
+		}
+	case errors.Is(err, ErrNotificationPushRejected):
+		logger.Warn("Push rejected, dropping", zap.Error(err))
+	case job.attempts >= p.config.MaxAttempts:
+		logger.Warn("Push failed, dropping after max attempts", zap.Error(err))
+	default:
+		backoff := time.Duration(p.config.RetryBackoffMs) * time.Millisecond << (job.attempts - 1)
+		logger.Debug("Push failed, retrying", zap.Duration("backoff", backoff), zap.Error(err))
+		time.AfterFunc(backoff, func() {
+			if p.ctx.Err() == nil {
+				p.enqueue(job)
+			}
+		})
+	}
+}
+
+// notificationPushTargetsGet loads the notification codes each user opted out of pushes for, and their device tokens
+// by provider.
+func notificationPushTargetsGet(ctx context.Context, db *sql.DB, userIDs []string) (map[uuid.UUID]map[int32]bool, map[uuid.UUID]map[string][]string, error) {
+	optOuts := make(map[uuid.UUID]map[int32]bool)
+	rows, err := db.QueryContext(ctx, "SELECT user_id, code FROM user_notification_opt_out WHERE user_id = ANY($1::UUID[])", userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	var dbUserID uuid.UUID
+	var dbCode int32
+	for rows.Next() {
+		if err := rows.Scan(&dbUserID, &dbCode); err != nil {
+			_ = rows.Close()
+			return nil, nil, err
+		}
+		if optOuts[dbUserID] == nil {
+			optOuts[dbUserID] = make(map[int32]bool)
+		}
+		optOuts[dbUserID][dbCode] = true
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	tokens := make(map[uuid.UUID]map[string][]string)
+	rows, err = db.QueryContext(ctx, "SELECT user_id, provider, token FROM user_push_token WHERE user_id = ANY($1::UUID[])", userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	var dbProvider, dbToken string
+	for rows.Next() {
+		if err := rows.Scan(&dbUserID, &dbProvider, &dbToken); err != nil {
+			return nil, nil, err
+		}
+		if tokens[dbUserID] == nil {
+			tokens[dbUserID] = make(map[string][]string)
+		}
+		tokens[dbUserID][dbProvider] = append(tokens[dbUserID][dbProvider], dbToken)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return optOuts, tokens, nil
+}
+
+func notificationPushTokenValidate(provider, token string) error {
+	if provider == "" || len(provider) > notificationPushProviderMaxLength {
+		return ErrNotificationPushProviderInvalid
+	}
+	if token == "" || len(token) > notificationPushTokenMaxLength {
+		return ErrNotificationPushTokenLength
+	}
+	return nil
+}
+
+// NotificationPushTokenRegister registers a user's device token for a push provider. A token registered by another
+// user, such as after switching accounts on a device, is moved to this user.
+func NotificationPushTokenRegister(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, provider, token string) error {
+	if err := notificationPushTokenValidate(provider, token); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_push_token (provider, token, user_id) VALUES ($1, $2, $3)
+ON CONFLICT (provider, token) DO UPDATE SET user_id = $3, update_time = now()`
+	if _, err := db.ExecContext(ctx, query, provider, token, userID); err != nil {
+		logger.Error("Error registering push token", zap.String("user_id", userID.String()), zap.String("provider", provider), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// NotificationPushTokenUnregister removes a user's device token for a push provider.
+func NotificationPushTokenUnregister(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, provider, token string) error {
+	if err := notificationPushTokenValidate(provider, token); err != nil {
+		return err
+	}
+
+	if _, err := db.ExecContext(ctx, "DELETE FROM user_push_token WHERE provider = $1 AND token = $2 AND user_id = $3", provider, token, userID); err != nil {
+		logger.Error("Error unregistering push token", zap.String("user_id", userID.String()), zap.String("provider", provider), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// NotificationPushOptOutsGet lists the notification codes a user opted out of pushes for.
+func NotificationPushOptOutsGet(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID) ([]int32, error) {
+	rows, err := db.QueryContext(ctx, "SELECT code FROM user_notification_opt_out WHERE user_id = $1 ORDER BY code", userID)
+	if err != nil {
+		logger.Error("Error listing push opt-outs", zap.String("user_id", userID.String()), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make([]int32, 0)
+	var dbCode int32
+	for rows.Next() {
+		if err := rows.Scan(&dbCode); err != nil {
+			logger.Error("Error parsing push opt-outs", zap.String("user_id", userID.String()), zap.Error(err))
+			return nil, err
+		}
+		codes = append(codes, dbCode)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error listing push opt-outs", zap.String("user_id", userID.String()), zap.Error(err))
+		return nil, err
+	}
+	return codes, nil
+}
+
+// NotificationPushOptOutsSet replaces the notification codes a user opted out of pushes for. Notifications with these
+// codes are still delivered to the user's sockets and persisted as usual.
+func NotificationPushOptOutsSet(ctx context.Context, logger *zap.Logger, db *sql.DB, userID uuid.UUID, codes []int32) error {
+	if err := ExecuteInTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_notification_opt_out WHERE user_id = $1", userID); err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO user_notification_opt_out (user_id, code) SELECT $1, unnest($2::INTEGER[]) ON CONFLICT DO NOTHING", userID, codes)
+		return err
+	}); err != nil {
+		logger.Error("Error setting push opt-outs", zap.String("user_id", userID.String()), zap.Error(err))
+		return fmt.Errorf("error setting push opt-outs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+const (
+	NotificationPushProviderFcm     = "fcm"
+	NotificationPushProviderApns    = "apns"
+	NotificationPushProviderWebhook = "webhook"
+	NotificationPushProviderLocal   = "local"
+
+	notificationPushHttpTimeout     = 10 * time.Second
+	notificationPushLocalMaxRecords = 1000
+)
+
+// newNotificationPushConfigProviders creates the push providers enabled in the config.
+func newNotificationPushConfigProviders(logger *zap.Logger, config *PushConfig) []NotificationPushProvider {
+	client := &http.Client{Timeout: notificationPushHttpTimeout}
+	providers := make([]NotificationPushProvider, 0, 4)
+	if config.FcmURL != "" {
+		providers = append(providers, &fcmNotificationPushProvider{client: client, url: config.FcmURL, key: config.FcmKey})
+	}
+	if config.ApnsURL != "" {
+		providers = append(providers, &apnsNotificationPushProvider{client: client, url: strings.TrimSuffix(config.ApnsURL, "/"), key: config.ApnsKey, topic: config.ApnsTopic})
+	}
+	if config.WebhookURL != "" {
+		providers = append(providers, &webhookNotificationPushProvider{client: client, url: config.WebhookURL, key: config.WebhookKey})
+	}
+	if config.LocalEnabled {
+		providers = append(providers, NewLocalNotificationPushProvider(logger, false))
+	}
+	return providers
+}
+
+// notificationPushData is the notification as sent to push services alongside the platform specific alert.
+func notificationPushData(notification *api.Notification) map[string]string {
+	data := map[string]string{
+		"id":        notification.Id,
+		"subject":   notification.Subject,
+		"content":   notification.Content,
+		"code":      strconv.FormatInt(int64(notification.Code), 10),
+		"sender_id": notification.SenderId,
+	}
+	if notification.CreateTime != nil {
+		data["create_time"] = strconv.FormatInt(notification.CreateTime.Seconds, 10)
+	}
+	return data
+}
+
+// notificationPushPost sends a JSON push request and maps the response status to the push error semantics. Tokens
+// the service reports as gone are invalid, other client errors are rejected, and server errors are retried.
+func notificationPushPost(ctx context.Context, client *http.Client, url string, body interface{}, header http.Header) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationPushRejected, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotificationPushRejected, err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: status code %d", ErrNotificationPushTokenInvalid, resp.StatusCode)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: status code %d", ErrNotificationPushRejected, resp.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// fcmNotificationPushProvider sends FCM HTTP v1 messages to device tokens.
+type fcmNotificationPushProvider struct {
+	client *http.Client
+	url    string
+	key    string
+}
+
+func (p *fcmNotificationPushProvider) Name() string {
+	return NotificationPushProviderFcm
+}
+
+func (p *fcmNotificationPushProvider) TokenRequired() bool {
+	return true
+}
+
+func (p *fcmNotificationPushProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	body := map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        token,
+			"notification": map[string]string{"title": notification.Subject},
+			"data":         notificationPushData(notification),
+		},
+	}
+	header := http.Header{}
+	if p.key != "" {
+		header.Set("Authorization", "Bearer "+p.key)
+	}
+	return notificationPushPost(ctx, p.client, p.url, body, header)
+}
+
+// apnsNotificationPushProvider sends alerts to device tokens through the APNs provider API.
+type apnsNotificationPushProvider struct {
+	client *http.Client
+	url    string
+	key    string
+	topic  string
+}
+
+func (p *apnsNotificationPushProvider) Name() string {
+	return NotificationPushProviderApns
+}
+
+func (p *apnsNotificationPushProvider) TokenRequired() bool {
+	return true
+}
+
+func (p *apnsNotificationPushProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	body := map[string]interface{}{
+		"aps":  map[string]interface{}{"alert": map[string]string{"title": notification.Subject}},
+		"data": notificationPushData(notification),
+	}
+	header := http.Header{}
+	header.Set("apns-topic", p.topic)
+	header.Set("apns-push-type", "alert")
+	if notification.Id != "" {
+		header.Set("apns-collapse-id", notification.Id)
+	}
+	if p.key != "" {
+		header.Set("Authorization", "bearer "+p.key)
+	}
+	return notificationPushPost(ctx, p.client, p.url+"/3/device/"+url.PathEscape(token), body, header)
+}
+
+// webhookNotificationPushProvider POSTs notifications for offline users to a webhook, optionally signing the body.
+type webhookNotificationPushProvider struct {
+	client *http.Client
+	url    string
+	key    string
+}
+
+type webhookNotificationPush struct {
+	UserID       string            `json:"user_id"`
+	Notification map[string]string `json:"notification"`
+}
+
+func (p *webhookNotificationPushProvider) Name() string {
+	return NotificationPushProviderWebhook
+}
+
+func (p *webhookNotificationPushProvider) TokenRequired() bool {
+	return false
+}
+
+func (p *webhookNotificationPushProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	body := &webhookNotificationPush{UserID: userID.String(), Notification: notificationPushData(notification)}
+	header := http.Header{}
+	if p.key != "" {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNotificationPushRejected, err)
+		}
+		mac := hmac.New(sha256.New, []byte(p.key))
+		mac.Write(data)
+		header.Set("X-Nakama-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+	return notificationPushPost(ctx, p.client, p.url, body, header)
+}
+
+// LocalNotificationPush is a push recorded by the local stand-in provider.
+type LocalNotificationPush struct {
+	UserID       uuid.UUID
+	Token        string
+	Notification *api.Notification
+}
+
+// LocalNotificationPushProvider is a stand-in provider that logs and records pushes instead of sending them, for
+// local testing. It can be made to fail a number of pushes to exercise retries.
+type LocalNotificationPushProvider struct {
+	sync.Mutex
+	logger        *zap.Logger
+	tokenRequired bool
+	pushes        []*LocalNotificationPush
+	failCount     int
+	failErr       error
+}
+
+func NewLocalNotificationPushProvider(logger *zap.Logger, tokenRequired bool) *LocalNotificationPushProvider {
+	return &LocalNotificationPushProvider{
+		logger:        logger,
+		tokenRequired: tokenRequired,
+	}
+}
+
+func (p *LocalNotificationPushProvider) Name() string {
+	return NotificationPushProviderLocal
+}
+
+func (p *LocalNotificationPushProvider) TokenRequired() bool {
+	return p.tokenRequired
+}
+
+func (p *LocalNotificationPushProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.failCount > 0 {
+		p.failCount--
+		return p.failErr
+	}
+
+	p.logger.Info("Local push", zap.String("user_id", userID.String()), zap.String("token", token), zap.String("notification_id", notification.Id), zap.String("subject", notification.Subject), zap.Int32("code", notification.Code))
+	if len(p.pushes) >= notificationPushLocalMaxRecords {
+		p.pushes = p.pushes[1:]
+	}
+	p.pushes = append(p.pushes, &LocalNotificationPush{UserID: userID, Token: token, Notification: notification})
+	return nil
+}
+
+// Fail makes the next count pushes return the error.
+func (p *LocalNotificationPushProvider) Fail(count int, err error) {
+	p.Lock()
+	p.failCount, p.failErr = count, err
+	p.Unlock()
+}
+
+// Pushes returns the most recent pushes recorded, oldest first.
+func (p *LocalNotificationPushProvider) Pushes() []*LocalNotificationPush {
+	p.Lock()
+	defer p.Unlock()
+	pushes := make([]*LocalNotificationPush, len(p.pushes))
+	copy(pushes, p.pushes)
+	return pushes
+}
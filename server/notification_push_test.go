@@ -0,0 +1,231 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+)
+
+// notificationPushAttemptProvider signals each push attempt, so tests can wait for retries to complete.
+type notificationPushAttemptProvider struct {
+	*LocalNotificationPushProvider
+	attempts chan struct{}
+}
+
+func (p *notificationPushAttemptProvider) Push(ctx context.Context, userID uuid.UUID, token string, notification *api.Notification) error {
+	err := p.LocalNotificationPushProvider.Push(ctx, userID, token, notification)
+	p.attempts <- struct{}{}
+	return err
+}
+
+func TestNotificationPusherRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failCount int
+		failErr   error
+		attempts  int
+		want      int
+	}{
+		{name: "success", attempts: 1, want: 1},
+		{name: "retried", failCount: 2, failErr: errors.New("unavailable"), attempts: 3, want: 1},
+		{name: "max attempts", failCount: 3, failErr: errors.New("unavailable"), attempts: 3, want: 0},
+		{name: "rejected", failCount: 1, failErr: ErrNotificationPushRejected, attempts: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := loggerForTest(t)
+			config := NewPushConfig()
+			config.MaxAttempts = 3
+			config.RetryBackoffMs = 1
+			pusher := NewLocalNotificationPusher(logger, nil, config)
+			t.Cleanup(pusher.Stop)
+
+			provider := &notificationPushAttemptProvider{LocalNotificationPushProvider: NewLocalNotificationPushProvider(logger, false), attempts: make(chan struct{}, 10)}
+			provider.Fail(tt.failCount, tt.failErr)
+			if err := pusher.RegisterProvider(provider); err != nil {
+				t.Fatalf("error registering provider: %v", err)
+			}
+			if err := pusher.RegisterProvider(provider); !errors.Is(err, ErrNotificationPushProviderExists) {
+				t.Fatalf("expected duplicate provider error, got %v", err)
+			}
+
+			userID := uuid.Must(uuid.NewV4())
+			pusher.enqueue(&notificationPushJob{logger: logger, provider: provider, userID: userID, notification: &api.Notification{Id: "n1", Subject: "hello"}})
+
+			// Wait for every attempt, failed pushes are retried after a backoff.
+			for i := 0; i < tt.attempts; i++ {
+				select {
+				case <-provider.attempts:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("expected %d push attempts, got %d", tt.attempts, i)
+				}
+			}
+			pusher.Stop()
+			if extra := len(provider.attempts); extra != 0 {
+				t.Fatalf("expected %d push attempts, got %d", tt.attempts, tt.attempts+extra)
+			}
+			pushes := provider.Pushes()
+			if len(pushes) != tt.want {
+				t.Fatalf("expected %d pushes, got %d", tt.want, len(pushes))
+			}
+			if tt.want > 0 && (pushes[0].UserID != userID || pushes[0].Notification.Id != "n1") {
+				t.Fatalf("unexpected push: %+v", pushes[0])
+			}
+		})
+	}
+}
+
+func TestNotificationPusherOptOuts(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	logger := loggerForTest(t)
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+	if err := NotificationPushTokenRegister(ctx, logger, db, userID, NotificationPushProviderLocal, "device"); err != nil {
+		t.Fatalf("error registering push token: %v", err)
+	}
+	if err := NotificationPushOptOutsSet(ctx, logger, db, userID, []int32{NotificationCodeFriendRequest}); err != nil {
+		t.Fatalf("error setting push opt-outs: %v", err)
+	}
+	if codes, err := NotificationPushOptOutsGet(ctx, logger, db, userID); err != nil || len(codes) != 1 || codes[0] != NotificationCodeFriendRequest {
+		t.Fatalf("unexpected push opt-outs: %v, %v", codes, err)
+	}
+
+	config := NewPushConfig()
+	config.Workers = 1
+	pusher := NewLocalNotificationPusher(logger, db, config)
+	t.Cleanup(pusher.Stop)
+	provider := &notificationPushAttemptProvider{LocalNotificationPushProvider: NewLocalNotificationPushProvider(logger, true), attempts: make(chan struct{}, 10)}
+	if err := pusher.RegisterProvider(provider); err != nil {
+		t.Fatalf("error registering provider: %v", err)
+	}
+
+	pusher.Push(logger, map[uuid.UUID][]*api.Notification{userID: {
+		{Id: "n1", Code: NotificationCodeFriendRequest},
+		{Id: "n2", Code: NotificationCodeGroupAdd},
+		{Id: "n3", Code: NotificationCodeFriendAccept},
+	}})
+
+	// The notification opted out of is never queued, the others are pushed to the registered token.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-provider.attempts:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected 2 push attempts, got %d", i)
+		}
+	}
+	pusher.Stop()
+	if extra := len(provider.attempts) + len(pusher.jobs); extra != 0 {
+		t.Fatalf("expected 2 pushes, got %d more", extra)
+	}
+	for _, push := range provider.Pushes() {
+		if push.Token != "device" || push.Notification.Code == NotificationCodeFriendRequest {
+			t.Fatalf("unexpected push: %+v", push)
+		}
+	}
+}
+
+func TestNotificationPusherDropped(t *testing.T) {
+	logger := loggerForTest(t)
+	config := NewPushConfig()
+	config.Workers = 0
+	config.QueueSize = 1
+	pusher := NewLocalNotificationPusher(logger, nil, config)
+	t.Cleanup(pusher.Stop)
+
+	// Without workers the queue fills after one job, the rest are counted to be logged together.
+	provider := NewLocalNotificationPushProvider(logger, false)
+	for i := 0; i < 3; i++ {
+		pusher.enqueue(&notificationPushJob{logger: logger, provider: provider, userID: uuid.Must(uuid.NewV4()), notification: &api.Notification{Id: "n1"}})
+	}
+	if dropped := pusher.dropped.Load(); dropped != 2 {
+		t.Fatalf("expected 2 dropped notifications, got %d", dropped)
+	}
+}
+
+func TestNotificationPushHttpProviders(t *testing.T) {
+	var status int
+	var request *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	userID := uuid.Must(uuid.NewV4())
+	notification := &api.Notification{Id: "n1", Subject: "Friend request", Content: "{}", Code: NotificationCodeFriendRequest}
+
+	fcm := &fcmNotificationPushProvider{client: server.Client(), url: server.URL + "/send", key: "fcm-key"}
+	status = 200
+	if err := fcm.Push(context.Background(), userID, "device", notification); err != nil {
+		t.Fatalf("error pushing: %v", err)
+	}
+	var fcmBody struct {
+		Message struct {
+			Token        string            `json:"token"`
+			Notification map[string]string `json:"notification"`
+			Data         map[string]string `json:"data"`
+		} `json:"message"`
+	}
+	if err := json.Unmarshal(body, &fcmBody); err != nil {
+		t.Fatalf("error decoding body: %v", err)
+	}
+	if request.Header.Get("Authorization") != "Bearer fcm-key" || fcmBody.Message.Token != "device" || fcmBody.Message.Notification["title"] != "Friend request" || fcmBody.Message.Data["code"] != "-2" {
+		t.Fatalf("unexpected fcm request: %v %s", request.Header, body)
+	}
+
+	apns := &apnsNotificationPushProvider{client: server.Client(), url: server.URL, key: "apns-key", topic: "com.example.app"}
+	status = http.StatusGone
+	if err := apns.Push(context.Background(), userID, "device", notification); !errors.Is(err, ErrNotificationPushTokenInvalid) {
+		t.Fatalf("expected invalid token error, got %v", err)
+	}
+	if request.URL.Path != "/3/device/device" || request.Header.Get("apns-topic") != "com.example.app" {
+		t.Fatalf("unexpected apns request: %s %v", request.URL.Path, request.Header)
+	}
+
+	webhook := &webhookNotificationPushProvider{client: server.Client(), url: server.URL, key: "secret"}
+	status = http.StatusBadRequest
+	if err := webhook.Push(context.Background(), userID, "", notification); !errors.Is(err, ErrNotificationPushRejected) {
+		t.Fatalf("expected rejected error, got %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	if request.Header.Get("X-Nakama-Signature") != hex.EncodeToString(mac.Sum(nil)) {
+		t.Fatalf("unexpected webhook signature")
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := webhook.Push(context.Background(), userID, "", notification); err == nil || errors.Is(err, ErrNotificationPushRejected) || errors.Is(err, ErrNotificationPushTokenInvalid) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,706 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/heroiclabs/nakama/v3/internal/cronexpr"
+	"github.com/jackc/pgtype"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	NotificationScheduleSegmentUsers  = "users"
+	NotificationScheduleSegmentAll    = "all"
+	NotificationScheduleSegmentGroup  = "group"
+	NotificationScheduleSegmentActive = "active"
+
+	NotificationScheduleStatusScheduled = "scheduled"
+	NotificationScheduleStatusSending   = "sending"
+	NotificationScheduleStatusComplete  = "complete"
+	NotificationScheduleStatusCancelled = "cancelled"
+
+	// A run not updated for this long was abandoned by the node sending it, and is resumed by another.
+	notificationScheduleStaleSec = 300
+)
+
+var (
+	ErrNotificationScheduleInvalid   = errors.New("invalid notification schedule")
+	ErrNotificationScheduleNotFound  = errors.New("notification schedule not found")
+	ErrNotificationScheduleNotActive = errors.New("notification schedule is already complete or cancelled")
+)
+
+// NotificationSchedule is a notification sent at a later time, or repeatedly on a cron schedule, to a segment of
+// users: a list of users, all users, the members of a group, or the users online within a number of days.
+type NotificationSchedule struct {
+	Id         string                 `json:"id"`
+	Subject    string                 `json:"subject"`
+	Content    map[string]interface{} `json:"content"`
+	Code       int                    `json:"code"`
+	SenderId   string                 `json:"sender_id"`
+	Persistent bool                   `json:"persistent"`
+	// Push the notification to recipients who are not connected, through their registered push providers.
+	Push       bool     `json:"push"`
+	Segment    string   `json:"segment"`
+	UserIds    []string `json:"user_ids,omitempty"`
+	GroupId    string   `json:"group_id,omitempty"`
+	ActiveDays int      `json:"active_days,omitempty"`
+	// The UNIX time of the next send. When creating a schedule zero sends as soon as possible, or at the first cron
+	// time if the schedule is recurring.
+	SendTime    int64  `json:"send_time"`
+	Cron        string `json:"cron,omitempty"`
+	Status      string `json:"status"`
+	RunCount    int    `json:"run_count"`
+	SentCount   int64  `json:"sent_count"`
+	LastRunTime int64  `json:"last_run_time,omitempty"`
+	CreateTime  int64  `json:"create_time"`
+	UpdateTime  int64  `json:"update_time"`
+}
+
+type notificationScheduleListCursor struct {
+	Status     string
+	CreateTime time.Time
+	Id         string
+}
+
+// notificationScheduleValidate checks and normalises a new schedule, and returns the time of its first send.
+func notificationScheduleValidate(schedule *NotificationSchedule, maxUserIds int, now time.Time) (time.Time, error) {
+	if schedule.Subject == "" || len(schedule.Subject) > 255 {
+		return time.Time{}, fmt.Errorf("%w: subject must be 1-255 bytes", ErrNotificationScheduleInvalid)
+	}
+	if schedule.Code <= 0 || schedule.Code > math.MaxInt16 {
+		return time.Time{}, fmt.Errorf("%w: code must be 1-%d", ErrNotificationScheduleInvalid, math.MaxInt16)
+	}
+	if schedule.Content == nil {
+		schedule.Content = map[string]interface{}{}
+	}
+	if schedule.SenderId == "" {
+		schedule.SenderId = uuid.Nil.String()
+	} else if _, err := uuid.FromString(schedule.SenderId); err != nil {
+		return time.Time{}, fmt.Errorf("%w: sender ID must be empty or a valid UUID", ErrNotificationScheduleInvalid)
+	}
+
+	switch schedule.Segment {
+	case NotificationScheduleSegmentUsers:
+		if len(schedule.UserIds) == 0 || len(schedule.UserIds) > maxUserIds {
+			return time.Time{}, fmt.Errorf("%w: user IDs must list 1-%d users", ErrNotificationScheduleInvalid, maxUserIds)
+		}
+		for _, userID := range schedule.UserIds {
+			if _, err := uuid.FromString(userID); err != nil {
+				return time.Time{}, fmt.Errorf("%w: user IDs must be valid UUIDs", ErrNotificationScheduleInvalid)
+			}
+		}
+		schedule.GroupId, schedule.ActiveDays = "", 0
+	case NotificationScheduleSegmentAll:
+		schedule.UserIds, schedule.GroupId, schedule.ActiveDays = nil, "", 0
+	case NotificationScheduleSegmentGroup:
+		if _, err := uuid.FromString(schedule.GroupId); err != nil {
+			return time.Time{}, fmt.Errorf("%w: group ID must be a valid UUID", ErrNotificationScheduleInvalid)
+		}
+		schedule.UserIds, schedule.ActiveDays = nil, 0
+	case NotificationScheduleSegmentActive:
+		if schedule.ActiveDays < 1 {
+			return time.Time{}, fmt.Errorf("%w: active days must be >= 1", ErrNotificationScheduleInvalid)
+		}
+		schedule.UserIds, schedule.GroupId = nil, ""
+	default:
+		return time.Time{}, fmt.Errorf("%w: segment must be one of '%s', '%s', '%s' or '%s'", ErrNotificationScheduleInvalid, NotificationScheduleSegmentUsers, NotificationScheduleSegmentAll, NotificationScheduleSegmentGroup, NotificationScheduleSegmentActive)
+	}
+
+	if schedule.SendTime < 0 {
+		return time.Time{}, fmt.Errorf("%w: send time must be >= 0", ErrNotificationScheduleInvalid)
+	}
+	sendTime := now
+	if schedule.SendTime > 0 {
+		sendTime = time.Unix(schedule.SendTime, 0).UTC()
+	}
+	if schedule.Cron != "" {
+		expr, err := cronexpr.Parse(schedule.Cron)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: cron expression is invalid: %v", ErrNotificationScheduleInvalid, err)
+		}
+		next := expr.Next(now)
+		if next.IsZero() {
+			return time.Time{}, fmt.Errorf("%w: cron expression never matches", ErrNotificationScheduleInvalid)
+		}
+		if schedule.SendTime == 0 {
+			sendTime = next
+		}
+	}
+
+	return sendTime, nil
+}
+
+// notificationScheduleNextRun returns the time a schedule should next be sent after a run completes at the given
+// time, or the zero time if it does not recur.
+func notificationScheduleNextRun(cron string, now time.Time) (time.Time, error) {
+	if cron == "" {
+		return time.Time{}, nil
+	}
+	expr, err := cronexpr.Parse(cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expr.Next(now), nil
+}
+
+// NotificationScheduleCreate stores a new notification schedule to be sent by the notification scheduler.
+func NotificationScheduleCreate(ctx context.Context, logger *zap.Logger, db *sql.DB, schedule *NotificationSchedule, maxUserIds int) (*NotificationSchedule, error) {
+	sendTime, err := notificationScheduleValidate(schedule, maxUserIds, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(schedule.Content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: content could not be encoded: %v", ErrNotificationScheduleInvalid, err)
+	}
+	userIDs := schedule.UserIds
+	if userIDs == nil {
+		userIDs = []string{}
+	}
+	var groupID *string
+	if schedule.GroupId != "" {
+		groupID = &schedule.GroupId
+	}
+
+	schedule.Id = uuid.Must(uuid.NewV4()).String()
+	schedule.Status = NotificationScheduleStatusScheduled
+
+	query := `INSERT INTO notification_schedule (id, subject, content, code, sender_id, persistent, push, segment, user_ids, group_id, active_days, cron, status, next_run_time)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::UUID[], $10, $11, $12, $13, $14)
+RETURNING create_time, update_time`
+	var dbCreateTime, dbUpdateTime pgtype.Timestamptz
+	if err := db.QueryRowContext(ctx, query, schedule.Id, schedule.Subject, content, schedule.Code, schedule.SenderId, schedule.Persistent, schedule.Push, schedule.Segment, userIDs, groupID, schedule.ActiveDays, schedule.Cron, schedule.Status, sendTime).Scan(&dbCreateTime, &dbUpdateTime); err != nil {
+		logger.Error("Error creating notification schedule", zap.Error(err))
+		return nil, err
+	}
+
+	schedule.SendTime = sendTime.Unix()
+	schedule.RunCount, schedule.SentCount, schedule.LastRunTime = 0, 0, 0
+	schedule.CreateTime = dbCreateTime.Time.Unix()
+	schedule.UpdateTime = dbUpdateTime.Time.Unix()
+	return schedule, nil
+}
+
+// NotificationScheduleCancel stops a scheduled or recurring notification from being sent again. A run in progress
+// stops before its next batch.
+func NotificationScheduleCancel(ctx context.Context, logger *zap.Logger, db *sql.DB, id string) error {
+	if _, err := uuid.FromString(id); err != nil {
+		return ErrNotificationScheduleNotFound
+	}
+
+	query := `UPDATE notification_schedule SET status = $2, next_run_time = NULL, update_time = now()
+WHERE id = $1 AND status IN ($3, $4)`
+	res, err := db.ExecContext(ctx, query, id, NotificationScheduleStatusCancelled, NotificationScheduleStatusScheduled, NotificationScheduleStatusSending)
+	if err != nil {
+		logger.Error("Error cancelling notification schedule", zap.Error(err))
+		return err
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 1 {
+		return nil
+	}
+
+	// Nothing was cancelled, find out why.
+	if _, err := NotificationScheduleGet(ctx, logger, db, id); err != nil {
+		return err
+	}
+	return ErrNotificationScheduleNotActive
+}
+
+const notificationScheduleColumns = `id, subject, content, code, sender_id, persistent, push, segment, user_ids::VARCHAR[], group_id, active_days, cron, status, next_run_time, run_count, sent_count, last_run_time, create_time, update_time`
+
+type notificationScheduleScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func notificationScheduleScan(row notificationScheduleScanner) (*NotificationSchedule, time.Time, error) {
+	var dbContent []byte
+	var dbUserIDs pgtype.VarcharArray
+	var dbGroupID sql.NullString
+	var dbNextRunTime, dbLastRunTime, dbCreateTime, dbUpdateTime pgtype.Timestamptz
+	schedule := &NotificationSchedule{}
+	if err := row.Scan(&schedule.Id, &schedule.Subject, &dbContent, &schedule.Code, &schedule.SenderId, &schedule.Persistent, &schedule.Push, &schedule.Segment, &dbUserIDs, &dbGroupID, &schedule.ActiveDays, &schedule.Cron, &schedule.Status, &dbNextRunTime, &schedule.RunCount, &schedule.SentCount, &dbLastRunTime, &dbCreateTime, &dbUpdateTime); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if err := json.Unmarshal(dbContent, &schedule.Content); err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(dbUserIDs.Elements) > 0 {
+		schedule.UserIds = make([]string, 0, len(dbUserIDs.Elements))
+		for _, userID := range dbUserIDs.Elements {
+			schedule.UserIds = append(schedule.UserIds, userID.String)
+		}
+	}
+	schedule.GroupId = dbGroupID.String
+	if dbNextRunTime.Status == pgtype.Present {
+		schedule.SendTime = dbNextRunTime.Time.Unix()
+	}
+	if dbLastRunTime.Status == pgtype.Present {
+		schedule.LastRunTime = dbLastRunTime.Time.Unix()
+	}
+	schedule.CreateTime = dbCreateTime.Time.Unix()
+	schedule.UpdateTime = dbUpdateTime.Time.Unix()
+	return schedule, dbCreateTime.Time, nil
+}
+
+// NotificationScheduleGet returns a notification schedule and its progress.
+func NotificationScheduleGet(ctx context.Context, logger *zap.Logger, db *sql.DB, id string) (*NotificationSchedule, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNotificationScheduleNotFound
+	}
+
+	schedule, _, err := notificationScheduleScan(db.QueryRowContext(ctx, "SELECT "+notificationScheduleColumns+" FROM notification_schedule WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotificationScheduleNotFound
+		}
+		logger.Error("Error retrieving notification schedule", zap.Error(err))
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// NotificationScheduleList lists notification schedules newest first, optionally only those with a given status.
+func NotificationScheduleList(ctx context.Context, logger *zap.Logger, db *sql.DB, status string, limit int, cursor string) ([]*NotificationSchedule, string, error) {
+	switch status {
+	case "", NotificationScheduleStatusScheduled, NotificationScheduleStatusSending, NotificationScheduleStatusComplete, NotificationScheduleStatusCancelled:
+	default:
+		return nil, "", fmt.Errorf("%w: unknown status '%s'", ErrNotificationScheduleInvalid, status)
+	}
+
+	var incomingCursor *notificationScheduleListCursor
+	if cursor != "" {
+		cb, err := base64.URLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: cursor is invalid", ErrNotificationScheduleInvalid)
+		}
+		incomingCursor = &notificationScheduleListCursor{}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(incomingCursor); err != nil || incomingCursor.Status != status {
+			return nil, "", fmt.Errorf("%w: cursor is invalid", ErrNotificationScheduleInvalid)
+		}
+	}
+
+	query := "SELECT " + notificationScheduleColumns + " FROM notification_schedule WHERE ($1 = '' OR status = $1)"
+	params := []interface{}{status, limit + 1}
+	if incomingCursor != nil {
+		query += " AND (create_time, id) < ($3, $4)"
+		params = append(params, incomingCursor.CreateTime, incomingCursor.Id)
+	}
+	query += " ORDER BY create_time DESC, id DESC LIMIT $2"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		logger.Error("Error listing notification schedules", zap.Error(err))
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	schedules := make([]*NotificationSchedule, 0, limit)
+	var nextCursor *notificationScheduleListCursor
+	var lastCreateTime time.Time
+	for rows.Next() {
+		if len(schedules) >= limit {
+			nextCursor = &notificationScheduleListCursor{
+				Status:     status,
+				CreateTime: lastCreateTime,
+				Id:         schedules[len(schedules)-1].Id,
+			}
+			break
+		}
+		schedule, createTime, err := notificationScheduleScan(rows)
+		if err != nil {
+			logger.Error("Error parsing notification schedules", zap.Error(err))
+			return nil, "", err
+		}
+		schedules = append(schedules, schedule)
+		lastCreateTime = createTime
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Error listing notification schedules", zap.Error(err))
+		return nil, "", err
+	}
+
+	var nextCursorStr string
+	if nextCursor != nil {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(nextCursor); err != nil {
+			logger.Error("Error creating notification schedule list cursor", zap.Error(err))
+			return nil, "", err
+		}
+		nextCursorStr = base64.URLEncoding.EncodeToString(cursorBuf.Bytes())
+	}
+
+	return schedules, nextCursorStr, nil
+}
+
+const (
+	userOnlineTimeFlushInterval = 10 * time.Second
+	// Pending users written in one update, and the number of pending users that triggers an early write.
+	userOnlineTimeBatchSize = 1000
+)
+
+// UserOnlineTimeWriter records when users were last online, for notifications sent to recently active users.
+type UserOnlineTimeWriter interface {
+	Record(userID uuid.UUID)
+	Stop()
+}
+
+// LocalUserOnlineTimeWriter coalesces the users seen online and writes them periodically in batches, so session
+// starts and ends don't each write to the database. Online times are accurate to the flush interval.
+type LocalUserOnlineTimeWriter struct {
+	sync.Mutex
+	logger  *zap.Logger
+	db      *sql.DB
+	pending map[uuid.UUID]struct{}
+	flushCh chan struct{}
+
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+	doneCh      chan struct{}
+}
+
+func NewLocalUserOnlineTimeWriter(logger *zap.Logger, db *sql.DB) UserOnlineTimeWriter {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	w := &LocalUserOnlineTimeWriter{
+		logger:  logger,
+		db:      db,
+		pending: make(map[uuid.UUID]struct{}),
+		flushCh: make(chan struct{}, 1),
+
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+		doneCh:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.doneCh)
+
+		ticker := time.NewTicker(userOnlineTimeFlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.ctx.Done():
+				// Write what remains before stopping.
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				w.flush(ctx)
+				cancel()
+				return
+			case <-ticker.C:
+				w.flush(w.ctx)
+			case <-w.flushCh:
+				w.flush(w.ctx)
+			}
+		}
+	}()
+
+	return w
+}
+
+// Record marks a user as online now. It never blocks on the database.
+func (w *LocalUserOnlineTimeWriter) Record(userID uuid.UUID) {
+	w.Lock()
+	w.pending[userID] = struct{}{}
+	full := len(w.pending) >= userOnlineTimeBatchSize
+	w.Unlock()
+
+	if full {
+		select {
+		case w.flushCh <- struct{}{}:
+		default:
+			// A write is already due.
+		}
+	}
+}
+
+func (w *LocalUserOnlineTimeWriter) Stop() {
+	w.ctxCancelFn()
+	<-w.doneCh
+}
+
+func (w *LocalUserOnlineTimeWriter) flush(ctx context.Context) {
+	w.Lock()
+	if len(w.pending) == 0 {
+		w.Unlock()
+		return
+	}
+	pending := w.pending
+	w.pending = make(map[uuid.UUID]struct{}, len(pending))
+	w.Unlock()
+
+	userIDs := make([]string, 0, userOnlineTimeBatchSize)
+	for userID := range pending {
+		userIDs = append(userIDs, userID.String())
+		if len(userIDs) == userOnlineTimeBatchSize {
+			w.write(ctx, userIDs)
+			userIDs = userIDs[:0]
+		}
+	}
+	if len(userIDs) > 0 {
+		w.write(ctx, userIDs)
+	}
+}
+
+func (w *LocalUserOnlineTimeWriter) write(ctx context.Context, userIDs []string) {
+	if _, err := w.db.ExecContext(ctx, "UPDATE users SET online_time = now() WHERE id = ANY($1::UUID[])", userIDs); err != nil {
+		w.logger.Warn("Error updating user online times", zap.Int("count", len(userIDs)), zap.Error(err))
+	}
+}
+
+type NotificationScheduler interface {
+	Start()
+	Stop()
+}
+
+// LocalNotificationScheduler periodically sends the notification schedules that are due, in batches of users.
+// Each batch is claimed before it is sent, so a node failing mid-batch may drop that batch but never sends it twice,
+// and runs abandoned by a failed node are resumed by another.
+type LocalNotificationScheduler struct {
+	logger  *zap.Logger
+	db      *sql.DB
+	tracker Tracker
+	router  MessageRouter
+	config  *NotificationConfig
+
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+}
+
+func NewLocalNotificationScheduler(logger *zap.Logger, db *sql.DB, tracker Tracker, router MessageRouter, config *NotificationConfig) NotificationScheduler {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	return &LocalNotificationScheduler{
+		logger:  logger,
+		db:      db,
+		tracker: tracker,
+		router:  router,
+		config:  config,
+
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+	}
+}
+
+func (s *LocalNotificationScheduler) Start() {
+	if s.config.ScheduleIntervalSec < 1 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.config.ScheduleIntervalSec) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Process(s.ctx); err != nil && s.ctx.Err() == nil {
+					s.logger.Error("Failed to send scheduled notifications", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (s *LocalNotificationScheduler) Stop() {
+	s.ctxCancelFn()
+}
+
+// notificationScheduleRun is a notification schedule claimed for sending.
+type notificationScheduleRun struct {
+	id         string
+	subject    string
+	content    string
+	code       int32
+	senderID   string
+	persistent bool
+	push       bool
+	segment    string
+	userIDs    []string
+	groupID    string
+	activeDays int
+	cron       string
+	cursor     uuid.UUID
+}
+
+// Process sends the notification schedules that are due until none remain, and returns the number of runs completed.
+func (s *LocalNotificationScheduler) Process(ctx context.Context) (int, error) {
+	runs := 0
+	for {
+		run, err := s.claim(ctx)
+		if err != nil {
+			return runs, err
+		}
+		if run == nil {
+			return runs, nil
+		}
+		if err := s.send(ctx, run); err != nil {
+			return runs, err
+		}
+		runs++
+	}
+}
+
+func (s *LocalNotificationScheduler) claim(ctx context.Context) (*notificationScheduleRun, error) {
+	query := `UPDATE notification_schedule SET status = $2, update_time = now()
+WHERE id = (
+	SELECT id FROM notification_schedule
+	WHERE (status = $1 AND next_run_time <= now()) OR (status = $2 AND update_time < $3)
+	ORDER BY next_run_time ASC
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, subject, content, code, sender_id, persistent, push, segment, user_ids::VARCHAR[], group_id, active_days, cron, run_cursor`
+
+	run := &notificationScheduleRun{}
+	var dbUserIDs pgtype.VarcharArray
+	var dbGroupID, dbCursor sql.NullString
+	if err := s.db.QueryRowContext(ctx, query, NotificationScheduleStatusScheduled, NotificationScheduleStatusSending, time.Now().UTC().Add(-notificationScheduleStaleSec*time.Second)).Scan(&run.id, &run.subject, &run.content, &run.code, &run.senderID, &run.persistent, &run.push, &run.segment, &dbUserIDs, &dbGroupID, &run.activeDays, &run.cron, &dbCursor); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	run.userIDs = make([]string, 0, len(dbUserIDs.Elements))
+	for _, userID := range dbUserIDs.Elements {
+		run.userIDs = append(run.userIDs, userID.String)
+	}
+	run.groupID = dbGroupID.String
+	run.cursor = uuid.FromStringOrNil(dbCursor.String)
+	return run, nil
+}
+
+func (s *LocalNotificationScheduler) send(ctx context.Context, run *notificationScheduleRun) error {
+	logger := s.logger.With(zap.String("notification_schedule_id", run.id))
+
+	for {
+		userIDs, err := s.recipients(ctx, run)
+		if err != nil {
+			return err
+		}
+		if len(userIDs) == 0 {
+			break
+		}
+
+		// Claim the batch, which also stops the run if it was cancelled.
+		run.cursor = userIDs[len(userIDs)-1]
+		query := `UPDATE notification_schedule SET run_cursor = $2, sent_count = sent_count + $3, update_time = now()
+WHERE id = $1 AND status = $4`
+		res, err := s.db.ExecContext(ctx, query, run.id, run.cursor, len(userIDs), NotificationScheduleStatusSending)
+		if err != nil {
+			return err
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected != 1 {
+			logger.Debug("Scheduled notification cancelled")
+			return nil
+		}
+
+		createTime := &timestamppb.Timestamp{Seconds: time.Now().UTC().Unix()}
+		notifications := make(map[uuid.UUID][]*api.Notification, len(userIDs))
+		for _, userID := range userIDs {
+			notifications[userID] = []*api.Notification{{
+				Id:         uuid.Must(uuid.NewV4()).String(),
+				Subject:    run.subject,
+				Content:    run.content,
+				Code:       run.code,
+				SenderId:   run.senderID,
+				CreateTime: createTime,
+				Persistent: run.persistent,
+			}}
+		}
+		if err := notificationSend(ctx, logger, s.db, s.tracker, s.router, notifications, run.push); err != nil {
+			return err
+		}
+		logger.Debug("Sent scheduled notification batch", zap.Int("count", len(userIDs)))
+
+		if len(userIDs) < s.config.ScheduleBatchSize {
+			break
+		}
+	}
+
+	status := NotificationScheduleStatusComplete
+	var nextRunTime *time.Time
+	next, err := notificationScheduleNextRun(run.cron, time.Now().UTC())
+	if err != nil {
+		logger.Error("Invalid scheduled notification cron expression", zap.String("cron", run.cron), zap.Error(err))
+	} else if !next.IsZero() {
+		status = NotificationScheduleStatusScheduled
+		nextRunTime = &next
+	}
+
+	query := `UPDATE notification_schedule SET status = $2, next_run_time = $3, run_cursor = NULL, run_count = run_count + 1, last_run_time = now(), update_time = now()
+WHERE id = $1 AND status = $4`
+	if _, err := s.db.ExecContext(ctx, query, run.id, status, nextRunTime, NotificationScheduleStatusSending); err != nil {
+		return err
+	}
+	return nil
+}
+
+// recipients returns the next batch of users in the run's segment, in user ID order after the run's cursor.
+func (s *LocalNotificationScheduler) recipients(ctx context.Context, run *notificationScheduleRun) ([]uuid.UUID, error) {
+	var query string
+	var params []interface{}
+	switch run.segment {
+	case NotificationScheduleSegmentUsers:
+		query = "SELECT id FROM users WHERE id = ANY($3::UUID[]) AND id > $1 AND disable_time = '1970-01-01 00:00:00 UTC' ORDER BY id ASC LIMIT $2"
+		params = []interface{}{run.cursor, s.config.ScheduleBatchSize, run.userIDs}
+	case NotificationScheduleSegmentAll:
+		query = "SELECT id FROM users WHERE id > $1 AND disable_time = '1970-01-01 00:00:00 UTC' ORDER BY id ASC LIMIT $2"
+		params = []interface{}{run.cursor, s.config.ScheduleBatchSize}
+	case NotificationScheduleSegmentGroup:
+		query = "SELECT destination_id FROM group_edge WHERE source_id = $3 AND state <= $4 AND destination_id > $1 ORDER BY destination_id ASC LIMIT $2"
+		params = []interface{}{run.cursor, s.config.ScheduleBatchSize, run.groupID, api.GroupUserList_GroupUser_MEMBER}
+	case NotificationScheduleSegmentActive:
+		query = "SELECT id FROM users WHERE online_time >= $3 AND id > $1 AND disable_time = '1970-01-01 00:00:00 UTC' ORDER BY id ASC LIMIT $2"
+		params = []interface{}{run.cursor, s.config.ScheduleBatchSize, time.Now().UTC().AddDate(0, 0, -run.activeDays)}
+	default:
+		return nil, fmt.Errorf("unknown notification schedule segment '%s'", run.segment)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := make([]uuid.UUID, 0, s.config.ScheduleBatchSize)
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
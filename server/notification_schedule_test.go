@@ -0,0 +1,239 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationScheduleValidate(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 30, 0, 0, time.UTC)
+	userID := uuid.Must(uuid.NewV4()).String()
+
+	tests := []struct {
+		name     string
+		schedule *NotificationSchedule
+		sendTime time.Time
+		invalid  bool
+	}{
+		{
+			name:     "immediate to all users",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentAll},
+			sendTime: now,
+		},
+		{
+			name:     "at a time to users",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentUsers, UserIds: []string{userID}, SendTime: now.Add(time.Hour).Unix()},
+			sendTime: now.Add(time.Hour),
+		},
+		{
+			name:     "recurring starts at the first cron time",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentActive, ActiveDays: 7, Cron: "0 18 * * *"},
+			sendTime: time.Date(2024, 8, 1, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "recurring with a send time starts at the send time",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentGroup, GroupId: userID, Cron: "0 18 * * *", SendTime: now.Add(time.Minute).Unix()},
+			sendTime: now.Add(time.Minute),
+		},
+		{
+			name:     "missing subject",
+			schedule: &NotificationSchedule{Code: 1, Segment: NotificationScheduleSegmentAll},
+			invalid:  true,
+		},
+		{
+			name:     "reserved code",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 0, Segment: NotificationScheduleSegmentAll},
+			invalid:  true,
+		},
+		{
+			name:     "no users",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentUsers},
+			invalid:  true,
+		},
+		{
+			name:     "too many users",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentUsers, UserIds: []string{userID, userID, userID}},
+			invalid:  true,
+		},
+		{
+			name:     "invalid group",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentGroup, GroupId: "group"},
+			invalid:  true,
+		},
+		{
+			name:     "no active days",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentActive},
+			invalid:  true,
+		},
+		{
+			name:     "unknown segment",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: "friends"},
+			invalid:  true,
+		},
+		{
+			name:     "invalid cron",
+			schedule: &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentAll, Cron: "every day"},
+			invalid:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sendTime, err := notificationScheduleValidate(tt.schedule, 2, now)
+			if tt.invalid {
+				assert.True(t, errors.Is(err, ErrNotificationScheduleInvalid), "expected invalid schedule error, got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.sendTime, sendTime)
+			assert.Equal(t, uuid.Nil.String(), tt.schedule.SenderId)
+			assert.NotNil(t, tt.schedule.Content)
+		})
+	}
+}
+
+func TestNotificationScheduleValidateClearsUnusedTargets(t *testing.T) {
+	schedule := &NotificationSchedule{Subject: "hello", Code: 1, Segment: NotificationScheduleSegmentAll, UserIds: []string{uuid.Must(uuid.NewV4()).String()}, GroupId: uuid.Must(uuid.NewV4()).String(), ActiveDays: 3}
+	_, err := notificationScheduleValidate(schedule, 10, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Nil(t, schedule.UserIds)
+	assert.Empty(t, schedule.GroupId)
+	assert.Zero(t, schedule.ActiveDays)
+}
+
+func TestNotificationScheduleNextRun(t *testing.T) {
+	now := time.Date(2024, 8, 1, 18, 0, 0, 0, time.UTC)
+
+	next, err := notificationScheduleNextRun("", now)
+	require.NoError(t, err)
+	assert.True(t, next.IsZero(), "one-off schedules should not recur")
+
+	// A run finishing exactly on a cron time recurs at the following one.
+	next, err = notificationScheduleNextRun("0 18 * * *", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 8, 2, 18, 0, 0, 0, time.UTC), next)
+
+	next, err = notificationScheduleNextRun("*/15 * * * *", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 8, 1, 18, 15, 0, 0, time.UTC), next)
+
+	_, err = notificationScheduleNextRun("not cron", now)
+	assert.Error(t, err)
+}
+
+func TestUserOnlineTimeWriter(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	userID := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, userID)
+
+	writer := NewLocalUserOnlineTimeWriter(loggerForTest(t), db)
+	writer.Record(userID)
+	writer.Record(userID)
+	// Stopping writes the pending users.
+	writer.Stop()
+
+	var onlineTime sql.NullTime
+	require.NoError(t, db.QueryRow("SELECT online_time FROM users WHERE id = $1", userID).Scan(&onlineTime))
+	assert.True(t, onlineTime.Valid, "online time not written")
+}
+
+func TestLocalNotificationSchedulerProcess(t *testing.T) {
+	ctx := context.Background()
+	db := NewDB(t)
+	defer db.Close()
+	tracker := StartLocalTracker(logger, NewConfig(logger), nil, nil, metrics, nil)
+	defer tracker.Stop()
+
+	userIDs := make([]string, 3)
+	for i := range userIDs {
+		userID := uuid.Must(uuid.NewV4())
+		InsertUser(t, db, userID)
+		userIDs[i] = userID.String()
+	}
+
+	config := NewNotificationConfig()
+	config.ScheduleBatchSize = 2
+	scheduler := NewLocalNotificationScheduler(logger, db, tracker, &DummyMessageRouter{}, config).(*LocalNotificationScheduler)
+
+	create := func(sendTime int64, cron string) *NotificationSchedule {
+		schedule, err := NotificationScheduleCreate(ctx, logger, db, &NotificationSchedule{
+			Subject:    GenerateString(),
+			Code:       101,
+			Persistent: true,
+			Segment:    NotificationScheduleSegmentUsers,
+			UserIds:    userIDs,
+			SendTime:   sendTime,
+			Cron:       cron,
+		}, config.ScheduleMaxUserIds)
+		require.NoError(t, err)
+		return schedule
+	}
+	now := time.Now().UTC().Unix()
+	due := create(0, "")
+	later := create(now+3600, "")
+	recurring := create(now-60, "0 0 * * *")
+	cancelled := create(0, "")
+
+	require.NoError(t, NotificationScheduleCancel(ctx, logger, db, cancelled.Id))
+	assert.ErrorIs(t, NotificationScheduleCancel(ctx, logger, db, cancelled.Id), ErrNotificationScheduleNotActive)
+	assert.ErrorIs(t, NotificationScheduleCancel(ctx, logger, db, uuid.Must(uuid.NewV4()).String()), ErrNotificationScheduleNotFound)
+
+	_, err := scheduler.Process(ctx)
+	require.NoError(t, err)
+
+	// Due schedules are sent to every user across batches, once.
+	schedule, err := NotificationScheduleGet(ctx, logger, db, due.Id)
+	require.NoError(t, err)
+	assert.Equal(t, NotificationScheduleStatusComplete, schedule.Status)
+	assert.Equal(t, 1, schedule.RunCount)
+	assert.EqualValues(t, 3, schedule.SentCount)
+	var count int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM notification WHERE subject = $1", due.Subject).Scan(&count))
+	assert.Equal(t, 3, count)
+
+	// Recurring schedules are sent, then scheduled again.
+	schedule, err = NotificationScheduleGet(ctx, logger, db, recurring.Id)
+	require.NoError(t, err)
+	assert.Equal(t, NotificationScheduleStatusScheduled, schedule.Status)
+	assert.Equal(t, 1, schedule.RunCount)
+	assert.Greater(t, schedule.SendTime, now)
+
+	// Schedules not yet due, or cancelled, are not claimed.
+	for _, id := range []string{later.Id, cancelled.Id} {
+		schedule, err = NotificationScheduleGet(ctx, logger, db, id)
+		require.NoError(t, err)
+		assert.Equal(t, 0, schedule.RunCount)
+		assert.EqualValues(t, 0, schedule.SentCount)
+	}
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM notification WHERE subject = $1", cancelled.Subject).Scan(&count))
+	assert.Equal(t, 0, count)
+
+	// Nothing is sent twice.
+	_, err = scheduler.Process(ctx)
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM notification WHERE subject = $1", due.Subject).Scan(&count))
+	assert.Equal(t, 3, count)
+}
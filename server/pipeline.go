@@ -38,10 +38,11 @@ type Pipeline struct {
 	tracker              Tracker
 	router               MessageRouter
 	runtime              *Runtime
+	onlineTimeWriter     UserOnlineTimeWriter
 	node                 string
 }
 
-func NewPipeline(logger *zap.Logger, config Config, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, sessionRegistry SessionRegistry, statusRegistry StatusRegistry, matchRegistry MatchRegistry, partyRegistry PartyRegistry, matchmaker Matchmaker, tracker Tracker, router MessageRouter, runtime *Runtime) *Pipeline {
+func NewPipeline(logger *zap.Logger, config Config, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, protojsonUnmarshaler *protojson.UnmarshalOptions, sessionRegistry SessionRegistry, statusRegistry StatusRegistry, matchRegistry MatchRegistry, partyRegistry PartyRegistry, matchmaker Matchmaker, tracker Tracker, router MessageRouter, runtime *Runtime, onlineTimeWriter UserOnlineTimeWriter) *Pipeline {
 	return &Pipeline{
 		logger:               logger,
 		config:               config,
@@ -56,6 +57,7 @@ func NewPipeline(logger *zap.Logger, config Config, db *sql.DB, protojsonMarshal
 		tracker:              tracker,
 		router:               router,
 		runtime:              runtime,
+		onlineTimeWriter:     onlineTimeWriter,
 		node:                 config.GetName(),
 	}
 }
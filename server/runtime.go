@@ -512,6 +512,8 @@ type Runtime struct {
 
 	matchmakerMatchedFunction  RuntimeMatchmakerMatchedFunction
 	matchmakerOverrideFunction RuntimeMatchmakerOverrideFunction
+	matchmakerFormation        *MatchmakerFormation
+	goNakamaModule             *RuntimeGoNakamaModule
 
 	tournamentEndFunction                  RuntimeTournamentEndFunction
 	tournamentResetFunction                RuntimeTournamentResetFunction
@@ -642,7 +644,7 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 
 	matchProvider := NewMatchProvider()
 
-	goModules, goRPCFns, goBeforeRtFns, goAfterRtFns, goBeforeReqFns, goAfterReqFns, goMatchmakerMatchedFn, goMatchmakerCustomMatchingFn, goTournamentEndFn, goTournamentResetFn, goLeaderboardResetFn, goPurchaseNotificationAppleFn, goSubscriptionNotificationAppleFn, goPurchaseNotificationGoogleFn, goSubscriptionNotificationGoogleFn, goIndexFilterFns, fleetManager, allEventFns, goMatchNamesListFn, err := NewRuntimeProviderGo(ctx, logger, startupLogger, db, protojsonMarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, storageIndex, runtimeConfig.Path, paths, eventQueue, matchProvider, fmCallbackHandler)
+	goModules, goRPCFns, goBeforeRtFns, goAfterRtFns, goBeforeReqFns, goAfterReqFns, goMatchmakerMatchedFn, goMatchmakerCustomMatchingFn, goTournamentEndFn, goTournamentResetFn, goLeaderboardResetFn, goPurchaseNotificationAppleFn, goSubscriptionNotificationAppleFn, goPurchaseNotificationGoogleFn, goSubscriptionNotificationGoogleFn, goIndexFilterFns, fleetManager, allEventFns, goMatchNamesListFn, goNakamaModule, err := NewRuntimeProviderGo(ctx, logger, startupLogger, db, protojsonMarshaler, config, version, socialClient, leaderboardCache, leaderboardRankCache, leaderboardScheduler, sessionRegistry, sessionCache, statusRegistry, matchRegistry, tracker, metrics, streamManager, router, storageIndex, runtimeConfig.Path, paths, eventQueue, matchProvider, fmCallbackHandler)
 	if err != nil {
 		startupLogger.Error("Error initialising Go runtime provider", zap.Error(err))
 		return nil, nil, err
@@ -2625,6 +2627,8 @@ func NewRuntime(ctx context.Context, logger, startupLogger *zap.Logger, db *sql.
 		afterReqFunctions:                      allAfterReqFunctions,
 		matchmakerMatchedFunction:              allMatchmakerMatchedFunction,
 		matchmakerOverrideFunction:             allMatchmakerOverrideFunction,
+		matchmakerFormation:                    goNakamaModule.matchmakerFormation,
+		goNakamaModule:                         goNakamaModule,
 		tournamentEndFunction:                  allTournamentEndFunction,
 		tournamentResetFunction:                allTournamentResetFunction,
 		leaderboardResetFunction:               allLeaderboardResetFunction,
@@ -2706,6 +2710,20 @@ func runtimeInfo(paths []string, jsRpcIDs, luaRpcIDs, goRpcIDs map[string]bool,
 	}, nil
 }
 
+// SetMatchmaker gives runtime functions access to the matchmaker, which is created after the runtime.
+func (r *Runtime) SetMatchmaker(matchmaker Matchmaker) {
+	if r.goNakamaModule != nil {
+		r.goNakamaModule.SetMatchmaker(matchmaker)
+	}
+}
+
+// SetNotificationPusher gives runtime functions access to the push notification providers.
+func (r *Runtime) SetNotificationPusher(notificationPusher NotificationPusher) {
+	if r.goNakamaModule != nil {
+		r.goNakamaModule.SetNotificationPusher(notificationPusher)
+	}
+}
+
 func (r *Runtime) MatchCreateFunction() RuntimeMatchCreateFunction {
 	return r.matchCreateFunction
 }
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"strings"
 	"sync"
@@ -65,6 +66,9 @@ type RuntimeGoNakamaModule struct {
 	satori               runtime.Satori
 	fleetManager         runtime.FleetManager
 	storageIndex         StorageIndex
+	matchmakerFormation  *MatchmakerFormation
+	matchmaker           Matchmaker
+	notificationPusher   NotificationPusher
 }
 
 func NewRuntimeGoNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshaler *protojson.MarshalOptions, config Config, socialClient *social.Client, leaderboardCache LeaderboardCache, leaderboardRankCache LeaderboardRankCache, leaderboardScheduler LeaderboardScheduler, sessionRegistry SessionRegistry, sessionCache SessionCache, statusRegistry StatusRegistry, matchRegistry MatchRegistry, tracker Tracker, metrics Metrics, streamManager StreamManager, router MessageRouter, storageIndex StorageIndex) *RuntimeGoNakamaModule {
@@ -86,6 +90,7 @@ func NewRuntimeGoNakamaModule(logger *zap.Logger, db *sql.DB, protojsonMarshaler
 		streamManager:        streamManager,
 		router:               router,
 		storageIndex:         storageIndex,
+		matchmakerFormation:  &MatchmakerFormation{},
 
 		node: config.GetName(),
 
@@ -542,7 +547,7 @@ func (n *RuntimeGoNakamaModule) AccountDeleteId(ctx context.Context, userID stri
 		return errors.New("expects user ID to be a valid identifier")
 	}
 
-	return DeleteAccount(ctx, n.logger, n.db, n.config, n.leaderboardCache, n.leaderboardRankCache, n.sessionRegistry, n.sessionCache, n.tracker, u, recorded)
+	return DeleteAccount(ctx, n.logger, n.db, n.config, n.leaderboardCache, n.leaderboardRankCache, n.sessionRegistry, n.sessionCache, n.tracker, n.storageIndex, u, recorded)
 }
 
 // @group accounts
@@ -1583,6 +1588,87 @@ func (n *RuntimeGoNakamaModule) MatchSignal(ctx context.Context, id string, data
 	return n.matchRegistry.Signal(ctx, id, data)
 }
 
+// @group matches
+// @summary Register a function that chooses the matchmaker's matches from the candidate pools gathered each interval, in place of the default selection. If the function returns an error or runs past its time budget, that interval falls back to the default algorithm. Replaces any previously registered function.
+// @param fn(type=MatchmakerFormationFunction) The formation function, or nil to remove it.
+// @param budget(type=time.Duration) How long the function may run each interval. Defaults to 500ms if not positive.
+func (n *RuntimeGoNakamaModule) MatchmakerFormationRegister(fn MatchmakerFormationFunction, budget time.Duration) {
+	n.matchmakerFormation.Set(fn, budget)
+}
+
+// @group matches
+// @summary Get the status of a matchmaker ticket on this node, including its queue position, intervals waited, number of compatible tickets and estimated remaining wait.
+// @param ticket(type=string) The matchmaker ticket to look up.
+// @return status(*MatchmakerTicketStatus) The ticket status.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) MatchmakerTicketStatus(ticket string) (*MatchmakerTicketStatus, error) {
+	if ticket == "" {
+		return nil, errors.New("expects a ticket string")
+	}
+
+	n.RLock()
+	matchmaker := n.matchmaker
+	n.RUnlock()
+	if matchmaker == nil {
+		return nil, errors.New("matchmaker not available")
+	}
+
+	return matchmaker.TicketStatus(ticket)
+}
+
+// @group matches
+// @summary List the matchmaker queues on this node, with their waiting tickets and recent wait times.
+// @return queues([]*MatchmakerQueueStatus) The queue summaries, ordered by queue name.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) MatchmakerQueueStatus() ([]*MatchmakerQueueStatus, error) {
+	n.RLock()
+	matchmaker := n.matchmaker
+	n.RUnlock()
+	if matchmaker == nil {
+		return nil, errors.New("matchmaker not available")
+	}
+
+	return matchmaker.QueueStatus(), nil
+}
+
+// @group matches
+// @summary Offer open slots in a running match to the matchmaker, so waiting tickets can be placed into it. The match handler still confirms each player in its match join attempt.
+// @param matchID(type=string) The ID of the match with open slots.
+// @param slots(type=int) The number of open slots, zero or fewer removes the match from the matchmaker.
+// @param query(type=string) Query the tickets' properties must match, or an empty string to accept any ticket whose query matches the match.
+// @param stringProperties(type=map[string]string) String properties of the match, matched against ticket queries.
+// @param numericProperties(type=map[string]float64) Numeric properties of the match, matched against ticket queries.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) MatchmakerBackfillSet(matchID string, slots int, query string, stringProperties map[string]string, numericProperties map[string]float64) error {
+	if matchID == "" {
+		return errors.New("expects a match ID")
+	}
+
+	n.RLock()
+	matchmaker := n.matchmaker
+	n.RUnlock()
+	if matchmaker == nil {
+		return errors.New("matchmaker not available")
+	}
+
+	return matchmaker.BackfillSet(matchID, slots, query, stringProperties, numericProperties)
+}
+
+// @group matches
+// @summary Stop offering a match's open slots to the matchmaker.
+// @param matchID(type=string) The ID of the match.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) MatchmakerBackfillRemove(matchID string) error {
+	n.RLock()
+	matchmaker := n.matchmaker
+	n.RUnlock()
+	if matchmaker == nil {
+		return errors.New("matchmaker not available")
+	}
+
+	return matchmaker.BackfillRemove(matchID)
+}
+
 // @group notifications
 // @summary Send one in-app notification to a user.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
@@ -1769,6 +1855,149 @@ func (n *RuntimeGoNakamaModule) NotificationsDelete(ctx context.Context, notific
 	return nil
 }
 
+// @group notifications
+// @summary Register a user's device token for a push provider, so notifications sent while they are not connected are pushed to the device. A token registered by another user is moved to this user.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param userId(type=string) The ID of the user the device belongs to.
+// @param provider(type=string) The name of the push provider the token is for, such as 'fcm' or 'apns'.
+// @param token(type=string) The device token.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationPushTokenRegister(ctx context.Context, userId, provider, token string) error {
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return errors.New("expects user ID to be a valid identifier")
+	}
+
+	return NotificationPushTokenRegister(ctx, n.logger, n.db, userID, provider, token)
+}
+
+// @group notifications
+// @summary Unregister a user's device token for a push provider.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param userId(type=string) The ID of the user the device belongs to.
+// @param provider(type=string) The name of the push provider the token is for.
+// @param token(type=string) The device token.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationPushTokenUnregister(ctx context.Context, userId, provider, token string) error {
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return errors.New("expects user ID to be a valid identifier")
+	}
+
+	return NotificationPushTokenUnregister(ctx, n.logger, n.db, userID, provider, token)
+}
+
+// @group notifications
+// @summary List the notification codes a user opted out of push notifications for.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param userId(type=string) The ID of the user.
+// @return codes([]int) The notification codes that are not pushed to the user.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationPushOptOutsGet(ctx context.Context, userId string) ([]int, error) {
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return nil, errors.New("expects user ID to be a valid identifier")
+	}
+
+	dbCodes, err := NotificationPushOptOutsGet(ctx, n.logger, n.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]int, 0, len(dbCodes))
+	for _, code := range dbCodes {
+		codes = append(codes, int(code))
+	}
+	return codes, nil
+}
+
+// @group notifications
+// @summary Replace the notification codes a user opted out of push notifications for. Notifications with these codes are still delivered to connected sockets and persisted as usual.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param userId(type=string) The ID of the user.
+// @param codes(type=[]int) The notification codes not to push to the user. An empty list opts back in to all codes.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationPushOptOutsSet(ctx context.Context, userId string, codes []int) error {
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return errors.New("expects user ID to be a valid identifier")
+	}
+
+	dbCodes := make([]int32, 0, len(codes))
+	for _, code := range codes {
+		if code < math.MinInt32 || code > math.MaxInt32 {
+			return errors.New("expects codes to be valid 32-bit integers")
+		}
+		dbCodes = append(dbCodes, int32(code))
+	}
+
+	return NotificationPushOptOutsSet(ctx, n.logger, n.db, userID, dbCodes)
+}
+
+// @group notifications
+// @summary Register a push provider, which is sent the notifications for users who are not connected along with the providers enabled in the server configuration.
+// @param provider(type=NotificationPushProvider) The push provider. Its name must be unique.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationPushProviderRegister(provider NotificationPushProvider) error {
+	n.RLock()
+	notificationPusher := n.notificationPusher
+	n.RUnlock()
+	if notificationPusher == nil {
+		return errors.New("push notifications are not available")
+	}
+
+	return notificationPusher.RegisterProvider(provider)
+}
+
+// @group notifications
+// @summary Schedule a notification to be sent later, or repeatedly on a cron schedule, to a segment of users. Segments are 'users' for the listed user IDs, 'all' for all users, 'group' for the members of a group, and 'active' for the users online within a number of days. Notifications are sent in batches by the notification scheduler.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param schedule(type=*NotificationSchedule) The notification, segment, and send time or cron expression. A zero send time sends as soon as possible, or at the first cron time if a cron expression is set.
+// @return schedule(*NotificationSchedule) The created schedule, with its ID and next send time.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationScheduleCreate(ctx context.Context, schedule *NotificationSchedule) (*NotificationSchedule, error) {
+	if schedule == nil {
+		return nil, errors.New("expects a notification schedule")
+	}
+
+	return NotificationScheduleCreate(ctx, n.logger, n.db, schedule, n.config.GetNotification().ScheduleMaxUserIds)
+}
+
+// @group notifications
+// @summary Cancel a scheduled or recurring notification. A send in progress stops before its next batch.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the notification schedule.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationScheduleCancel(ctx context.Context, id string) error {
+	return NotificationScheduleCancel(ctx, n.logger, n.db, id)
+}
+
+// @group notifications
+// @summary Get a notification schedule and its progress.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the notification schedule.
+// @return schedule(*NotificationSchedule) The notification schedule.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationScheduleGet(ctx context.Context, id string) (*NotificationSchedule, error) {
+	return NotificationScheduleGet(ctx, n.logger, n.db, id)
+}
+
+// @group notifications
+// @summary List notification schedules, newest first.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param status(type=string, optional=true) Only list schedules with this status: 'scheduled', 'sending', 'complete' or 'cancelled'. Empty lists all schedules.
+// @param limit(type=int, default=100) The number of schedules to list, between 1 and 100.
+// @param cursor(type=string, optional=true) A cursor from a previous list to fetch the next page.
+// @return schedules([]*NotificationSchedule) The notification schedules.
+// @return cursor(string) A cursor to fetch the next page, or empty if there are no more schedules.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) NotificationScheduleList(ctx context.Context, status string, limit int, cursor string) ([]*NotificationSchedule, string, error) {
+	if limit < 1 || limit > 100 {
+		return nil, "", errors.New("expects limit to be 1-100")
+	}
+
+	return NotificationScheduleList(ctx, n.logger, n.db, status, limit, cursor)
+}
+
 // @group wallets
 // @summary Update a user's wallet with the given changeset.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
@@ -1996,6 +2225,17 @@ func (n *RuntimeGoNakamaModule) StorageRead(ctx context.Context, reads []*runtim
 // @return acks([]*api.StorageObjectAck) A list of acks with the version of the written objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeGoNakamaModule) StorageWrite(ctx context.Context, writes []*runtime.StorageWrite) ([]*api.StorageObjectAck, error) {
+	return n.StorageWriteExpiring(ctx, writes, time.Time{})
+}
+
+// @group storage
+// @summary Write one or more objects by their collection/keyname and optional user, which are deleted once they expire. This function is not part of the runtime.NakamaModule interface, Go modules call it by asserting the module to an interface with this method, e.g. nk.(interface{ StorageWriteExpiring(context.Context, []*runtime.StorageWrite, time.Time) ([]*api.StorageObjectAck, error) }).
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param objectIds(type=[]*runtime.StorageWrite) An array of object identifiers to be written.
+// @param expireTime(type=time.Time) When the objects expire. Objects written with a zero time never expire.
+// @return acks([]*api.StorageObjectAck) A list of acks with the version of the written objects.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) StorageWriteExpiring(ctx context.Context, writes []*runtime.StorageWrite, expireTime time.Time) ([]*api.StorageObjectAck, error) {
 	size := len(writes)
 	if size == 0 {
 		return make([]*api.StorageObjectAck, 0), nil
@@ -2028,6 +2268,7 @@ func (n *RuntimeGoNakamaModule) StorageWrite(ctx context.Context, writes []*runt
 				PermissionRead:  &wrapperspb.Int32Value{Value: int32(write.PermissionRead)},
 				PermissionWrite: &wrapperspb.Int32Value{Value: int32(write.PermissionWrite)},
 			},
+			ExpireTime: expireTime,
 		}
 		if write.UserID == "" {
 			op.OwnerID = uuid.Nil.String()
@@ -2119,7 +2360,66 @@ func (n *RuntimeGoNakamaModule) StorageIndexList(ctx context.Context, callerID,
 		return nil, errors.New("limit must be 1-10000")
 	}
 
-	return n.storageIndex.List(ctx, cid, indexName, query, limit)
+	objects, _, _, err := n.storageIndex.List(ctx, cid, indexName, query, limit, nil, "")
+	return objects, err
+}
+
+// @group storage
+// @summary List storage index entries, sorted and one page at a time.
+// @param indexName(type=string) Name of the index to list entries from.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permissions are bypassed.
+// @param queryString(type=string) Query to filter index entries.
+// @param limit(type=int) Maximum number of results to be returned.
+// @param order(type=[]string, optional=true) The field names to sort by, prefixed with "-" for descending order. Sorted by score if empty. Ordering by value fields requires a sortable index.
+// @param cursor(type=string, optional=true) Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return objects(*api.StorageObjectList) A list of storage objects.
+// @return cursor(string) A cursor for the next page, or empty if there are no more results.
+// @return total(int) The number of index entries matching the query.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) StorageIndexListPage(ctx context.Context, callerID, indexName, query string, limit int, order []string, cursor string) (*api.StorageObjects, string, int, error) {
+	cid := uuid.Nil
+	if callerID != "" {
+		id, err := uuid.FromString(callerID)
+		if err != nil {
+			return nil, "", 0, errors.New("expects caller id to be empty or a valid user id")
+		}
+		cid = id
+	}
+
+	if indexName == "" {
+		return nil, "", 0, errors.New("expects a non-empty indexName")
+	}
+
+	if limit < 1 || limit > 10_000 {
+		return nil, "", 0, errors.New("limit must be 1-10000")
+	}
+
+	return n.storageIndex.List(ctx, cid, indexName, query, limit, order, cursor)
+}
+
+// @group storage
+// @summary Compute aggregations, such as counts, terms facets, numeric range buckets and min/max/avg, over storage index entries.
+// @param indexName(type=string) Name of the index to aggregate entries from.
+// @param queryString(type=string) Query to filter index entries. Aggregates all entries if empty.
+// @param aggregations(type=[]*StorageIndexAggregation) The aggregations to compute.
+// @return result(*StorageIndexAggregateResult) The number of matching entries and the result of each aggregation.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) StorageIndexAggregate(ctx context.Context, indexName, query string, aggregations []*StorageIndexAggregation) (*StorageIndexAggregateResult, error) {
+	if indexName == "" {
+		return nil, errors.New("expects a non-empty indexName")
+	}
+
+	return n.storageIndex.Aggregate(ctx, indexName, query, aggregations)
+}
+
+// @group storage
+// @summary Subscribe to committed storage object creates, updates and deletes.
+// @param collection(type=string) The collection to receive changes for, or all collections if empty.
+// @param keyPrefix(type=string) Only receive changes for keys with this prefix. Receives all keys if empty.
+// @param fn(type=StorageChangeFunction) Called in commit order for each change, and should return quickly.
+// @return unsubscribe(func()) Cancels the subscription.
+func (n *RuntimeGoNakamaModule) StorageChangeSubscribe(collection, keyPrefix string, fn StorageChangeFunction) func() {
+	return n.storageIndex.ChangeFeed().Subscribe(collection, keyPrefix, fn)
 }
 
 // @group users
@@ -2345,6 +2645,84 @@ func (n *RuntimeGoNakamaModule) LeaderboardCreate(ctx context.Context, id string
 	return nil
 }
 
+// @group leaderboards
+// @summary Setup a new derived leaderboard, whose score for each owner is the weighted sum of their scores in other leaderboards and is recomputed whenever one of those changes. Derived leaderboards are authoritative, and have their own rank cache and reset schedule. The leaderboard will be created if it doesn't already exist, otherwise its configuration will not be updated.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param leaderboardID(type=string) The unique identifier for the new leaderboard.
+// @param sortOrder(type=string, default="desc") The sort order for records in the leaderboard. Possible values are "asc" or "desc".
+// @param resetSchedule(type=string) The cron format used to define the reset schedule for the leaderboard. This controls when a leaderboard is reset and can be used to power daily/weekly/monthly leaderboards.
+// @param metadata(type=map[string]interface{}) The metadata you want associated to the leaderboard.
+// @param sources(type=[]*LeaderboardDerivedSource) The source leaderboards and the weight applied to each. Sources must be regular leaderboards, not tournaments or other derived leaderboards.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardDerivedCreate(ctx context.Context, id, sortOrder, resetSchedule string, metadata map[string]interface{}, sources []*LeaderboardDerivedSource) error {
+	if id == "" {
+		return errors.New("expects a leaderboard ID string")
+	}
+
+	sort := LeaderboardSortOrderDescending //nolint:ineffassign
+	switch sortOrder {
+	case "desc", "descending":
+		sort = LeaderboardSortOrderDescending
+	case "asc", "ascending":
+		sort = LeaderboardSortOrderAscending
+	default:
+		return errors.New("expects sort order to be 'asc' or 'desc'")
+	}
+
+	if resetSchedule != "" {
+		if _, err := cronexpr.Parse(resetSchedule); err != nil {
+			return errors.New("expects reset schedule to be a valid CRON expression")
+		}
+	}
+
+	metadataStr := "{}"
+	if metadata != nil {
+		metadataBytes, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata: %v", err.Error())
+		}
+		metadataStr = string(metadataBytes)
+	}
+
+	_, created, err := n.leaderboardCache.CreateDerived(ctx, id, sort, resetSchedule, metadataStr, sources)
+	if err != nil {
+		return err
+	}
+
+	if created {
+		// Only need to update the scheduler and backfill records for newly created leaderboards.
+		n.leaderboardScheduler.Update()
+		if _, err := LeaderboardDerivedBackfill(ctx, n.logger, n.db, n.leaderboardCache, n.leaderboardRankCache, id); err != nil {
+			n.logger.Error("Error backfilling derived leaderboard", zap.String("leaderboard_id", id), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// @group leaderboards
+// @summary Set the anti-cheat rules applied to record writes in a leaderboard. Writes over the rate limit are rejected, and writes that change the owner's score by more than the maximum delta are quarantined until approved or deleted in the console. Setting all values to 0 removes the rules.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The unique identifier of the leaderboard. Tournaments are not supported.
+// @param maxScoreDelta(type=int64) The largest score change a single write may make, or 0 for no limit.
+// @param rateLimitCount(type=int) The number of writes allowed per owner in each interval on each node, or 0 for no limit. Counts are not shared across a cluster.
+// @param rateLimitIntervalSec(type=int) The rate limit interval in seconds, or 0 for no limit.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardValidationSet(ctx context.Context, id string, maxScoreDelta int64, rateLimitCount, rateLimitIntervalSec int) error {
+	if id == "" {
+		return errors.New("expects a leaderboard ID string")
+	}
+
+	return n.leaderboardCache.SetValidation(ctx, id, maxScoreDelta, rateLimitCount, rateLimitIntervalSec)
+}
+
+// @group leaderboards
+// @summary Register a function that inspects every leaderboard record write before it's applied. Returning an error rejects the write, and returning a non-empty reason quarantines it until approved or deleted in the console. Replaces any previously registered function.
+// @param fn(type=LeaderboardValidationFunction) The validation function, or nil to remove it.
+func (n *RuntimeGoNakamaModule) LeaderboardValidationRegister(fn LeaderboardValidationFunction) {
+	n.leaderboardCache.SetValidationFunction(fn)
+}
+
 // @group leaderboards
 // @summary Delete a leaderboard and all scores that belong to it.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
@@ -2551,31 +2929,145 @@ func (n *RuntimeGoNakamaModule) LeaderboardRecordDelete(ctx context.Context, id,
 		return errors.New("expects a leaderboard ID string")
 	}
 
-	if _, err := uuid.FromString(ownerID); err != nil {
-		return errors.New("expects owner ID to be a valid identifier")
+	if _, err := uuid.FromString(ownerID); err != nil {
+		return errors.New("expects owner ID to be a valid identifier")
+	}
+
+	return LeaderboardRecordDelete(ctx, n.logger, n.db, n.leaderboardCache, n.leaderboardRankCache, uuid.Nil, id, ownerID)
+}
+
+// @group leaderboards
+// @summary Fetch the list of leaderboard records around the owner.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param ownerId(type=string) The owner ID around which to show records.
+// @param limit(type=int) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=int64) Time since epoch in seconds. Must be greater than 0.
+// @return leaderboardRecordsHaystack(*api.LeaderboardRecordList) A list of leaderboard records and possibly a cursor. If cursor is empty/nil there are no further results.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardRecordsHaystack(ctx context.Context, id, ownerID string, limit int, cursor string, expiry int64) (*api.LeaderboardRecordList, error) {
+	if id == "" {
+		return nil, errors.New("expects a leaderboard ID string")
+	}
+
+	owner, err := uuid.FromString(ownerID)
+	if err != nil {
+		return nil, errors.New("expects owner ID to be a valid identifier")
+	}
+
+	if limit < 1 || limit > 100 {
+		return nil, errors.New("limit must be 1-100")
+	}
+
+	if expiry < 0 {
+		return nil, errors.New("expiry should be time since epoch in seconds and has to be a positive integer")
+	}
+
+	return LeaderboardRecordsHaystack(ctx, n.logger, n.db, n.leaderboardCache, n.leaderboardRankCache, id, cursor, owner, limit, expiry)
+}
+
+// @group leaderboards
+// @summary Get an owner's rank in the current period of a leaderboard, and the percentage of ranked records at or above it. Only available if rank cache is not disabled for the leaderboard.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to get the percentile for.
+// @param expiry(type=int64) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return percentile(*LeaderboardRecordPercentile) The owner's rank, the number of ranked records and the percentile. Rank is 0 if the owner has no ranked record.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardRecordPercentile(ctx context.Context, id, ownerID string, expiry int64) (*LeaderboardRecordPercentile, error) {
+	if id == "" {
+		return nil, errors.New("expects a leaderboard ID string")
+	}
+
+	owner, err := uuid.FromString(ownerID)
+	if err != nil {
+		return nil, errors.New("expects owner ID to be a valid identifier")
+	}
+
+	if expiry < 0 {
+		return nil, errors.New("expiry should be time since epoch in seconds and has to be a positive integer")
+	}
+
+	return LeaderboardRecordPercentileGet(n.leaderboardCache, n.leaderboardRankCache, id, owner, expiry)
+}
+
+// @group leaderboards
+// @summary List an owner's final ranks in past periods of a leaderboard, most recent first.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to list rank history for.
+// @param limit(type=int) Return only the required number of past periods denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return history([]*LeaderboardRankHistory) The owner's rank, score and percentile in each past period.
+// @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any).
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardRankHistoryList(ctx context.Context, id, ownerID string, limit int, cursor string) ([]*LeaderboardRankHistory, string, error) {
+	if id == "" {
+		return nil, "", errors.New("expects a leaderboard ID string")
+	}
+
+	owner, err := uuid.FromString(ownerID)
+	if err != nil {
+		return nil, "", errors.New("expects owner ID to be a valid identifier")
+	}
+
+	if limit < 1 || limit > 100 {
+		return nil, "", errors.New("limit must be 1-100")
+	}
+
+	return LeaderboardRankHistoryList(ctx, n.logger, n.db, n.leaderboardCache, id, owner, limit, cursor)
+}
+
+// @group leaderboards
+// @summary List records owned by a user and their friends, with ranks relative to each other rather than the whole leaderboard.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param userId(type=string) The user whose own and friends' records are listed.
+// @param limit(type=int) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=int64) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(*api.LeaderboardRecordList) A list of leaderboard records ranked within the subset, the subset record count and possibly a cursor. If cursor is empty/nil there are no further results.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) LeaderboardRecordsListFriends(ctx context.Context, id, userID string, limit int, cursor string, expiry int64) (*api.LeaderboardRecordList, error) {
+	if id == "" {
+		return nil, errors.New("expects a leaderboard ID string")
+	}
+
+	userUUID, err := uuid.FromString(userID)
+	if err != nil {
+		return nil, errors.New("expects user ID to be a valid identifier")
+	}
+
+	if limit < 1 || limit > 100 {
+		return nil, errors.New("limit must be 1-100")
+	}
+
+	if expiry < 0 {
+		return nil, errors.New("expiry should be time since epoch in seconds and has to be a positive integer")
 	}
 
-	return LeaderboardRecordDelete(ctx, n.logger, n.db, n.leaderboardCache, n.leaderboardRankCache, uuid.Nil, id, ownerID)
+	return LeaderboardRecordsListFriends(ctx, n.logger, n.db, n.leaderboardCache, id, userUUID, limit, cursor, expiry)
 }
 
 // @group leaderboards
-// @summary Fetch the list of leaderboard records around the owner.
+// @summary List records owned by members of a group, with ranks relative to each other rather than the whole leaderboard.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
 // @param id(type=string) The ID of the leaderboard to list records for.
-// @param ownerId(type=string) The owner ID around which to show records.
+// @param groupId(type=string) The group whose members' records are listed.
 // @param limit(type=int) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
 // @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
-// @param expiry(type=int64) Time since epoch in seconds. Must be greater than 0.
-// @return leaderboardRecordsHaystack(*api.LeaderboardRecordList) A list of leaderboard records and possibly a cursor. If cursor is empty/nil there are no further results.
+// @param expiry(type=int64) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(*api.LeaderboardRecordList) A list of leaderboard records ranked within the subset, the subset record count and possibly a cursor. If cursor is empty/nil there are no further results.
 // @return error(error) An optional error value if an error occurred.
-func (n *RuntimeGoNakamaModule) LeaderboardRecordsHaystack(ctx context.Context, id, ownerID string, limit int, cursor string, expiry int64) (*api.LeaderboardRecordList, error) {
+func (n *RuntimeGoNakamaModule) LeaderboardRecordsListGroup(ctx context.Context, id, groupID string, limit int, cursor string, expiry int64) (*api.LeaderboardRecordList, error) {
 	if id == "" {
 		return nil, errors.New("expects a leaderboard ID string")
 	}
 
-	owner, err := uuid.FromString(ownerID)
+	groupUUID, err := uuid.FromString(groupID)
 	if err != nil {
-		return nil, errors.New("expects owner ID to be a valid identifier")
+		return nil, errors.New("expects group ID to be a valid identifier")
 	}
 
 	if limit < 1 || limit > 100 {
@@ -2586,7 +3078,7 @@ func (n *RuntimeGoNakamaModule) LeaderboardRecordsHaystack(ctx context.Context,
 		return nil, errors.New("expiry should be time since epoch in seconds and has to be a positive integer")
 	}
 
-	return LeaderboardRecordsHaystack(ctx, n.logger, n.db, n.leaderboardCache, n.leaderboardRankCache, id, cursor, owner, limit, expiry)
+	return LeaderboardRecordsListGroup(ctx, n.logger, n.db, n.leaderboardCache, id, groupUUID, limit, cursor, expiry)
 }
 
 // @group leaderboards
@@ -3514,6 +4006,169 @@ func (n *RuntimeGoNakamaModule) GroupUsersAdd(ctx context.Context, callerID, gro
 	return AddGroupUsers(ctx, n.logger, n.db, n.tracker, n.router, caller, group, users)
 }
 
+// @group groups
+// @summary Request to join a group for a particular user, answering the group's join questionnaire.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param groupId(type=string) The ID of the group to join.
+// @param userId(type=string) The user ID requesting to join this group.
+// @param username(type=string) The username of the user requesting to join this group.
+// @param answers(type=map[string]string) Answers to the group's join questions, keyed by question ID.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupUserJoinWithAnswers(ctx context.Context, groupID, userID, username string, answers map[string]string) error {
+	group, err := uuid.FromString(groupID)
+	if err != nil {
+		return errors.New("expects group ID to be a valid identifier")
+	}
+
+	user, err := uuid.FromString(userID)
+	if err != nil {
+		return errors.New("expects user ID to be a valid identifier")
+	}
+
+	if username == "" {
+		return errors.New("expects a username string")
+	}
+
+	return JoinGroupWithAnswers(ctx, n.logger, n.db, n.tracker, n.router, group, user, username, answers)
+}
+
+// @group groups
+// @summary Get the questions asked of users requesting to join a group.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param groupId(type=string) The ID of the group.
+// @return questionnaire(*GroupJoinQuestionnaire) The group's join questions and request expiry.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupJoinQuestionnaireGet(ctx context.Context, groupID string) (*GroupJoinQuestionnaire, error) {
+	group, err := uuid.FromString(groupID)
+	if err != nil {
+		return nil, errors.New("expects group ID to be a valid identifier")
+	}
+
+	return GroupJoinQuestionnaireGet(ctx, n.logger, n.db, group)
+}
+
+// @group groups
+// @summary Set the questions asked of users requesting to join a group, and how long their requests last.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permissions are bypassed.
+// @param groupId(type=string) The ID of the group.
+// @param questionnaire(type=*GroupJoinQuestionnaire) The join questions and request expiry.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupJoinQuestionnaireSet(ctx context.Context, callerID, groupID string, questionnaire *GroupJoinQuestionnaire) error {
+	caller := uuid.Nil
+	if callerID != "" {
+		var err error
+		if caller, err = uuid.FromString(callerID); err != nil {
+			return errors.New("expects caller ID to be empty or a valid identifier")
+		}
+	}
+
+	group, err := uuid.FromString(groupID)
+	if err != nil {
+		return errors.New("expects group ID to be a valid identifier")
+	}
+
+	if questionnaire == nil {
+		return errors.New("expects a questionnaire")
+	}
+
+	return GroupJoinQuestionnaireSet(ctx, n.logger, n.db, caller, group, questionnaire)
+}
+
+// @group groups
+// @summary List pending requests to join a group, with the answers given to its join questions.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permissions are bypassed.
+// @param groupId(type=string) The ID of the group.
+// @param limit(type=int) Return only the required number of requests denoted by this limit value.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return requests([]*GroupJoinRequest) The pending join requests, oldest first.
+// @return cursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any).
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupJoinRequestsList(ctx context.Context, callerID, groupID string, limit int, cursor string) ([]*GroupJoinRequest, string, error) {
+	caller := uuid.Nil
+	if callerID != "" {
+		var err error
+		if caller, err = uuid.FromString(callerID); err != nil {
+			return nil, "", errors.New("expects caller ID to be empty or a valid identifier")
+		}
+	}
+
+	group, err := uuid.FromString(groupID)
+	if err != nil {
+		return nil, "", errors.New("expects group ID to be a valid identifier")
+	}
+
+	if limit < 1 || limit > 100 {
+		return nil, "", errors.New("expects limit to be 1-100")
+	}
+
+	return GroupJoinRequestsList(ctx, n.logger, n.db, caller, group, limit, cursor)
+}
+
+// @group groups
+// @summary Approve pending requests to join a group, making the users members.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permissions are bypassed.
+// @param groupId(type=string) The ID of the group.
+// @param userIds(type=[]string) Array of user IDs whose requests to approve. Users without a pending request are skipped.
+// @return approved([]string) The IDs of the users approved.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupJoinRequestsApprove(ctx context.Context, callerID, groupID string, userIDs []string) ([]string, error) {
+	return n.groupJoinRequestsResolve(ctx, callerID, groupID, userIDs, GroupJoinRequestsApprove)
+}
+
+// @group groups
+// @summary Reject pending requests to join a group, notifying the users.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permissions are bypassed.
+// @param groupId(type=string) The ID of the group.
+// @param userIds(type=[]string) Array of user IDs whose requests to reject. Users without a pending request are skipped.
+// @return rejected([]string) The IDs of the users rejected.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) GroupJoinRequestsReject(ctx context.Context, callerID, groupID string, userIDs []string) ([]string, error) {
+	return n.groupJoinRequestsResolve(ctx, callerID, groupID, userIDs, GroupJoinRequestsReject)
+}
+
+func (n *RuntimeGoNakamaModule) groupJoinRequestsResolve(ctx context.Context, callerID, groupID string, userIDs []string, fn func(context.Context, *zap.Logger, *sql.DB, Tracker, MessageRouter, uuid.UUID, uuid.UUID, []uuid.UUID) ([]uuid.UUID, error)) ([]string, error) {
+	caller := uuid.Nil
+	if callerID != "" {
+		var err error
+		if caller, err = uuid.FromString(callerID); err != nil {
+			return nil, errors.New("expects caller ID to be empty or a valid identifier")
+		}
+	}
+
+	group, err := uuid.FromString(groupID)
+	if err != nil {
+		return nil, errors.New("expects group ID to be a valid identifier")
+	}
+
+	if len(userIDs) == 0 {
+		return []string{}, nil
+	}
+
+	users := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		uid, err := uuid.FromString(userID)
+		if err != nil {
+			return nil, errors.New("expects each user ID to be a valid identifier")
+		}
+		users = append(users, uid)
+	}
+
+	resolved, err := fn(ctx, n.logger, n.db, n.tracker, n.router, caller, group, users)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedIDs := make([]string, 0, len(resolved))
+	for _, uid := range resolved {
+		resolvedIDs = append(resolvedIDs, uid.String())
+	}
+	return resolvedIDs, nil
+}
+
 // @group groups
 // @summary Ban users from a group.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
@@ -4066,6 +4721,18 @@ func (n *RuntimeGoNakamaModule) SetEventFn(fn RuntimeEventCustomFunction) {
 	n.Unlock()
 }
 
+func (n *RuntimeGoNakamaModule) SetMatchmaker(matchmaker Matchmaker) {
+	n.Lock()
+	n.matchmaker = matchmaker
+	n.Unlock()
+}
+
+func (n *RuntimeGoNakamaModule) SetNotificationPusher(notificationPusher NotificationPusher) {
+	n.Lock()
+	n.notificationPusher = notificationPusher
+	n.Unlock()
+}
+
 // @group chat
 // @summary Send a message on a realtime chat channel.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
@@ -4091,7 +4758,7 @@ func (n *RuntimeGoNakamaModule) ChannelMessageSend(ctx context.Context, channelI
 		contentStr = string(contentBytes)
 	}
 
-	return ChannelMessageSend(ctx, n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, contentStr, senderId, senderUsername, persist)
+	return ChannelMessageSend(ctx, n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, contentStr, senderId, senderUsername, persist)
 }
 
 // @group chat
@@ -4124,7 +4791,7 @@ func (n *RuntimeGoNakamaModule) ChannelMessageUpdate(ctx context.Context, channe
 		contentStr = string(contentBytes)
 	}
 
-	return ChannelMessageUpdate(ctx, n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderId, senderUsername, persist)
+	return ChannelMessageUpdate(ctx, n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderId, senderUsername, persist)
 }
 
 // @group chat
@@ -4179,6 +4846,214 @@ func (n *RuntimeGoNakamaModule) ChannelMessagesList(ctx context.Context, channel
 	return list.Messages, list.NextCursor, list.PrevCursor, nil
 }
 
+// @group chat
+// @summary Search the chat messages in a channel for all the words in a search text, newest first.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel to search.
+// @param text(type=string) The words to search for.
+// @param limit(type=int) The number of messages to return per page, up to the configured search max limit.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. An empty string skips the permission check.
+// @return channelMessageList([]*rtapi.ChannelMessage) Matching messages from the specified channel.
+// @return nextCursor(string) Cursor for the next page of messages, if any.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessagesSearch(ctx context.Context, channelId, text string, limit int, cursor, callerId string) ([]*api.ChannelMessage, string, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if maxLimit := n.config.GetChat().SearchMaxLimit; limit < 1 || limit > maxLimit {
+		return nil, "", fmt.Errorf("limit must be 1-%d", maxLimit)
+	}
+
+	caller := uuid.Nil
+	if callerId != "" {
+		if caller, err = uuid.FromString(callerId); err != nil {
+			return nil, "", errors.New("expects caller ID to be a valid identifier")
+		}
+	}
+
+	list, err := ChannelMessagesSearch(ctx, n.logger, n.db, caller, channelIdToStreamResult.Stream, channelId, text, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return list.Messages, list.NextCursor, nil
+}
+
+// @group chat
+// @summary Delete all the chat messages a user sent within a time range, in one channel or in every channel, and notify connected channel members of the removals.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param userId(type=string) The ID of the user whose messages are deleted.
+// @param channelId(type=string, optional=true, default="") The ID of the channel to purge messages from. An empty string purges messages from all channels.
+// @param startTime(type=int64) Delete messages sent at or after this time, in seconds since the epoch.
+// @param endTime(type=int64, optional=true, default=0) Delete messages sent before this time, in seconds since the epoch. 0 deletes messages up to now.
+// @return count(int) The number of messages deleted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessagesPurge(ctx context.Context, userId, channelId string, startTime, endTime int64) (int, error) {
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return 0, errors.New("expects user ID to be a valid identifier")
+	}
+
+	var stream *PresenceStream
+	if channelId != "" {
+		channelIdToStreamResult, err := ChannelIdToStream(channelId)
+		if err != nil {
+			return 0, err
+		}
+		stream = &channelIdToStreamResult.Stream
+	}
+
+	var end time.Time
+	if endTime > 0 {
+		end = time.Unix(endTime, 0)
+	}
+
+	return ChannelMessagesPurge(ctx, n.logger, n.db, n.router, userID, stream, time.Unix(startTime, 0), end)
+}
+
+// @group chat
+// @summary Reply to a persisted chat message, starting or continuing its thread. The reply is persisted, and sent on the channel with the chat reply code and content holding 'parent_id' and the reply 'content'.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel to send the reply on.
+// @param parentId(type=string) The ID of the chat message to reply to.
+// @param content(type=map[string]interface{}) Reply content.
+// @param senderId(type=string, optional=true) The UUID for the sender of this reply, who must be a member of the group, a participant of the direct message or have joined the room. If left empty, it will be assumed that it is a system message.
+// @param senderUsername(type=string, optional=true) The username of the user to send this reply as. If left empty, it will be assumed that it is a system message.
+// @return channelMessageReply(*rtapi.ChannelMessageAck) Message sent ack containing the following variables: 'channelId', 'contentStr', 'senderId', 'senderUsername', and 'persist'.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessageReply(ctx context.Context, channelId, parentId string, content map[string]interface{}, senderId, senderUsername string) (*rtapi.ChannelMessageAck, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	contentStr := "{}"
+	if content != nil {
+		contentBytes, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding content: %v", err.Error())
+		}
+		contentStr = string(contentBytes)
+	}
+
+	return ChannelMessageReply(ctx, n.logger, n.db, n.tracker, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, parentId, contentStr, senderId, senderUsername)
+}
+
+// @group chat
+// @summary List the replies in a chat message's thread, oldest first.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param parentId(type=string) The ID of the message to list replies to.
+// @param limit(type=int) The number of replies to return per page.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. An empty string skips the permission check.
+// @return channelMessageList([]*rtapi.ChannelMessage) Replies to the specified message.
+// @return nextCursor(string) Cursor for the next page of replies, if any.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessageThreadList(ctx context.Context, channelId, parentId string, limit int, cursor, callerId string) ([]*api.ChannelMessage, string, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if limit < 1 || limit > 100 {
+		return nil, "", errors.New("limit must be 1-100")
+	}
+
+	caller := uuid.Nil
+	if callerId != "" {
+		if caller, err = uuid.FromString(callerId); err != nil {
+			return nil, "", errors.New("expects caller ID to be a valid identifier")
+		}
+	}
+
+	list, err := ChannelMessageThreadList(ctx, n.logger, n.db, caller, channelIdToStreamResult.Stream, channelId, parentId, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return list.Messages, list.NextCursor, nil
+}
+
+// @group chat
+// @summary Add a user's reaction to a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message to react to.
+// @param emoji(type=string) The reaction, 1-64 bytes with no whitespace.
+// @param userId(type=string) The ID of the user reacting, who must be allowed to send messages to the channel.
+// @param username(type=string) The username of the user reacting.
+// @return reaction(*ChannelMessageReaction) The updated reaction count on the message.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessageReactionAdd(ctx context.Context, channelId, messageId, emoji, userId, username string) (*ChannelMessageReaction, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return nil, errors.New("expects user ID to be a valid identifier")
+	}
+
+	return ChannelMessageReactionAdd(ctx, n.logger, n.db, n.tracker, n.router, channelIdToStreamResult.Stream, channelId, messageId, emoji, userID, username)
+}
+
+// @group chat
+// @summary Remove a user's reaction from a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message the reaction is on.
+// @param emoji(type=string) The reaction to remove.
+// @param userId(type=string) The ID of the user who reacted, who must be allowed to send messages to the channel.
+// @param username(type=string) The username of the user who reacted.
+// @return reaction(*ChannelMessageReaction) The updated reaction count on the message.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessageReactionRemove(ctx context.Context, channelId, messageId, emoji, userId, username string) (*ChannelMessageReaction, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.FromString(userId)
+	if err != nil {
+		return nil, errors.New("expects user ID to be a valid identifier")
+	}
+
+	return ChannelMessageReactionRemove(ctx, n.logger, n.db, n.tracker, n.router, channelIdToStreamResult.Stream, channelId, messageId, emoji, userID, username)
+}
+
+// @group chat
+// @summary List the aggregated reaction counts on a set of chat messages, most popular first.
+// @param ctx(type=context.Context) The context object represents information about the server and requester.
+// @param channelId(type=string) The ID of the channel the messages are in.
+// @param messageIds(type=[]string) The IDs of the messages to list reactions for.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. Reactions added by the caller are marked. An empty string skips the permission check.
+// @return reactions(map[string][]*ChannelMessageReaction) Reaction counts keyed by message ID. Messages without reactions are omitted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeGoNakamaModule) ChannelMessageReactionsList(ctx context.Context, channelId string, messageIds []string, callerId string) (map[string][]*ChannelMessageReaction, error) {
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(messageIds) > 100 {
+		return nil, errors.New("expects at most 100 message IDs")
+	}
+
+	caller := uuid.Nil
+	if callerId != "" {
+		if caller, err = uuid.FromString(callerId); err != nil {
+			return nil, errors.New("expects caller ID to be a valid identifier")
+		}
+	}
+
+	return ChannelMessageReactionsList(ctx, n.logger, n.db, caller, channelIdToStreamResult.Stream, messageIds)
+}
+
 // @group chat
 // @summary Create a channel identifier to be used in other runtime calls. Does not create a channel.
 // @param ctx(type=context.Context) The context object represents information about the server and requester.
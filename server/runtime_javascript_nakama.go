@@ -237,6 +237,8 @@ func (n *runtimeJavascriptNakamaModule) mappings(r *goja.Runtime) map[string]fun
 		"storageDelete":                        n.storageDelete(r),
 		"multiUpdate":                          n.multiUpdate(r),
 		"leaderboardCreate":                    n.leaderboardCreate(r),
+		"leaderboardDerivedCreate":             n.leaderboardDerivedCreate(r),
+		"leaderboardValidationSet":             n.leaderboardValidationSet(r),
 		"leaderboardDelete":                    n.leaderboardDelete(r),
 		"leaderboardList":                      n.leaderboardList(r),
 		"leaderboardRecordsList":               n.leaderboardRecordsList(r),
@@ -245,6 +247,10 @@ func (n *runtimeJavascriptNakamaModule) mappings(r *goja.Runtime) map[string]fun
 		"leaderboardRecordDelete":              n.leaderboardRecordDelete(r),
 		"leaderboardsGetId":                    n.leaderboardsGetId(r),
 		"leaderboardRecordsHaystack":           n.leaderboardRecordsHaystack(r),
+		"leaderboardRecordsListFriends":        n.leaderboardRecordsListFriends(r),
+		"leaderboardRecordsListGroup":          n.leaderboardRecordsListGroup(r),
+		"leaderboardRecordPercentile":          n.leaderboardRecordPercentile(r),
+		"leaderboardRankHistoryList":           n.leaderboardRankHistoryList(r),
 		"purchaseValidateApple":                n.purchaseValidateApple(r),
 		"purchaseValidateGoogle":               n.purchaseValidateGoogle(r),
 		"purchaseValidateHuawei":               n.purchaseValidateHuawei(r),
@@ -293,10 +299,17 @@ func (n *runtimeJavascriptNakamaModule) mappings(r *goja.Runtime) map[string]fun
 		"channelMessageUpdate":                 n.channelMessageUpdate(r),
 		"channelMessageRemove":                 n.channelMessageRemove(r),
 		"channelMessagesList":                  n.channelMessagesList(r),
+		"channelMessageReply":                  n.channelMessageReply(r),
+		"channelMessageThreadList":             n.channelMessageThreadList(r),
+		"channelMessageReactionAdd":            n.channelMessageReactionAdd(r),
+		"channelMessageReactionRemove":         n.channelMessageReactionRemove(r),
+		"channelMessageReactionsList":          n.channelMessageReactionsList(r),
 		"channelIdBuild":                       n.channelIdBuild(r),
 		"binaryToString":                       n.binaryToString(r),
 		"stringToBinary":                       n.stringToBinary(r),
 		"storageIndexList":                     n.storageIndexList(r),
+		"storageIndexListPage":                 n.storageIndexListPage(r),
+		"storageIndexAggregate":                n.storageIndexAggregate(r),
 	}
 }
 
@@ -354,58 +367,154 @@ func (n *runtimeJavascriptNakamaModule) stringToBinary(r *goja.Runtime) func(goj
 // @return error(error) An optional error value if an error occurred.
 func (n *runtimeJavascriptNakamaModule) storageIndexList(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
 	return func(f goja.FunctionCall) goja.Value {
-		idxName := getJsString(r, f.Argument(0))
-		queryString := getJsString(r, f.Argument(1))
-		limit := 100
-		if !goja.IsUndefined(f.Argument(2)) && !goja.IsNull(f.Argument(2)) {
-			limit = int(getJsInt(r, f.Argument(2)))
-			if limit < 1 || limit > 10_000 {
-				panic(r.NewTypeError("limit must be 1-10000"))
-			}
+		idxName, queryString, limit, callerID := n.storageIndexListArgs(r, f)
+
+		objectList, _, _, err := n.storageIndex.List(n.ctx, callerID, idxName, queryString, limit, nil, "")
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to lookup storage index: %s", err.Error())))
 		}
-		callerID := uuid.Nil
-		if !goja.IsUndefined(f.Argument(3)) && !goja.IsNull(f.Argument(3)) {
-			callerIdStr := getJsString(r, f.Argument(3))
-			cid, err := uuid.FromString(callerIdStr)
+
+		return r.ToValue(storageIndexListToJs(r, objectList))
+	}
+}
+
+// @group storage
+// @summary List a page of storage index entries, optionally ordered, along with a cursor for the next page and the total number of matching entries.
+// @param indexName(type=string) Name of the index to list entries from.
+// @param queryString(type=string) Query to filter index entries.
+// @param limit(type=int) Maximum number of results to be returned.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permission checks are bypassed.
+// @param order(type=[]string, optional=true) The field names to sort by, prefixed with "-" for descending order. Sorted by score if empty. Ordering by value fields requires a sortable index.
+// @param cursor(type=string, optional=true) Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return result(nkruntime.StorageIndexResult) A list of storage objects, the cursor for the next page, and the number of matching entries.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) storageIndexListPage(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		idxName, queryString, limit, callerID := n.storageIndexListArgs(r, f)
+
+		var order []string
+		if !goja.IsUndefined(f.Argument(4)) && !goja.IsNull(f.Argument(4)) {
+			var err error
+			order, err = exportToSlice[[]string](f.Argument(4))
 			if err != nil {
-				panic(r.NewTypeError("expects caller id to be valid identifier"))
+				panic(r.NewTypeError("expects order to be an array of strings"))
 			}
-			callerID = cid
+		}
+		var cursor string
+		if !goja.IsUndefined(f.Argument(5)) && !goja.IsNull(f.Argument(5)) {
+			cursor = getJsString(r, f.Argument(5))
 		}
 
-		objectList, err := n.storageIndex.List(n.ctx, callerID, idxName, queryString, int(limit))
+		objectList, newCursor, total, err := n.storageIndex.List(n.ctx, callerID, idxName, queryString, limit, order, cursor)
 		if err != nil {
 			panic(r.NewGoError(fmt.Errorf("failed to lookup storage index: %s", err.Error())))
 		}
 
-		objects := make([]interface{}, 0, len(objectList.Objects))
-		for _, o := range objectList.Objects {
-			objectMap := make(map[string]interface{}, 9)
-			objectMap["key"] = o.Key
-			objectMap["collection"] = o.Collection
-			if o.UserId != "" {
-				objectMap["userId"] = o.UserId
-			} else {
-				objectMap["userId"] = nil
-			}
-			objectMap["version"] = o.Version
-			objectMap["permissionRead"] = o.PermissionRead
-			objectMap["permissionWrite"] = o.PermissionWrite
-			objectMap["createTime"] = o.CreateTime.Seconds
-			objectMap["updateTime"] = o.UpdateTime.Seconds
+		result := map[string]interface{}{
+			"objects": storageIndexListToJs(r, objectList),
+			"total":   total,
+		}
+		if newCursor != "" {
+			result["cursor"] = newCursor
+		} else {
+			result["cursor"] = nil
+		}
 
-			valueMap := make(map[string]interface{})
-			err = json.Unmarshal([]byte(o.Value), &valueMap)
-			if err != nil {
-				panic(r.NewGoError(fmt.Errorf("failed to convert value to json: %s", err.Error())))
-			}
-			pointerizeSlices(valueMap)
-			objectMap["value"] = valueMap
+		return r.ToValue(result)
+	}
+}
 
-			objects = append(objects, objectMap)
+func (n *runtimeJavascriptNakamaModule) storageIndexListArgs(r *goja.Runtime, f goja.FunctionCall) (string, string, int, uuid.UUID) {
+	idxName := getJsString(r, f.Argument(0))
+	queryString := getJsString(r, f.Argument(1))
+	limit := 100
+	if !goja.IsUndefined(f.Argument(2)) && !goja.IsNull(f.Argument(2)) {
+		limit = int(getJsInt(r, f.Argument(2)))
+		if limit < 1 || limit > 10_000 {
+			panic(r.NewTypeError("limit must be 1-10000"))
+		}
+	}
+	callerID := uuid.Nil
+	if !goja.IsUndefined(f.Argument(3)) && !goja.IsNull(f.Argument(3)) {
+		callerIdStr := getJsString(r, f.Argument(3))
+		cid, err := uuid.FromString(callerIdStr)
+		if err != nil {
+			panic(r.NewTypeError("expects caller id to be valid identifier"))
+		}
+		callerID = cid
+	}
+	return idxName, queryString, limit, callerID
+}
+
+func storageIndexListToJs(r *goja.Runtime, objectList *api.StorageObjects) []interface{} {
+	objects := make([]interface{}, 0, len(objectList.Objects))
+	for _, o := range objectList.Objects {
+		objectMap := make(map[string]interface{}, 9)
+		objectMap["key"] = o.Key
+		objectMap["collection"] = o.Collection
+		if o.UserId != "" {
+			objectMap["userId"] = o.UserId
+		} else {
+			objectMap["userId"] = nil
+		}
+		objectMap["version"] = o.Version
+		objectMap["permissionRead"] = o.PermissionRead
+		objectMap["permissionWrite"] = o.PermissionWrite
+		objectMap["createTime"] = o.CreateTime.Seconds
+		objectMap["updateTime"] = o.UpdateTime.Seconds
+
+		valueMap := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(o.Value), &valueMap); err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to convert value to json: %s", err.Error())))
+		}
+		pointerizeSlices(valueMap)
+		objectMap["value"] = valueMap
+
+		objects = append(objects, objectMap)
+	}
+	return objects
+}
+
+// @group storage
+// @summary Compute aggregations, such as counts, terms facets, numeric range buckets and min/max/avg, over storage index entries.
+// @param indexName(type=string) Name of the index to aggregate entries from.
+// @param queryString(type=string) Query to filter index entries. Aggregates all entries if empty.
+// @param aggregations(type=nkruntime.StorageIndexAggregation[]) The aggregations, each with a name, type (count, terms, range, min, max, avg or sum), and optionally a field, size and ranges.
+// @return result(nkruntime.StorageIndexAggregateResult) The number of matching entries and the result of each aggregation by name.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) storageIndexAggregate(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		idxName := getJsString(r, f.Argument(0))
+		var queryString string
+		if !goja.IsUndefined(f.Argument(1)) && !goja.IsNull(f.Argument(1)) {
+			queryString = getJsString(r, f.Argument(1))
+		}
+
+		aggsBytes, err := json.Marshal(f.Argument(2).Export())
+		if err != nil {
+			panic(r.NewTypeError("expects aggregations to be an array of objects"))
+		}
+		var aggs []*StorageIndexAggregation
+		if err := json.Unmarshal(aggsBytes, &aggs); err != nil {
+			panic(r.NewTypeError("expects aggregations to be an array of objects"))
+		}
+
+		result, err := n.storageIndex.Aggregate(n.ctx, idxName, queryString, aggs)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to aggregate storage index: %s", err.Error())))
+		}
+
+		resultBytes, err := json.Marshal(result)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to convert result: %s", err.Error())))
 		}
+		resultMap := make(map[string]interface{})
+		if err := json.Unmarshal(resultBytes, &resultMap); err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to convert result: %s", err.Error())))
+		}
+		pointerizeSlices(resultMap)
 
-		return r.ToValue(objects)
+		return r.ToValue(resultMap)
 	}
 }
 
@@ -2012,7 +2121,7 @@ func (n *runtimeJavascriptNakamaModule) accountDeleteId(r *goja.Runtime) func(go
 			recorded = getJsBool(r, f.Argument(1))
 		}
 
-		if err := DeleteAccount(n.ctx, n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, userID, recorded); err != nil {
+		if err := DeleteAccount(n.ctx, n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, n.storageIndex, userID, recorded); err != nil {
 			panic(r.NewGoError(fmt.Errorf("error while trying to delete account: %v", err.Error())))
 		}
 
@@ -4379,7 +4488,7 @@ func (n *runtimeJavascriptNakamaModule) storageRead(r *goja.Runtime) func(goja.F
 
 // @group storage
 // @summary Write one or more objects by their collection/keyname and optional user.
-// @param objectIds(type=nkruntime.StorageWriteRequest[]) An array of object identifiers to be written.
+// @param objectIds(type=nkruntime.StorageWriteRequest[]) An array of object identifiers to be written. Objects with an expireTime, in UTC seconds, are deleted once they expire.
 // @return acks(nkruntime.StorageWriteAck[]) A list of acks with the version of the written objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *runtimeJavascriptNakamaModule) storageWrite(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
@@ -4503,6 +4612,22 @@ func jsArrayToStorageOpWrites(dataSlice []map[string]any) (StorageOpWrites, erro
 			writeOp.PermissionWrite = &wrapperspb.Int32Value{Value: 1}
 		}
 
+		var expireTime time.Time
+		if expireTimeIn, ok := dataMap["expireTime"]; ok && expireTimeIn != nil {
+			var seconds int64
+			switch v := expireTimeIn.(type) {
+			case int64:
+				seconds = v
+			case float64:
+				seconds = int64(v)
+			default:
+				return nil, errors.New("expects 'expireTime' value to be a number")
+			}
+			if seconds > 0 {
+				expireTime = time.Unix(seconds, 0)
+			}
+		}
+
 		if writeOp.Collection == "" {
 			return nil, errors.New("expects collection to be supplied")
 		} else if writeOp.Key == "" {
@@ -4512,8 +4637,9 @@ func jsArrayToStorageOpWrites(dataSlice []map[string]any) (StorageOpWrites, erro
 		}
 
 		ops = append(ops, &StorageOpWrite{
-			OwnerID: userID.String(),
-			Object:  writeOp,
+			OwnerID:    userID.String(),
+			Object:     writeOp,
+			ExpireTime: expireTime,
 		})
 	}
 
@@ -5082,6 +5208,146 @@ func (n *runtimeJavascriptNakamaModule) leaderboardCreate(r *goja.Runtime) func(
 	}
 }
 
+// @group leaderboards
+// @summary Setup a new derived leaderboard, whose score for each owner is the weighted sum of their scores in other leaderboards and is recomputed whenever one of those changes. Derived leaderboards are authoritative, and have their own rank cache and reset schedule. The leaderboard will be created if it doesn't already exist, otherwise its configuration will not be updated.
+// @param id(type=string) The unique identifier for the new leaderboard.
+// @param sources(type=nkruntime.LeaderboardDerivedSource[]) The source leaderboards, each with a leaderboardId and the weight applied to its scores. Sources must be regular leaderboards, not tournaments or other derived leaderboards.
+// @param sortOrder(type=string, optional=true, default="desc") The sort order for records in the leaderboard. Possible values are "asc" or "desc".
+// @param resetSchedule(type=string, optional=true) The cron format used to define the reset schedule for the leaderboard. This controls when a leaderboard is reset and can be used to power daily/weekly/monthly leaderboards.
+// @param metadata(type=object, optional=true) The metadata you want associated to the leaderboard.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) leaderboardDerivedCreate(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
+		}
+
+		sourcesIn, ok := f.Argument(1).Export().([]interface{})
+		if !ok {
+			panic(r.NewTypeError("expects sources to be an array of objects"))
+		}
+		sources := make([]*LeaderboardDerivedSource, 0, len(sourcesIn))
+		for _, sourceIn := range sourcesIn {
+			sourceMap, ok := sourceIn.(map[string]interface{})
+			if !ok {
+				panic(r.NewTypeError("expects sources to be an array of objects"))
+			}
+			sourceId, ok := sourceMap["leaderboardId"].(string)
+			if !ok || sourceId == "" {
+				panic(r.NewTypeError("expects each source to have a leaderboardId string"))
+			}
+			source := &LeaderboardDerivedSource{LeaderboardId: sourceId, Weight: 1}
+			switch weight := sourceMap["weight"].(type) {
+			case nil:
+			case int64:
+				source.Weight = float64(weight)
+			case float64:
+				source.Weight = weight
+			default:
+				panic(r.NewTypeError("expects each source weight to be a number"))
+			}
+			sources = append(sources, source)
+		}
+
+		sortOrder := "desc"
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			sortOrder = getJsString(r, f.Argument(2))
+		}
+		var sortOrderNumber int
+		switch sortOrder {
+		case "asc", "ascending":
+			sortOrderNumber = LeaderboardSortOrderAscending
+		case "desc", "descending":
+			sortOrderNumber = LeaderboardSortOrderDescending
+		default:
+			panic(r.NewTypeError("expects sort order to be 'asc' or 'desc'"))
+		}
+
+		resetSchedule := ""
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			resetSchedule = getJsString(r, f.Argument(3))
+		}
+		if resetSchedule != "" {
+			if _, err := cronexpr.Parse(resetSchedule); err != nil {
+				panic(r.NewTypeError("expects reset schedule to be a valid CRON expression"))
+			}
+		}
+
+		metadataStr := "{}"
+		if f.Argument(4) != goja.Undefined() && f.Argument(4) != goja.Null() {
+			metadataMap, ok := f.Argument(4).Export().(map[string]interface{})
+			if !ok {
+				panic(r.NewTypeError("expects metadata to be an object"))
+			}
+			metadataBytes, err := json.Marshal(metadataMap)
+			if err != nil {
+				panic(r.NewTypeError(fmt.Sprintf("error encoding metadata: %v", err.Error())))
+			}
+			metadataStr = string(metadataBytes)
+		}
+
+		_, created, err := n.leaderboardCache.CreateDerived(n.ctx, id, sortOrderNumber, resetSchedule, metadataStr, sources)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error creating derived leaderboard: %v", err.Error())))
+		}
+
+		if created {
+			// Only need to update the scheduler and backfill records for newly created leaderboards.
+			n.leaderboardScheduler.Update()
+			if _, err := LeaderboardDerivedBackfill(n.ctx, n.logger, n.db, n.leaderboardCache, n.rankCache, id); err != nil {
+				n.logger.Error("Error backfilling derived leaderboard", zap.String("leaderboard_id", id), zap.Error(err))
+			}
+		}
+
+		return goja.Undefined()
+	}
+}
+
+// @group leaderboards
+// @summary Set the anti-cheat rules applied to record writes in a leaderboard. Writes over the rate limit are rejected, and writes that change the owner's score by more than the maximum delta are quarantined until approved or deleted in the console. Setting all values to 0 removes the rules.
+// @param id(type=string) The unique identifier of the leaderboard. Tournaments are not supported.
+// @param maxScoreDelta(type=number, optional=true, default=0) The largest score change a single write may make, or 0 for no limit.
+// @param rateLimitCount(type=number, optional=true, default=0) The number of writes allowed per owner in each interval on each node, or 0 for no limit. Counts are not shared across a cluster.
+// @param rateLimitIntervalSec(type=number, optional=true, default=0) The rate limit interval in seconds, or 0 for no limit.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) leaderboardValidationSet(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
+		}
+
+		var maxScoreDelta int64
+		if f.Argument(1) != goja.Undefined() && f.Argument(1) != goja.Null() {
+			maxScoreDelta = getJsInt(r, f.Argument(1))
+			if maxScoreDelta < 0 {
+				panic(r.NewTypeError("expects max score delta to be 0 or greater"))
+			}
+		}
+		var rateLimitCount int64
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			rateLimitCount = getJsInt(r, f.Argument(2))
+			if rateLimitCount < 0 {
+				panic(r.NewTypeError("expects rate limit count to be 0 or greater"))
+			}
+		}
+		var rateLimitIntervalSec int64
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			rateLimitIntervalSec = getJsInt(r, f.Argument(3))
+			if rateLimitIntervalSec < 0 {
+				panic(r.NewTypeError("expects rate limit interval to be 0 or greater"))
+			}
+		}
+
+		if err := n.leaderboardCache.SetValidation(n.ctx, id, maxScoreDelta, int(rateLimitCount), int(rateLimitIntervalSec)); err != nil {
+			panic(r.NewGoError(fmt.Errorf("error setting leaderboard validation: %v", err.Error())))
+		}
+
+		return goja.Undefined()
+	}
+}
+
 // @group leaderboards
 // @summary Delete a leaderboard and all scores that belong to it.
 // @param id(type=string) The unique identifier for the leaderboard to delete.
@@ -5471,66 +5737,278 @@ func (n *runtimeJavascriptNakamaModule) leaderboardRecordsHaystack(r *goja.Runti
 	}
 }
 
-// @group purchases
-// @summary Validates and stores the purchases present in an Apple App Store Receipt.
-// @param userId(type=string) The user ID of the owner of the receipt.
-// @param receipt(type=string) Base-64 encoded receipt data returned by the purchase operation itself.
-// @param persist(type=bool, optional=true, default=true) Persist the purchase so that seenBefore can be computed to protect against replay attacks.
-// @param passwordOverride(type=string, optional=true) Override the iap.apple.shared_password provided in your configuration.
-// @return validation(nkruntime.ValidatePurchaseResponse) The resulting successfully validated purchases. Any previously validated purchases are returned with a seenBefore flag.
+// @group leaderboards
+// @summary List records owned by a user and their friends, with ranks relative to each other rather than the whole leaderboard.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param userId(type=string) The user whose own and friends' records are listed.
+// @param limit(type=number, optional=true, default=10) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(nkruntime.LeaderboardRecordList) A page of leaderboard records ranked within the subset, the subset record count and possibly a cursor. If cursor is empty/nil there are no further results.
 // @return error(error) An optional error value if an error occurred.
-func (n *runtimeJavascriptNakamaModule) purchaseValidateApple(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+func (n *runtimeJavascriptNakamaModule) leaderboardRecordsListFriends(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
 	return func(f goja.FunctionCall) goja.Value {
-		password := n.config.GetIAP().Apple.SharedPassword
-		if f.Argument(3) != goja.Undefined() {
-			password = getJsString(r, f.Argument(3))
-		}
-
-		if password == "" {
-			panic(r.NewGoError(errors.New("apple IAP is not configured")))
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
 		}
 
-		userID := getJsString(r, f.Argument(0))
-		if userID == "" {
-			panic(r.NewTypeError("expects a user ID string"))
-		}
-		uid, err := uuid.FromString(userID)
+		userID, err := uuid.FromString(getJsString(r, f.Argument(1)))
 		if err != nil {
 			panic(r.NewTypeError("expects user ID to be a valid identifier"))
 		}
 
-		receipt := getJsString(r, f.Argument(1))
-		if receipt == "" {
-			panic(r.NewTypeError("expects receipt"))
+		limit := 10
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			limit = int(getJsInt(r, f.Argument(2)))
+			if limit < 1 || limit > 100 {
+				panic(r.NewTypeError("limit must be 1-100"))
+			}
 		}
 
-		persist := true
-		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
-			persist = getJsBool(r, f.Argument(2))
+		cursor := ""
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			cursor = getJsString(r, f.Argument(3))
 		}
 
-		validation, err := ValidatePurchasesApple(n.ctx, n.logger, n.db, uid, password, receipt, persist)
-		if err != nil {
-			panic(r.NewGoError(fmt.Errorf("error validating Apple receipt: %s", err.Error())))
+		overrideExpiry := int64(0)
+		if f.Argument(4) != goja.Undefined() && f.Argument(4) != goja.Null() {
+			overrideExpiry = getJsInt(r, f.Argument(4))
+			if overrideExpiry < 0 {
+				panic(r.NewTypeError("expiry should be time since epoch in seconds and has to be a positive integer"))
+			}
 		}
 
-		validationResult := purchaseResponseToJsObject(validation)
+		records, err := LeaderboardRecordsListFriends(n.ctx, n.logger, n.db, n.leaderboardCache, id, userID, limit, cursor, overrideExpiry)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error listing leaderboard records: %v", err.Error())))
+		}
 
-		return r.ToValue(validationResult)
+		return leaderboardRecordsListToJs(r, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount)
 	}
 }
 
-// @group purchases
-// @summary Validates and stores a purchase receipt from the Google Play Store.
-// @param userId(type=string) The user ID of the owner of the receipt.
-// @param receipt(type=string) JSON encoded Google receipt.
-// @param persist(type=bool, optional=true, default=true) Persist the purchase so that seenBefore can be computed to protect against replay attacks.
-// @return validation(nkruntime.ValidatePurchaseResponse) The resulting successfully validated purchases. Any previously validated purchases are returned with a seenBefore flag.
+// @group leaderboards
+// @summary List records owned by members of a group, with ranks relative to each other rather than the whole leaderboard.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param groupId(type=string) The group whose members' records are listed.
+// @param limit(type=number, optional=true, default=10) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(nkruntime.LeaderboardRecordList) A page of leaderboard records ranked within the subset, the subset record count and possibly a cursor. If cursor is empty/nil there are no further results.
 // @return error(error) An optional error value if an error occurred.
-func (n *runtimeJavascriptNakamaModule) purchaseValidateGoogle(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+func (n *runtimeJavascriptNakamaModule) leaderboardRecordsListGroup(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
 	return func(f goja.FunctionCall) goja.Value {
-		clientEmail := n.config.GetIAP().Google.ClientEmail
-		privateKey := n.config.GetIAP().Google.PrivateKey
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
+		}
+
+		groupID, err := uuid.FromString(getJsString(r, f.Argument(1)))
+		if err != nil {
+			panic(r.NewTypeError("expects group ID to be a valid identifier"))
+		}
+
+		limit := 10
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			limit = int(getJsInt(r, f.Argument(2)))
+			if limit < 1 || limit > 100 {
+				panic(r.NewTypeError("limit must be 1-100"))
+			}
+		}
+
+		cursor := ""
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			cursor = getJsString(r, f.Argument(3))
+		}
+
+		overrideExpiry := int64(0)
+		if f.Argument(4) != goja.Undefined() && f.Argument(4) != goja.Null() {
+			overrideExpiry = getJsInt(r, f.Argument(4))
+			if overrideExpiry < 0 {
+				panic(r.NewTypeError("expiry should be time since epoch in seconds and has to be a positive integer"))
+			}
+		}
+
+		records, err := LeaderboardRecordsListGroup(n.ctx, n.logger, n.db, n.leaderboardCache, id, groupID, limit, cursor, overrideExpiry)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error listing leaderboard records: %v", err.Error())))
+		}
+
+		return leaderboardRecordsListToJs(r, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount)
+	}
+}
+
+// @group leaderboards
+// @summary Get an owner's rank in the current period of a leaderboard, and the percentage of ranked records at or above it. Only available if rank cache is not disabled for the leaderboard.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to get the percentile for.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return percentile(nkruntime.LeaderboardRecordPercentile) The owner's rank, the number of ranked records and the percentile. Rank is 0 if the owner has no ranked record.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) leaderboardRecordPercentile(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
+		}
+
+		ownerID, err := uuid.FromString(getJsString(r, f.Argument(1)))
+		if err != nil {
+			panic(r.NewTypeError("expects owner ID to be a valid identifier"))
+		}
+
+		overrideExpiry := int64(0)
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			overrideExpiry = getJsInt(r, f.Argument(2))
+			if overrideExpiry < 0 {
+				panic(r.NewTypeError("expiry should be time since epoch in seconds and has to be a positive integer"))
+			}
+		}
+
+		percentile, err := LeaderboardRecordPercentileGet(n.leaderboardCache, n.rankCache, id, ownerID, overrideExpiry)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error getting leaderboard record percentile: %v", err.Error())))
+		}
+
+		return r.ToValue(map[string]interface{}{
+			"leaderboardId": percentile.LeaderboardId,
+			"ownerId":       percentile.OwnerId,
+			"rank":          percentile.Rank,
+			"count":         percentile.Count,
+			"percentile":    percentile.Percentile,
+			"expiryTime":    percentile.ExpiryTime,
+		})
+	}
+}
+
+// @group leaderboards
+// @summary List an owner's final ranks in past periods of a leaderboard, most recent first.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to list rank history for.
+// @param limit(type=number, optional=true, default=10) Return only the required number of past periods denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return result(nkruntime.LeaderboardRankHistoryList) The owner's rank, score and percentile in each past period, and an optional next page cursor.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) leaderboardRankHistoryList(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		id := getJsString(r, f.Argument(0))
+		if id == "" {
+			panic(r.NewTypeError("expects a leaderboard ID string"))
+		}
+
+		ownerID, err := uuid.FromString(getJsString(r, f.Argument(1)))
+		if err != nil {
+			panic(r.NewTypeError("expects owner ID to be a valid identifier"))
+		}
+
+		limit := 10
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			limit = int(getJsInt(r, f.Argument(2)))
+			if limit < 1 || limit > 100 {
+				panic(r.NewTypeError("limit must be 1-100"))
+			}
+		}
+
+		cursor := ""
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			cursor = getJsString(r, f.Argument(3))
+		}
+
+		history, nextCursor, err := LeaderboardRankHistoryList(n.ctx, n.logger, n.db, n.leaderboardCache, id, ownerID, limit, cursor)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error listing leaderboard rank history: %v", err.Error())))
+		}
+
+		historyData := make([]interface{}, 0, len(history))
+		for _, h := range history {
+			historyData = append(historyData, map[string]interface{}{
+				"leaderboardId": h.LeaderboardId,
+				"ownerId":       h.OwnerId,
+				"username":      h.Username,
+				"score":         h.Score,
+				"subscore":      h.Subscore,
+				"numScore":      h.NumScore,
+				"rank":          h.Rank,
+				"count":         h.Count,
+				"percentile":    h.Percentile,
+				"expiryTime":    h.ExpiryTime,
+			})
+		}
+
+		result := map[string]interface{}{
+			"history": historyData,
+		}
+		if nextCursor != "" {
+			result["nextCursor"] = nextCursor
+		} else {
+			result["nextCursor"] = nil
+		}
+
+		return r.ToValue(result)
+	}
+}
+
+// @group purchases
+// @summary Validates and stores the purchases present in an Apple App Store Receipt.
+// @param userId(type=string) The user ID of the owner of the receipt.
+// @param receipt(type=string) Base-64 encoded receipt data returned by the purchase operation itself.
+// @param persist(type=bool, optional=true, default=true) Persist the purchase so that seenBefore can be computed to protect against replay attacks.
+// @param passwordOverride(type=string, optional=true) Override the iap.apple.shared_password provided in your configuration.
+// @return validation(nkruntime.ValidatePurchaseResponse) The resulting successfully validated purchases. Any previously validated purchases are returned with a seenBefore flag.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) purchaseValidateApple(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		password := n.config.GetIAP().Apple.SharedPassword
+		if f.Argument(3) != goja.Undefined() {
+			password = getJsString(r, f.Argument(3))
+		}
+
+		if password == "" {
+			panic(r.NewGoError(errors.New("apple IAP is not configured")))
+		}
+
+		userID := getJsString(r, f.Argument(0))
+		if userID == "" {
+			panic(r.NewTypeError("expects a user ID string"))
+		}
+		uid, err := uuid.FromString(userID)
+		if err != nil {
+			panic(r.NewTypeError("expects user ID to be a valid identifier"))
+		}
+
+		receipt := getJsString(r, f.Argument(1))
+		if receipt == "" {
+			panic(r.NewTypeError("expects receipt"))
+		}
+
+		persist := true
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			persist = getJsBool(r, f.Argument(2))
+		}
+
+		validation, err := ValidatePurchasesApple(n.ctx, n.logger, n.db, uid, password, receipt, persist)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("error validating Apple receipt: %s", err.Error())))
+		}
+
+		validationResult := purchaseResponseToJsObject(validation)
+
+		return r.ToValue(validationResult)
+	}
+}
+
+// @group purchases
+// @summary Validates and stores a purchase receipt from the Google Play Store.
+// @param userId(type=string) The user ID of the owner of the receipt.
+// @param receipt(type=string) JSON encoded Google receipt.
+// @param persist(type=bool, optional=true, default=true) Persist the purchase so that seenBefore can be computed to protect against replay attacks.
+// @return validation(nkruntime.ValidatePurchaseResponse) The resulting successfully validated purchases. Any previously validated purchases are returned with a seenBefore flag.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) purchaseValidateGoogle(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		clientEmail := n.config.GetIAP().Google.ClientEmail
+		privateKey := n.config.GetIAP().Google.PrivateKey
 
 		if f.Argument(3) != goja.Undefined() {
 			clientEmail = getJsString(r, f.Argument(3))
@@ -8017,7 +8495,7 @@ func (n *runtimeJavascriptNakamaModule) channelMessageSend(r *goja.Runtime) func
 			panic(r.NewTypeError(err.Error()))
 		}
 
-		ack, err := ChannelMessageSend(n.ctx, n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, contentStr, senderId.String(), senderUsername, persist)
+		ack, err := ChannelMessageSend(n.ctx, n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, contentStr, senderId.String(), senderUsername, persist)
 		if err != nil {
 			panic(r.NewGoError(fmt.Errorf("failed to send channel message: %s", err.Error())))
 		}
@@ -8095,7 +8573,7 @@ func (n *runtimeJavascriptNakamaModule) channelMessageUpdate(r *goja.Runtime) fu
 			panic(r.NewTypeError(err.Error()))
 		}
 
-		ack, err := ChannelMessageUpdate(n.ctx, n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderId.String(), senderUsername, persist)
+		ack, err := ChannelMessageUpdate(n.ctx, n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderId.String(), senderUsername, persist)
 		if err != nil {
 			panic(r.NewGoError(fmt.Errorf("failed to update channel message: %s", err.Error())))
 		}
@@ -8243,6 +8721,271 @@ func (n *runtimeJavascriptNakamaModule) channelMessagesList(r *goja.Runtime) fun
 	}
 }
 
+// @group chat
+// @summary Reply to a persisted chat message, starting or continuing its thread. The reply is persisted, and sent on the channel with the chat reply code and content holding 'parent_id' and the reply 'content'.
+// @param channelId(type=string) The ID of the channel to send the reply on.
+// @param parentId(type=string) The ID of the chat message to reply to.
+// @param content(type=object) Reply content.
+// @param senderId(type=string, optional=true) The UUID for the sender of this reply, who must be a member of the group, a participant of the direct message or have joined the room. If left empty, it will be assumed that it is a system message.
+// @param senderUsername(type=string, optional=true) The username of the user to send this reply as. If left empty, it will be assumed that it is a system message.
+// @return channelMessageReply(nkruntime.ChannelMessageAck) Reply sent ack containing the following variables: 'channelId', 'messageId', 'code', 'username', 'createTime', 'updateTime', and 'persistent'.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) channelMessageReply(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		channelId := getJsString(r, f.Argument(0))
+
+		parentId := getJsString(r, f.Argument(1))
+		if _, err := uuid.FromString(parentId); err != nil {
+			panic(r.NewTypeError(errChannelMessageIdInvalid.Error()))
+		}
+
+		contentStr := "{}"
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			contentMap, ok := f.Argument(2).Export().(map[string]interface{})
+			if !ok {
+				panic(r.NewTypeError("expects content to be an object"))
+			}
+			contentBytes, err := json.Marshal(contentMap)
+			if err != nil {
+				panic(r.NewTypeError(fmt.Sprintf("error encoding content: %v", err.Error())))
+			}
+			if len(contentBytes) == 0 || contentBytes[0] != byteBracket {
+				panic(r.NewTypeError("expects message content to be a valid JSON object"))
+			}
+			contentStr = string(contentBytes)
+		}
+
+		senderId := uuid.Nil
+		if !goja.IsUndefined(f.Argument(3)) && !goja.IsNull(f.Argument(3)) {
+			senderIdStr := getJsString(r, f.Argument(3))
+			senderUUID, err := uuid.FromString(senderIdStr)
+			if err != nil {
+				panic(r.NewTypeError("expects sender id to be valid identifier"))
+			}
+			senderId = senderUUID
+		}
+
+		var senderUsername string
+		if !goja.IsUndefined(f.Argument(4)) && !goja.IsNull(f.Argument(4)) {
+			senderUsername = getJsString(r, f.Argument(4))
+		}
+
+		channelIdToStreamResult, err := ChannelIdToStream(channelId)
+		if err != nil {
+			panic(r.NewTypeError(err.Error()))
+		}
+
+		ack, err := ChannelMessageReply(n.ctx, n.logger, n.db, n.tracker, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, parentId, contentStr, senderId.String(), senderUsername)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to send channel message reply: %s", err.Error())))
+		}
+
+		channelMessageAckMap := make(map[string]interface{}, 7)
+		channelMessageAckMap["channelId"] = ack.ChannelId
+		channelMessageAckMap["messageId"] = ack.MessageId
+		channelMessageAckMap["code"] = ack.Code
+		channelMessageAckMap["username"] = ack.Username
+		channelMessageAckMap["createTime"] = ack.CreateTime.Seconds
+		channelMessageAckMap["updateTime"] = ack.UpdateTime.Seconds
+		channelMessageAckMap["persistent"] = ack.Persistent
+
+		return r.ToValue(channelMessageAckMap)
+	}
+}
+
+// @group chat
+// @summary List the replies in a chat message's thread, oldest first.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param parentId(type=string) The ID of the message to list replies to.
+// @param limit(type=number, optional=true, default=100) The number of replies to return per page.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. An empty string skips the permission check.
+// @return channelMessageThreadList(nkruntime.ChannelMessageList) Replies to the specified message and the cursor for the next page, if any.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) channelMessageThreadList(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		channelId := getJsString(r, f.Argument(0))
+
+		parentId := getJsString(r, f.Argument(1))
+		if _, err := uuid.FromString(parentId); err != nil {
+			panic(r.NewTypeError(errChannelMessageIdInvalid.Error()))
+		}
+
+		limit := 100
+		if f.Argument(2) != goja.Undefined() && f.Argument(2) != goja.Null() {
+			limit = int(getJsInt(r, f.Argument(2)))
+			if limit < 1 || limit > 100 {
+				panic(r.NewTypeError("limit must be 1-100"))
+			}
+		}
+
+		var cursor string
+		if f.Argument(3) != goja.Undefined() && f.Argument(3) != goja.Null() {
+			cursor = getJsString(r, f.Argument(3))
+		}
+
+		caller := uuid.Nil
+		if !goja.IsUndefined(f.Argument(4)) && !goja.IsNull(f.Argument(4)) {
+			callerUUID, err := uuid.FromString(getJsString(r, f.Argument(4)))
+			if err != nil {
+				panic(r.NewTypeError("expects caller id to be valid identifier"))
+			}
+			caller = callerUUID
+		}
+
+		channelIdToStreamResult, err := ChannelIdToStream(channelId)
+		if err != nil {
+			panic(r.NewTypeError(err.Error()))
+		}
+
+		list, err := ChannelMessageThreadList(n.ctx, n.logger, n.db, caller, channelIdToStreamResult.Stream, channelId, parentId, limit, cursor)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to list channel message replies: %s", err.Error())))
+		}
+
+		messages := make([]interface{}, 0, len(list.Messages))
+		for _, message := range list.Messages {
+			messages = append(messages, map[string]interface{}{
+				"channelId":  message.ChannelId,
+				"messageId":  message.MessageId,
+				"code":       message.Code.Value,
+				"senderId":   message.SenderId,
+				"username":   message.Username,
+				"content":    message.Content,
+				"createTime": message.CreateTime.Seconds,
+				"updateTime": message.UpdateTime.Seconds,
+				"persistent": message.Persistent.Value,
+				"roomName":   message.RoomName,
+				"groupId":    message.GroupId,
+				"userIdOne":  message.UserIdOne,
+				"userIdTwo":  message.UserIdTwo,
+			})
+		}
+
+		result := map[string]interface{}{
+			"messages":   messages,
+			"nextCursor": list.NextCursor,
+		}
+
+		return r.ToValue(result)
+	}
+}
+
+// @group chat
+// @summary Add a user's reaction to a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message to react to.
+// @param emoji(type=string) The reaction, 1-64 bytes with no whitespace.
+// @param userId(type=string) The ID of the user reacting, who must be allowed to send messages to the channel.
+// @param username(type=string, optional=true) The username of the user reacting.
+// @return reaction(object) The updated reaction count on the message, containing 'emoji', 'count' and 'reacted'.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) channelMessageReactionAdd(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return n.channelMessageReactionChange(r, ChannelMessageReactionAdd)
+}
+
+// @group chat
+// @summary Remove a user's reaction from a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message the reaction is on.
+// @param emoji(type=string) The reaction to remove.
+// @param userId(type=string) The ID of the user who reacted, who must be allowed to send messages to the channel.
+// @param username(type=string, optional=true) The username of the user who reacted.
+// @return reaction(object) The updated reaction count on the message, containing 'emoji', 'count' and 'reacted'.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) channelMessageReactionRemove(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return n.channelMessageReactionChange(r, ChannelMessageReactionRemove)
+}
+
+func (n *runtimeJavascriptNakamaModule) channelMessageReactionChange(r *goja.Runtime, fn func(context.Context, *zap.Logger, *sql.DB, Tracker, MessageRouter, PresenceStream, string, string, string, uuid.UUID, string) (*ChannelMessageReaction, error)) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		channelId := getJsString(r, f.Argument(0))
+		messageId := getJsString(r, f.Argument(1))
+		emoji := getJsString(r, f.Argument(2))
+
+		userID, err := uuid.FromString(getJsString(r, f.Argument(3)))
+		if err != nil {
+			panic(r.NewTypeError("expects user id to be valid identifier"))
+		}
+
+		var username string
+		if !goja.IsUndefined(f.Argument(4)) && !goja.IsNull(f.Argument(4)) {
+			username = getJsString(r, f.Argument(4))
+		}
+
+		channelIdToStreamResult, err := ChannelIdToStream(channelId)
+		if err != nil {
+			panic(r.NewTypeError(err.Error()))
+		}
+
+		reaction, err := fn(n.ctx, n.logger, n.db, n.tracker, n.router, channelIdToStreamResult.Stream, channelId, messageId, emoji, userID, username)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to change channel message reaction: %s", err.Error())))
+		}
+
+		return r.ToValue(channelMessageReactionToJs(reaction))
+	}
+}
+
+// @group chat
+// @summary List the aggregated reaction counts on a set of chat messages, most popular first.
+// @param channelId(type=string) The ID of the channel the messages are in.
+// @param messageIds(type=string[]) The IDs of the messages to list reactions for, at most 100.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. Reactions added by the caller are marked. An empty string skips the permission check.
+// @return reactions(object) Reaction counts keyed by message ID, each containing 'emoji', 'count' and 'reacted'. Messages without reactions are omitted.
+// @return error(error) An optional error value if an error occurred.
+func (n *runtimeJavascriptNakamaModule) channelMessageReactionsList(r *goja.Runtime) func(goja.FunctionCall) goja.Value {
+	return func(f goja.FunctionCall) goja.Value {
+		channelId := getJsString(r, f.Argument(0))
+
+		messageIds, err := exportToSlice[[]string](f.Argument(1))
+		if err != nil {
+			panic(r.NewTypeError("expects message ids to be an array of strings"))
+		}
+		if len(messageIds) > 100 {
+			panic(r.NewTypeError("expects at most 100 message ids"))
+		}
+
+		caller := uuid.Nil
+		if !goja.IsUndefined(f.Argument(2)) && !goja.IsNull(f.Argument(2)) {
+			callerUUID, err := uuid.FromString(getJsString(r, f.Argument(2)))
+			if err != nil {
+				panic(r.NewTypeError("expects caller id to be valid identifier"))
+			}
+			caller = callerUUID
+		}
+
+		channelIdToStreamResult, err := ChannelIdToStream(channelId)
+		if err != nil {
+			panic(r.NewTypeError(err.Error()))
+		}
+
+		reactions, err := ChannelMessageReactionsList(n.ctx, n.logger, n.db, caller, channelIdToStreamResult.Stream, messageIds)
+		if err != nil {
+			panic(r.NewGoError(fmt.Errorf("failed to list channel message reactions: %s", err.Error())))
+		}
+
+		result := make(map[string]interface{}, len(reactions))
+		for messageId, messageReactions := range reactions {
+			reactionList := make([]interface{}, 0, len(messageReactions))
+			for _, reaction := range messageReactions {
+				reactionList = append(reactionList, channelMessageReactionToJs(reaction))
+			}
+			result[messageId] = reactionList
+		}
+
+		return r.ToValue(result)
+	}
+}
+
+func channelMessageReactionToJs(reaction *ChannelMessageReaction) map[string]interface{} {
+	return map[string]interface{}{
+		"emoji":   reaction.Emoji,
+		"count":   reaction.Count,
+		"reacted": reaction.Reacted,
+	}
+}
+
 // @group chat
 // @summary Create a channel identifier to be used in other runtime calls. Does not create a channel.
 // @param senderId(type=string) UserID of the message sender (when applicable). Defaults to the system user if void.
@@ -253,12 +253,18 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"storage_delete":                     n.storageDelete,
 		"multi_update":                       n.multiUpdate,
 		"leaderboard_create":                 n.leaderboardCreate,
+		"leaderboard_derived_create":         n.leaderboardDerivedCreate,
+		"leaderboard_validation_set":         n.leaderboardValidationSet,
 		"leaderboard_delete":                 n.leaderboardDelete,
 		"leaderboard_list":                   n.leaderboardList,
 		"leaderboard_records_list":           n.leaderboardRecordsList,
 		"leaderboard_records_list_cursor_from_rank": n.leaderboardRecordsListCursorFromRank,
 		"leaderboard_record_write":                  n.leaderboardRecordWrite,
 		"leaderboard_records_haystack":              n.leaderboardRecordsHaystack,
+		"leaderboard_records_list_friends":          n.leaderboardRecordsListFriends,
+		"leaderboard_records_list_group":            n.leaderboardRecordsListGroup,
+		"leaderboard_record_percentile":             n.leaderboardRecordPercentile,
+		"leaderboard_rank_history_list":             n.leaderboardRankHistoryList,
 		"leaderboard_record_delete":                 n.leaderboardRecordDelete,
 		"leaderboards_get_id":                       n.leaderboardsGetId,
 		"purchase_validate_apple":                   n.purchaseValidateApple,
@@ -305,8 +311,15 @@ func (n *RuntimeLuaNakamaModule) Loader(l *lua.LState) int {
 		"channel_message_update":                    n.channelMessageUpdate,
 		"channel_message_remove":                    n.channelMessageRemove,
 		"channel_messages_list":                     n.channelMessagesList,
+		"channel_message_reply":                     n.channelMessageReply,
+		"channel_message_thread_list":               n.channelMessageThreadList,
+		"channel_message_reaction_add":              n.channelMessageReactionAdd,
+		"channel_message_reaction_remove":           n.channelMessageReactionRemove,
+		"channel_message_reactions_list":            n.channelMessageReactionsList,
 		"channel_id_build":                          n.channelIdBuild,
 		"storage_index_list":                        n.storageIndexList,
+		"storage_index_list_page":                   n.storageIndexListPage,
+		"storage_index_aggregate":                   n.storageIndexAggregate,
 		"get_satori":                                n.getSatori,
 	}
 
@@ -512,6 +525,8 @@ func (n *RuntimeLuaNakamaModule) registerLeaderboardReset(l *lua.LState) int {
 // @param key(type=string) Key of storage objects to index. Set to empty string to index all objects of collection.
 // @param fields(type=table) A table of strings with the keys of the storage object whose values are to be indexed.
 // @param maxEntries(type=int) Maximum number of entries kept in the index.
+// @param indexOnly(type=bool, optional=true, default=false) Only index the objects, do not keep their values in the index.
+// @param sortable(type=bool, optional=true, default=false) Store indexed fields as sortable values so entries can be ordered and aggregated by them.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) registerStorageIndex(l *lua.LState) int {
 	idxName := l.CheckString(1)
@@ -528,8 +543,9 @@ func (n *RuntimeLuaNakamaModule) registerStorageIndex(l *lua.LState) int {
 	})
 	maxEntries := l.CheckInt(5)
 	indexOnly := l.OptBool(6, false)
+	sortable := l.OptBool(7, false)
 
-	if err := n.storageIndex.CreateIndex(context.Background(), idxName, collection, key, fields, maxEntries, indexOnly); err != nil {
+	if err := n.storageIndex.CreateIndex(context.Background(), idxName, collection, key, fields, maxEntries, indexOnly, sortable); err != nil {
 		l.RaiseError("failed to create storage index: %s", err.Error())
 	}
 
@@ -5814,7 +5830,7 @@ func (n *RuntimeLuaNakamaModule) storageRead(l *lua.LState) int {
 
 // @group storage
 // @summary Write one or more objects by their collection/keyname and optional user.
-// @param objectIds(type=table) A table of object identifiers to be written.
+// @param objectIds(type=table) A table of object identifiers to be written. Objects with an expire_time, in UTC seconds, are deleted once they expire.
 // @return acks(table) A list of acks with the version of the written objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageWrite(l *lua.LState) int {
@@ -5872,6 +5888,7 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 		}
 
 		var userID uuid.UUID
+		var expireTime time.Time
 		d := &api.WriteStorageObject{}
 		dataTable.ForEach(func(k, v lua.LValue) {
 			if conversionError {
@@ -5955,6 +5972,15 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 					return
 				}
 				d.PermissionWrite = &wrapperspb.Int32Value{Value: int32(v.(lua.LNumber))}
+			case "expire_time":
+				if v.Type() != lua.LTNumber {
+					conversionError = true
+					l.ArgError(1, "expects expire_time to be number")
+					return
+				}
+				if seconds := int64(v.(lua.LNumber)); seconds > 0 {
+					expireTime = time.Unix(seconds, 0)
+				}
 			}
 		})
 
@@ -5986,8 +6012,9 @@ func tableToStorageWrites(l *lua.LState, dataTable *lua.LTable) (StorageOpWrites
 		}
 
 		ops = append(ops, &StorageOpWrite{
-			OwnerID: userID.String(),
-			Object:  d,
+			OwnerID:    userID.String(),
+			Object:     d,
+			ExpireTime: expireTime,
 		})
 	})
 
@@ -6738,6 +6765,117 @@ func (n *RuntimeLuaNakamaModule) leaderboardCreate(l *lua.LState) int {
 	return 0
 }
 
+// @group leaderboards
+// @summary Setup a new derived leaderboard, whose score for each owner is the weighted sum of their scores in other leaderboards and is recomputed whenever one of those changes. Derived leaderboards are authoritative, and have their own rank cache and reset schedule. The leaderboard will be created if it doesn't already exist, otherwise its configuration will not be updated.
+// @param id(type=string) The unique identifier for the new leaderboard.
+// @param sources(type=table) A list of tables, each with the leaderboard_id of a source leaderboard and the weight applied to its scores. Sources must be regular leaderboards, not tournaments or other derived leaderboards.
+// @param sortOrder(type=string, optional=true, default="desc") The sort order for records in the leaderboard. Possible values are "asc" or "desc".
+// @param resetSchedule(type=string, optional=true) The cron format used to define the reset schedule for the leaderboard. This controls when a leaderboard is reset and can be used to power daily/weekly/monthly leaderboards.
+// @param metadata(type=table, optional=true) The metadata you want associated to the leaderboard.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardDerivedCreate(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	sourcesBytes, err := json.Marshal(RuntimeLuaConvertLuaValue(l.CheckTable(2)))
+	if err != nil {
+		l.ArgError(2, fmt.Sprintf("failed to convert sources: %s", err.Error()))
+		return 0
+	}
+	var sources []*LeaderboardDerivedSource
+	if err := json.Unmarshal(sourcesBytes, &sources); err != nil {
+		l.ArgError(2, "expects sources to be a list of tables")
+		return 0
+	}
+
+	sortOrder := l.OptString(3, "desc")
+	var sortOrderNumber int
+	switch sortOrder {
+	case "asc", "ascending":
+		sortOrderNumber = LeaderboardSortOrderAscending
+	case "desc", "descending":
+		sortOrderNumber = LeaderboardSortOrderDescending
+	default:
+		l.ArgError(3, "expects sort order to be 'asc' or 'desc'")
+		return 0
+	}
+
+	resetSchedule := l.OptString(4, "")
+	if resetSchedule != "" {
+		if _, err := cronexpr.Parse(resetSchedule); err != nil {
+			l.ArgError(4, "expects reset schedule to be a valid CRON expression")
+			return 0
+		}
+	}
+
+	metadata := l.OptTable(5, nil)
+	metadataStr := "{}"
+	if metadata != nil {
+		metadataMap := RuntimeLuaConvertLuaTable(metadata)
+		metadataBytes, err := json.Marshal(metadataMap)
+		if err != nil {
+			l.RaiseError("error encoding metadata: %v", err.Error())
+			return 0
+		}
+		metadataStr = string(metadataBytes)
+	}
+
+	_, created, err := n.leaderboardCache.CreateDerived(l.Context(), id, sortOrderNumber, resetSchedule, metadataStr, sources)
+	if err != nil {
+		l.RaiseError("error creating derived leaderboard: %v", err.Error())
+		return 0
+	}
+
+	if created {
+		// Only need to update the scheduler and backfill records for newly created leaderboards.
+		n.leaderboardScheduler.Update()
+		if _, err := LeaderboardDerivedBackfill(l.Context(), n.logger, n.db, n.leaderboardCache, n.rankCache, id); err != nil {
+			n.logger.Error("Error backfilling derived leaderboard", zap.String("leaderboard_id", id), zap.Error(err))
+		}
+	}
+
+	return 0
+}
+
+// @group leaderboards
+// @summary Set the anti-cheat rules applied to record writes in a leaderboard. Writes over the rate limit are rejected, and writes that change the owner's score by more than the maximum delta are quarantined until approved or deleted in the console. Setting all values to 0 removes the rules.
+// @param id(type=string) The unique identifier of the leaderboard. Tournaments are not supported.
+// @param maxScoreDelta(type=number, optional=true, default=0) The largest score change a single write may make, or 0 for no limit.
+// @param rateLimitCount(type=number, optional=true, default=0) The number of writes allowed per owner in each interval on each node, or 0 for no limit. Counts are not shared across a cluster.
+// @param rateLimitIntervalSec(type=number, optional=true, default=0) The rate limit interval in seconds, or 0 for no limit.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardValidationSet(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	maxScoreDelta := l.OptInt64(2, 0)
+	if maxScoreDelta < 0 {
+		l.ArgError(2, "expects max score delta to be 0 or greater")
+		return 0
+	}
+	rateLimitCount := l.OptInt(3, 0)
+	if rateLimitCount < 0 {
+		l.ArgError(3, "expects rate limit count to be 0 or greater")
+		return 0
+	}
+	rateLimitIntervalSec := l.OptInt(4, 0)
+	if rateLimitIntervalSec < 0 {
+		l.ArgError(4, "expects rate limit interval to be 0 or greater")
+		return 0
+	}
+
+	if err := n.leaderboardCache.SetValidation(l.Context(), id, maxScoreDelta, rateLimitCount, rateLimitIntervalSec); err != nil {
+		l.RaiseError("error setting leaderboard validation: %v", err.Error())
+	}
+	return 0
+}
+
 // @group leaderboards
 // @summary Delete a leaderboard and all scores that belong to it.
 // @param id(type=string) The unique identifier for the leaderboard to delete.
@@ -6882,7 +7020,7 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsList(l *lua.LState) int {
 		return 0
 	}
 
-	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, false)
+	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, true)
 }
 
 // @group leaderboards
@@ -7082,6 +7220,208 @@ func (n *RuntimeLuaNakamaModule) leaderboardRecordsHaystack(l *lua.LState) int {
 	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, true)
 }
 
+// @group leaderboards
+// @summary Get an owner's rank in the current period of a leaderboard, and the percentage of ranked records at or above it. Only available if rank cache is not disabled for the leaderboard.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to get the percentile for.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return percentile(table) The owner's rank, the number of ranked records and the percentile. Rank is 0 if the owner has no ranked record.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRecordPercentile(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	ownerID, err := uuid.FromString(l.CheckString(2))
+	if err != nil {
+		l.ArgError(2, "expects owner ID to be a valid identifier")
+		return 0
+	}
+
+	expiry := l.OptInt(3, 0)
+	if expiry < 0 {
+		l.ArgError(3, "expiry should be time since epoch in seconds and has to be a positive integer")
+		return 0
+	}
+
+	percentile, err := LeaderboardRecordPercentileGet(n.leaderboardCache, n.rankCache, id, ownerID, int64(expiry))
+	if err != nil {
+		l.RaiseError("error getting leaderboard record percentile: %v", err.Error())
+		return 0
+	}
+
+	percentileTable := l.CreateTable(0, 6)
+	percentileTable.RawSetString("leaderboard_id", lua.LString(percentile.LeaderboardId))
+	percentileTable.RawSetString("owner_id", lua.LString(percentile.OwnerId))
+	percentileTable.RawSetString("rank", lua.LNumber(percentile.Rank))
+	percentileTable.RawSetString("count", lua.LNumber(percentile.Count))
+	percentileTable.RawSetString("percentile", lua.LNumber(percentile.Percentile))
+	percentileTable.RawSetString("expiry_time", lua.LNumber(percentile.ExpiryTime))
+	l.Push(percentileTable)
+
+	return 1
+}
+
+// @group leaderboards
+// @summary List an owner's final ranks in past periods of a leaderboard, most recent first.
+// @param id(type=string) The ID of the leaderboard.
+// @param ownerId(type=string) The owner to list rank history for.
+// @param limit(type=number, optional=true, default=10) Return only the required number of past periods denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return history(table) The owner's rank, score and percentile in each past period.
+// @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any).
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRankHistoryList(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	ownerID, err := uuid.FromString(l.CheckString(2))
+	if err != nil {
+		l.ArgError(2, "expects owner ID to be a valid identifier")
+		return 0
+	}
+
+	limit := l.OptInt(3, 10)
+	if limit < 1 || limit > 100 {
+		l.ArgError(3, "limit must be 1-100")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+
+	history, nextCursor, err := LeaderboardRankHistoryList(l.Context(), n.logger, n.db, n.leaderboardCache, id, ownerID, limit, cursor)
+	if err != nil {
+		l.RaiseError("error listing leaderboard rank history: %v", err.Error())
+		return 0
+	}
+
+	historyTable := l.CreateTable(len(history), 0)
+	for i, h := range history {
+		hTable := l.CreateTable(0, 10)
+		hTable.RawSetString("leaderboard_id", lua.LString(h.LeaderboardId))
+		hTable.RawSetString("owner_id", lua.LString(h.OwnerId))
+		hTable.RawSetString("username", lua.LString(h.Username))
+		hTable.RawSetString("score", lua.LNumber(h.Score))
+		hTable.RawSetString("subscore", lua.LNumber(h.Subscore))
+		hTable.RawSetString("num_score", lua.LNumber(h.NumScore))
+		hTable.RawSetString("rank", lua.LNumber(h.Rank))
+		hTable.RawSetString("count", lua.LNumber(h.Count))
+		hTable.RawSetString("percentile", lua.LNumber(h.Percentile))
+		hTable.RawSetString("expiry_time", lua.LNumber(h.ExpiryTime))
+		historyTable.RawSetInt(i+1, hTable)
+	}
+	l.Push(historyTable)
+
+	if nextCursor != "" {
+		l.Push(lua.LString(nextCursor))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+// @group leaderboards
+// @summary List records owned by a user and their friends, with ranks relative to each other rather than the whole leaderboard.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param userId(type=string) The user whose own and friends' records are listed.
+// @param limit(type=number, optional=true, default=10) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(table) A page of leaderboard records ranked within the subset.
+// @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any).
+// @return prevCursor(string) Always nil, subset listings only page forward.
+// @return rankCount(number) The number of records in the subset.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRecordsListFriends(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	userID, err := uuid.FromString(l.CheckString(2))
+	if err != nil {
+		l.ArgError(2, "expects user ID to be a valid identifier")
+		return 0
+	}
+
+	limit := l.OptInt(3, 10)
+	if limit < 1 || limit > 100 {
+		l.ArgError(3, "limit must be 1-100")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+
+	expiry := l.OptInt(5, 0)
+	if expiry < 0 {
+		l.ArgError(5, "expiry should be time since epoch in seconds and has to be a positive integer")
+		return 0
+	}
+
+	records, err := LeaderboardRecordsListFriends(l.Context(), n.logger, n.db, n.leaderboardCache, id, userID, limit, cursor, int64(expiry))
+	if err != nil {
+		l.RaiseError("error listing leaderboard records: %v", err.Error())
+		return 0
+	}
+
+	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, true)
+}
+
+// @group leaderboards
+// @summary List records owned by members of a group, with ranks relative to each other rather than the whole leaderboard.
+// @param id(type=string) The ID of the leaderboard to list records for.
+// @param groupId(type=string) The group whose members' records are listed.
+// @param limit(type=number, optional=true, default=10) Return only the required number of leaderboard records denoted by this limit value. Between 1-100.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param expiry(type=number, optional=true, default=0) Time since epoch in seconds. Must be equal or greater than 0, 0 is the current period.
+// @return records(table) A page of leaderboard records ranked within the subset.
+// @return nextCursor(string) An optional next page cursor that can be used to retrieve the next page of records (if any).
+// @return prevCursor(string) Always nil, subset listings only page forward.
+// @return rankCount(number) The number of records in the subset.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) leaderboardRecordsListGroup(l *lua.LState) int {
+	id := l.CheckString(1)
+	if id == "" {
+		l.ArgError(1, "expects a leaderboard ID string")
+		return 0
+	}
+
+	groupID, err := uuid.FromString(l.CheckString(2))
+	if err != nil {
+		l.ArgError(2, "expects group ID to be a valid identifier")
+		return 0
+	}
+
+	limit := l.OptInt(3, 10)
+	if limit < 1 || limit > 100 {
+		l.ArgError(3, "limit must be 1-100")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+
+	expiry := l.OptInt(5, 0)
+	if expiry < 0 {
+		l.ArgError(5, "expiry should be time since epoch in seconds and has to be a positive integer")
+		return 0
+	}
+
+	records, err := LeaderboardRecordsListGroup(l.Context(), n.logger, n.db, n.leaderboardCache, id, groupID, limit, cursor, int64(expiry))
+	if err != nil {
+		l.RaiseError("error listing leaderboard records: %v", err.Error())
+		return 0
+	}
+
+	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, true)
+}
+
 // @group leaderboards
 // @summary Remove an owner's record from a leaderboard, if one exists.
 // @param id(type=string) The unique identifier for the leaderboard to delete from.
@@ -8009,7 +8349,7 @@ func (n *RuntimeLuaNakamaModule) tournamentRecordsList(l *lua.LState) int {
 		return 0
 	}
 
-	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, false)
+	return leaderboardRecordsToLua(l, records.Records, records.OwnerRecords, records.PrevCursor, records.NextCursor, records.RankCount, true)
 }
 
 func leaderboardRecordsToLua(l *lua.LState, records, ownerRecords []*api.LeaderboardRecord, prevCursor, nextCursor string, rankCount int64, skipOwnerRecords bool) int {
@@ -9348,7 +9688,7 @@ func (n *RuntimeLuaNakamaModule) accountDeleteId(l *lua.LState) int {
 
 	recorded := l.OptBool(2, false)
 
-	if err := DeleteAccount(l.Context(), n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, userID, recorded); err != nil {
+	if err := DeleteAccount(l.Context(), n.logger, n.db, n.config, n.leaderboardCache, n.rankCache, n.sessionRegistry, n.sessionCache, n.tracker, n.storageIndex, userID, recorded); err != nil {
 		l.RaiseError("error while trying to delete account: %v", err.Error())
 	}
 
@@ -9831,7 +10171,7 @@ func (n *RuntimeLuaNakamaModule) channelMessageSend(l *lua.LState) int {
 		return 0
 	}
 
-	ack, err := ChannelMessageSend(l.Context(), n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, contentStr, senderID, senderUsername, persist)
+	ack, err := ChannelMessageSend(l.Context(), n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, contentStr, senderID, senderUsername, persist)
 	if err != nil {
 		l.RaiseError("failed to send channel message: %v", err.Error())
 		return 0
@@ -9906,7 +10246,7 @@ func (n *RuntimeLuaNakamaModule) channelMessageUpdate(l *lua.LState) int {
 		return 0
 	}
 
-	ack, err := ChannelMessageUpdate(l.Context(), n.logger, n.db, n.router, channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderID, senderUsername, persist)
+	ack, err := ChannelMessageUpdate(l.Context(), n.logger, n.db, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, messageId, contentStr, senderID, senderUsername, persist)
 	if err != nil {
 		l.RaiseError("failed to send channel message: %v", err.Error())
 		return 0
@@ -10056,6 +10396,287 @@ func (n *RuntimeLuaNakamaModule) channelMessagesList(l *lua.LState) int {
 	return 3
 }
 
+// @group chat
+// @summary Reply to a persisted chat message, starting or continuing its thread. The reply is persisted, and sent on the channel with the chat reply code and content holding 'parent_id' and the reply 'content'.
+// @param channelId(type=string) The ID of the channel to send the reply on.
+// @param parentId(type=string) The ID of the chat message to reply to.
+// @param content(type=table) Reply content.
+// @param senderId(type=string, optional=true) The UUID for the sender of this reply, who must be a member of the group, a participant of the direct message or have joined the room. If left empty, it will be assumed that it is a system message.
+// @param senderUsername(type=string, optional=true) The username of the user to send this reply as. If left empty, it will be assumed that it is a system message.
+// @return ack(table) Reply sent ack containing the following variables: 'channelId', 'messageId', 'code', 'username', 'createTime', 'updateTime', and 'persistent'.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelMessageReply(l *lua.LState) int {
+	channelId := l.CheckString(1)
+
+	parentId := l.CheckString(2)
+	if _, err := uuid.FromString(parentId); err != nil {
+		l.ArgError(2, errChannelMessageIdInvalid.Error())
+		return 0
+	}
+
+	content := l.OptTable(3, nil)
+	contentStr := "{}"
+	if content != nil {
+		contentMap := RuntimeLuaConvertLuaTable(content)
+		contentBytes, err := json.Marshal(contentMap)
+		if err != nil {
+			l.RaiseError("error encoding metadata: %v", err.Error())
+			return 0
+		}
+		if len(contentBytes) == 0 || contentBytes[0] != byteBracket {
+			l.ArgError(3, "expects message content to be a valid JSON object")
+			return 0
+		}
+		contentStr = string(contentBytes)
+	}
+
+	s := l.OptString(4, "")
+	senderID := uuid.Nil.String()
+	if s != "" {
+		suid, err := uuid.FromString(s)
+		if err != nil {
+			l.ArgError(4, "expects sender id to either be not set, empty string or a valid UUID")
+			return 0
+		}
+		senderID = suid.String()
+	}
+
+	senderUsername := l.OptString(5, "")
+
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	ack, err := ChannelMessageReply(l.Context(), n.logger, n.db, n.tracker, n.router, n.config.GetChat().ContentFilter(), channelIdToStreamResult.Stream, channelId, parentId, contentStr, senderID, senderUsername)
+	if err != nil {
+		l.RaiseError("failed to send channel message reply: %v", err.Error())
+		return 0
+	}
+
+	ackTable := l.CreateTable(0, 7)
+	ackTable.RawSetString("channelId", lua.LString(ack.ChannelId))
+	ackTable.RawSetString("messageId", lua.LString(ack.MessageId))
+	ackTable.RawSetString("code", lua.LNumber(ack.Code.Value))
+	ackTable.RawSetString("username", lua.LString(ack.Username))
+	ackTable.RawSetString("createTime", lua.LNumber(ack.CreateTime.Seconds))
+	ackTable.RawSetString("updateTime", lua.LNumber(ack.UpdateTime.Seconds))
+	ackTable.RawSetString("persistent", lua.LBool(ack.Persistent.Value))
+
+	l.Push(ackTable)
+	return 1
+}
+
+// @group chat
+// @summary List the replies in a chat message's thread, oldest first.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param parentId(type=string) The ID of the message to list replies to.
+// @param limit(type=number, optional=true, default=100) The number of replies to return per page.
+// @param cursor(type=string, optional=true, default="") Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. An empty string skips the permission check.
+// @return messages(table) Replies to the specified message.
+// @return nextCursor(string) Cursor for the next page of replies, if any.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelMessageThreadList(l *lua.LState) int {
+	channelId := l.CheckString(1)
+
+	parentId := l.CheckString(2)
+	if _, err := uuid.FromString(parentId); err != nil {
+		l.ArgError(2, errChannelMessageIdInvalid.Error())
+		return 0
+	}
+
+	limit := l.OptInt(3, 100)
+	if limit < 1 || limit > 100 {
+		l.ArgError(3, "limit must be 1-100")
+		return 0
+	}
+
+	cursor := l.OptString(4, "")
+
+	caller := uuid.Nil
+	if callerStr := l.OptString(5, ""); callerStr != "" {
+		var err error
+		if caller, err = uuid.FromString(callerStr); err != nil {
+			l.ArgError(5, "expects caller id to either be not set, empty string or a valid UUID")
+			return 0
+		}
+	}
+
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	list, err := ChannelMessageThreadList(l.Context(), n.logger, n.db, caller, channelIdToStreamResult.Stream, channelId, parentId, limit, cursor)
+	if err != nil {
+		l.RaiseError("failed to list channel message replies: %v", err.Error())
+		return 0
+	}
+
+	messagesTable := l.CreateTable(len(list.Messages), 0)
+	for i, message := range list.Messages {
+		messageTable := l.CreateTable(0, 13)
+
+		messageTable.RawSetString("channelId", lua.LString(message.ChannelId))
+		messageTable.RawSetString("messageId", lua.LString(message.MessageId))
+		messageTable.RawSetString("code", lua.LNumber(message.Code.Value))
+		messageTable.RawSetString("senderId", lua.LString(message.SenderId))
+		messageTable.RawSetString("username", lua.LString(message.Username))
+		messageTable.RawSetString("content", lua.LString(message.Content))
+		messageTable.RawSetString("createTime", lua.LNumber(message.CreateTime.Seconds))
+		messageTable.RawSetString("updateTime", lua.LNumber(message.UpdateTime.Seconds))
+		messageTable.RawSetString("persistent", lua.LBool(message.Persistent.Value))
+		messageTable.RawSetString("roomName", lua.LString(message.RoomName))
+		messageTable.RawSetString("groupId", lua.LString(message.GroupId))
+		messageTable.RawSetString("userIdOne", lua.LString(message.UserIdOne))
+		messageTable.RawSetString("userIdTwo", lua.LString(message.UserIdTwo))
+
+		messagesTable.RawSetInt(i+1, messageTable)
+	}
+
+	l.Push(messagesTable)
+
+	if list.NextCursor != "" {
+		l.Push(lua.LString(list.NextCursor))
+	} else {
+		l.Push(lua.LNil)
+	}
+
+	return 2
+}
+
+// @group chat
+// @summary Add a user's reaction to a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message to react to.
+// @param emoji(type=string) The reaction, 1-64 bytes with no whitespace.
+// @param userId(type=string) The ID of the user reacting, who must be allowed to send messages to the channel.
+// @param username(type=string, optional=true) The username of the user reacting.
+// @return reaction(table) The updated reaction count on the message, containing 'emoji', 'count' and 'reacted'.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelMessageReactionAdd(l *lua.LState) int {
+	return n.channelMessageReactionChange(l, ChannelMessageReactionAdd)
+}
+
+// @group chat
+// @summary Remove a user's reaction from a persisted chat message. The updated reaction count is sent on the channel with the chat reaction code.
+// @param channelId(type=string) The ID of the channel the message is in.
+// @param messageId(type=string) The ID of the message the reaction is on.
+// @param emoji(type=string) The reaction to remove.
+// @param userId(type=string) The ID of the user who reacted, who must be allowed to send messages to the channel.
+// @param username(type=string, optional=true) The username of the user who reacted.
+// @return reaction(table) The updated reaction count on the message, containing 'emoji', 'count' and 'reacted'.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelMessageReactionRemove(l *lua.LState) int {
+	return n.channelMessageReactionChange(l, ChannelMessageReactionRemove)
+}
+
+func (n *RuntimeLuaNakamaModule) channelMessageReactionChange(l *lua.LState, fn func(context.Context, *zap.Logger, *sql.DB, Tracker, MessageRouter, PresenceStream, string, string, string, uuid.UUID, string) (*ChannelMessageReaction, error)) int {
+	channelId := l.CheckString(1)
+	messageId := l.CheckString(2)
+	emoji := l.CheckString(3)
+
+	userID, err := uuid.FromString(l.CheckString(4))
+	if err != nil {
+		l.ArgError(4, "expects user id to be a valid UUID")
+		return 0
+	}
+
+	username := l.OptString(5, "")
+
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	reaction, err := fn(l.Context(), n.logger, n.db, n.tracker, n.router, channelIdToStreamResult.Stream, channelId, messageId, emoji, userID, username)
+	if err != nil {
+		l.RaiseError("failed to change channel message reaction: %v", err.Error())
+		return 0
+	}
+
+	l.Push(channelMessageReactionToLua(l, reaction))
+	return 1
+}
+
+// @group chat
+// @summary List the aggregated reaction counts on a set of chat messages, most popular first.
+// @param channelId(type=string) The ID of the channel the messages are in.
+// @param messageIds(type=table) The IDs of the messages to list reactions for, at most 100.
+// @param callerId(type=string, optional=true, default="") User ID of the caller, who must be allowed to read the channel. Reactions added by the caller are marked. An empty string skips the permission check.
+// @return reactions(table) Reaction counts keyed by message ID, each containing 'emoji', 'count' and 'reacted'. Messages without reactions are omitted.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) channelMessageReactionsList(l *lua.LState) int {
+	channelId := l.CheckString(1)
+
+	messageIdsTable := l.CheckTable(2)
+	messageIds := make([]string, 0, messageIdsTable.Len())
+	var conversionError bool
+	messageIdsTable.ForEach(func(k lua.LValue, v lua.LValue) {
+		if conversionError {
+			return
+		}
+		if v.Type() != lua.LTString {
+			l.ArgError(2, "expects message ids to be a table of strings")
+			conversionError = true
+			return
+		}
+		messageIds = append(messageIds, v.String())
+	})
+	if conversionError {
+		return 0
+	}
+	if len(messageIds) > 100 {
+		l.ArgError(2, "expects at most 100 message ids")
+		return 0
+	}
+
+	caller := uuid.Nil
+	if callerStr := l.OptString(3, ""); callerStr != "" {
+		var err error
+		if caller, err = uuid.FromString(callerStr); err != nil {
+			l.ArgError(3, "expects caller id to either be not set, empty string or a valid UUID")
+			return 0
+		}
+	}
+
+	channelIdToStreamResult, err := ChannelIdToStream(channelId)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	reactions, err := ChannelMessageReactionsList(l.Context(), n.logger, n.db, caller, channelIdToStreamResult.Stream, messageIds)
+	if err != nil {
+		l.RaiseError("failed to list channel message reactions: %v", err.Error())
+		return 0
+	}
+
+	reactionsTable := l.CreateTable(0, len(reactions))
+	for messageId, messageReactions := range reactions {
+		messageTable := l.CreateTable(len(messageReactions), 0)
+		for i, reaction := range messageReactions {
+			messageTable.RawSetInt(i+1, channelMessageReactionToLua(l, reaction))
+		}
+		reactionsTable.RawSetString(messageId, messageTable)
+	}
+
+	l.Push(reactionsTable)
+	return 1
+}
+
+func channelMessageReactionToLua(l *lua.LState, reaction *ChannelMessageReaction) *lua.LTable {
+	reactionTable := l.CreateTable(0, 3)
+	reactionTable.RawSetString("emoji", lua.LString(reaction.Emoji))
+	reactionTable.RawSetString("count", lua.LNumber(reaction.Count))
+	reactionTable.RawSetString("reacted", lua.LBool(reaction.Reacted))
+	return reactionTable
+}
+
 // @group chat
 // @summary Create a channel identifier to be used in other runtime calls. Does not create a channel.
 // @param senderId(type=string) UserID of the message sender (when applicable). An empty string defaults to the system user.
@@ -10109,12 +10730,93 @@ func (n *RuntimeLuaNakamaModule) channelIdBuild(l *lua.LState) int {
 // @return objects(table) A list of storage objects.
 // @return error(error) An optional error value if an error occurred.
 func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
+	idxName, queryString, limit, callerID, ok := storageIndexListLuaArgs(l)
+	if !ok {
+		return 0
+	}
+
+	objectList, _, _, err := n.storageIndex.List(l.Context(), callerID, idxName, queryString, limit, nil, "")
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	lv := storageIndexListToLua(l, objectList)
+	if lv == nil {
+		return 0
+	}
+	l.Push(lv)
+
+	return 1
+}
+
+// @group storage
+// @summary List a page of storage index entries, optionally ordered, along with a cursor for the next page and the total number of matching entries.
+// @param indexName(type=string) Name of the index to list entries from.
+// @param queryString(type=string) Query to filter index entries.
+// @param limit(type=int) Maximum number of results to be returned.
+// @param callerId(type=string, optional=true) User ID of the caller, will apply permissions checks of the user. If empty defaults to system user and permission checks are bypassed.
+// @param order(type=table, optional=true) The field names to sort by, prefixed with "-" for descending order. Sorted by score if empty. Ordering by value fields requires a sortable index.
+// @param cursor(type=string, optional=true) Pagination cursor from previous result. Don't set to start fetching from the beginning.
+// @return objects(table) A list of storage objects.
+// @return cursor(string) A cursor for the next page, or nil if there are no more results.
+// @return total(number) The number of index entries matching the query.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) storageIndexListPage(l *lua.LState) int {
+	idxName, queryString, limit, callerID, ok := storageIndexListLuaArgs(l)
+	if !ok {
+		return 0
+	}
+
+	var order []string
+	if orderTable := l.OptTable(5, nil); orderTable != nil {
+		order = make([]string, 0, orderTable.Len())
+		var conversionError bool
+		orderTable.ForEach(func(k lua.LValue, v lua.LValue) {
+			if conversionError {
+				return
+			}
+			if v.Type() != lua.LTString {
+				l.ArgError(5, "expects order to be a table of strings")
+				conversionError = true
+				return
+			}
+			order = append(order, v.String())
+		})
+		if conversionError {
+			return 0
+		}
+	}
+	cursor := l.OptString(6, "")
+
+	objectList, newCursor, total, err := n.storageIndex.List(l.Context(), callerID, idxName, queryString, limit, order, cursor)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	lv := storageIndexListToLua(l, objectList)
+	if lv == nil {
+		return 0
+	}
+	l.Push(lv)
+	if newCursor != "" {
+		l.Push(lua.LString(newCursor))
+	} else {
+		l.Push(lua.LNil)
+	}
+	l.Push(lua.LNumber(total))
+
+	return 3
+}
+
+func storageIndexListLuaArgs(l *lua.LState) (string, string, int, uuid.UUID, bool) {
 	idxName := l.CheckString(1)
 	queryString := l.CheckString(2)
 	limit := l.OptInt(3, 100)
 	if limit < 1 || limit > 10_000 {
 		l.ArgError(3, "invalid limit: expects value 1-10000")
-		return 0
+		return "", "", 0, uuid.Nil, false
 	}
 	callerID := uuid.Nil
 	callerIDStr := l.OptString(4, "")
@@ -10122,17 +10824,15 @@ func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
 		cid, err := uuid.FromString(callerIDStr)
 		if err != nil {
 			l.ArgError(4, "expects caller ID to be empty or a valid identifier")
-			return 0
+			return "", "", 0, uuid.Nil, false
 		}
 		callerID = cid
 	}
 
-	objectList, err := n.storageIndex.List(l.Context(), callerID, idxName, queryString, limit)
-	if err != nil {
-		l.RaiseError(err.Error())
-		return 0
-	}
+	return idxName, queryString, limit, callerID, true
+}
 
+func storageIndexListToLua(l *lua.LState, objectList *api.StorageObjects) *lua.LTable {
 	lv := l.CreateTable(len(objectList.GetObjects()), 0)
 	for i, v := range objectList.GetObjects() {
 		vt := l.CreateTable(0, 9)
@@ -10150,17 +10850,59 @@ func (n *RuntimeLuaNakamaModule) storageIndexList(l *lua.LState) int {
 		vt.RawSetString("update_time", lua.LNumber(v.UpdateTime.Seconds))
 
 		valueMap := make(map[string]interface{})
-		err = json.Unmarshal([]byte(v.Value), &valueMap)
-		if err != nil {
+		if err := json.Unmarshal([]byte(v.Value), &valueMap); err != nil {
 			l.RaiseError(fmt.Sprintf("failed to convert value to json: %s", err.Error()))
-			return 0
+			return nil
 		}
 		valueTable := RuntimeLuaConvertMap(l, valueMap)
 		vt.RawSetString("value", valueTable)
 
 		lv.RawSetInt(i+1, vt)
 	}
-	l.Push(lv)
+
+	return lv
+}
+
+// @group storage
+// @summary Compute aggregations, such as counts, terms facets, numeric range buckets and min/max/avg, over storage index entries.
+// @param indexName(type=string) Name of the index to aggregate entries from.
+// @param queryString(type=string) Query to filter index entries. Aggregates all entries if empty.
+// @param aggregations(type=table) A list of aggregations, each with a name, type (count, terms, range, min, max, avg or sum), and optionally a field, size and ranges.
+// @return result(table) The number of matching entries and the result of each aggregation by name.
+// @return error(error) An optional error value if an error occurred.
+func (n *RuntimeLuaNakamaModule) storageIndexAggregate(l *lua.LState) int {
+	idxName := l.CheckString(1)
+	queryString := l.OptString(2, "")
+	aggsTable := l.CheckTable(3)
+
+	aggsBytes, err := json.Marshal(RuntimeLuaConvertLuaValue(aggsTable))
+	if err != nil {
+		l.ArgError(3, fmt.Sprintf("failed to convert aggregations: %s", err.Error()))
+		return 0
+	}
+	var aggs []*StorageIndexAggregation
+	if err := json.Unmarshal(aggsBytes, &aggs); err != nil {
+		l.ArgError(3, "expects aggregations to be a list of tables")
+		return 0
+	}
+
+	result, err := n.storageIndex.Aggregate(l.Context(), idxName, queryString, aggs)
+	if err != nil {
+		l.RaiseError(err.Error())
+		return 0
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		l.RaiseError(fmt.Sprintf("failed to convert result: %s", err.Error()))
+		return 0
+	}
+	resultMap := make(map[string]interface{})
+	if err := json.Unmarshal(resultBytes, &resultMap); err != nil {
+		l.RaiseError(fmt.Sprintf("failed to convert result: %s", err.Error()))
+		return 0
+	}
+	l.Push(RuntimeLuaConvertMap(l, resultMap))
 
 	return 1
 }
@@ -262,6 +262,8 @@ func (s *sessionWS) LoginSession(userID string, username string, evrID evr.EvrId
 		},
 	}, s.userID)
 
+	s.updateOnlineTime()
+
 	return nil
 }
 
@@ -433,6 +435,9 @@ func (s *sessionWS) Consume() {
 	if fn := s.runtime.EventSessionStart(); fn != nil {
 		fn(s.userID.String(), s.username.Load(), s.vars, s.expiry, s.id.String(), s.clientIP, s.clientPort, s.lang, time.Now().UTC().Unix())
 	}
+	if s.format != SessionFormatEvr { // Evr sessions are authenticated in-band.
+		s.updateOnlineTime()
+	}
 
 	s.conn.SetReadLimit(s.config.GetSocket().MaxMessageSizeBytes)
 	if err := s.conn.SetReadDeadline(time.Now().Add(s.pongWaitDuration)); err != nil {
@@ -742,6 +747,14 @@ func (s *sessionWS) SendBytes(payload []byte, reliable bool) error {
 	}
 }
 
+// updateOnlineTime records the session's user as online now. The write is batched with other sessions'.
+func (s *sessionWS) updateOnlineTime() {
+	if s.pipeline == nil || s.pipeline.onlineTimeWriter == nil || s.userID == uuid.Nil {
+		return
+	}
+	s.pipeline.onlineTimeWriter.Record(s.userID)
+}
+
 func (s *sessionWS) CloseLock() {
 	s.closeMu.Lock()
 }
@@ -788,6 +801,7 @@ func (s *sessionWS) Close(msg string, reason runtime.PresenceReason, envelopes .
 	if isDebug {
 		// s.logger.Info("Cleaned up closed connection session registry")
 	}
+	s.updateOnlineTime()
 
 	// Clean up internals.
 	s.pingTimer.Stop()
@@ -0,0 +1,391 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+const (
+	StorageChangeCreate = "create"
+	StorageChangeUpdate = "update"
+	StorageChangeDelete = "delete"
+
+	storageChangeFeedDefaultQueueSize = 10_000
+	storageChangeFeedMaxBatchSize     = 100
+	storageChangeFeedWebhookTimeout   = 10 * time.Second
+	storageChangeFeedWebhookBackoff   = 500 * time.Millisecond
+)
+
+// StorageChangeEvent describes a committed storage object create, update or delete.
+type StorageChangeEvent struct {
+	Type       string    `json:"type"`
+	Collection string    `json:"collection"`
+	Key        string    `json:"key"`
+	UserID     string    `json:"user_id"`
+	Version    string    `json:"version,omitempty"` // Empty for deletes.
+	Value      string    `json:"value,omitempty"`   // Empty for deletes.
+	CreateTime time.Time `json:"create_time,omitempty"`
+	UpdateTime time.Time `json:"update_time,omitempty"`
+	Time       time.Time `json:"time"` // When the change was published.
+}
+
+// StorageChangeFunction receives storage change events. It is called from the feed's delivery goroutine, in the order
+// the changes were published, and should return quickly. Changes committed concurrently may be published in a different
+// order than they were committed, so compare versions or update times where ordering matters.
+type StorageChangeFunction func(ctx context.Context, event *StorageChangeEvent)
+
+type storageChangeSubscription struct {
+	collection string
+	keyPrefix  string
+	fn         StorageChangeFunction
+}
+
+func (s *storageChangeSubscription) matches(event *StorageChangeEvent) bool {
+	return (s.collection == "" || s.collection == event.Collection) && strings.HasPrefix(event.Key, s.keyPrefix)
+}
+
+// storageChangeSink is an outbound destination for storage change events.
+type storageChangeSink interface {
+	Send(events []*StorageChangeEvent)
+	Close()
+}
+
+// StorageChangeFeed delivers storage change events, after commit, to in-process subscribers and the configured sinks.
+type StorageChangeFeed struct {
+	sync.RWMutex
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+	logger      *zap.Logger
+	config      *StorageConfig
+
+	queue         chan *StorageChangeEvent
+	subscriptions map[uint64]*storageChangeSubscription
+	nextID        uint64
+	sinks         []storageChangeSink
+	done          chan struct{}
+}
+
+func NewStorageChangeFeed(logger *zap.Logger, config *StorageConfig) (*StorageChangeFeed, error) {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	queueSize := config.ChangeFeedQueueSize
+	if queueSize < 1 {
+		queueSize = storageChangeFeedDefaultQueueSize
+	}
+
+	f := &StorageChangeFeed{
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+		logger:      logger,
+		config:      config,
+
+		queue:         make(chan *StorageChangeEvent, queueSize),
+		subscriptions: make(map[uint64]*storageChangeSubscription),
+		done:          make(chan struct{}),
+	}
+
+	if config.ChangeFeedFile != "" {
+		sink, err := newStorageChangeFileSink(config.ChangeFeedFile)
+		if err != nil {
+			ctxCancelFn()
+			return nil, fmt.Errorf("failed to open storage change feed file: %w", err)
+		}
+		f.sinks = append(f.sinks, sink)
+	}
+	if config.ChangeFeedWebhookURL != "" {
+		f.sinks = append(f.sinks, newStorageChangeWebhookSink(ctx, logger, config.ChangeFeedWebhookURL, config.ChangeFeedWebhookRetries, queueSize))
+	}
+
+	go f.run()
+
+	return f, nil
+}
+
+// Subscribe registers a function to receive changes in the collection, or all collections if empty, for keys with the
+// prefix. It returns a function that cancels the subscription.
+func (f *StorageChangeFeed) Subscribe(collection, keyPrefix string, fn StorageChangeFunction) func() {
+	f.Lock()
+	id := f.nextID
+	f.nextID++
+	f.subscriptions[id] = &storageChangeSubscription{
+		collection: collection,
+		keyPrefix:  keyPrefix,
+		fn:         fn,
+	}
+	f.Unlock()
+
+	return func() {
+		f.Lock()
+		delete(f.subscriptions, id)
+		f.Unlock()
+	}
+}
+
+// PublishWrites publishes the committed storage object writes.
+func (f *StorageChangeFeed) PublishWrites(objects []*api.StorageObject) {
+	now := time.Now()
+	for _, o := range objects {
+		event := &StorageChangeEvent{
+			Type:       StorageChangeUpdate,
+			Collection: o.Collection,
+			Key:        o.Key,
+			UserID:     o.UserId,
+			Version:    o.Version,
+			Value:      o.Value,
+			CreateTime: o.CreateTime.AsTime(),
+			UpdateTime: o.UpdateTime.AsTime(),
+			Time:       now,
+		}
+		// Inserts set the create and update times to the same value.
+		if event.CreateTime.Equal(event.UpdateTime) {
+			event.Type = StorageChangeCreate
+		}
+		f.publish(event)
+	}
+}
+
+// PublishDeletes publishes the committed storage object deletes. Only deletes that removed an object should be given.
+func (f *StorageChangeFeed) PublishDeletes(ops StorageOpDeletes) {
+	now := time.Now()
+	for _, op := range ops {
+		f.publish(&StorageChangeEvent{
+			Type:       StorageChangeDelete,
+			Collection: op.ObjectID.Collection,
+			Key:        op.ObjectID.Key,
+			UserID:     op.OwnerID,
+			Time:       now,
+		})
+	}
+}
+
+func (f *StorageChangeFeed) publish(event *StorageChangeEvent) {
+	select {
+	case <-f.ctx.Done():
+	case f.queue <- event:
+	default:
+		f.logger.Warn("Storage change feed queue full, dropping event", zap.String("collection", event.Collection), zap.String("key", event.Key), zap.String("user_id", event.UserID))
+	}
+}
+
+func (f *StorageChangeFeed) run() {
+	defer close(f.done)
+
+	batch := make([]*StorageChangeEvent, 0, storageChangeFeedMaxBatchSize)
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case event := <-f.queue:
+			batch = append(batch[:0], event)
+		}
+		// Deliver whatever else is already waiting as part of the same batch.
+	collect:
+		for len(batch) < storageChangeFeedMaxBatchSize {
+			select {
+			case event := <-f.queue:
+				batch = append(batch, event)
+			default:
+				break collect
+			}
+		}
+
+		f.RLock()
+		subscriptions := make([]*storageChangeSubscription, 0, len(f.subscriptions))
+		for _, s := range f.subscriptions {
+			subscriptions = append(subscriptions, s)
+		}
+		f.RUnlock()
+
+		for _, event := range batch {
+			for _, s := range subscriptions {
+				if s.matches(event) {
+					f.deliver(s, event)
+				}
+			}
+		}
+
+		if len(f.sinks) == 0 {
+			continue
+		}
+		sinkBatch := batch
+		if len(f.config.ChangeFeedCollections) != 0 {
+			sinkBatch = make([]*StorageChangeEvent, 0, len(batch))
+			for _, event := range batch {
+				if slices.Contains(f.config.ChangeFeedCollections, event.Collection) {
+					sinkBatch = append(sinkBatch, event)
+				}
+			}
+			if len(sinkBatch) == 0 {
+				continue
+			}
+		}
+		for _, sink := range f.sinks {
+			sink.Send(sinkBatch)
+		}
+	}
+}
+
+func (f *StorageChangeFeed) deliver(s *storageChangeSubscription, event *StorageChangeEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.logger.Error("Storage change subscriber panicked", zap.Any("recover", r), zap.String("collection", event.Collection), zap.String("key", event.Key))
+		}
+	}()
+	s.fn(f.ctx, event)
+}
+
+// Stop ends delivery and closes the sinks. Events still queued are not delivered.
+func (f *StorageChangeFeed) Stop() {
+	f.ctxCancelFn()
+	<-f.done
+	for _, sink := range f.sinks {
+		sink.Close()
+	}
+}
+
+// storageChangeFileSink appends events to a local file as JSON lines.
+type storageChangeFileSink struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+func newStorageChangeFileSink(path string) (*storageChangeFileSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &storageChangeFileSink{
+		file:    file,
+		encoder: json.NewEncoder(file),
+	}, nil
+}
+
+func (s *storageChangeFileSink) Send(events []*StorageChangeEvent) {
+	for _, event := range events {
+		_ = s.encoder.Encode(event)
+	}
+}
+
+func (s *storageChangeFileSink) Close() {
+	_ = s.file.Close()
+}
+
+// storageChangeWebhookSink POSTs batches of events as a JSON array, retrying failed requests with backoff. Batches are
+// sent from a separate goroutine so a slow endpoint does not hold up in-process subscribers.
+type storageChangeWebhookSink struct {
+	ctx     context.Context
+	logger  *zap.Logger
+	url     string
+	retries int
+	client  *http.Client
+	queue   chan []*StorageChangeEvent
+	done    chan struct{}
+}
+
+func newStorageChangeWebhookSink(ctx context.Context, logger *zap.Logger, url string, retries, queueSize int) *storageChangeWebhookSink {
+	s := &storageChangeWebhookSink{
+		ctx:     ctx,
+		logger:  logger,
+		url:     url,
+		retries: retries,
+		client:  &http.Client{Timeout: storageChangeFeedWebhookTimeout},
+		queue:   make(chan []*StorageChangeEvent, max(1, queueSize/storageChangeFeedMaxBatchSize)),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *storageChangeWebhookSink) Send(events []*StorageChangeEvent) {
+	select {
+	case s.queue <- slices.Clone(events):
+	default:
+		s.logger.Warn("Storage change feed webhook queue full, dropping events", zap.Int("count", len(events)))
+	}
+}
+
+func (s *storageChangeWebhookSink) run() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case events := <-s.queue:
+			body, err := json.Marshal(events)
+			if err != nil {
+				s.logger.Error("Failed to encode storage change events", zap.Error(err))
+				continue
+			}
+			if err := s.post(body); err != nil {
+				s.logger.Error("Failed to send storage change events, dropping", zap.Int("count", len(events)), zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *storageChangeWebhookSink) post(body []byte) error {
+	backoff := storageChangeFeedWebhookBackoff
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-s.ctx.Done():
+				return s.ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var req *http.Request
+		req, err = http.NewRequestWithContext(s.ctx, http.MethodPost, s.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		var resp *http.Response
+		resp, err = s.client.Do(req)
+		if err != nil {
+			continue
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			// The request will not succeed on retry.
+			return err
+		}
+	}
+	return err
+}
+
+func (s *storageChangeWebhookSink) Close() {
+	<-s.done
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/heroiclabs/nakama-common/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStorageChangeFeed_Subscribe(t *testing.T) {
+	ctx := context.Background()
+
+	filePath := filepath.Join(t.TempDir(), "changes.jsonl")
+	webhookEvents := make(chan []*StorageChangeEvent, 10)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fail the first attempt to exercise the retry.
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var events []*StorageChangeEvent
+		_ = json.NewDecoder(r.Body).Decode(&events)
+		webhookEvents <- events
+	}))
+	defer server.Close()
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{
+		ChangeFeedCollections:    []string{"profiles"},
+		ChangeFeedWebhookURL:     server.URL,
+		ChangeFeedWebhookRetries: 2,
+		ChangeFeedFile:           filePath,
+	}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	received := make(chan *StorageChangeEvent, 10)
+	unsubscribe := storageIdx.ChangeFeed().Subscribe("profiles", "player_", func(ctx context.Context, event *StorageChangeEvent) {
+		received <- event
+	})
+
+	created := timestamppb.New(time.Now().Add(-time.Minute))
+	updated := timestamppb.Now()
+	storageIdx.Write(ctx, []*api.StorageObject{
+		{Collection: "profiles", Key: "player_1", UserId: uuid.Nil.String(), Value: `{"a":1}`, Version: "v1", CreateTime: updated, UpdateTime: updated},
+		{Collection: "profiles", Key: "player_2", UserId: uuid.Nil.String(), Value: `{"a":2}`, Version: "v2", CreateTime: created, UpdateTime: updated},
+		{Collection: "profiles", Key: "settings", UserId: uuid.Nil.String(), Value: `{}`, Version: "v3", CreateTime: updated, UpdateTime: updated},
+		{Collection: "other", Key: "player_3", UserId: uuid.Nil.String(), Value: `{}`, Version: "v4", CreateTime: updated, UpdateTime: updated},
+	})
+	storageIdx.Delete(ctx, StorageOpDeletes{
+		{OwnerID: uuid.Nil.String(), ObjectID: &api.DeleteStorageObjectId{Collection: "profiles", Key: "player_1"}},
+	})
+
+	expected := []struct{ typ, key string }{
+		{StorageChangeCreate, "player_1"},
+		{StorageChangeUpdate, "player_2"},
+		{StorageChangeDelete, "player_1"},
+	}
+	for _, e := range expected {
+		select {
+		case event := <-received:
+			assert.Equal(t, e.typ, event.Type)
+			assert.Equal(t, e.key, event.Key)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s %s", e.typ, e.key)
+		}
+	}
+	unsubscribe()
+
+	webhookCount := 0
+	for webhookCount < 4 {
+		select {
+		case events := <-webhookEvents:
+			for _, event := range events {
+				assert.Equal(t, "profiles", event.Collection, "sinks should only receive the configured collections")
+			}
+			webhookCount += len(events)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for webhook events, received %d", webhookCount)
+		}
+	}
+	assert.Equal(t, 4, webhookCount)
+
+	storageIdx.Stop()
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 4)
+}
+
+func TestStorageChangeFeed_Deletes(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	collection := "test_change_feed_deletes"
+
+	u1 := uuid.Must(uuid.NewV4())
+	InsertUser(t, db, u1)
+
+	storageIdx, err := NewLocalStorageIndex(logger, db, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer storageIdx.Stop()
+
+	received := make(chan *StorageChangeEvent, 10)
+	storageIdx.ChangeFeed().Subscribe(collection, "", func(ctx context.Context, event *StorageChangeEvent) {
+		if event.Type == StorageChangeDelete {
+			received <- event
+		}
+	})
+
+	writeOps := StorageOpWrites{
+		{OwnerID: u1.String(), Object: &api.WriteStorageObject{Collection: collection, Key: "a", Value: `{}`}},
+		{OwnerID: u1.String(), Object: &api.WriteStorageObject{Collection: collection, Key: "b", Value: `{}`}},
+	}
+	if _, _, err := StorageWriteObjects(ctx, logger, db, metrics, storageIdx, true, writeOps); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// A delete that removes nothing is not published.
+	if _, err := StorageDeleteObjects(ctx, logger, db, storageIdx, true, StorageOpDeletes{
+		{OwnerID: u1.String(), ObjectID: &api.DeleteStorageObjectId{Collection: collection, Key: "missing"}},
+		{OwnerID: u1.String(), ObjectID: &api.DeleteStorageObjectId{Collection: collection, Key: "a"}},
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Objects removed along with their owner are returned to be published.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	deletes, err := storageDeleteUserObjects(ctx, tx, u1)
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err.Error())
+	}
+	storageIdx.Delete(ctx, deletes)
+
+	for _, key := range []string{"a", "b"} {
+		select {
+		case event := <-received:
+			assert.Equal(t, key, event.Key)
+			assert.Equal(t, u1.String(), event.UserID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for delete of %s", key)
+		}
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected delete of %s", event.Key)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/heroiclabs/nakama-common/api"
+	"go.uber.org/zap"
+)
+
+// storageNotExpiredCondition excludes expired storage objects that have not yet been deleted by the reaper.
+const storageNotExpiredCondition = "(expire_time IS NULL OR expire_time > now())"
+
+type StorageExpiryReaper interface {
+	Start()
+	Stop()
+}
+
+// LocalStorageExpiryReaper periodically deletes storage objects past their expiry time, in batches, and evicts them
+// from the storage indices.
+type LocalStorageExpiryReaper struct {
+	logger       *zap.Logger
+	db           *sql.DB
+	metrics      Metrics
+	storageIndex StorageIndex
+	config       *StorageConfig
+
+	ctx         context.Context
+	ctxCancelFn context.CancelFunc
+}
+
+func NewLocalStorageExpiryReaper(logger *zap.Logger, db *sql.DB, metrics Metrics, storageIndex StorageIndex, config *StorageConfig) StorageExpiryReaper {
+	ctx, ctxCancelFn := context.WithCancel(context.Background())
+
+	return &LocalStorageExpiryReaper{
+		logger:       logger,
+		db:           db,
+		metrics:      metrics,
+		storageIndex: storageIndex,
+		config:       config,
+
+		ctx:         ctx,
+		ctxCancelFn: ctxCancelFn,
+	}
+}
+
+func (r *LocalStorageExpiryReaper) Start() {
+	if r.config.ExpiryReapIntervalSec < 1 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(r.config.ExpiryReapIntervalSec) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Reap(r.ctx); err != nil && r.ctx.Err() == nil {
+					r.logger.Error("Failed to reap expired storage objects", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+func (r *LocalStorageExpiryReaper) Stop() {
+	r.ctxCancelFn()
+}
+
+// Reap deletes expired storage objects until none remain, and returns the number deleted.
+func (r *LocalStorageExpiryReaper) Reap(ctx context.Context) (int, error) {
+	batchSize := r.config.ExpiryReapBatchSize
+	if batchSize < 1 {
+		batchSize = 1000
+	}
+
+	total := 0
+	for {
+		deletes, err := storageDeleteExpired(ctx, r.db, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(deletes) == 0 {
+			return total, nil
+		}
+		total += len(deletes)
+
+		counts := make(map[string]int64)
+		for _, d := range deletes {
+			counts[d.ObjectID.Collection]++
+		}
+		for collection, count := range counts {
+			r.metrics.StorageExpiredCount(map[string]string{"collection": collection}, count)
+		}
+		r.storageIndex.Delete(ctx, deletes)
+		r.logger.Debug("Reaped expired storage objects", zap.Int("count", len(deletes)))
+
+		if len(deletes) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+func storageDeleteExpired(ctx context.Context, db *sql.DB, limit int) (StorageOpDeletes, error) {
+	query := `
+DELETE FROM storage
+WHERE (collection, key, user_id) IN (
+	SELECT collection, key, user_id FROM storage
+	WHERE expire_time <= now()
+	LIMIT $1
+)
+RETURNING collection, key, user_id`
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deletes := make(StorageOpDeletes, 0, limit)
+	for rows.Next() {
+		var collection, key, userID string
+		if err := rows.Scan(&collection, &key, &userID); err != nil {
+			return nil, err
+		}
+		deletes = append(deletes, &StorageOpDelete{
+			OwnerID: userID,
+			ObjectID: &api.DeleteStorageObjectId{
+				Collection: collection,
+				Key:        key,
+			},
+		})
+	}
+	return deletes, rows.Err()
+}
@@ -15,11 +15,20 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/blugelabs/bluge"
@@ -34,11 +43,32 @@ import (
 
 type StorageIndex interface {
 	Write(ctx context.Context, objects []*api.StorageObject) (creates int, deletes int)
+	WriteExpiring(ctx context.Context, objects []*api.StorageObject, expireTimes []time.Time) (creates int, deletes int)
 	Delete(ctx context.Context, objects StorageOpDeletes) (deletes int)
-	List(ctx context.Context, callerID uuid.UUID, indexName, query string, limit int) (*api.StorageObjects, error)
+	List(ctx context.Context, callerID uuid.UUID, indexName, query string, limit int, order []string, cursor string) (objects *api.StorageObjects, newCursor string, total int, err error)
 	Load(ctx context.Context) error
-	CreateIndex(ctx context.Context, name, collection, key string, fields []string, maxEntries int, indexOnly bool) error
+	CreateIndex(ctx context.Context, name, collection, key string, fields []string, maxEntries int, indexOnly, sortable bool) error
 	RegisterFilters(runtime *Runtime)
+	Aggregate(ctx context.Context, indexName, query string, aggs []*StorageIndexAggregation) (*StorageIndexAggregateResult, error)
+	Rebuild(ctx context.Context, indexName string) error
+	ChangeFeed() *StorageChangeFeed
+	Stop()
+}
+
+const (
+	storageIndexCheckpointInterval = time.Minute      // How often the checkpoint of a persisted index is updated.
+	storageIndexCheckpointMargin   = 30 * time.Second // Replay overlap, to cover storage writes that were in flight at the checkpoint.
+)
+
+// storageIndexCheckpoint is stored alongside a persisted index. The index is rebuilt if its configuration changes.
+type storageIndexCheckpoint struct {
+	UpdateTime time.Time `json:"update_time"` // All storage updates before this time have been applied to the index.
+	Collection string    `json:"collection"`
+	Key        string    `json:"key"`
+	Fields     []string  `json:"fields"`
+	MaxEntries int       `json:"max_entries"`
+	IndexOnly  bool      `json:"index_only"`
+	Sortable   bool      `json:"sortable"`
 }
 
 type storageIndex struct {
@@ -48,7 +78,23 @@ type storageIndex struct {
 	Key        string
 	Fields     []string
 	IndexOnly  bool
+	Sortable   bool // Whether the indexed values can be sorted by.
 	Index      *bluge.Writer
+
+	mu             sync.Mutex     // Serialises batches, checkpoints and swaps of the index writer.
+	loadMu         sync.Mutex     // Serialises loads and rebuilds, which scan the database without holding mu.
+	loading        bool           // Whether a load or rebuild is in progress, and batches should be kept in pending.
+	pending        []*index.Batch // Batches applied during a load or rebuild, replayed over its results once complete.
+	path           string         // The on-disk location of the index, if persisted.
+	checkpoint     time.Time      // The checkpoint the index was loaded from, if persisted.
+	checkpointedAt time.Time      // When the checkpoint was last written.
+}
+
+type storageIndexCursor struct {
+	Index  string
+	Query  string
+	Order  []string
+	Offset int
 }
 
 type LocalStorageIndex struct {
@@ -59,9 +105,15 @@ type LocalStorageIndex struct {
 	indicesByCollection   map[string][]*storageIndex
 	customFilterFunctions map[string]RuntimeStorageIndexFilterFunction
 	config                *StorageConfig
+	changeFeed            *StorageChangeFeed
 }
 
 func NewLocalStorageIndex(logger *zap.Logger, db *sql.DB, config *StorageConfig, metrics Metrics) (StorageIndex, error) {
+	changeFeed, err := NewStorageChangeFeed(logger, config)
+	if err != nil {
+		return nil, err
+	}
+
 	si := &LocalStorageIndex{
 		logger:                logger,
 		db:                    db,
@@ -70,15 +122,29 @@ func NewLocalStorageIndex(logger *zap.Logger, db *sql.DB, config *StorageConfig,
 		indicesByCollection:   make(map[string][]*storageIndex),
 		customFilterFunctions: make(map[string]RuntimeStorageIndexFilterFunction),
 		config:                config,
+		changeFeed:            changeFeed,
 	}
 
 	return si, nil
 }
 
 func (si *LocalStorageIndex) Write(ctx context.Context, objects []*api.StorageObject) (updates int, deletes int) {
+	return si.WriteExpiring(ctx, objects, nil)
+}
+
+// WriteExpiring indexes the storage objects, along with the time each expires at. Expire times are given in the same
+// order as the objects, and a zero or missing time never expires. Expired entries are excluded from index queries.
+func (si *LocalStorageIndex) WriteExpiring(ctx context.Context, objects []*api.StorageObject, expireTimes []time.Time) (updates int, deletes int) {
+	si.changeFeed.PublishWrites(objects)
+
 	batches := make(map[*storageIndex]*index.Batch, 0)
 
-	for _, so := range objects {
+	for i, so := range objects {
+		var expireTime time.Time
+		if i < len(expireTimes) {
+			expireTime = expireTimes[i]
+		}
+
 		indices, found := si.indicesByCollection[so.Collection]
 		if !found {
 			continue
@@ -122,7 +188,7 @@ func (si *LocalStorageIndex) Write(ctx context.Context, objects []*api.StorageOb
 					}
 				}
 
-				doc, err := si.mapIndexStorageFields(so.UserId, so.Collection, so.Key, so.Version, so.Value, so.PermissionRead, so.PermissionWrite, so.CreateTime.AsTime(), so.UpdateTime.AsTime(), idx.Fields, idx.IndexOnly)
+				doc, err := si.mapIndexStorageFields(so.UserId, so.Collection, so.Key, so.Version, so.Value, so.PermissionRead, so.PermissionWrite, so.CreateTime.AsTime(), so.UpdateTime.AsTime(), expireTime, idx.Fields, idx.IndexOnly, idx.Sortable)
 				if err != nil {
 					si.logger.Error("Failed to map storage object values to index", zap.Error(err))
 					continue
@@ -139,52 +205,71 @@ func (si *LocalStorageIndex) Write(ctx context.Context, objects []*api.StorageOb
 	}
 
 	for idx, b := range batches {
-		if err := idx.Index.Batch(b); err != nil {
-			si.logger.Error("Failed to update index", zap.String("index_name", idx.Name), zap.Error(err))
-			continue
-		}
+		si.applyWriteBatch(ctx, idx, b)
+	}
 
-		reader, err := idx.Index.Reader()
-		if err != nil {
-			si.logger.Error("Failed to get index storage reader", zap.Error(err))
-			continue
-		}
-		count, _ := reader.Count() // cannot return err
+	return updates, deletes
+}
 
-		si.metrics.GaugeStorageIndexEntries(idx.Name, float64(count))
+// applyWriteBatch applies a batch of storage writes to the index, then checkpoints and evicts entries as needed.
+func (si *LocalStorageIndex) applyWriteBatch(ctx context.Context, idx *storageIndex, b *index.Batch) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
 
-		// Apply eviction strategy if size of index is +10% than max size
-		if count > uint64(float32(idx.MaxEntries)*(1.1)) {
-			deleteCount := int(count - uint64(idx.MaxEntries))
-			req := bluge.NewTopNSearch(deleteCount, bluge.NewMatchAllQuery())
-			req.SortBy([]string{"update_time"})
+	if err := idx.Index.Batch(b); err != nil {
+		si.logger.Error("Failed to update index", zap.String("index_name", idx.Name), zap.Error(err))
+		return
+	}
+	if idx.loading {
+		idx.pending = append(idx.pending, b)
+	}
 
-			results, err := reader.Search(ctx, req)
-			if err != nil {
-				si.logger.Error("Failed to evict storage index documents", zap.String("index_name", idx.Name))
-				continue
-			}
+	reader, err := idx.Index.Reader()
+	if err != nil {
+		si.logger.Error("Failed to get index storage reader", zap.Error(err))
+		return
+	}
+	count, _ := reader.Count() // cannot return err
 
-			ids, err := si.queryMatchesToDocumentIds(results)
-			if err != nil {
-				si.logger.Error("Failed to get query results document ids", zap.Error(err))
-				continue
-			}
+	si.metrics.GaugeStorageIndexEntries(idx.Name, float64(count))
 
-			evictBatch := bluge.NewBatch()
-			for _, docID := range ids {
-				evictBatch.Delete(bluge.Identifier(docID))
-			}
-			if err = idx.Index.Batch(evictBatch); err != nil {
-				si.logger.Error("Failed to update index", zap.String("index_name", idx.Name), zap.Error(err))
-			}
+	if idx.path != "" && time.Since(idx.checkpointedAt) > storageIndexCheckpointInterval {
+		if err := si.writeCheckpoint(idx, time.Now()); err != nil {
+			si.logger.Warn("Failed to write storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
 		}
 	}
 
-	return updates, deletes
+	// Apply eviction strategy if size of index is +10% than max size
+	if count > uint64(float32(idx.MaxEntries)*(1.1)) {
+		deleteCount := int(count - uint64(idx.MaxEntries))
+		req := bluge.NewTopNSearch(deleteCount, bluge.NewMatchAllQuery())
+		req.SortBy([]string{"update_time"})
+
+		results, err := reader.Search(ctx, req)
+		if err != nil {
+			si.logger.Error("Failed to evict storage index documents", zap.String("index_name", idx.Name))
+			return
+		}
+
+		ids, err := si.queryMatchesToDocumentIds(results)
+		if err != nil {
+			si.logger.Error("Failed to get query results document ids", zap.Error(err))
+			return
+		}
+
+		evictBatch := bluge.NewBatch()
+		for _, docID := range ids {
+			evictBatch.Delete(bluge.Identifier(docID))
+		}
+		if err = idx.Index.Batch(evictBatch); err != nil {
+			si.logger.Error("Failed to update index", zap.String("index_name", idx.Name), zap.Error(err))
+		}
+	}
 }
 
 func (si *LocalStorageIndex) Delete(ctx context.Context, objects StorageOpDeletes) (deletes int) {
+	si.changeFeed.PublishDeletes(objects)
+
 	batches := make(map[*storageIndex]*index.Batch, 0)
 
 	for _, d := range objects {
@@ -208,53 +293,101 @@ func (si *LocalStorageIndex) Delete(ctx context.Context, objects StorageOpDelete
 	}
 
 	for idx, b := range batches {
+		idx.mu.Lock()
 		if err := idx.Index.Batch(b); err != nil {
 			si.logger.Error("Failed to evict entries from index", zap.String("index_name", idx.Name), zap.Error(err))
-			continue
+		} else if idx.loading {
+			idx.pending = append(idx.pending, b)
 		}
+		idx.mu.Unlock()
 	}
 
 	return deletes
 }
 
-func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, indexName, query string, limit int) (*api.StorageObjects, error) {
+// List returns the index entries matching the query. Results are sorted by score unless an order is given, as a list
+// of field names prefixed with "-" for descending order (e.g. "-value.score", "update_time"). Only sortable indices
+// can be sorted by their values. The returned cursor is empty on the last page, and is only valid for the same query
+// and order. Total is the number of entries matching the query.
+func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, indexName, query string, limit int, order []string, cursor string) (*api.StorageObjects, string, int, error) {
 	idx, found := si.indexByName[indexName]
 	if !found {
-		return nil, fmt.Errorf("index %q not found", indexName)
+		return nil, "", 0, fmt.Errorf("index %q not found", indexName)
+	}
+
+	var sc *storageIndexCursor
+	if cursor != "" {
+		sc = &storageIndexCursor{}
+		cb, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			return nil, "", 0, errors.New("malformed cursor was used")
+		}
+		if err := gob.NewDecoder(bytes.NewReader(cb)).Decode(sc); err != nil {
+			return nil, "", 0, errors.New("malformed cursor was used")
+		}
+		if sc.Index != indexName || sc.Query != query || !slices.Equal(sc.Order, order) {
+			return nil, "", 0, errors.New("cursor does not match the index, query and order")
+		}
+	}
+
+	for _, field := range order {
+		field = strings.TrimPrefix(field, "-")
+		if field != "_score" && field != "create_time" && field != "update_time" && !idx.Sortable {
+			return nil, "", 0, fmt.Errorf("index %q is not sortable by %q", indexName, field)
+		}
 	}
 
 	if limit > idx.MaxEntries {
 		si.logger.Warn("Attempted to list more index entries than configured maximum index size", zap.String("index_name", idx.Name), zap.Int("limit", limit), zap.Int("max_entries", idx.MaxEntries))
 	}
 
-	if query == "" {
-		query = "*"
+	queryString := query
+	if queryString == "" {
+		queryString = "*"
 	}
 
-	parsedQuery, err := ParseQueryString(query)
+	parsedQuery, err := ParseQueryString(queryString)
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
-	searchReq := bluge.NewTopNSearch(limit, parsedQuery)
+	searchReq := bluge.NewTopNSearch(limit, storageIndexNotExpiredQuery(parsedQuery)).WithStandardAggregations()
+	if len(order) > 0 {
+		searchReq.SortBy(order)
+	}
+	offset := 0
+	if sc != nil {
+		offset = sc.Offset
+		searchReq.SetFrom(offset)
+	}
 
-	indexReader, err := idx.Index.Reader()
+	indexReader, err := idx.reader()
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	results, err := indexReader.Search(ctx, searchReq)
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	indexResults, err := si.queryMatchesToStorageIndexResults(results)
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
+	total := int(results.Aggregations().Count())
 
 	if len(indexResults) == 0 {
-		return &api.StorageObjects{Objects: []*api.StorageObject{}}, nil
+		return &api.StorageObjects{Objects: []*api.StorageObject{}}, "", total, nil
+	}
+
+	var newCursor string
+	if next := offset + len(indexResults); next < total {
+		cursorBuf := new(bytes.Buffer)
+		if err := gob.NewEncoder(cursorBuf).Encode(&storageIndexCursor{Index: indexName, Query: query, Order: order, Offset: next}); err != nil {
+			return nil, "", 0, err
+		}
+		newCursor = base64.RawURLEncoding.EncodeToString(cursorBuf.Bytes())
 	}
 
 	if !si.config.DisableIndexOnly && idx.IndexOnly {
@@ -277,7 +410,7 @@ func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, index
 			})
 		}
 
-		return &api.StorageObjects{Objects: objects}, nil
+		return &api.StorageObjects{Objects: objects}, newCursor, total, nil
 	}
 
 	storageReads := make([]*api.ReadStorageObjectId, 0, len(indexResults))
@@ -291,7 +424,7 @@ func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, index
 
 	objects, err := StorageReadObjects(ctx, si.logger, si.db, callerID, storageReads)
 	if err != nil {
-		return nil, err
+		return nil, "", 0, err
 	}
 
 	// Sort the objects read from the db according to the results from the index as StorageReadObjects does not guarantee ordering of the results
@@ -310,42 +443,118 @@ func (si *LocalStorageIndex) List(ctx context.Context, callerID uuid.UUID, index
 
 	objects.Objects = sortedObjects
 
-	return objects, nil
+	return objects, newCursor, total, nil
 }
 
+// Load indexes the storage objects of every index. Persisted indices only replay the changes since their checkpoint,
+// then drop the entries of objects deleted while the node was down. Storage writes are not blocked while the database
+// is scanned; they are applied as usual and replayed over the loaded entries once the scan completes.
 func (si *LocalStorageIndex) Load(ctx context.Context) error {
-	var rangeError error
 	for _, idx := range si.indexByName {
 		t := time.Now()
-		if err := si.load(ctx, idx); err != nil {
+		since := idx.checkpoint
+
+		entries, stale, err := si.loadIndex(ctx, idx, since, t)
+		if err != nil {
 			return err
 		}
 
 		elapsedTimeMs := time.Since(t).Milliseconds()
-		si.logger.Info("Storage index loaded.", zap.Any("config", idx), zap.Int64("elapsed_time_ms", elapsedTimeMs))
+		si.logger.Info("Storage index loaded.", zap.Any("config", idx), zap.Time("since", since), zap.Int("entries", entries), zap.Int("stale_entries", stale), zap.Int64("elapsed_time_ms", elapsedTimeMs))
 	}
 
-	return rangeError
+	return nil
 }
 
-func (si *LocalStorageIndex) load(ctx context.Context, idx *storageIndex) error {
-	query := `
-SELECT user_id, key, version, value, read, write, create_time, update_time
-FROM storage
-WHERE collection = $1
-ORDER BY collection, key, user_id
-LIMIT $2`
-	params := []any{idx.Collection, 10_000}
+func (si *LocalStorageIndex) loadIndex(ctx context.Context, idx *storageIndex, since, t time.Time) (int, int, error) {
+	idx.loadMu.Lock()
+	defer idx.loadMu.Unlock()
+
+	idx.startLoading()
+	defer idx.stopLoading()
 
+	// Only loads and rebuilds replace the writer, so it can be used without holding mu.
+	entries, err := si.load(ctx, idx, idx.Index, since)
+	if err != nil {
+		return 0, 0, err
+	}
+	var stale int
+	if !since.IsZero() {
+		if stale, err = si.reconcile(ctx, idx); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if err := si.replayPending(idx, idx.Index); err != nil {
+		return 0, 0, err
+	}
+	if err := si.writeCheckpoint(idx, t); err != nil {
+		si.logger.Warn("Failed to write storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
+	}
+
+	return entries, stale, nil
+}
+
+// reader returns a reader of the current index, which a rebuild may replace.
+func (idx *storageIndex) reader() (*bluge.Reader, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.Index.Reader()
+}
+
+// startLoading keeps the batches applied to the index from now on, so they can be replayed over the entries loaded
+// from a database scan that may have read older versions of the same objects.
+func (idx *storageIndex) startLoading() {
+	idx.mu.Lock()
+	idx.loading = true
+	idx.pending = nil
+	idx.mu.Unlock()
+}
+
+// stopLoading stops keeping the batches applied to the index, and discards any that were not replayed.
+func (idx *storageIndex) stopLoading() {
+	idx.mu.Lock()
+	idx.loading = false
+	idx.pending = nil
+	idx.mu.Unlock()
+}
+
+// replayPending applies the batches kept since startLoading to the given writer. The caller must hold the index lock.
+func (si *LocalStorageIndex) replayPending(idx *storageIndex, w *bluge.Writer) error {
+	for _, b := range idx.pending {
+		if err := w.Batch(b); err != nil {
+			return err
+		}
+	}
+	idx.pending = nil
+	return nil
+}
+
+// load indexes the storage objects updated since the given time (or all if zero) into the given writer, and returns
+// how many were indexed.
+func (si *LocalStorageIndex) load(ctx context.Context, idx *storageIndex, w *bluge.Writer, since time.Time) (int, error) {
+	conditions := []string{"collection = $1", storageNotExpiredCondition}
+	baseParams := []any{idx.Collection, 10_000}
 	if idx.Key != "" {
-		query = `
-SELECT user_id, key, version, value, read, write, create_time, update_time
+		baseParams = append(baseParams, idx.Key)
+		conditions = append(conditions, fmt.Sprintf("key = $%d", len(baseParams)))
+	}
+	if !since.IsZero() {
+		baseParams = append(baseParams, since)
+		conditions = append(conditions, fmt.Sprintf("update_time >= $%d", len(baseParams)))
+	}
+	buildQuery := func(conditions []string) string {
+		return `
+SELECT user_id, key, version, value, read, write, create_time, update_time, expire_time
 FROM storage
-WHERE collection = $1 AND key = $3
+WHERE ` + strings.Join(conditions, " AND ") + `
 ORDER BY collection, key, user_id
 LIMIT $2`
-		params = append(params, idx.Key)
 	}
+	query := buildQuery(conditions)
+	params := baseParams
 
 	filterFn := si.customFilterFunctions[idx.Name]
 
@@ -353,7 +562,7 @@ LIMIT $2`
 	for {
 		rows, err := si.db.QueryContext(ctx, query, params...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		defer rows.Close()
 
@@ -369,9 +578,10 @@ LIMIT $2`
 			var dbWrite int32
 			var dbCreateTime time.Time
 			var dbUpdateTime time.Time
-			if err = rows.Scan(&dbUserID, &dbKey, &dbVersion, &dbValue, &dbRead, &dbWrite, &dbCreateTime, &dbUpdateTime); err != nil {
+			var dbExpireTime sql.NullTime
+			if err = rows.Scan(&dbUserID, &dbKey, &dbVersion, &dbValue, &dbRead, &dbWrite, &dbCreateTime, &dbUpdateTime, &dbExpireTime); err != nil {
 				rows.Close()
-				return err
+				return 0, err
 			}
 
 			if filterFn != nil {
@@ -390,15 +600,19 @@ LIMIT $2`
 					si.logger.Error("Error invoking custom Storage Index Filter function", zap.String("index_name", idx.Name), zap.Error(err))
 				}
 				if !ok {
+					if !since.IsZero() {
+						// The object may have been indexed before the checkpoint.
+						batch.Delete(si.storageIndexDocumentId(idx.Collection, dbKey, dbUserID.String()))
+					}
 					continue
 				}
 			}
 
-			doc, err := si.mapIndexStorageFields(dbUserID.String(), idx.Collection, dbKey, dbVersion, dbValue, dbRead, dbWrite, dbCreateTime, dbUpdateTime, idx.Fields, idx.IndexOnly)
+			doc, err := si.mapIndexStorageFields(dbUserID.String(), idx.Collection, dbKey, dbVersion, dbValue, dbRead, dbWrite, dbCreateTime, dbUpdateTime, dbExpireTime.Time, idx.Fields, idx.IndexOnly, idx.Sortable)
 			if err != nil {
 				rows.Close()
 				si.logger.Error("Failed to map storage object values to index", zap.Error(err))
-				return err
+				return 0, err
 			}
 
 			if doc == nil {
@@ -414,37 +628,224 @@ LIMIT $2`
 		rows.Close()
 
 		if err = idx.Index.Batch(batch); err != nil {
-			return err
+			return 0, err
 		}
 
 		if count >= idx.MaxEntries || !rowsRead {
 			break
 		}
 
-		query = `
-SELECT user_id, key, version, value, read, write, create_time, update_time
-FROM storage
-WHERE collection = $1
-AND (collection, key, user_id) > ($1, $3, $4)
-ORDER BY collection, key, user_id
-LIMIT $2`
+		params = append(baseParams[:len(baseParams):len(baseParams)], dbKey, dbUserID)
 		if idx.Key != "" {
-			query = `
-SELECT user_id, key, version, value, read, write, create_time, update_time
+			query = buildQuery(append(conditions[:len(conditions):len(conditions)], fmt.Sprintf("user_id > $%d", len(params))))
+		} else {
+			query = buildQuery(append(conditions[:len(conditions):len(conditions)], fmt.Sprintf("(key, user_id) > ($%d, $%d)", len(params)-1, len(params))))
+		}
+	}
+
+	return count, nil
+}
+
+// Rebuild re-indexes all of the index's storage objects from the database into a new index, which then replaces the
+// current one, dropping any stale entries. The current index keeps serving lists and storage writes during the rebuild,
+// and the writes are replayed into the new index before it is swapped in.
+func (si *LocalStorageIndex) Rebuild(ctx context.Context, indexName string) error {
+	idx, found := si.indexByName[indexName]
+	if !found {
+		return fmt.Errorf("index %q not found", indexName)
+	}
+	t := time.Now()
+
+	idx.loadMu.Lock()
+	defer idx.loadMu.Unlock()
+
+	cfg := BlugeInMemoryConfig()
+	var rebuildPath string
+	if idx.path != "" {
+		rebuildPath = idx.path + ".rebuild"
+		if err := os.RemoveAll(rebuildPath); err != nil {
+			return fmt.Errorf("failed to remove storage index rebuild directory: %w", err)
+		}
+		cfg = BlugeDiskConfig(rebuildPath)
+	}
+	w, err := bluge.OpenWriter(cfg)
+	if err != nil {
+		return err
+	}
+	discard := func() {
+		_ = w.Close()
+		if rebuildPath != "" {
+			_ = os.RemoveAll(rebuildPath)
+		}
+	}
+
+	idx.startLoading()
+	defer idx.stopLoading()
+
+	entries, err := si.load(ctx, idx, w, time.Time{})
+	if err != nil {
+		discard()
+		return err
+	}
+
+	idx.mu.Lock()
+	if err := si.replayPending(idx, w); err != nil {
+		idx.mu.Unlock()
+		discard()
+		return err
+	}
+	old := idx.Index
+	if rebuildPath == "" {
+		idx.Index = w
+	} else if err := si.swapPersisted(idx, w, rebuildPath); err != nil {
+		idx.mu.Unlock()
+		return err
+	}
+	if err := si.writeCheckpoint(idx, t); err != nil {
+		si.logger.Warn("Failed to write storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
+	}
+	idx.mu.Unlock()
+
+	if rebuildPath == "" {
+		if err := old.Close(); err != nil {
+			si.logger.Warn("Failed to close storage index", zap.String("index_name", idx.Name), zap.Error(err))
+		}
+	}
+
+	si.logger.Info("Storage index rebuilt.", zap.String("index_name", idx.Name), zap.Int("entries", entries), zap.Int64("elapsed_time_ms", time.Since(t).Milliseconds()))
+	return nil
+}
+
+// swapPersisted replaces a persisted index with the one rebuilt at the given path. The caller must hold the index lock.
+func (si *LocalStorageIndex) swapPersisted(idx *storageIndex, w *bluge.Writer, rebuildPath string) error {
+	if err := w.Close(); err != nil {
+		_ = os.RemoveAll(rebuildPath)
+		return err
+	}
+	if err := idx.Index.Close(); err != nil {
+		si.logger.Warn("Failed to close storage index", zap.String("index_name", idx.Name), zap.Error(err))
+	}
+	if err := os.RemoveAll(idx.path); err != nil {
+		return fmt.Errorf("failed to remove storage index directory: %w", err)
+	}
+	if err := os.Rename(rebuildPath, idx.path); err != nil {
+		return fmt.Errorf("failed to move rebuilt storage index: %w", err)
+	}
+	writer, err := bluge.OpenWriter(BlugeDiskConfig(idx.path))
+	if err != nil {
+		return err
+	}
+	idx.Index = writer
+	return nil
+}
+
+// reconcile removes the entries of a persisted index whose storage objects no longer exist, such as those deleted
+// while the node was down, and returns how many were removed. The caller must hold the index load lock.
+func (si *LocalStorageIndex) reconcile(ctx context.Context, idx *storageIndex) (int, error) {
+	existing, err := si.indexDocumentIds(ctx, idx)
+	if err != nil {
+		return 0, err
+	}
+	if len(existing) == 0 {
+		return 0, nil
+	}
+
+	conditions := []string{"collection = $1", storageNotExpiredCondition}
+	baseParams := []any{idx.Collection, 10_000}
+	if idx.Key != "" {
+		baseParams = append(baseParams, idx.Key)
+		conditions = append(conditions, fmt.Sprintf("key = $%d", len(baseParams)))
+	}
+	buildQuery := func(conditions []string) string {
+		return `
+SELECT user_id, key
 FROM storage
-WHERE collection = $1
-AND key = $3
-AND user_id > $4
+WHERE ` + strings.Join(conditions, " AND ") + `
 ORDER BY collection, key, user_id
 LIMIT $2`
+	}
+	query := buildQuery(conditions)
+	params := baseParams
+
+	live := make(map[string]struct{}, len(existing))
+	for {
+		rows, err := si.db.QueryContext(ctx, query, params...)
+		if err != nil {
+			return 0, err
+		}
+
+		var rowsRead bool
+		var dbUserID *uuid.UUID
+		var dbKey string
+		for rows.Next() {
+			rowsRead = true
+			if err = rows.Scan(&dbUserID, &dbKey); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			live[string(si.storageIndexDocumentId(idx.Collection, dbKey, dbUserID.String()))] = struct{}{}
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+
+		if !rowsRead {
+			break
+		}
+
+		params = append(baseParams[:len(baseParams):len(baseParams)], dbKey, dbUserID)
+		if idx.Key != "" {
+			query = buildQuery(append(conditions[:len(conditions):len(conditions)], fmt.Sprintf("user_id > $%d", len(params))))
+		} else {
+			query = buildQuery(append(conditions[:len(conditions):len(conditions)], fmt.Sprintf("(key, user_id) > ($%d, $%d)", len(params)-1, len(params))))
 		}
-		params = []any{idx.Collection, 10_000, dbKey, dbUserID}
 	}
 
-	return nil
+	return si.deleteUnseen(idx, existing, live)
+}
+
+// indexDocumentIds returns the IDs of all documents in the index.
+func (si *LocalStorageIndex) indexDocumentIds(ctx context.Context, idx *storageIndex) ([]string, error) {
+	reader, err := idx.Index.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	count, err := reader.Count()
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return []string{}, nil
+	}
+	results, err := reader.Search(ctx, bluge.NewTopNSearch(int(count), bluge.NewMatchAllQuery()))
+	if err != nil {
+		return nil, err
+	}
+	return si.queryMatchesToDocumentIds(results)
 }
 
-func (si *LocalStorageIndex) mapIndexStorageFields(userID, collection, key, version, value string, read, write int32, createTime, updateTime time.Time, filters []string, indexOnly bool) (*bluge.Document, error) {
+// deleteUnseen removes the given documents from the index unless they are in seen, and returns how many were removed.
+func (si *LocalStorageIndex) deleteUnseen(idx *storageIndex, ids []string, seen map[string]struct{}) (int, error) {
+	batch := bluge.NewBatch()
+	var deletes int
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			batch.Delete(bluge.Identifier(id))
+			deletes++
+		}
+	}
+	if deletes == 0 {
+		return 0, nil
+	}
+	if err := idx.Index.Batch(batch); err != nil {
+		return 0, err
+	}
+	return deletes, nil
+}
+
+func (si *LocalStorageIndex) mapIndexStorageFields(userID, collection, key, version, value string, read, write int32, createTime, updateTime, expireTime time.Time, filters []string, indexOnly, sortable bool) (*bluge.Document, error) {
 	if collection == "" || key == "" || userID == "" {
 		return nil, errors.New("insufficient fields to create index document id")
 	}
@@ -472,6 +873,9 @@ func (si *LocalStorageIndex) mapIndexStorageFields(userID, collection, key, vers
 	rv := bluge.NewDocument(string(si.storageIndexDocumentId(collection, key, userID)))
 	rv.AddField(bluge.NewDateTimeField("create_time", createTime).StoreValue().Sortable())
 	rv.AddField(bluge.NewDateTimeField("update_time", updateTime).StoreValue().Sortable())
+	if !expireTime.IsZero() {
+		rv.AddField(bluge.NewNumericField("expire_time", float64(expireTime.Unix())))
+	}
 	rv.AddField(bluge.NewKeywordField("collection", collection).StoreValue())
 	rv.AddField(bluge.NewKeywordField("key", key).StoreValue())
 	rv.AddField(bluge.NewKeywordField("user_id", userID).StoreValue())
@@ -487,7 +891,11 @@ func (si *LocalStorageIndex) mapIndexStorageFields(userID, collection, key, vers
 		rv.AddField(bluge.NewStoredOnlyField("json", json))
 	}
 
-	BlugeWalkDocument(mapValue, []string{"value"}, rv)
+	if sortable {
+		BlugeWalkSortableDocument(mapValue, []string{"value"}, rv)
+	} else {
+		BlugeWalkDocument(mapValue, []string{"value"}, rv)
+	}
 
 	return rv, nil
 }
@@ -583,7 +991,7 @@ func (si *LocalStorageIndex) queryMatchesToDocumentIds(dmi search.DocumentMatchI
 	return ids, nil
 }
 
-func (si *LocalStorageIndex) CreateIndex(ctx context.Context, name, collection, key string, fields []string, maxEntries int, indexOnly bool) error {
+func (si *LocalStorageIndex) CreateIndex(ctx context.Context, name, collection, key string, fields []string, maxEntries int, indexOnly, sortable bool) error {
 	if name == "" {
 		return errors.New("storage index 'name' must be set")
 	}
@@ -601,20 +1009,34 @@ func (si *LocalStorageIndex) CreateIndex(ctx context.Context, name, collection,
 		return fmt.Errorf("cannot create index: index with name %q already exists", name)
 	}
 
-	idx, err := bluge.OpenWriter(BlugeInMemoryConfig())
-	if err != nil {
-		return err
-	}
-
 	storageIdx := &storageIndex{
 		Name:       name,
 		Collection: collection,
 		Key:        key,
 		Fields:     fields,
 		MaxEntries: maxEntries,
-		Index:      idx,
 		IndexOnly:  indexOnly,
+		Sortable:   sortable,
 	}
+
+	cfg := BlugeInMemoryConfig()
+	if si.config.IndexDir != "" {
+		storageIdx.path = filepath.Join(si.config.IndexDir, url.PathEscape(name))
+		storageIdx.checkpoint = si.readCheckpoint(storageIdx)
+		if storageIdx.checkpoint.IsZero() {
+			// Start from scratch if there is no valid checkpoint.
+			if err := os.RemoveAll(storageIdx.path); err != nil {
+				return fmt.Errorf("failed to remove storage index directory: %w", err)
+			}
+		}
+		cfg = BlugeDiskConfig(storageIdx.path)
+	}
+
+	idx, err := bluge.OpenWriter(cfg)
+	if err != nil {
+		return err
+	}
+	storageIdx.Index = idx
 	si.indexByName[name] = storageIdx
 
 	if indices, ok := si.indicesByCollection[collection]; ok {
@@ -634,11 +1056,84 @@ func (si *LocalStorageIndex) CreateIndex(ctx context.Context, name, collection,
 		"fields":      fields,
 		"max_entries": maxEntries,
 		"index_only":  indexOnly,
+		"sortable":    sortable,
 	}))
 
 	return nil
 }
 
+// readCheckpoint returns the checkpoint of a persisted index, or zero if it is missing or the index configuration has changed.
+func (si *LocalStorageIndex) readCheckpoint(idx *storageIndex) time.Time {
+	data, err := os.ReadFile(idx.path + ".checkpoint")
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			si.logger.Warn("Failed to read storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
+		}
+		return time.Time{}
+	}
+	cp := &storageIndexCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		si.logger.Warn("Failed to unmarshal storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
+		return time.Time{}
+	}
+	if cp.Collection != idx.Collection || cp.Key != idx.Key || cp.MaxEntries != idx.MaxEntries || cp.IndexOnly != idx.IndexOnly || cp.Sortable != idx.Sortable || !slices.Equal(cp.Fields, idx.Fields) {
+		si.logger.Info("Storage index configuration changed, rebuilding.", zap.String("index_name", idx.Name))
+		return time.Time{}
+	}
+	return cp.UpdateTime
+}
+
+// writeCheckpoint records that all storage updates before the given time have been applied to a persisted index. The
+// caller must hold the index lock.
+func (si *LocalStorageIndex) writeCheckpoint(idx *storageIndex, updateTime time.Time) error {
+	if idx.path == "" {
+		return nil
+	}
+	idx.checkpointedAt = time.Now()
+	data, err := json.Marshal(&storageIndexCheckpoint{
+		UpdateTime: updateTime.Add(-storageIndexCheckpointMargin),
+		Collection: idx.Collection,
+		Key:        idx.Key,
+		Fields:     idx.Fields,
+		MaxEntries: idx.MaxEntries,
+		IndexOnly:  idx.IndexOnly,
+		Sortable:   idx.Sortable,
+	})
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves a partial checkpoint.
+	tmp := idx.path + ".checkpoint.tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idx.path+".checkpoint")
+}
+
+// ChangeFeed returns the feed of committed storage changes.
+func (si *LocalStorageIndex) ChangeFeed() *StorageChangeFeed {
+	return si.changeFeed
+}
+
+// Stop stops the change feed, and checkpoints and closes the persisted indices.
+func (si *LocalStorageIndex) Stop() {
+	si.changeFeed.Stop()
+
+	for _, idx := range si.indexByName {
+		if idx.path == "" {
+			continue
+		}
+		idx.mu.Lock()
+		if err := si.writeCheckpoint(idx, time.Now()); err != nil {
+			si.logger.Warn("Failed to write storage index checkpoint", zap.String("index_name", idx.Name), zap.Error(err))
+		}
+		if err := idx.Index.Close(); err != nil {
+			si.logger.Warn("Failed to close storage index", zap.String("index_name", idx.Name), zap.Error(err))
+		}
+		idx.mu.Unlock()
+	}
+}
+
 func (si *LocalStorageIndex) RegisterFilters(runtime *Runtime) {
 	for name := range si.indexByName {
 		fn := runtime.StorageIndexFilterFunction(name)
@@ -648,6 +1143,13 @@ func (si *LocalStorageIndex) RegisterFilters(runtime *Runtime) {
 	}
 }
 
+// storageIndexNotExpiredQuery restricts the query to index entries that have not expired. Expired objects remain
+// indexed until the expiry reaper deletes them.
+func storageIndexNotExpiredQuery(query bluge.Query) bluge.Query {
+	expired := bluge.NewNumericRangeInclusiveQuery(bluge.MinNumeric, float64(time.Now().Unix()), false, true).SetField("expire_time")
+	return bluge.NewBooleanQuery().AddMust(query).AddMustNot(expired)
+}
+
 func (si *LocalStorageIndex) storageIndexDocumentId(collection, key, userID string) bluge.Identifier {
 	id := fmt.Sprintf("%s.%s.%s", collection, key, userID)
 
@@ -0,0 +1,237 @@
+// Copyright 2024 The Nakama Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/aggregations"
+)
+
+const (
+	StorageIndexAggregationTypeCount = "count"
+	StorageIndexAggregationTypeTerms = "terms"
+	StorageIndexAggregationTypeRange = "range"
+	StorageIndexAggregationTypeMin   = "min"
+	StorageIndexAggregationTypeMax   = "max"
+	StorageIndexAggregationTypeAvg   = "avg"
+	StorageIndexAggregationTypeSum   = "sum"
+
+	storageIndexAggregationDefaultTermsSize = 10
+	storageIndexAggregationMaxTermsSize     = 1000
+	storageIndexAggregationMaxAggregations  = 20
+	storageIndexAggregationTotalName        = "_total"
+)
+
+// StorageIndexAggregation describes a single aggregation over the entries matching a storage index query.
+type StorageIndexAggregation struct {
+	Name   string                          `json:"name"`             // The name of the aggregation in the result.
+	Type   string                          `json:"type"`             // One of count, terms, range, min, max, avg or sum.
+	Field  string                          `json:"field,omitempty"`  // The indexed field, e.g. "value.group". Not used by count.
+	Size   int                             `json:"size,omitempty"`   // The maximum number of terms buckets (default 10).
+	Ranges []*StorageIndexAggregationRange `json:"ranges,omitempty"` // The range buckets.
+}
+
+// StorageIndexAggregationRange is a numeric range bucket, including From and excluding To. A nil bound is unbounded.
+type StorageIndexAggregationRange struct {
+	Name string   `json:"name,omitempty"`
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+}
+
+type StorageIndexAggregateResult struct {
+	Total        uint64                                    `json:"total"` // The number of entries matching the query.
+	Aggregations map[string]*StorageIndexAggregationResult `json:"aggregations"`
+}
+
+type StorageIndexAggregationResult struct {
+	Value   *float64                             `json:"value,omitempty"`   // The metric value. Nil if there were no values.
+	Buckets []*StorageIndexAggregationBucketItem `json:"buckets,omitempty"` // The terms or range buckets.
+}
+
+type StorageIndexAggregationBucketItem struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+}
+
+func (a *StorageIndexAggregation) build() (search.Aggregation, error) {
+	if a.Name == "" || strings.HasPrefix(a.Name, "_") {
+		return nil, fmt.Errorf("invalid aggregation name %q", a.Name)
+	}
+	if a.Type != StorageIndexAggregationTypeCount && a.Field == "" {
+		return nil, fmt.Errorf("aggregation %q must set a field", a.Name)
+	}
+
+	switch a.Type {
+	case StorageIndexAggregationTypeCount:
+		return aggregations.CountMatches(), nil
+	case StorageIndexAggregationTypeTerms:
+		size := a.Size
+		if size == 0 {
+			size = storageIndexAggregationDefaultTermsSize
+		}
+		if size < 1 || size > storageIndexAggregationMaxTermsSize {
+			return nil, fmt.Errorf("aggregation %q size must be 1-%d", a.Name, storageIndexAggregationMaxTermsSize)
+		}
+		return aggregations.NewTermsAggregation(search.Field(a.Field), size), nil
+	case StorageIndexAggregationTypeRange:
+		if len(a.Ranges) == 0 {
+			return nil, fmt.Errorf("aggregation %q must set at least one range", a.Name)
+		}
+		agg := aggregations.Ranges(search.Field(a.Field))
+		for _, r := range a.Ranges {
+			low, high := math.Inf(-1), math.Inf(1)
+			if r.From != nil {
+				low = *r.From
+			}
+			if r.To != nil {
+				high = *r.To
+			}
+			agg.AddRange(aggregations.NamedRange(r.name(), low, high))
+		}
+		return agg, nil
+	case StorageIndexAggregationTypeMin:
+		return aggregations.Min(search.Field(a.Field)), nil
+	case StorageIndexAggregationTypeMax:
+		return aggregations.Max(search.Field(a.Field)), nil
+	case StorageIndexAggregationTypeAvg:
+		return aggregations.Avg(search.Field(a.Field)), nil
+	case StorageIndexAggregationTypeSum:
+		return aggregations.Sum(search.Field(a.Field)), nil
+	default:
+		return nil, fmt.Errorf("aggregation %q has an unknown type %q", a.Name, a.Type)
+	}
+}
+
+func (r *StorageIndexAggregationRange) name() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	bound := func(v *float64) string {
+		if v == nil {
+			return "*"
+		}
+		return fmt.Sprintf("%g", *v)
+	}
+	return bound(r.From) + "-" + bound(r.To)
+}
+
+// storageIndexAggregationFields overrides the fields an aggregation requests to be loaded.
+type storageIndexAggregationFields struct {
+	search.Aggregation
+	fields []string
+}
+
+func (a *storageIndexAggregationFields) Fields() []string {
+	return a.fields
+}
+
+// Aggregate computes the aggregations over the index entries matching the query. Aggregating over indexed values
+// requires a sortable index.
+func (si *LocalStorageIndex) Aggregate(ctx context.Context, indexName, query string, aggs []*StorageIndexAggregation) (*StorageIndexAggregateResult, error) {
+	idx, found := si.indexByName[indexName]
+	if !found {
+		return nil, fmt.Errorf("index %q not found", indexName)
+	}
+	if len(aggs) == 0 {
+		return nil, errors.New("expects at least one aggregation")
+	}
+	if len(aggs) > storageIndexAggregationMaxAggregations {
+		return nil, fmt.Errorf("expects at most %d aggregations", storageIndexAggregationMaxAggregations)
+	}
+
+	if query == "" {
+		query = "*"
+	}
+	parsedQuery, err := ParseQueryString(query)
+	if err != nil {
+		return nil, err
+	}
+
+	searchReq := bluge.NewTopNSearch(0, storageIndexNotExpiredQuery(parsedQuery))
+	searchReq.Aggregations().Add(storageIndexAggregationTotalName, aggregations.CountMatches())
+	loadedFields := make(map[string]struct{}, len(aggs))
+	for _, a := range aggs {
+		if _, ok := searchReq.Aggregations()[a.Name]; ok {
+			return nil, fmt.Errorf("duplicate aggregation name %q", a.Name)
+		}
+		if a.Field != "" && !idx.Sortable && strings.HasPrefix(a.Field, "value.") {
+			// Aggregations read the document values only stored for sortable indices.
+			return nil, fmt.Errorf("index %q must be sortable to aggregate by %q", indexName, a.Field)
+		}
+		agg, err := a.build()
+		if err != nil {
+			return nil, err
+		}
+		// Bluge loads the document values once per listed field, so a field shared by
+		// several aggregations would otherwise be counted more than once.
+		fields := make([]string, 0, 1)
+		for _, f := range agg.Fields() {
+			if _, ok := loadedFields[f]; !ok {
+				loadedFields[f] = struct{}{}
+				fields = append(fields, f)
+			}
+		}
+		searchReq.AddAggregation(a.Name, &storageIndexAggregationFields{Aggregation: agg, fields: fields})
+	}
+
+	indexReader, err := idx.reader()
+	if err != nil {
+		return nil, err
+	}
+	results, err := indexReader.Search(ctx, searchReq)
+	if err != nil {
+		return nil, err
+	}
+	// Consume the iterator to complete the aggregations.
+	next, err := results.Next()
+	for err == nil && next != nil {
+		next, err = results.Next()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := results.Aggregations()
+	total := uint64(bucket.Metric(storageIndexAggregationTotalName))
+	result := &StorageIndexAggregateResult{
+		Total:        total,
+		Aggregations: make(map[string]*StorageIndexAggregationResult, len(aggs)),
+	}
+	for _, a := range aggs {
+		r := &StorageIndexAggregationResult{}
+		switch a.Type {
+		case StorageIndexAggregationTypeTerms, StorageIndexAggregationTypeRange:
+			buckets := bucket.Buckets(a.Name)
+			r.Buckets = make([]*StorageIndexAggregationBucketItem, 0, len(buckets))
+			for _, b := range buckets {
+				r.Buckets = append(r.Buckets, &StorageIndexAggregationBucketItem{Key: b.Name(), Count: b.Count()})
+			}
+		default:
+			v := bucket.Metric(a.Name)
+			if !math.IsInf(v, 0) && !math.IsNaN(v) {
+				r.Value = &v
+			}
+		}
+		result.Aggregations[a.Name] = r
+	}
+	return result, nil
+}
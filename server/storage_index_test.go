@@ -3,6 +3,8 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -60,12 +62,12 @@ func TestLocalStorageIndex_Write(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	if err := storageIdx.CreateIndex(ctx, indexName1, collection1, key, []string{"one", "two"}, maxEntries1, false); err != nil {
+	if err := storageIdx.CreateIndex(ctx, indexName1, collection1, key, []string{"one", "two"}, maxEntries1, false, false); err != nil {
 		t.Fatal(err.Error())
 	}
 
 	// Matches all keys
-	if err := storageIdx.CreateIndex(ctx, indexName2, collection1, "", []string{"three"}, maxEntries2, false); err != nil {
+	if err := storageIdx.CreateIndex(ctx, indexName2, collection1, "", []string{"three"}, maxEntries2, false, false); err != nil {
 		t.Fatal(err.Error())
 	}
 
@@ -125,13 +127,13 @@ func TestLocalStorageIndex_Write(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		entries, err := storageIdx.List(ctx, uuid.Nil, indexName1, "", maxEntries1) // Match all
+		entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName1, "", maxEntries1, nil, "") // Match all
 		if err != nil {
 			t.Fatal(err.Error())
 		}
 		assert.Len(t, entries.Objects, 2, "indexed results length was not 2")
 
-		entries, err = storageIdx.List(ctx, uuid.Nil, indexName2, "", maxEntries1) // Match all
+		entries, _, _, err = storageIdx.List(ctx, uuid.Nil, indexName2, "", maxEntries1, nil, "") // Match all
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -172,7 +174,7 @@ func TestLocalStorageIndex_Write(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		entries, err := storageIdx.List(ctx, uuid.Nil, indexName1, "+value.three:3", maxEntries1)
+		entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName1, "+value.three:3", maxEntries1, nil, "")
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -275,7 +277,7 @@ func TestLocalStorageIndex_Write(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		entries, err := storageIdx.List(ctx, uuid.Nil, indexName2, "", maxEntries2)
+		entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName2, "", maxEntries2, nil, "")
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -336,7 +338,7 @@ func TestLocalStorageIndex_List(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		if err := storageIdx.CreateIndex(ctx, indexName, collection, key, []string{"one", "two", "three"}, maxEntries, true); err != nil {
+		if err := storageIdx.CreateIndex(ctx, indexName, collection, key, []string{"one", "two", "three"}, maxEntries, true, false); err != nil {
 			t.Fatal(err.Error())
 		}
 
@@ -371,7 +373,7 @@ func TestLocalStorageIndex_List(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		entries, err := storageIdx.List(ctx, uuid.Nil, indexName, "value.one:1 value.three:3", 10)
+		entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName, "value.one:1 value.three:3", 10, nil, "")
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -429,7 +431,7 @@ func TestLocalStorageIndex_List(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		if err := storageIdx.CreateIndex(ctx, indexName, collection, key, []string{"one", "two", "three"}, maxEntries, true); err != nil {
+		if err := storageIdx.CreateIndex(ctx, indexName, collection, key, []string{"one", "two", "three"}, maxEntries, true, false); err != nil {
 			t.Fatal(err.Error())
 		}
 
@@ -464,7 +466,7 @@ func TestLocalStorageIndex_List(t *testing.T) {
 			t.Fatal(err.Error())
 		}
 
-		entries, err := storageIdx.List(ctx, uuid.Nil, indexName, "value.one:1 value.three:3", 10)
+		entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName, "value.one:1 value.three:3", 10, nil, "")
 		if err != nil {
 			t.Fatal(err.Error())
 		}
@@ -489,6 +491,236 @@ func TestLocalStorageIndex_List(t *testing.T) {
 	})
 }
 
+func TestLocalStorageIndex_ListOrderCursor(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_index_order"
+	collection := "test_collection"
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"score", "name"}, 100, true, true); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	objects := make([]*api.StorageObject, 0, 5)
+	for i, name := range []string{"c", "a", "e", "b", "d"} {
+		value, _ := json.Marshal(map[string]any{
+			"score": i % 2,
+			"name":  name,
+		})
+		objects = append(objects, &api.StorageObject{
+			Collection:     collection,
+			Key:            name,
+			UserId:         uuid.Nil.String(),
+			Value:          string(value),
+			PermissionRead: 2,
+			CreateTime:     timestamppb.Now(),
+			UpdateTime:     timestamppb.Now(),
+		})
+	}
+	storageIdx.Write(ctx, objects)
+
+	keys := make([]string, 0, 5)
+	cursor := ""
+	for i := 0; i < 3; i++ {
+		entries, newCursor, total, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 2, []string{"-value.score", "value.name"}, cursor)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		assert.Equal(t, 5, total, "total did not match")
+		for _, o := range entries.Objects {
+			keys = append(keys, o.Key)
+		}
+		cursor = newCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, "", cursor, "expected no cursor on the last page")
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, keys, "results were not sorted")
+
+	_, _, _, err = storageIdx.List(ctx, uuid.Nil, indexName, "", 2, nil, "invalid")
+	assert.Error(t, err, "expected an error for a malformed cursor")
+}
+
+func TestLocalStorageIndex_Persistence(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_index_persisted"
+	collection := "test_collection"
+	config := &StorageConfig{IndexDir: t.TempDir()}
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, config, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+	storageIdx.Write(ctx, []*api.StorageObject{
+		{
+			Collection:     collection,
+			Key:            "key",
+			UserId:         uuid.Nil.String(),
+			Value:          `{"one":1}`,
+			PermissionRead: 2,
+			CreateTime:     timestamppb.Now(),
+			UpdateTime:     timestamppb.Now(),
+		},
+	})
+	storageIdx.Stop()
+
+	// Reopen with the same configuration.
+	storageIdx, err = NewLocalStorageIndex(logger, nil, config, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.False(t, storageIdx.(*LocalStorageIndex).indexByName[indexName].checkpoint.IsZero(), "expected a checkpoint")
+	entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Len(t, entries.Objects, 1, "expected the persisted entry")
+	storageIdx.Stop()
+
+	// Reopen with a different configuration, which discards the persisted index.
+	storageIdx, err = NewLocalStorageIndex(logger, nil, config, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one", "two"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.True(t, storageIdx.(*LocalStorageIndex).indexByName[indexName].checkpoint.IsZero(), "expected no checkpoint")
+	entries, _, _, err = storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Len(t, entries.Objects, 0, "expected an empty index")
+	storageIdx.Stop()
+}
+
+func TestLocalStorageIndex_PersistenceDeletedWhileStopped(t *testing.T) {
+	db := NewDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	indexName := "test_index_persisted_deletes"
+	collection := "test_collection_persisted_deletes"
+	config := &StorageConfig{IndexDir: t.TempDir()}
+
+	storageIdx, err := NewLocalStorageIndex(logger, db, config, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	writeOps := StorageOpWrites{
+		{
+			OwnerID: uuid.Nil.String(),
+			Object:  &api.WriteStorageObject{Collection: collection, Key: "key1", Value: `{"one":1}`},
+		},
+		{
+			OwnerID: uuid.Nil.String(),
+			Object:  &api.WriteStorageObject{Collection: collection, Key: "key2", Value: `{"one":2}`},
+		},
+	}
+	if _, _, err := StorageWriteObjects(ctx, logger, db, metrics, storageIdx, true, writeOps); err != nil {
+		t.Fatal(err.Error())
+	}
+	defer func() {
+		if _, err := db.ExecContext(ctx, "DELETE FROM storage WHERE collection = $1", collection); err != nil {
+			t.Fatalf("Failed to teardown: %s", err.Error())
+		}
+	}()
+	storageIdx.Stop()
+
+	// Delete an object while the index is not running.
+	if _, err := db.ExecContext(ctx, "DELETE FROM storage WHERE collection = $1 AND key = $2", collection, "key2"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	storageIdx, err = NewLocalStorageIndex(logger, db, config, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer storageIdx.Stop()
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.False(t, storageIdx.(*LocalStorageIndex).indexByName[indexName].checkpoint.IsZero(), "expected a checkpoint")
+	if err := storageIdx.Load(ctx); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if assert.Len(t, entries.Objects, 1, "expected the deleted entry to be removed") {
+		assert.Equal(t, "key1", entries.Objects[0].Key)
+	}
+}
+
+func TestLocalStorageIndex_ConcurrentCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_index_concurrent_checkpoint"
+	collection := "test_collection"
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{IndexDir: t.TempDir()}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer storageIdx.Stop()
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 100, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Every write is due a checkpoint, so concurrent writers race to checkpoint unless serialised.
+	storageIdx.(*LocalStorageIndex).indexByName[indexName].checkpointedAt = time.Now().Add(-time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				storageIdx.Write(ctx, []*api.StorageObject{
+					{
+						Collection:     collection,
+						Key:            fmt.Sprintf("key_%d_%d", i, j),
+						UserId:         uuid.Nil.String(),
+						Value:          `{"one":1}`,
+						PermissionRead: 2,
+						CreateTime:     timestamppb.Now(),
+						UpdateTime:     timestamppb.Now(),
+					},
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, _, total, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 100, nil, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Len(t, entries.Objects, 80)
+	assert.Equal(t, 80, total)
+}
+
 func TestLocalStorageIndex_Delete(t *testing.T) {
 	db := NewDB(t)
 	defer db.Close()
@@ -513,7 +745,7 @@ func TestLocalStorageIndex_Delete(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, maxEntries, false); err != nil {
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, maxEntries, false, false); err != nil {
 		t.Fatal(err.Error())
 	}
 
@@ -540,7 +772,7 @@ func TestLocalStorageIndex_Delete(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	entries, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 10)
+	entries, _, _, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
 	if err != nil {
 		t.Fatal(err.Error())
 	}
@@ -557,7 +789,7 @@ func TestLocalStorageIndex_Delete(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	entries, err = storageIdx.List(ctx, uuid.Nil, indexName, "", 10)
+	entries, _, _, err = storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
 	if err != nil {
 		t.Fatal(err.Error())
 	}
@@ -578,3 +810,128 @@ func TestLocalStorageIndex_Delete(t *testing.T) {
 		t.Fatalf("Failed to teardown: %s", err.Error())
 	}
 }
+
+func TestLocalStorageIndex_Aggregate(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_index_aggregate"
+	collection := "test_collection"
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"group", "level"}, 100, true, true); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	objects := make([]*api.StorageObject, 0, 5)
+	for i, group := range []string{"blue", "red", "blue", "green", "blue"} {
+		value, _ := json.Marshal(map[string]any{
+			"group": group,
+			"level": (i + 1) * 10,
+		})
+		objects = append(objects, &api.StorageObject{
+			Collection:     collection,
+			Key:            "key" + strconv.Itoa(i),
+			UserId:         uuid.Nil.String(),
+			Value:          string(value),
+			PermissionRead: 2,
+			CreateTime:     timestamppb.Now(),
+			UpdateTime:     timestamppb.Now(),
+		})
+	}
+	storageIdx.Write(ctx, objects)
+
+	twenty, forty := 20.0, 40.0
+	result, err := storageIdx.Aggregate(ctx, indexName, "", []*StorageIndexAggregation{
+		{Name: "count", Type: StorageIndexAggregationTypeCount},
+		{Name: "groups", Type: StorageIndexAggregationTypeTerms, Field: "value.group", Size: 1},
+		{Name: "levels", Type: StorageIndexAggregationTypeRange, Field: "value.level", Ranges: []*StorageIndexAggregationRange{
+			{To: &twenty},
+			{Name: "mid", From: &twenty, To: &forty},
+			{From: &forty},
+		}},
+		{Name: "min", Type: StorageIndexAggregationTypeMin, Field: "value.level"},
+		{Name: "max", Type: StorageIndexAggregationTypeMax, Field: "value.level"},
+		{Name: "avg", Type: StorageIndexAggregationTypeAvg, Field: "value.level"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assert.Equal(t, uint64(5), result.Total)
+	assert.Equal(t, 5.0, *result.Aggregations["count"].Value)
+	assert.Equal(t, []*StorageIndexAggregationBucketItem{{Key: "blue", Count: 3}}, result.Aggregations["groups"].Buckets)
+	levels := make(map[string]uint64)
+	for _, b := range result.Aggregations["levels"].Buckets {
+		levels[b.Key] = b.Count
+	}
+	assert.Equal(t, map[string]uint64{"*-20": 1, "mid": 2, "40-*": 2}, levels)
+	assert.Equal(t, 10.0, *result.Aggregations["min"].Value)
+	assert.Equal(t, 50.0, *result.Aggregations["max"].Value)
+	assert.Equal(t, 30.0, *result.Aggregations["avg"].Value)
+
+	result, err = storageIdx.Aggregate(ctx, indexName, "+value.group:purple", []*StorageIndexAggregation{
+		{Name: "avg", Type: StorageIndexAggregationTypeAvg, Field: "value.level"},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.Equal(t, uint64(0), result.Total)
+	assert.Nil(t, result.Aggregations["avg"].Value, "expected no value without matches")
+
+	_, err = storageIdx.Aggregate(ctx, indexName, "", []*StorageIndexAggregation{
+		{Name: "a", Type: StorageIndexAggregationTypeCount},
+		{Name: "a", Type: StorageIndexAggregationTypeCount},
+	})
+	assert.Error(t, err, "expected an error for duplicate names")
+}
+
+func TestLocalStorageIndex_Expired(t *testing.T) {
+	ctx := context.Background()
+
+	indexName := "test_index_expired"
+	collection := "test_collection"
+
+	storageIdx, err := NewLocalStorageIndex(logger, nil, &StorageConfig{}, metrics)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer storageIdx.Stop()
+	if err := storageIdx.CreateIndex(ctx, indexName, collection, "", []string{"one"}, 10, true, false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	objects := make([]*api.StorageObject, 0, 3)
+	for _, key := range []string{"expired", "live", "forever"} {
+		objects = append(objects, &api.StorageObject{
+			Collection:     collection,
+			Key:            key,
+			UserId:         uuid.Nil.String(),
+			Value:          `{"one":1}`,
+			PermissionRead: 2,
+			CreateTime:     timestamppb.Now(),
+			UpdateTime:     timestamppb.Now(),
+		})
+	}
+	storageIdx.WriteExpiring(ctx, objects, []time.Time{time.Now().Add(-time.Minute), time.Now().Add(time.Hour)})
+
+	entries, _, total, err := storageIdx.List(ctx, uuid.Nil, indexName, "", 10, nil, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	keys := make([]string, 0, len(entries.Objects))
+	for _, o := range entries.Objects {
+		keys = append(keys, o.Key)
+	}
+	assert.ElementsMatch(t, []string{"live", "forever"}, keys, "expired entries should not be listed")
+	assert.Equal(t, 2, total)
+
+	result, err := storageIdx.Aggregate(ctx, indexName, "", []*StorageIndexAggregation{{Name: "count", Type: StorageIndexAggregationTypeCount}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	assert.EqualValues(t, 2, result.Total, "expired entries should not be aggregated")
+}